@@ -43,7 +43,10 @@ test_setup() {
 
 test_run() {
     set -e
-    bin/braccept -testName "${TEST_NAME:?}" -keysDirPath "${BRCONF_DIR}/keys" "$@"
+    local cases="acceptance/${TEST_DIR}/cases"
+    local args=(-reportPath "${TEST_ARTIFACTS_DIR}/braccept.xml")
+    [ -d "$cases" ] && args+=(-casesPath "$cases")
+    bin/braccept -testName "${TEST_NAME:?}" -keysDirPath "${BRCONF_DIR}/keys" "${args[@]}" "$@"
 }
 
 test_teardown() {
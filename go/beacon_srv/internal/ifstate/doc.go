@@ -31,4 +31,12 @@
 //
 // The handler handles interface state requests. It can be instantiated with
 // the NewHandler constructor.
+//
+// Link down handler
+//
+// The link down handler handles the notifications of the border routers, that
+// BFD detected the link of an interface to be down. It expires the interface
+// and triggers the revoker. Notifications are only accepted from the border
+// router that owns the interface. It can be instantiated with the
+// NewLinkDownHandler constructor.
 package ifstate
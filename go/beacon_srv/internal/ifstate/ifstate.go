@@ -176,6 +176,22 @@ func (intf *Interface) Expire() bool {
 	return false
 }
 
+// ExpireNow changes the state of the interface to expired, regardless of
+// when it was last activated, unless it is already expired or revoked. The
+// times for last beacon origination and propagation are reset to the zero
+// value. The return value indicates whether the state changed.
+func (intf *Interface) ExpireNow() bool {
+	intf.mu.Lock()
+	defer intf.mu.Unlock()
+	if intf.state == Expired || intf.state == Revoked {
+		return false
+	}
+	intf.lastOriginate = time.Time{}
+	intf.lastPropagate = time.Time{}
+	intf.state = Expired
+	return true
+}
+
 // Revoke changes the state of the interface to revoked and updates the
 // revocation, unless the current state is active. In that case, the
 // interface has been activated in the meantime and should not be revoked.
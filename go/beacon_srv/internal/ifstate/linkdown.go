@@ -0,0 +1,110 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ifstate
+
+import (
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/topology"
+)
+
+type linkDownHandler struct {
+	ia             addr.IA
+	intfs          *Interfaces
+	triggerRevoker func()
+	request        *infra.Request
+}
+
+// NewLinkDownHandler creates a handler for the interface state infos the
+// border routers of the local AS send, when BFD detects the link of an
+// interface to be down. The handler expires the inactive interfaces, and
+// calls triggerRevoker, such that they are revoked without waiting for the
+// keepalive timeout. Interface state infos are only accepted from the control
+// address of the border router that owns the interface, according to the
+// topology. The messages are not signed, i.e., the handler relies on the
+// local network not allowing to spoof the border router addresses.
+func NewLinkDownHandler(ia addr.IA, intfs *Interfaces, triggerRevoker func()) infra.Handler {
+	f := func(r *infra.Request) *infra.HandlerResult {
+		handler := &linkDownHandler{
+			ia:             ia,
+			intfs:          intfs,
+			triggerRevoker: triggerRevoker,
+			request:        r,
+		}
+		return handler.Handle()
+	}
+	return infra.HandlerFunc(f)
+}
+
+func (h *linkDownHandler) Handle() *infra.HandlerResult {
+	logger := log.FromCtx(h.request.Context())
+	infos, ok := h.request.Message.(*path_mgmt.IFStateInfos)
+	if !ok {
+		logger.Error("[LinkDownHandler] Wrong message type",
+			"type", common.TypeOf(h.request.Message))
+		return infra.MetricsErrInternal
+	}
+	peer, ok := h.request.Peer.(*snet.Addr)
+	if !ok || !peer.IA.Equal(h.ia) {
+		logger.Error("[LinkDownHandler] Interface state infos not from local AS",
+			"peer", h.request.Peer)
+		return infra.MetricsErrInvalid
+	}
+	logger.Debug("[LinkDownHandler] Received", "infos", infos)
+	for _, info := range infos.Infos {
+		intf := h.intfs.Get(info.IfID)
+		if intf == nil {
+			continue
+		}
+		if !fromOwningBR(peer, intf.TopoInfo()) {
+			logger.Error("[LinkDownHandler] Interface state info not from owning border router",
+				"peer", peer, "ifid", info.IfID)
+			return infra.MetricsErrInvalid
+		}
+	}
+	var expired bool
+	for _, info := range infos.Infos {
+		if info.Active {
+			continue
+		}
+		intf := h.intfs.Get(info.IfID)
+		if intf == nil {
+			logger.Warn("[LinkDownHandler] Link down for non-existent ifid", "ifid", info.IfID)
+			continue
+		}
+		if intf.ExpireNow() {
+			logger.Info("[LinkDownHandler] Border router detected link down", "ifid", info.IfID)
+			expired = true
+		}
+	}
+	if expired {
+		h.triggerRevoker()
+	}
+	return infra.MetricsResultOk
+}
+
+// fromOwningBR checks that peer is the control address of the border router
+// described by topoInfo.
+func fromOwningBR(peer *snet.Addr, topoInfo topology.IFInfo) bool {
+	if topoInfo.CtrlAddrs == nil {
+		return false
+	}
+	ctrlAddr := topoInfo.CtrlAddrs.PublicAddr(topoInfo.CtrlAddrs.Overlay)
+	return ctrlAddr != nil && peer.Host != nil && ctrlAddr.Equal(peer.Host)
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ifstate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/smartystreets/assertions"
+	"github.com/smartystreets/assertions/should"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestLinkDownHandler(t *testing.T) {
+	topoProvider := xtest.TopoProviderFromFile(t, "testdata/topology.json")
+	localIA := topoProvider.Get().ISD_AS
+	// br1-ff00_0_111-1 owns the interfaces 101 and 104.
+	br1 := &snet.Addr{IA: localIA, Host: &addr.AppAddr{
+		L3: addr.HostFromIPStr("127.0.0.81"),
+		L4: addr.NewL4UDPInfo(31029),
+	}}
+
+	tests := []struct {
+		name      string
+		peer      *snet.Addr
+		infos     []*path_mgmt.IFStateInfo
+		result    *infra.HandlerResult
+		expired   []bool
+		triggered bool
+	}{
+		{
+			name: "Link down from local border router",
+			peer: br1,
+			infos: []*path_mgmt.IFStateInfo{
+				{IfID: 101, Active: false},
+				{IfID: 104, Active: true},
+			},
+			result:    infra.MetricsResultOk,
+			expired:   []bool{true, false},
+			triggered: true,
+		},
+		{
+			name: "Link down from border router not owning the interface",
+			peer: br1,
+			infos: []*path_mgmt.IFStateInfo{
+				{IfID: 101, Active: false},
+				{IfID: 102, Active: false},
+			},
+			result:  infra.MetricsErrInvalid,
+			expired: []bool{false, false},
+		},
+		{
+			name: "Link down from other host in local AS",
+			peer: &snet.Addr{
+				IA: localIA,
+				Host: &addr.AppAddr{
+					L3: addr.HostFromIPStr("127.0.0.42"),
+					L4: addr.NewL4UDPInfo(31029),
+				},
+			},
+			infos: []*path_mgmt.IFStateInfo{
+				{IfID: 101, Active: false},
+			},
+			result:  infra.MetricsErrInvalid,
+			expired: []bool{false},
+		},
+		{
+			name: "Link down from remote AS",
+			peer: &snet.Addr{IA: xtest.MustParseIA("1-ff00:0:112")},
+			infos: []*path_mgmt.IFStateInfo{
+				{IfID: 101, Active: false},
+				{IfID: 102, Active: false},
+			},
+			result:  infra.MetricsErrInvalid,
+			expired: []bool{false, false},
+		},
+		{
+			name: "Link down for unknown interface",
+			peer: br1,
+			infos: []*path_mgmt.IFStateInfo{
+				{IfID: 42, Active: false},
+			},
+			result: infra.MetricsResultOk,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assertions.New(t)
+			intfs := NewInterfaces(topoProvider.Get().IFInfoMap, Config{})
+			activateAll(intfs)
+			var triggered bool
+			h := NewLinkDownHandler(localIA, intfs, func() { triggered = true })
+			msg := &path_mgmt.IFStateInfos{Infos: test.infos}
+			req := infra.NewRequest(context.Background(), msg, nil, test.peer, 0)
+			assert.So(h.Handle(req), should.Equal, test.result)
+			for i, expired := range test.expired {
+				state := intfs.Get(test.infos[i].IfID).State()
+				assert.So(state == Expired, should.Equal, expired)
+			}
+			assert.So(triggered, should.Equal, test.triggered)
+		})
+	}
+}
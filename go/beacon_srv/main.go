@@ -153,6 +153,8 @@ func realMain() int {
 	msgr.AddHandler(infra.ChainRequest, trustStore.NewChainReqHandler(false))
 	msgr.AddHandler(infra.TRCRequest, trustStore.NewTRCReqHandler(false))
 	msgr.AddHandler(infra.IfStateReq, ifstate.NewHandler(intfs))
+	msgr.AddHandler(infra.IfStateInfos, ifstate.NewLinkDownHandler(topo.ISD_AS, intfs,
+		func() { tasks.TriggerRevoker() }))
 	msgr.AddHandler(infra.SignedRev, revocation.NewHandler(store,
 		trustStore.NewVerifier(), 5*time.Second))
 	msgr.AddHandler(infra.Seg, beaconing.NewHandler(topo.ISD_AS, intfs, store,
@@ -483,6 +485,18 @@ func (t *periodicTasks) createSigner(topo *topology.Topo) (infra.Signer, error)
 	return signer, nil
 }
 
+// TriggerRevoker runs the revoker immediately, if the tasks are running.
+func (t *periodicTasks) TriggerRevoker() {
+	if t == nil {
+		return
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.running {
+		t.revoker.TriggerRun()
+	}
+}
+
 func (t *periodicTasks) Kill() {
 	t.mtx.Lock()
 	defer t.mtx.Unlock()
@@ -0,0 +1,119 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bfd implements bidirectional forwarding detection (BFD) between the
+// router and the peer routers of its external interfaces.
+//
+// The BFD control packets of RFC 5880 are sent directly over the link, as the
+// payload of SCION packets without a path, with the L4 type common.L4BFD. A
+// session that has been up goes down if no control packet is received from the
+// peer router within the detection time, i.e., within a few intervals. The
+// router then marks the interface as down in ifstate, and notifies the beacon
+// service, which revokes the interface.
+package bfd
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/rctx"
+	"github.com/scionproto/scion/go/border/rpkt"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+)
+
+var (
+	cfg      brconf.BFD
+	onChange func(ifid common.IFIDType, up bool)
+	// updateMtx serializes calls to Update.
+	updateMtx sync.Mutex
+	// sessions contains the current map[common.IFIDType]*Session.
+	sessions atomic.Value
+)
+
+func init() {
+	sessions.Store(map[common.IFIDType]*Session(nil))
+}
+
+// Init configures BFD. The function f is called whenever a session goes up,
+// or goes down after having been up. It must be called before Update.
+func Init(c brconf.BFD, f func(ifid common.IFIDType, up bool)) {
+	cfg, onChange = c, f
+}
+
+// Update starts sessions for the external interfaces of ctx that do not have
+// one, and stops the sessions of removed interfaces. It does nothing if BFD is
+// disabled.
+func Update(ctx *rctx.Ctx) {
+	if !cfg.Enable {
+		return
+	}
+	updateMtx.Lock()
+	defer updateMtx.Unlock()
+	old := sessions.Load().(map[common.IFIDType]*Session)
+	m := make(map[common.IFIDType]*Session, len(ctx.Conf.Net.IFs))
+	for ifid := range ctx.Conf.Net.IFs {
+		if s, ok := old[ifid]; ok {
+			m[ifid] = s
+			continue
+		}
+		s := newSession(ifid, cfg, sender(ifid), onChange)
+		m[ifid] = s
+		go func() {
+			defer log.LogPanicAndExit()
+			s.Run()
+		}()
+	}
+	sessions.Store(m)
+	for ifid, s := range old {
+		if _, ok := m[ifid]; !ok {
+			s.Close()
+		}
+	}
+}
+
+// Process handles the BFD control packet b received on the external interface
+// ifid.
+func Process(ifid common.IFIDType, b common.RawBytes) error {
+	s, ok := sessions.Load().(map[common.IFIDType]*Session)[ifid]
+	if !ok {
+		return common.NewBasicError("No BFD session for interface", nil, "ifid", ifid)
+	}
+	msg := &layers.BFD{}
+	if err := msg.DecodeFromBytes(b, gopacket.NilDecodeFeedback); err != nil {
+		return common.NewBasicError("Unable to parse BFD packet", err, "ifid", ifid)
+	}
+	return s.Handle(msg, time.Now())
+}
+
+// sender returns a function that sends BFD control packets over the external
+// interface ifid of the current router context.
+func sender(ifid common.IFIDType) func(*layers.BFD) error {
+	return func(msg *layers.BFD) error {
+		buf := gopacket.NewSerializeBuffer()
+		if err := msg.SerializeTo(buf, gopacket.SerializeOptions{}); err != nil {
+			return err
+		}
+		rp, err := rpkt.NewBFDPkt(rctx.Get(), ifid, buf.Bytes())
+		if err != nil {
+			return err
+		}
+		return rp.Route()
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfd
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/metrics"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+)
+
+// rttInterval is the interval at which the round trip time to the peer router
+// is measured with a poll sequence.
+const rttInterval = time.Second
+
+// Session is a BFD session with the peer router of an external interface. It
+// implements the asynchronous mode of RFC 5880, without authentication and
+// without the echo function.
+type Session struct {
+	IFID common.IFIDType
+	cfg  brconf.BFD
+	// send sends a BFD control packet to the peer router.
+	send func(msg *layers.BFD) error
+	// onChange is called whenever the session goes up, or goes down after
+	// having been up.
+	onChange func(ifid common.IFIDType, up bool)
+	logger   log.Logger
+	stop     chan struct{}
+
+	mtx        sync.Mutex
+	state      layers.BFDState
+	diag       layers.BFDDiagnostic
+	localDisc  layers.BFDDiscriminator
+	remoteDisc layers.BFDDiscriminator
+	// remoteMinTx, remoteMinRx and remoteMult are the timing parameters
+	// announced by the peer router.
+	remoteMinTx time.Duration
+	remoteMinRx time.Duration
+	remoteMult  uint8
+	// lastRecv is the time the last valid BFD control packet was received.
+	lastRecv time.Time
+	// pollSent is the time the outstanding poll was sent, or the zero value
+	// if there is no outstanding poll.
+	pollSent time.Time
+	// lastPoll is the time the last poll was sent.
+	lastPoll time.Time
+	// wasUp indicates whether the session has been up. A session that never
+	// came up does not indicate a link failure, e.g., if the peer router does
+	// not run BFD.
+	wasUp bool
+
+	upGauge  prometheus.Gauge
+	rttGauge prometheus.Gauge
+}
+
+// newSession creates a session in state Down.
+func newSession(ifid common.IFIDType, cfg brconf.BFD, send func(*layers.BFD) error,
+	onChange func(common.IFIDType, bool)) *Session {
+
+	sock := fmt.Sprintf("intf:%d", ifid)
+	return &Session{
+		IFID:      ifid,
+		cfg:       cfg,
+		send:      send,
+		onChange:  onChange,
+		logger:    log.New("bfd", ifid),
+		stop:      make(chan struct{}),
+		state:     layers.BFDStateDown,
+		localDisc: layers.BFDDiscriminator(rand.Uint32() | 1),
+		upGauge:   metrics.BFDUp.WithLabelValues(sock),
+		rttGauge:  metrics.BFDRTT.WithLabelValues(sock),
+	}
+}
+
+// Run periodically sends BFD control packets to the peer router, and checks
+// whether the detection time has expired, until Close is called.
+func (s *Session) Run() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-timer.C:
+			s.checkDetect(now)
+			s.sendCtrl(now, false)
+			timer.Reset(s.txInterval())
+		}
+	}
+}
+
+// Close stops the session.
+func (s *Session) Close() {
+	close(s.stop)
+	s.upGauge.Set(0)
+}
+
+// State returns the current state of the session.
+func (s *Session) State() layers.BFDState {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.state
+}
+
+// Handle processes a BFD control packet received from the peer router.
+func (s *Session) Handle(msg *layers.BFD, now time.Time) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	if err := s.checkDiscs(msg); err != nil {
+		s.mtx.Unlock()
+		return err
+	}
+	// Only take the timing parameters from packets that passed the checks.
+	s.remoteDisc = msg.MyDiscriminator
+	s.remoteMinTx = interval(msg.DesiredMinTxInterval)
+	s.remoteMinRx = interval(msg.RequiredMinRxInterval)
+	s.remoteMult = uint8(msg.DetectMultiplier)
+	s.lastRecv = now
+	if msg.Final && !s.pollSent.IsZero() {
+		s.rttGauge.Set(now.Sub(s.pollSent).Seconds())
+		s.pollSent = time.Time{}
+	}
+	var change *bool
+	switch {
+	case msg.State == layers.BFDStateAdminDown:
+		if s.state != layers.BFDStateDown {
+			change = s.setState(layers.BFDStateDown, layers.BFDDiagnosticNeighborSignalDown)
+		}
+	case s.state == layers.BFDStateDown:
+		switch msg.State {
+		case layers.BFDStateDown:
+			change = s.setState(layers.BFDStateInit, layers.BFDDiagnosticNone)
+		case layers.BFDStateInit:
+			change = s.setState(layers.BFDStateUp, layers.BFDDiagnosticNone)
+		}
+	case s.state == layers.BFDStateInit:
+		if msg.State == layers.BFDStateInit || msg.State == layers.BFDStateUp {
+			change = s.setState(layers.BFDStateUp, layers.BFDDiagnosticNone)
+		}
+	case s.state == layers.BFDStateUp:
+		if msg.State == layers.BFDStateDown {
+			change = s.setState(layers.BFDStateDown, layers.BFDDiagnosticNeighborSignalDown)
+		}
+	}
+	s.mtx.Unlock()
+	s.notify(change)
+	if msg.Poll {
+		// Answer the poll immediately, such that the peer router can measure
+		// the round trip time.
+		s.sendCtrl(now, true)
+	}
+	return nil
+}
+
+// checkDiscs checks the discriminators of msg against the session. Once the
+// discriminator of the peer router is known, packets without our
+// discriminator are dropped, such that they cannot bring the session down.
+// The caller must hold the lock.
+func (s *Session) checkDiscs(msg *layers.BFD) error {
+	switch {
+	case msg.YourDiscriminator == 0 && s.remoteDisc != 0:
+		return common.NewBasicError("Missing discriminator", nil, "state", msg.State)
+	case msg.YourDiscriminator != 0 && msg.YourDiscriminator != s.localDisc:
+		return common.NewBasicError("Unknown discriminator", nil,
+			"expected", s.localDisc, "actual", msg.YourDiscriminator)
+	case s.remoteDisc != 0 && msg.MyDiscriminator != s.remoteDisc:
+		return common.NewBasicError("Unknown remote discriminator", nil,
+			"expected", s.remoteDisc, "actual", msg.MyDiscriminator)
+	}
+	return nil
+}
+
+// checkDetect brings the session down if no control packet has been
+// received within the detection time.
+func (s *Session) checkDetect(now time.Time) {
+	s.mtx.Lock()
+	var change *bool
+	if s.state != layers.BFDStateDown && now.Sub(s.lastRecv) > s.detectTime() {
+		change = s.setState(layers.BFDStateDown, layers.BFDDiagnosticTimeExpired)
+	}
+	if !s.pollSent.IsZero() && now.Sub(s.pollSent) > s.detectTime() {
+		// The poll or its answer got lost.
+		s.pollSent = time.Time{}
+	}
+	s.mtx.Unlock()
+	s.notify(change)
+}
+
+// setState changes the state of the session. It returns a pointer to the new
+// liveness of the interface if it changed, nil otherwise. The caller must
+// hold the lock, and must call notify after releasing it.
+func (s *Session) setState(state layers.BFDState, diag layers.BFDDiagnostic) *bool {
+	prev := s.state
+	s.state, s.diag = state, diag
+	s.logger.Debug("BFD session state changed", "prev", prev, "state", state, "diag", diag)
+	metrics.BFDStateChanges.WithLabelValues(fmt.Sprintf("intf:%d", s.IFID),
+		state.String()).Inc()
+	switch {
+	case state == layers.BFDStateUp:
+		s.wasUp = true
+		s.upGauge.Set(1)
+		up := true
+		return &up
+	case prev == layers.BFDStateUp:
+		s.remoteDisc = 0
+		s.pollSent = time.Time{}
+		s.upGauge.Set(0)
+		up := false
+		return &up
+	case state == layers.BFDStateDown:
+		s.remoteDisc = 0
+	}
+	return nil
+}
+
+func (s *Session) notify(change *bool) {
+	if change == nil {
+		return
+	}
+	if *change {
+		log.Info("BFD session up", "ifid", s.IFID)
+	} else {
+		s.mtx.Lock()
+		diag := s.diag
+		s.mtx.Unlock()
+		log.Info("BFD session down", "ifid", s.IFID, "diag", diag)
+	}
+	s.onChange(s.IFID, *change)
+}
+
+// sendCtrl sends a control packet to the peer router. If final is set, the
+// packet answers a poll of the peer router.
+func (s *Session) sendCtrl(now time.Time, final bool) {
+	s.mtx.Lock()
+	msg := &layers.BFD{
+		Version:               1,
+		Diagnostic:            s.diag,
+		State:                 s.state,
+		Final:                 final,
+		DetectMultiplier:      layers.BFDDetectMultiplier(s.cfg.DetectMult),
+		MyDiscriminator:       s.localDisc,
+		YourDiscriminator:     s.remoteDisc,
+		DesiredMinTxInterval:  micros(s.cfg.Interval.Duration),
+		RequiredMinRxInterval: micros(s.cfg.Interval.Duration),
+	}
+	if !final && s.state == layers.BFDStateUp && s.pollSent.IsZero() &&
+		now.Sub(s.lastPoll) >= rttInterval {
+
+		msg.Poll = true
+		s.pollSent, s.lastPoll = now, now
+	}
+	s.mtx.Unlock()
+	if err := s.send(msg); err != nil {
+		s.logger.Debug("Unable to send BFD packet", "err", err)
+	}
+}
+
+// txInterval returns the interval until the next control packet is sent. It
+// is the larger of the local interval and the interval the peer router
+// requires, reduced by a random jitter of up to 25% as in RFC 5880.
+func (s *Session) txInterval() time.Duration {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	tx := s.cfg.Interval.Duration
+	if s.remoteMinRx > tx {
+		tx = s.remoteMinRx
+	}
+	return tx - time.Duration(rand.Int63n(int64(tx)/4+1))
+}
+
+// detectTime returns the time after which the session goes down if no
+// control packet is received. The caller must hold the lock.
+func (s *Session) detectTime() time.Duration {
+	rx := s.cfg.Interval.Duration
+	if s.remoteMinTx > rx {
+		rx = s.remoteMinTx
+	}
+	mult := s.remoteMult
+	if mult == 0 {
+		mult = s.cfg.DetectMult
+	}
+	return time.Duration(mult) * rx
+}
+
+// validate performs the checks of RFC 5880 that do not depend on the session
+// state.
+func validate(msg *layers.BFD) error {
+	switch {
+	case msg.Version != 1:
+		return common.NewBasicError("Unsupported BFD version", nil, "version", msg.Version)
+	case msg.AuthPresent:
+		return common.NewBasicError("BFD authentication not supported", nil)
+	case msg.DetectMultiplier == 0:
+		return common.NewBasicError("Invalid detect multiplier", nil)
+	case msg.Multipoint:
+		return common.NewBasicError("Multipoint BFD not supported", nil)
+	case msg.MyDiscriminator == 0:
+		return common.NewBasicError("Invalid discriminator", nil)
+	case msg.YourDiscriminator == 0 &&
+		msg.State != layers.BFDStateDown && msg.State != layers.BFDStateAdminDown:
+		return common.NewBasicError("Missing discriminator", nil, "state", msg.State)
+	}
+	return nil
+}
+
+func interval(i layers.BFDTimeInterval) time.Duration {
+	return time.Duration(i) * time.Microsecond
+}
+
+func micros(d time.Duration) layers.BFDTimeInterval {
+	return layers.BFDTimeInterval(d / time.Microsecond)
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/metrics"
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+func init() {
+	metrics.Init("test")
+}
+
+// testPair connects two sessions with an in-memory link.
+type testPair struct {
+	a, b *Session
+	now  time.Time
+	// down drops all packets on the link.
+	down    bool
+	changes map[common.IFIDType][]bool
+}
+
+func newTestPair() *testPair {
+	p := &testPair{
+		now:     time.Now(),
+		changes: make(map[common.IFIDType][]bool),
+	}
+	cfg := brconf.BFD{Enable: true, DetectMult: 3}
+	cfg.Interval.Duration = 10 * time.Millisecond
+	onChange := func(ifid common.IFIDType, up bool) {
+		p.changes[ifid] = append(p.changes[ifid], up)
+	}
+	p.a = newSession(1, cfg, p.sendTo(func() *Session { return p.b }), onChange)
+	p.b = newSession(2, cfg, p.sendTo(func() *Session { return p.a }), onChange)
+	return p
+}
+
+// sendTo returns a send function that passes the serialized control packet
+// to the session returned by dst.
+func (p *testPair) sendTo(dst func() *Session) func(*layers.BFD) error {
+	return func(msg *layers.BFD) error {
+		if p.down {
+			return nil
+		}
+		buf := gopacket.NewSerializeBuffer()
+		if err := msg.SerializeTo(buf, gopacket.SerializeOptions{}); err != nil {
+			return err
+		}
+		decoded := &layers.BFD{}
+		if err := decoded.DecodeFromBytes(buf.Bytes(), gopacket.NilDecodeFeedback); err != nil {
+			return err
+		}
+		return dst().Handle(decoded, p.now)
+	}
+}
+
+func (p *testPair) up() {
+	p.a.sendCtrl(p.now, false)
+	p.b.sendCtrl(p.now, false)
+	p.a.sendCtrl(p.now, false)
+}
+
+func TestSession(t *testing.T) {
+	Convey("Sessions come up with a three-way handshake", t, func() {
+		p := newTestPair()
+		p.a.sendCtrl(p.now, false)
+		SoMsg("b init", p.b.State(), ShouldEqual, layers.BFDStateInit)
+		p.b.sendCtrl(p.now, false)
+		SoMsg("a up", p.a.State(), ShouldEqual, layers.BFDStateUp)
+		p.a.sendCtrl(p.now, false)
+		SoMsg("b up", p.b.State(), ShouldEqual, layers.BFDStateUp)
+		SoMsg("a changes", p.changes[1], ShouldResemble, []bool{true})
+		SoMsg("b changes", p.changes[2], ShouldResemble, []bool{true})
+	})
+	Convey("Session goes down if the detection time expires", t, func() {
+		p := newTestPair()
+		p.up()
+		p.down = true
+		p.a.checkDetect(p.now.Add(30 * time.Millisecond))
+		SoMsg("a up within detection time", p.a.State(), ShouldEqual, layers.BFDStateUp)
+		p.a.checkDetect(p.now.Add(31 * time.Millisecond))
+		SoMsg("a down", p.a.State(), ShouldEqual, layers.BFDStateDown)
+		SoMsg("a changes", p.changes[1], ShouldResemble, []bool{true, false})
+		Convey("and comes up again once the link is restored", func() {
+			p.b.checkDetect(p.now.Add(31 * time.Millisecond))
+			SoMsg("b down", p.b.State(), ShouldEqual, layers.BFDStateDown)
+			SoMsg("b changes", p.changes[2], ShouldResemble, []bool{true, false})
+			p.down = false
+			p.now = p.now.Add(time.Second)
+			p.up()
+			SoMsg("a up", p.a.State(), ShouldEqual, layers.BFDStateUp)
+			SoMsg("b up", p.b.State(), ShouldEqual, layers.BFDStateUp)
+		})
+	})
+	Convey("Session that never came up does not go down", t, func() {
+		p := newTestPair()
+		p.a.sendCtrl(p.now, false)
+		p.b.checkDetect(p.now.Add(time.Second))
+		SoMsg("b down", p.b.State(), ShouldEqual, layers.BFDStateDown)
+		SoMsg("b changes", p.changes[2], ShouldBeEmpty)
+	})
+	Convey("Polls are answered immediately", t, func() {
+		p := newTestPair()
+		p.up()
+		p.now = p.now.Add(rttInterval)
+		p.a.sendCtrl(p.now, false)
+		SoMsg("poll answered", p.a.pollSent.IsZero(), ShouldBeTrue)
+		SoMsg("last poll", p.a.lastPoll, ShouldEqual, p.now)
+	})
+	Convey("Packets with a wrong discriminator are rejected", t, func() {
+		p := newTestPair()
+		p.up()
+		msg := &layers.BFD{
+			Version:           1,
+			State:             layers.BFDStateDown,
+			DetectMultiplier:  3,
+			MyDiscriminator:   1,
+			YourDiscriminator: p.a.localDisc + 1,
+		}
+		SoMsg("err", p.a.Handle(msg, p.now), ShouldNotBeNil)
+		SoMsg("a up", p.a.State(), ShouldEqual, layers.BFDStateUp)
+	})
+	Convey("Packets without discriminator are rejected once the peer is known", t, func() {
+		p := newTestPair()
+		p.up()
+		msg := &layers.BFD{
+			Version:              1,
+			State:                layers.BFDStateAdminDown,
+			DetectMultiplier:     1,
+			MyDiscriminator:      p.b.localDisc,
+			DesiredMinTxInterval: micros(time.Hour),
+		}
+		SoMsg("err", p.a.Handle(msg, p.now), ShouldNotBeNil)
+		SoMsg("a up", p.a.State(), ShouldEqual, layers.BFDStateUp)
+		SoMsg("remote timers unchanged", p.a.remoteMinTx, ShouldEqual, 10*time.Millisecond)
+	})
+	Convey("Packets with a wrong remote discriminator are rejected", t, func() {
+		p := newTestPair()
+		p.up()
+		msg := &layers.BFD{
+			Version:           1,
+			State:             layers.BFDStateDown,
+			DetectMultiplier:  3,
+			MyDiscriminator:   p.b.localDisc + 2,
+			YourDiscriminator: p.a.localDisc,
+		}
+		SoMsg("err", p.a.Handle(msg, p.now), ShouldNotBeNil)
+		SoMsg("a up", p.a.State(), ShouldEqual, layers.BFDStateUp)
+	})
+}
@@ -61,6 +61,10 @@ func br_child() int {
 
 	failures += revocation_child_to_internal_host()
 
+	failures += child_to_internal_host_sibra()
+	failures += internal_host_to_child_sibra()
+	failures += internal_host_to_child_sibra_bad_mac()
+
 	return failures
 }
 
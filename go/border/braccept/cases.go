@@ -0,0 +1,345 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+)
+
+// Test cases can also be described in YAML files, which are loaded at runtime with the
+// -casesPath flag, so new cases do not require recompiling braccept. A case file looks like:
+//
+//  Cases:
+//    - Name: child_to_internal_host_${Host}
+//      Desc: child to internal/host ${Host}
+//      Timeout: 250ms
+//      Params:
+//        - {Host: 192.168.0.51}
+//        - {Host: 192.168.0.71}
+//      Send:
+//        - Name: pkt0
+//          Dev: veth_141
+//          Layers: |
+//            Ethernet: SrcMAC=f0:0d:ca:fe:be:ef DstMAC=f0:0d:ca:fe:00:14 EthernetType=IPv4
+//            ...
+//            SCION: NextHdr=UDP CurrInfoF=4 CurrHopF=6 SrcType=IPv4 DstType=IPv4
+//              ADDR: SrcIA=1-ff00:0:4 Src=172.16.4.1 DstIA=1-ff00:0:1 Dst=${Host}
+//              ...
+//          Checksums:
+//            - {L4: UDP, L3: IP4}
+//            - {L4: UDP_1, L3: SCION}
+//          Macs:
+//            - {Scion: SCION, Info: IF_1, HopF: HF_2}
+//      Expect:
+//        - Base: pkt0
+//          Dev: veth_int
+//          Layers: |
+//            IP4: Src=192.168.0.11 Dst=${Host} Checksum=0
+//            ...
+//          Checksums:
+//            - {L4: UDP, L3: IP4}
+//
+// Layers uses the syntax of the parser package, indented with spaces instead of tabs. A packet
+// with a Base is a clone of the named packet, updated with its Layers. Checksums are not
+// inherited from the base and must be set again. Every ${Key} is replaced by the value of each
+// entry in Params, running the case once per entry.
+
+// caseFile is the top level structure of a YAML test case file.
+type caseFile struct {
+	Cases []*caseDesc `yaml:"Cases"`
+}
+
+// caseDesc describes a test case, which sends packets and waits for the expected packets.
+type caseDesc struct {
+	Name    string              `yaml:"Name"`
+	Desc    string              `yaml:"Desc"`
+	Timeout string              `yaml:"Timeout"`
+	Params  []map[string]string `yaml:"Params"`
+	Send    []*pktDesc          `yaml:"Send"`
+	Expect  []*pktDesc          `yaml:"Expect"`
+}
+
+// pktDesc describes a packet on a given interface.
+type pktDesc struct {
+	Name      string         `yaml:"Name"`
+	Base      string         `yaml:"Base"`
+	Dev       string         `yaml:"Dev"`
+	Layers    string         `yaml:"Layers"`
+	Checksums []checksumDesc `yaml:"Checksums"`
+	Macs      []macDesc      `yaml:"Macs"`
+	SIBRAMacs []string       `yaml:"SIBRAMacs"`
+}
+
+type checksumDesc struct {
+	L4 string `yaml:"L4"`
+	L3 string `yaml:"L3"`
+}
+
+type macDesc struct {
+	Scion   string `yaml:"Scion"`
+	Info    string `yaml:"Info"`
+	HopF    string `yaml:"HopF"`
+	MacHopF string `yaml:"MacHopF"`
+}
+
+// LoadCases loads the test cases from the YAML file at path, or from all the YAML files in
+// path if it is a directory. Parametrized cases are expanded into one case per parameter set.
+func LoadCases(path string) ([]*caseDesc, error) {
+	files := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return nil, common.NewBasicError("Unable to stat test cases", err, "path", path)
+	} else if info.IsDir() {
+		ymlFiles, _ := filepath.Glob(filepath.Join(path, "*.yml"))
+		yamlFiles, _ := filepath.Glob(filepath.Join(path, "*.yaml"))
+		files = append(ymlFiles, yamlFiles...)
+		sort.Strings(files)
+	}
+	var cases []*caseDesc
+	for _, name := range files {
+		fileCases, err := loadCaseFile(name)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, fileCases...)
+	}
+	return cases, nil
+}
+
+func loadCaseFile(name string) ([]*caseDesc, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to read test cases", err, "file", name)
+	}
+	var cf caseFile
+	if err := yaml.UnmarshalStrict(b, &cf); err != nil {
+		return nil, common.NewBasicError("Unable to parse test cases", err, "file", name)
+	}
+	var cases []*caseDesc
+	for _, c := range cf.Cases {
+		if c.Name == "" {
+			return nil, common.NewBasicError("Test case without name", nil, "file", name)
+		}
+		insts, err := c.instances()
+		if err != nil {
+			return nil, common.NewBasicError("Unable to expand test case", err,
+				"file", name, "case", c.Name)
+		}
+		cases = append(cases, insts...)
+	}
+	return cases, nil
+}
+
+// instances returns one case for each parameter set, with all the parameters substituted.
+func (c *caseDesc) instances() ([]*caseDesc, error) {
+	if len(c.Params) == 0 {
+		return []*caseDesc{c}, nil
+	}
+	insts := make([]*caseDesc, 0, len(c.Params))
+	for i, params := range c.Params {
+		var missing []string
+		expand := func(s string) string {
+			return os.Expand(s, func(key string) string {
+				v, ok := params[key]
+				if !ok {
+					missing = append(missing, key)
+				}
+				return v
+			})
+		}
+		inst := &caseDesc{
+			Name:    expand(c.Name),
+			Desc:    expand(c.Desc),
+			Timeout: expand(c.Timeout),
+			Send:    expandPkts(c.Send, expand),
+			Expect:  expandPkts(c.Expect, expand),
+		}
+		if len(missing) > 0 {
+			return nil, common.NewBasicError("Undefined parameters", nil,
+				"params", i, "missing", missing)
+		}
+		if inst.Name == c.Name {
+			inst.Name = fmt.Sprintf("%s/%d", c.Name, i)
+		}
+		insts = append(insts, inst)
+	}
+	return insts, nil
+}
+
+func expandPkts(pkts []*pktDesc, expand func(string) string) []*pktDesc {
+	res := make([]*pktDesc, len(pkts))
+	for i, p := range pkts {
+		res[i] = &pktDesc{
+			Name:      expand(p.Name),
+			Base:      expand(p.Base),
+			Dev:       expand(p.Dev),
+			Layers:    expand(p.Layers),
+			Checksums: make([]checksumDesc, len(p.Checksums)),
+			Macs:      make([]macDesc, len(p.Macs)),
+			SIBRAMacs: make([]string, len(p.SIBRAMacs)),
+		}
+		for j, cs := range p.Checksums {
+			res[i].Checksums[j] = checksumDesc{L4: expand(cs.L4), L3: expand(cs.L3)}
+		}
+		for j, m := range p.Macs {
+			res[i].Macs[j] = macDesc{Scion: expand(m.Scion), Info: expand(m.Info),
+				HopF: expand(m.HopF), MacHopF: expand(m.MacHopF)}
+		}
+		for j, tag := range p.SIBRAMacs {
+			res[i].SIBRAMacs[j] = expand(tag)
+		}
+	}
+	return res
+}
+
+func (c *caseDesc) desc() string {
+	if c.Desc != "" {
+		return c.Desc
+	}
+	return c.Name
+}
+
+func (c *caseDesc) timeout() string {
+	if c.Timeout != "" {
+		return c.Timeout
+	}
+	return defaultTimeout
+}
+
+// packets builds the packets to send and the expected packets of the test case.
+func (c *caseDesc) packets() (send, expect []*DevTaggedLayers, err error) {
+	// The parser panics on invalid packet descriptions.
+	defer func() {
+		if r := recover(); r != nil {
+			err = common.NewBasicError("Invalid packet description", nil, "err", r)
+		}
+	}()
+	if _, err := time.ParseDuration(c.timeout()); err != nil {
+		return nil, nil, common.NewBasicError("Invalid timeout", err)
+	}
+	named := make(map[string]*DevTaggedLayers)
+	build := func(descs []*pktDesc) ([]*DevTaggedLayers, error) {
+		pkts := make([]*DevTaggedLayers, len(descs))
+		for i, d := range descs {
+			pkt, err := d.build(named)
+			if err != nil {
+				return nil, err
+			}
+			if d.Name != "" {
+				named[d.Name] = pkt
+			}
+			pkts[i] = pkt
+		}
+		return pkts, nil
+	}
+	if send, err = build(c.Send); err != nil {
+		return nil, nil, err
+	}
+	if expect, err = build(c.Expect); err != nil {
+		return nil, nil, err
+	}
+	return send, expect, nil
+}
+
+func (d *pktDesc) build(named map[string]*DevTaggedLayers) (*DevTaggedLayers, error) {
+	var pkt *DevTaggedLayers
+	if d.Base != "" {
+		base, ok := named[d.Base]
+		if !ok {
+			return nil, common.NewBasicError("Unknown base packet", nil, "base", d.Base)
+		}
+		pkt = base.CloneAndUpdate(tabIndent(d.Layers))
+	} else {
+		if d.Layers == "" {
+			return nil, common.NewBasicError("Packet without layers", nil, "name", d.Name)
+		}
+		pkt = AllocatePacket()
+		pkt.ParsePacket(tabIndent(d.Layers))
+	}
+	if d.Dev != "" {
+		pkt.SetDev(d.Dev)
+	}
+	if pkt.Dev == "" {
+		return nil, common.NewBasicError("Packet without device", nil, "name", d.Name)
+	}
+	for _, cs := range d.Checksums {
+		pkt.SetChecksum(cs.L4, cs.L3)
+	}
+	for _, m := range d.Macs {
+		pkt.GenerateMac(m.Scion, m.Info, m.HopF, m.MacHopF)
+	}
+	for _, tag := range d.SIBRAMacs {
+		pkt.GenerateSIBRAMac(tag)
+	}
+	return pkt, nil
+}
+
+// tabIndent converts the space indentation of a YAML packet description into the tab
+// indentation required by the parser. The parser only cares about the relative indentation
+// of the lines, so each distinct indentation width becomes one tab level.
+func tabIndent(s string) string {
+	var lines []string
+	var widths []int
+	levels := make(map[int]int)
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		w := len(l) - len(strings.TrimLeft(l, " "))
+		lines = append(lines, l[w:])
+		widths = append(widths, w)
+		levels[w] = 0
+	}
+	sorted := make([]int, 0, len(levels))
+	for w := range levels {
+		sorted = append(sorted, w)
+	}
+	sort.Ints(sorted)
+	for i, w := range sorted {
+		levels[w] = i
+	}
+	for i := range lines {
+		lines[i] = strings.Repeat("\t", levels[widths[i]]) + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RunCases runs the test cases in order and returns the number of failed cases.
+func RunCases(cases []*caseDesc) int {
+	var failures int
+	for _, c := range cases {
+		send, expect, err := c.packets()
+		if err != nil {
+			log.Info(fmt.Sprintf("Test %s: %s\n", c.desc(), fail()))
+			log.Error(fmt.Sprintf("%s\n\n", err))
+			recordResult(c.desc(), 0, []string{err.Error()})
+			failures++
+			continue
+		}
+		SendPackets(send...)
+		failures += ExpectedPackets(c.desc(), c.timeout(), expect...)
+	}
+	return failures
+}
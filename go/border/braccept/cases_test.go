@@ -0,0 +1,215 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/border/braccept/parser"
+)
+
+func TestCaseInstances(t *testing.T) {
+	pkt := &pktDesc{
+		Name:      "pkt0",
+		Dev:       "veth_${If}",
+		Layers:    "IP4: Src=192.168.0.11 Dst=${Host}",
+		Checksums: []checksumDesc{{L4: "UDP", L3: "IP4"}},
+		Macs:      []macDesc{{Scion: "SCION", Info: "IF_1", HopF: "HF_${Hop}"}},
+	}
+	tests := []struct {
+		Name  string
+		Case  *caseDesc
+		Names []string
+		Pkts  []*pktDesc
+		Err   bool
+	}{
+		{
+			Name:  "case without params",
+			Case:  &caseDesc{Name: "plain", Send: []*pktDesc{pkt}},
+			Names: []string{"plain"},
+			Pkts:  []*pktDesc{pkt},
+		},
+		{
+			Name: "params are expanded",
+			Case: &caseDesc{
+				Name: "host_${Host}",
+				Params: []map[string]string{
+					{"Host": "192.168.0.71", "If": "int", "Hop": "1"},
+					{"Host": "192.168.0.72", "If": "141", "Hop": "2"},
+				},
+				Send: []*pktDesc{pkt},
+			},
+			Names: []string{"host_192.168.0.71", "host_192.168.0.72"},
+			Pkts: []*pktDesc{
+				{
+					Name:      "pkt0",
+					Dev:       "veth_int",
+					Layers:    "IP4: Src=192.168.0.11 Dst=192.168.0.71",
+					Checksums: []checksumDesc{{L4: "UDP", L3: "IP4"}},
+					Macs:      []macDesc{{Scion: "SCION", Info: "IF_1", HopF: "HF_1"}},
+					SIBRAMacs: []string{},
+				},
+				{
+					Name:      "pkt0",
+					Dev:       "veth_141",
+					Layers:    "IP4: Src=192.168.0.11 Dst=192.168.0.72",
+					Checksums: []checksumDesc{{L4: "UDP", L3: "IP4"}},
+					Macs:      []macDesc{{Scion: "SCION", Info: "IF_1", HopF: "HF_2"}},
+					SIBRAMacs: []string{},
+				},
+			},
+		},
+		{
+			Name: "unparametrized name is numbered",
+			Case: &caseDesc{
+				Name:   "fixed",
+				Params: []map[string]string{{"Host": "a"}, {"Host": "b"}},
+			},
+			Names: []string{"fixed/0", "fixed/1"},
+		},
+		{
+			Name: "missing param",
+			Case: &caseDesc{
+				Name:   "host_${Host}",
+				Params: []map[string]string{{"Host": "192.168.0.71"}},
+				Send:   []*pktDesc{pkt},
+			},
+			Err: true,
+		},
+	}
+	Convey("Parametrized cases are expanded", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				insts, err := test.Case.instances()
+				if test.Err {
+					SoMsg("err", err, ShouldNotBeNil)
+					return
+				}
+				SoMsg("err", err, ShouldBeNil)
+				var names []string
+				var pkts []*pktDesc
+				for _, inst := range insts {
+					names = append(names, inst.Name)
+					pkts = append(pkts, inst.Send...)
+				}
+				SoMsg("names", names, ShouldResemble, test.Names)
+				SoMsg("pkts", pkts, ShouldResemble, test.Pkts)
+			})
+		}
+	})
+}
+
+func TestTabIndent(t *testing.T) {
+	tests := []struct {
+		Name string
+		In   string
+		Out  string
+	}{
+		{
+			Name: "empty",
+		},
+		{
+			Name: "flat",
+			In:   "Ethernet: EthernetType=IPv4\nIP4: NextHdr=UDP\n",
+			Out:  "Ethernet: EthernetType=IPv4\nIP4: NextHdr=UDP",
+		},
+		{
+			Name: "nested",
+			In: "SCION: NextHdr=UDP\n  ADDR: Dst=${Host}\n  IF_1: Hops=2\n" +
+				"    HF_1: ConsEgress=141\n",
+			Out: "SCION: NextHdr=UDP\n\tADDR: Dst=${Host}\n\tIF_1: Hops=2\n" +
+				"\t\tHF_1: ConsEgress=141",
+		},
+		{
+			Name: "uneven indentation and blank lines",
+			In: "  SCION: NextHdr=UDP\n\n     IF_1: Hops=2\n        HF_1: ConsEgress=141\n" +
+				"  UDP_1: Src=1",
+			Out: "SCION: NextHdr=UDP\n\tIF_1: Hops=2\n\t\tHF_1: ConsEgress=141\nUDP_1: Src=1",
+		},
+	}
+	Convey("Space indentation is converted to tabs", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				SoMsg("out", tabIndent(test.In), ShouldEqual, test.Out)
+			})
+		}
+	})
+}
+
+func TestPktBuild(t *testing.T) {
+	const baseLayers = "Ethernet: SrcMAC=f0:0d:ca:fe:be:ef DstMAC=f0:0d:ca:fe:00:14 " +
+		"EthernetType=IPv4\nIP4: Src=192.168.14.3 Dst=192.168.14.2 NextHdr=UDP\n"
+	tests := []struct {
+		Name string
+		Desc *pktDesc
+		Dev  string
+		Dst  string
+		Err  bool
+	}{
+		{
+			Name: "clone inherits the device",
+			Desc: &pktDesc{Base: "pkt0", Layers: "IP4: Dst=192.168.0.71\n"},
+			Dev:  "veth_141",
+			Dst:  "192.168.0.71",
+		},
+		{
+			Name: "clone sets the device",
+			Desc: &pktDesc{Base: "pkt0", Dev: "veth_int"},
+			Dev:  "veth_int",
+			Dst:  "192.168.14.2",
+		},
+		{
+			Name: "unknown base",
+			Desc: &pktDesc{Base: "pkt1", Layers: "IP4: Dst=192.168.0.71\n"},
+			Err:  true,
+		},
+		{
+			Name: "packet without layers",
+			Desc: &pktDesc{Name: "pkt1", Dev: "veth_int"},
+			Err:  true,
+		},
+		{
+			Name: "packet without device",
+			Desc: &pktDesc{Name: "pkt1", Layers: baseLayers},
+			Err:  true,
+		},
+	}
+	Convey("Packets are built from their description", t, func() {
+		named := make(map[string]*DevTaggedLayers)
+		base, err := (&pktDesc{Name: "pkt0", Dev: "veth_141", Layers: baseLayers}).build(named)
+		SoMsg("base err", err, ShouldBeNil)
+		named["pkt0"] = base
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				pkt, err := test.Desc.build(named)
+				if test.Err {
+					SoMsg("err", err, ShouldNotBeNil)
+					return
+				}
+				SoMsg("err", err, ShouldBeNil)
+				SoMsg("dev", pkt.Dev, ShouldEqual, test.Dev)
+				SoMsg("dst", ip4Dst(pkt), ShouldEqual, test.Dst)
+				SoMsg("base dev", base.Dev, ShouldEqual, "veth_141")
+				SoMsg("base dst", ip4Dst(base), ShouldEqual, "192.168.14.2")
+			})
+		}
+	})
+}
+
+func ip4Dst(pkt *DevTaggedLayers) string {
+	return pkt.TaggedLayers.GetTaggedLayer("IP4").(*parser.IP4TaggedLayer).DstIP.String()
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"time"
 
@@ -23,7 +24,10 @@ import (
 
 	"github.com/scionproto/scion/go/border/braccept/layers"
 	"github.com/scionproto/scion/go/border/braccept/shared"
+	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/spse"
+	"github.com/scionproto/scion/go/lib/spse/scmp_auth"
 )
 
 func compareLayersHex(act, exp gopacket.Layer) {
@@ -53,6 +57,8 @@ func ComparePackets(act, exp gopacket.Packet) error {
 			err = compareLayersUDP(l, layersExp[i])
 		case *layers.SCMP:
 			err = compareLayersSCMP(l, layersExp[i])
+		case *layers.ScionE2E:
+			err = compareLayersE2E(l, layersExp[i])
 		case *gopacket.Payload:
 			err = compareLayersPayload(l, layersExp[i])
 		default:
@@ -123,6 +129,27 @@ func compareLayersSCMP(act, exp gopacket.Layer) error {
 	return compareLayers(act, exp)
 }
 
+func compareLayersE2E(act, exp gopacket.Layer) error {
+	actE2E := act.(*layers.ScionE2E)
+	expE2E, ok := exp.(*layers.ScionE2E)
+	if ok && isSCMPAuthDRKey(actE2E) && isSCMPAuthDRKey(expE2E) {
+		expMAC := expE2E.Data[scmp_auth.MACOffset:scmp_auth.DRKeyTotalLength]
+		if bytes.Equal(expMAC, make([]byte, scmp_auth.MACLength)) {
+			// The MAC of the expected packet is 0, ignore it.
+			// This is useful for SCMP errors generated and authenticated by the BR, as the
+			// MAC covers the SCMP timestamp.
+			copy(actE2E.Data[scmp_auth.MACOffset:scmp_auth.DRKeyTotalLength], expMAC)
+		}
+	}
+	return compareLayers(act, exp)
+}
+
+func isSCMPAuthDRKey(e2e *layers.ScionE2E) bool {
+	return e2e.Type == common.ExtnSCIONPacketSecurityType.Type &&
+		len(e2e.Data) >= scmp_auth.DRKeyTotalLength &&
+		spse.SecMode(e2e.Data[0]) == spse.ScmpAuthDRKey
+}
+
 func compareLayersPayload(act, exp gopacket.Layer) error {
 	// Try capnp decap first, otherwise do normal string comparison
 	actU, actErr := shared.CtrlCapnpDec(infra.NullSigVerifier, act.LayerContents())
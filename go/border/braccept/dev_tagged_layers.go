@@ -59,6 +59,10 @@ func (dtl *DevTaggedLayers) GenerateMac(scnTag string, infTag, hfTag, hfMacTag s
 	dtl.TaggedLayers.GenerateMac(scnTag, shared.HashMac, infTag, hfTag, hfMacTag)
 }
 
+func (dtl *DevTaggedLayers) GenerateSIBRAMac(hbhTag string) {
+	dtl.TaggedLayers.GenerateSIBRAMac(hbhTag, shared.HashMac)
+}
+
 func (dtl *DevTaggedLayers) String() string {
 	return dtl.TaggedLayers.String()
 }
@@ -28,6 +28,7 @@ import (
 
 func ExpectedPackets(desc string, to string, pkts ...*DevTaggedLayers) int {
 	var errors int
+	start := time.Now()
 	// Given that the number of interfaces changes depending on the BR configuration,
 	// we use a dynamic select/switch case approach, where each interface has an equivalent
 	// case entry, and the last one is always the timer channel for the timeout.
@@ -76,6 +77,7 @@ func ExpectedPackets(desc string, to string, pkts ...*DevTaggedLayers) int {
 	} else {
 		log.Info(fmt.Sprintf("Test %s: %s\n", desc, pass()))
 	}
+	recordResult(desc, time.Since(start), errStr)
 	return errors
 }
 
@@ -25,7 +25,12 @@ type ScionHBH struct {
 	layers.Extension
 }
 
+type ScionE2E struct {
+	layers.Extension
+}
+
 var LayerTypeScionHBH gopacket.LayerType
+var LayerTypeScionE2E gopacket.LayerType
 
 func init() {
 	// XXX(sgmonroy) Use init() to avoid initialization loop (HBH extension chaining)
@@ -36,6 +41,13 @@ func init() {
 			Decoder: gopacket.DecodeFunc(decodeScionHBH),
 		},
 	)
+	LayerTypeScionE2E = gopacket.RegisterLayerType(
+		1362,
+		gopacket.LayerTypeMetadata{
+			Name:    "ScionEndToEnd",
+			Decoder: gopacket.DecodeFunc(decodeScionE2E),
+		},
+	)
 }
 
 func (l *ScionHBH) LayerType() gopacket.LayerType {
@@ -55,3 +67,21 @@ func decodeScionHBH(data []byte, p gopacket.PacketBuilder) error {
 func (l *ScionHBH) LengthBytes() int {
 	return int(l.NumLines) * common.LineLen
 }
+
+func (l *ScionE2E) LayerType() gopacket.LayerType {
+	return LayerTypeScionE2E
+}
+
+func decodeScionE2E(data []byte, p gopacket.PacketBuilder) error {
+	e := &ScionE2E{}
+	err := e.DecodeFromBytes(data, p)
+	p.AddLayer(e)
+	if err != nil {
+		return err
+	}
+	return p.NextDecoder(scionNextLayerType(e.NextHeader))
+}
+
+func (l *ScionE2E) LengthBytes() int {
+	return int(l.NumLines) * common.LineLen
+}
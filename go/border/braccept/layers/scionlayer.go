@@ -117,6 +117,8 @@ func scionNextLayerType(t common.L4ProtocolType) gopacket.LayerType {
 	switch t {
 	case common.HopByHopClass:
 		return LayerTypeScionHBH
+	case common.End2EndClass:
+		return LayerTypeScionE2E
 	case common.L4SCMP:
 		return LayerTypeSCMP
 	case common.L4UDP:
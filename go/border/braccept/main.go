@@ -18,6 +18,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"time"
 
@@ -40,13 +41,21 @@ const (
 
 // Flag vars
 var (
-	testName    string
-	keysDirPath string
+	testName     string
+	keysDirPath  string
+	casesPath    string
+	reportPath   string
+	reportFormat string
 )
 
 func init() {
 	flag.StringVar(&testName, "testName", "", "Test to run")
 	flag.StringVar(&keysDirPath, "keysDirPath", "", "AS keys directory path")
+	flag.StringVar(&casesPath, "casesPath", "",
+		"YAML test cases file, or directory of YAML test cases files, to run")
+	flag.StringVar(&reportPath, "reportPath", "", "Write a report of the test results to this file")
+	flag.StringVar(&reportFormat, "reportFormat", reportJUnit,
+		fmt.Sprintf("Report format (%s|%s)", reportJUnit, reportJSON))
 }
 
 var (
@@ -73,6 +82,14 @@ func realMain() int {
 		return 1
 	}
 	defer log.LogPanicAndExit()
+	var yamlCases []*caseDesc
+	if casesPath != "" {
+		var err error
+		if yamlCases, err = LoadCases(casesPath); err != nil {
+			log.Crit("Unable to load test cases", "err", err)
+			return 1
+		}
+	}
 	if err := shared.Init(keysDirPath); err != nil {
 		log.Crit("", "err", err)
 		return 1
@@ -122,17 +139,36 @@ func realMain() int {
 		failures += br_core_coreIf()
 	case "br_core_childIf":
 		failures += br_core_childIf()
+	case "":
+		// Only run the YAML test cases.
 	default:
 		log.Crit("Wrong BR acceptance test name", "testName", testName)
 		return 1
 	}
+	if len(yamlCases) > 0 {
+		log.Info("YAML test cases:", "casesPath", casesPath, "cases", len(yamlCases))
+		failures += RunCases(yamlCases)
+	}
+	if reportPath != "" {
+		suite := testName
+		if suite == "" {
+			suite = filepath.Base(casesPath)
+		}
+		if err := WriteReport(reportPath, reportFormat, suite); err != nil {
+			log.Error("Unable to write report", "err", err)
+			return 1
+		}
+	}
 	return failures
 }
 
 func checkFlags() error {
 	flag.Parse()
-	if testName == "" {
-		return fmt.Errorf("ERROR: Missing testName flag")
+	if testName == "" && casesPath == "" {
+		return fmt.Errorf("ERROR: Missing testName or casesPath flag")
+	}
+	if reportFormat != reportJUnit && reportFormat != reportJSON {
+		return fmt.Errorf("ERROR: Unsupported reportFormat: %s", reportFormat)
 	}
 	if keysDirPath == "" {
 		return fmt.Errorf("ERROR: Missing keysDirPath flag")
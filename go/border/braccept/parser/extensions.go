@@ -16,12 +16,16 @@ package parser
 
 import (
 	"fmt"
+	"hash"
 	"strings"
+	"time"
 
 	"github.com/google/gopacket"
 
+	"github.com/scionproto/scion/go/border/braccept/shared"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/layers"
+	"github.com/scionproto/scion/go/lib/spse/scmp_auth"
 )
 
 var _ TaggedLayer = (*HBHTaggedLayer)(nil)
@@ -77,7 +81,7 @@ func (hbh *HBHTaggedLayer) Update(lines []string) {
 	if hbh == nil {
 		panic(fmt.Errorf("HBH Tagged Layer is nil!\n"))
 	}
-	if len(lines) != 2 {
+	if len(lines) < 2 {
 		panic(fmt.Errorf("Bad HBH layer!\n%s\n", strings.Join(lines, "\n")))
 	}
 	line := lines[0]
@@ -88,6 +92,9 @@ func (hbh *HBHTaggedLayer) Update(lines []string) {
 
 	layerType, _, kvStr := decodeLayerLine(lines[1])
 	kvs = getKeyValueMap(kvStr)
+	if layerType != "HBH.SIBRA" && len(lines) != 2 {
+		panic(fmt.Errorf("Bad HBH layer!\n%s\n", strings.Join(lines, "\n")))
+	}
 	var e common.Extension
 	switch layerType {
 	case "HBH.OHP":
@@ -98,6 +105,11 @@ func (hbh *HBHTaggedLayer) Update(lines []string) {
 		scmp := &hbh_scmp{}
 		scmp.updateFields(kvs)
 		e = scmp
+	case "HBH.SIBRA":
+		sibra := &hbh_sibra{}
+		sibra.updateFields(kvs)
+		sibra.parseSOFs(lines[2:])
+		e = sibra
 	default:
 		panic(fmt.Errorf("Unknown HBH layer Type '%s'", layerType))
 	}
@@ -164,6 +176,87 @@ func (scmp *hbh_scmp) updateFlags(flags string) {
 	}
 }
 
+// GenerateSIBRAMac sets the MACs of all opaque fields of the SIBRA extension.
+func (hbh *HBHTaggedLayer) GenerateSIBRAMac(hMac hash.Hash) {
+	e, err := layers.ExtnSIBRAFromRaw(hbh.Data)
+	if err != nil {
+		panic(err)
+	}
+	for _, sof := range e.SOFs {
+		sof.Mac = sof.CalcMac(hMac, e.ID, e.Info)
+	}
+	if hbh.Data, err = e.Pack(); err != nil {
+		panic(err)
+	}
+}
+
+// hbh_sibra is the SIBRA extension. ExpTime is the expiration time of the
+// reservation in seconds relative to the current time. The opaque fields are
+// listed on separate lines, their MACs are generated with GenerateSIBRAMac.
+type hbh_sibra struct {
+	layers.ExtnSIBRA
+}
+
+func (sibra *hbh_sibra) updateFields(kvs propMap) {
+	sibra.Info = &layers.SIBRAResInfo{ExpTime: shared.Now.Add(time.Minute)}
+	for k, v := range kvs {
+		switch k {
+		case "Version":
+			sibra.Version = uint8(StrToInt(v))
+		case "Flags":
+			sibra.updateFlags(v)
+		case "CurrHop":
+			sibra.CurrHop = uint8(StrToInt(v))
+		case "ID":
+			sibra.ID = uint64(HexToInt(v))
+		case "ExpTime":
+			sibra.Info.ExpTime = shared.Now.Add(time.Duration(StrToInt(v)) * time.Second)
+		case "BW":
+			sibra.Info.BW = uint32(StrToInt(v))
+		default:
+			panic(fmt.Errorf("Unknown HBH_SIBRA field: %s", k))
+		}
+	}
+}
+
+func (sibra *hbh_sibra) updateFlags(flags string) {
+	f := strings.Split(flags, ",")
+	for i := range f {
+		flag := f[i]
+		switch flag {
+		case "Forward":
+			sibra.Forward = true
+		case "Setup":
+			sibra.Setup = true
+		case "Request":
+			sibra.Request = true
+		default:
+			panic(fmt.Errorf("Error parsing HBH_SIBRA flags '%s'", flag))
+		}
+	}
+}
+
+func (sibra *hbh_sibra) parseSOFs(lines []string) {
+	for _, line := range lines {
+		layerType, _, kvStr := decodeLayerLine(line)
+		if layerType != "SOF" {
+			panic(fmt.Errorf("Bad SIBRA SOF layer!\n%s\n", line))
+		}
+		sof := &layers.SIBRASOF{Mac: make(common.RawBytes, layers.SIBRAMacLen)}
+		for k, v := range getKeyValueMap(kvStr) {
+			switch k {
+			case "Ingress":
+				sof.Ingress = common.IFIDType(StrToInt(v))
+			case "Egress":
+				sof.Egress = common.IFIDType(StrToInt(v))
+			default:
+				panic(fmt.Errorf("Unknown SIBRA SOF field: %s", k))
+			}
+		}
+		sibra.SOFs = append(sibra.SOFs, sof)
+	}
+}
+
 func parseHBHType(t string) uint8 {
 	var e common.ExtnType
 	switch t {
@@ -172,9 +265,147 @@ func parseHBHType(t string) uint8 {
 	case "OHP":
 		e = common.ExtnOneHopPathType
 	case "SIBRA":
-		panic(fmt.Errorf("Unsupported HBH Type: %s", t))
+		e = common.ExtnSIBRAType
 	default:
 		panic(fmt.Errorf("Unknown HBH Type: %s", t))
 	}
 	return e.Type
 }
+
+var _ TaggedLayer = (*E2ETaggedLayer)(nil)
+var _ gopacket.Layer = (*E2ETaggedLayer)(nil)
+
+type E2ETaggedLayer struct {
+	layers.Extension
+	tagged
+	options
+}
+
+func E2EParser(lines []string) TaggedLayer {
+	// default E2E layer values
+	e2e := &E2ETaggedLayer{}
+
+	//SerializeOptions
+	e2e.opts.FixLengths = true
+
+	e2e.Update(lines)
+	return e2e
+}
+
+func (e2e *E2ETaggedLayer) Layer() gopacket.Layer {
+	return e2e
+}
+
+func (e2e *E2ETaggedLayer) Clone() TaggedLayer {
+	clone := *e2e
+	return &clone
+}
+
+// XXX layers.Extension is missing following method to implement gopacket.Layer
+func (e2e *E2ETaggedLayer) LayerType() gopacket.LayerType {
+	return layers.LayerTypeEndToEndExtension
+}
+
+func (e2e *E2ETaggedLayer) String() string {
+	return fmt.Sprintf("NextHeader=%s NumLines=%d Type=%s Data=%x", e2e.NextHeader,
+		e2e.NumLines, common.ExtnType{Class: common.End2EndClass, Type: e2e.Type}, e2e.Data)
+}
+
+func (e2e *E2ETaggedLayer) Update(lines []string) {
+	if e2e == nil {
+		panic(fmt.Errorf("E2E Tagged Layer is nil!\n"))
+	}
+	if len(lines) != 2 {
+		panic(fmt.Errorf("Bad E2E layer!\n%s\n", strings.Join(lines, "\n")))
+	}
+	line := lines[0]
+	_, tag, kvStr := decodeLayerLine(line)
+	e2e.tag = tag
+	kvs := getKeyValueMap(kvStr)
+	e2e.updateFields(kvs)
+
+	layerType, _, kvStr := decodeLayerLine(lines[1])
+	kvs = getKeyValueMap(kvStr)
+	var e common.Extension
+	switch layerType {
+	case "E2E.SCMPAuthDRKey":
+		drkey := &e2e_scmp_auth_drkey{DRKeyExtn: scmp_auth.NewDRKeyExtn()}
+		drkey.updateFields(kvs)
+		e = drkey
+	default:
+		panic(fmt.Errorf("Unknown E2E layer Type '%s'", layerType))
+	}
+	var err error
+	e2e.Data, err = e.Pack()
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (e2e *E2ETaggedLayer) updateFields(kvs propMap) {
+	for k, v := range kvs {
+		switch k {
+		case "NextHdr":
+			e2e.NextHeader = parseScionProto(v)
+		case "Length":
+			e2e.NumLines = uint8(StrToInt(v))
+			e2e.opts.FixLengths = false
+		case "Type":
+			e2e.Type = parseE2EType(v)
+		default:
+			panic(fmt.Errorf("Unknown E2E field: %s", k))
+		}
+	}
+}
+
+// e2e_scmp_auth_drkey is the SCMPAuthDRKey SPSE extension. If no MAC is specified, the MAC
+// is all zeros, which the packet comparison treats as a wildcard.
+type e2e_scmp_auth_drkey struct {
+	*scmp_auth.DRKeyExtn
+}
+
+func (drkey *e2e_scmp_auth_drkey) updateFields(kvs propMap) {
+	for k, v := range kvs {
+		switch k {
+		case "Direction":
+			if err := drkey.SetDirection(parseDRKeyDirection(v)); err != nil {
+				panic(err)
+			}
+		case "MAC":
+			if err := drkey.SetMAC(HexToBytes(v)); err != nil {
+				panic(err)
+			}
+		default:
+			panic(fmt.Errorf("Unknown E2E_SCMPAuthDRKey field: %s", k))
+		}
+	}
+}
+
+func parseDRKeyDirection(d string) scmp_auth.Dir {
+	switch d {
+	case "AsToAs":
+		return scmp_auth.AsToAs
+	case "AsToHost":
+		return scmp_auth.AsToHost
+	case "HostToHost":
+		return scmp_auth.HostToHost
+	case "HostToAs":
+		return scmp_auth.HostToAs
+	case "AsToAsReversed":
+		return scmp_auth.AsToAsReversed
+	case "HostToHostReversed":
+		return scmp_auth.HostToHostReversed
+	}
+	panic(fmt.Errorf("Unknown SCMPAuthDRKey Direction: %s", d))
+}
+
+func parseE2EType(t string) uint8 {
+	var e common.ExtnType
+	switch t {
+	case "SPSE":
+		e = common.ExtnSCIONPacketSecurityType
+	default:
+		panic(fmt.Errorf("Unknown E2E Type: %s", t))
+	}
+	return e.Type
+}
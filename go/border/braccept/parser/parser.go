@@ -85,6 +85,7 @@ var parserMap = map[string]LayerParser{
 	"UDP":           UDPParser,
 	"SCION":         ScionParser,
 	"HBH":           HBHParser,
+	"E2E":           E2EParser,
 	"SCMP":          SCMPParser,
 	"IFStateReq":    IFStateReqParser,
 	"IFStateInfo":   IFStateInfoParser,
@@ -184,6 +185,15 @@ func (taggedLayers TaggedLayers) GenerateMac(scnTag string, hMac hash.Hash,
 	scn.GenerateMac(hMac, infTag, hfTag, hfMacTag)
 }
 
+func (taggedLayers TaggedLayers) GenerateSIBRAMac(hbhTag string, hMac hash.Hash) {
+	tl := taggedLayers.GetTaggedLayer(hbhTag)
+	hbh, ok := tl.(*HBHTaggedLayer)
+	if !ok {
+		panic(fmt.Errorf("GenerateSIBRAMac: Invalid tag '%s'\n", hbhTag))
+	}
+	hbh.GenerateSIBRAMac(hMac)
+}
+
 func (taggedLayers TaggedLayers) String() string {
 	var str []string
 	for _, tl := range taggedLayers {
@@ -116,6 +116,8 @@ func parseScionProto(protoName string) common.L4ProtocolType {
 		return common.L4SCMP
 	case "HBH":
 		return common.HopByHopClass
+	case "E2E":
+		return common.End2EndClass
 	}
 	panic(fmt.Errorf("Scion NextHeader '%s' not found", protoName))
 }
@@ -93,6 +93,10 @@ func (s *SCMPTaggedLayer) Update(lines []string) {
 			info := &InfoRevocation{}
 			skip = info.parse(lines[i:])
 			s.Info = info
+		case "InfoExtIdx":
+			info := &InfoExtIdx{}
+			info.updateFields(kvs)
+			s.Info = info
 		case "QUOTED":
 			s.updateQuotes(kvs)
 		default:
@@ -155,6 +159,21 @@ func (i *InfoPathOffsets) updateFields(kvs propMap) {
 	}
 }
 
+type InfoExtIdx struct {
+	scmp.InfoExtIdx
+}
+
+func (i *InfoExtIdx) updateFields(kvs propMap) {
+	for k, v := range kvs {
+		switch k {
+		case "Idx":
+			i.Idx = uint8(StrToInt(v))
+		default:
+			panic(fmt.Errorf("Unknown InfoExtIdx field: %s", k))
+		}
+	}
+}
+
 func (s *SCMPTaggedLayer) updateHeaderFields(kvs propMap) {
 	for k, v := range kvs {
 		switch k {
@@ -285,6 +304,12 @@ func (s *SCMPTaggedLayer) parseSCMPType(c string) {
 		s.Type = scmp.T_S_BadVersion
 	case "SETUP_NO_REQ":
 		s.Type = scmp.T_S_SetupNoReq
+	case "BAD_SOF":
+		s.Type = scmp.T_S_BadSOF
+	case "EXPIRED_RES":
+		s.Type = scmp.T_S_ExpiredRes
+	case "BW_EXCEEDED":
+		s.Type = scmp.T_S_BWExceeded
 	default:
 		if c[:2] == "0x" {
 			s.Type = scmp.Type(HexToInt(c[2:]))
@@ -0,0 +1,140 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+const (
+	reportJUnit = "junit"
+	reportJSON  = "json"
+)
+
+// testResult is the outcome of a single test.
+type testResult struct {
+	Name     string
+	Duration time.Duration
+	Errors   []string
+}
+
+var results []*testResult
+
+func recordResult(name string, d time.Duration, errs []string) {
+	results = append(results, &testResult{Name: name, Duration: d, Errors: errs})
+}
+
+// WriteReport writes the results of all the tests run so far to file, in the given format.
+func WriteReport(file, format, suite string) error {
+	var b []byte
+	var err error
+	switch format {
+	case reportJUnit:
+		b, err = junitReport(suite)
+	case reportJSON:
+		b, err = jsonReport(suite)
+	default:
+		return common.NewBasicError("Unsupported report format", nil, "format", format)
+	}
+	if err != nil {
+		return common.NewBasicError("Unable to encode report", err, "format", format)
+	}
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		return common.NewBasicError("Unable to write report", err, "file", file)
+	}
+	return nil
+}
+
+type junitSuite struct {
+	XMLName  xml.Name     `xml:"testsuite"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Cases    []*junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitReport(suite string) ([]byte, error) {
+	s := &junitSuite{Name: suite, Tests: len(results)}
+	var total time.Duration
+	for _, r := range results {
+		c := &junitCase{Name: r.Name, ClassName: suite, Time: seconds(r.Duration)}
+		if len(r.Errors) > 0 {
+			c.Failure = &junitFailure{
+				Message: "Test failed",
+				Text:    strings.Join(r.Errors, "\n"),
+			}
+			s.Failures++
+		}
+		total += r.Duration
+		s.Cases = append(s.Cases, c)
+	}
+	s.Time = seconds(total)
+	b, err := xml.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+type jsonSuite struct {
+	Suite    string
+	Tests    int
+	Failures int
+	Cases    []*jsonCase
+}
+
+type jsonCase struct {
+	Name     string
+	Duration float64
+	Passed   bool
+	Errors   []string `json:",omitempty"`
+}
+
+func jsonReport(suite string) ([]byte, error) {
+	s := &jsonSuite{Suite: suite, Tests: len(results), Cases: []*jsonCase{}}
+	for _, r := range results {
+		passed := len(r.Errors) == 0
+		if !passed {
+			s.Failures++
+		}
+		s.Cases = append(s.Cases, &jsonCase{Name: r.Name, Duration: r.Duration.Seconds(),
+			Passed: passed, Errors: r.Errors})
+	}
+	return json.MarshalIndent(s, "", "    ")
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReports(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Report func(suite string) ([]byte, error)
+		Out    string
+	}{
+		{
+			Name:   "JUnit",
+			Report: junitReport,
+			Out: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="br_child" tests="2" failures="1" time="1.750">
+    <testcase name="child to internal/host" classname="br_child" time="0.250"></testcase>
+    <testcase name="internal/host to child" classname="br_child" time="1.500">
+        <failure message="Test failed">Unexpected packet&#xA;Missing packet</failure>
+    </testcase>
+</testsuite>`,
+		},
+		{
+			Name:   "JSON",
+			Report: jsonReport,
+			Out: `{
+    "Suite": "br_child",
+    "Tests": 2,
+    "Failures": 1,
+    "Cases": [
+        {
+            "Name": "child to internal/host",
+            "Duration": 0.25,
+            "Passed": true
+        },
+        {
+            "Name": "internal/host to child",
+            "Duration": 1.5,
+            "Passed": false,
+            "Errors": [
+                "Unexpected packet",
+                "Missing packet"
+            ]
+        }
+    ]
+}`,
+		},
+	}
+	Convey("Reports contain the recorded results", t, func() {
+		results = nil
+		defer func() { results = nil }()
+		recordResult("child to internal/host", 250*time.Millisecond, nil)
+		recordResult("internal/host to child", 1500*time.Millisecond,
+			[]string{"Unexpected packet", "Missing packet"})
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				b, err := test.Report("br_child")
+				SoMsg("err", err, ShouldBeNil)
+				SoMsg("report", string(b), ShouldEqual, test.Out)
+			})
+		}
+	})
+}
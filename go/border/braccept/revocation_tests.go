@@ -310,8 +310,10 @@ func revocation_owned_peer() int {
 				HF_4: ConsIngress=0   ConsEgress=311 Flags=VerifyOnly
 				HF_5: ConsIngress=121 ConsEgress=0   Flags=Xover
 				HF_6: ConsIngress=131 ConsEgress=0   Flags=Xover
-		HBH: NextHdr=SCMP Type=SCMP
+		HBH: NextHdr=E2E Type=SCMP
 			HBH.SCMP: Flags=Error,HBH
+		E2E: NextHdr=SCMP Type=SPSE
+			E2E.SCMPAuthDRKey: Direction=AsToHost
 		SCMP: Class=PATH Type=REVOKED_IF Checksum=0
 			InfoRevocation: InfoF=4 HopF=6 IfID=121 Ingress=false
 				SignedRevInfo: IfID=121 IA=1-ff00:0:1 Link=peer TS=now TTL=10
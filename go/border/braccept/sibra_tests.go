@@ -0,0 +1,148 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+)
+
+func child_to_internal_host_sibra() int {
+	pkt0 := AllocatePacket()
+	pkt0.ParsePacket(`
+		Ethernet: SrcMAC=f0:0d:ca:fe:be:ef DstMAC=f0:0d:ca:fe:00:14 EthernetType=IPv4
+		IP4: Src=192.168.14.3 Dst=192.168.14.2 NextHdr=UDP Flags=DF
+		UDP: Src=40000 Dst=50000
+		SCION: NextHdr=HBH CurrInfoF=4 CurrHopF=6 SrcType=IPv4 DstType=IPv4
+			ADDR: SrcIA=1-ff00:0:4 Src=172.16.4.1 DstIA=1-ff00:0:1 Dst=192.168.0.51
+			IF_1: ISD=1 Hops=2
+				HF_1: ConsIngress=411 ConsEgress=0
+				HF_2: ConsIngress=0   ConsEgress=141
+		HBH: NextHdr=UDP Type=SIBRA
+			HBH.SIBRA: Flags=Forward CurrHop=1 ID=deadbeef BW=1000
+				SOF_1: Ingress=0   Egress=411
+				SOF_2: Ingress=141 Egress=0
+		UDP_1: Src=40111 Dst=40222
+	`)
+	pkt0.SetDev("veth_141")
+	pkt0.SetChecksum("UDP", "IP4")
+	pkt0.SetChecksum("UDP_1", "SCION")
+	pkt0.GenerateMac("SCION", "IF_1", "HF_2", "")
+	pkt0.GenerateSIBRAMac("HBH")
+
+	pkt1 := pkt0.CloneAndUpdate(`
+		Ethernet: SrcMAC=f0:0d:ca:fe:00:01 DstMAC=f0:0d:ca:fe:be:ef
+		IP4: Src=192.168.0.11 Dst=192.168.0.51 Checksum=0
+		UDP: Src=30001 Dst=30041
+	`)
+	pkt1.SetDev("veth_int")
+	pkt1.SetChecksum("UDP", "IP4")
+
+	SendPackets(pkt0)
+
+	return ExpectedPackets("SIBRA child to internal/host", defaultTimeout, pkt1)
+}
+
+func internal_host_to_child_sibra() int {
+	pkt0 := AllocatePacket()
+	pkt0.ParsePacket(`
+		Ethernet: SrcMAC=f0:0d:ca:fe:be:ef DstMAC=f0:0d:ca:fe:00:01 EthernetType=IPv4
+		IP4: Src=192.168.0.51 Dst=192.168.0.11 NextHdr=UDP Flags=DF
+		UDP: Src=30041 Dst=30001
+		SCION: NextHdr=HBH CurrInfoF=4 CurrHopF=5 SrcType=IPv4 DstType=IPv4
+			ADDR: SrcIA=1-ff00:0:1 Src=192.168.0.51 DstIA=1-ff00:0:4 Dst=172.16.4.1
+			IF_1: ISD=1 Hops=2 Flags=ConsDir
+				HF_1: ConsIngress=0   ConsEgress=141
+				HF_2: ConsIngress=411 ConsEgress=0
+		HBH: NextHdr=UDP Type=SIBRA
+			HBH.SIBRA: Flags=Forward CurrHop=0 ID=deadbeef BW=1000
+				SOF_1: Ingress=0   Egress=141
+				SOF_2: Ingress=411 Egress=0
+		UDP_1: Src=40111 Dst=40222
+	`)
+	pkt0.SetDev("veth_int")
+	pkt0.SetChecksum("UDP", "IP4")
+	pkt0.SetChecksum("UDP_1", "SCION")
+	pkt0.GenerateMac("SCION", "IF_1", "HF_1", "")
+	pkt0.GenerateSIBRAMac("HBH")
+
+	pkt1 := pkt0.CloneAndUpdate(`
+		Ethernet: SrcMAC=f0:0d:ca:fe:00:14 DstMAC=f0:0d:ca:fe:be:ef
+		IP4: Src=192.168.14.2 Dst=192.168.14.3 Checksum=0
+		UDP: Src=50000 Dst=40000
+		SCION: CurrHopF=6
+		HBH: NextHdr=UDP Type=SIBRA
+			HBH.SIBRA: Flags=Forward CurrHop=1 ID=deadbeef BW=1000
+				SOF_1: Ingress=0   Egress=141
+				SOF_2: Ingress=411 Egress=0
+	`)
+	pkt1.SetDev("veth_141")
+	pkt1.SetChecksum("UDP", "IP4")
+	pkt1.GenerateSIBRAMac("HBH")
+
+	SendPackets(pkt0)
+
+	return ExpectedPackets("SIBRA internal/host to child", defaultTimeout, pkt1)
+}
+
+func internal_host_to_child_sibra_bad_mac() int {
+	pkt0 := AllocatePacket()
+	pkt0.ParsePacket(`
+		Ethernet: SrcMAC=f0:0d:ca:fe:be:ef DstMAC=f0:0d:ca:fe:00:01 EthernetType=IPv4
+		IP4: Src=192.168.0.51 Dst=192.168.0.11 NextHdr=UDP Flags=DF
+		UDP: Src=30041 Dst=30001
+		SCION: NextHdr=HBH CurrInfoF=4 CurrHopF=5 SrcType=IPv4 DstType=IPv4
+			ADDR: SrcIA=1-ff00:0:1 Src=192.168.0.51 DstIA=1-ff00:0:4 Dst=172.16.4.1
+			IF_1: ISD=1 Hops=2 Flags=ConsDir
+				HF_1: ConsIngress=0   ConsEgress=141
+				HF_2: ConsIngress=411 ConsEgress=0
+		HBH: NextHdr=UDP Type=SIBRA
+			HBH.SIBRA: Flags=Forward CurrHop=0 ID=deadbeef BW=1000
+				SOF_1: Ingress=0   Egress=141
+				SOF_2: Ingress=411 Egress=0
+		UDP_1: Src=40111 Dst=40222
+	`)
+	pkt0.SetDev("veth_int")
+	pkt0.SetChecksum("UDP", "IP4")
+	pkt0.SetChecksum("UDP_1", "SCION")
+	pkt0.GenerateMac("SCION", "IF_1", "HF_1", "")
+	// The SIBRA opaque field MACs are left empty.
+
+	// SCMP error reply (reversed SCION header) from the BR to the source of the packet.
+	pkt1 := AllocatePacket()
+	pkt1.ParsePacket(fmt.Sprintf(`
+		Ethernet: SrcMAC=f0:0d:ca:fe:00:01 DstMAC=f0:0d:ca:fe:be:ef EthernetType=IPv4
+		IP4: Src=192.168.0.11 Dst=192.168.0.51 NextHdr=UDP Flags=DF Checksum=0
+		UDP: Src=30001 Dst=30041 Checksum=0
+		SCION: NextHdr=HBH CurrInfoF=4 CurrHopF=6 SrcType=IPv4 DstType=IPv4
+			ADDR: SrcIA=1-ff00:0:1 Src=192.168.0.11 DstIA=1-ff00:0:1 Dst=192.168.0.51
+			IF_1: ISD=1 Hops=2
+				HF_1: ConsIngress=411 ConsEgress=0
+				HF_2: ConsIngress=0   ConsEgress=141
+		HBH: NextHdr=E2E Type=SCMP
+			HBH.SCMP: Flags=Error
+		E2E: NextHdr=SCMP Type=SPSE
+			E2E.SCMPAuthDRKey: Direction=AsToHost
+		SCMP: Class=SIBRA Type=BAD_SOF Checksum=0
+			InfoExtIdx: Idx=0
+			QUOTED: RawPkt=%s
+	`, pkt0.Serialize()))
+	pkt1.SetDev("veth_int")
+	pkt1.SetChecksum("SCMP", "SCION")
+	pkt1.GenerateMac("SCION", "IF_1", "HF_2", "")
+
+	SendPackets(pkt0)
+
+	return ExpectedPackets("SIBRA internal/host to child, bad SOF MAC", defaultTimeout, pkt1)
+}
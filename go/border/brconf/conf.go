@@ -28,8 +28,11 @@ import (
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/as_conf"
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/drkey"
 	"github.com/scionproto/scion/go/lib/keyconf"
+	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/scrypto/cert"
 	"github.com/scionproto/scion/go/lib/topology"
 )
 
@@ -49,6 +52,17 @@ type BRConf struct {
 	MasterKeys keyconf.Master
 	// HFMacPool is the pool of Hop Field MAC generation instances.
 	HFMacPool *sync.Pool
+	// DRKeySV derives the DRKey secret values of the local AS, which are used
+	// to authenticate SCMP errors. It is set by the router after loading the
+	// configuration.
+	DRKeySV *drkey.SecretValueFactory
+	// SignKey is the AS signing key, which is used to sign the hash trees
+	// that authenticate SCMP errors. It is only loaded by the router if SCMP
+	// errors are authenticated with hash trees.
+	SignKey common.RawBytes
+	// SignAlgorithm is the algorithm of SignKey, as specified in the AS
+	// certificate.
+	SignAlgorithm string
 	// Net is the network configuration of this router.
 	Net *netconf.NetConf
 	// Dir is the configuration directory.
@@ -82,10 +96,13 @@ func Load(id, confDir string) (*BRConf, error) {
 // to topology with the oldConf.
 func WithNewTopo(id string, topo *topology.Topo, oldConf *BRConf) (*BRConf, error) {
 	conf := &BRConf{
-		Dir:        oldConf.Dir,
-		ASConf:     oldConf.ASConf,
-		MasterKeys: oldConf.MasterKeys,
-		HFMacPool:  oldConf.HFMacPool,
+		Dir:           oldConf.Dir,
+		ASConf:        oldConf.ASConf,
+		MasterKeys:    oldConf.MasterKeys,
+		HFMacPool:     oldConf.HFMacPool,
+		DRKeySV:       oldConf.DRKeySV,
+		SignKey:       oldConf.SignKey,
+		SignAlgorithm: oldConf.SignAlgorithm,
 	}
 	if err := conf.initTopo(id, topo); err != nil {
 		return nil, common.NewBasicError("Unable to initialize topo", err)
@@ -144,6 +161,28 @@ func (cfg *BRConf) loadMasterKeys() error {
 	return nil
 }
 
+// LoadSignKey loads the AS signing key from the config directory. The signing
+// algorithm is taken from the newest AS certificate in the config directory.
+func (cfg *BRConf) LoadSignKey() error {
+	certsPath := filepath.Join(cfg.Dir, "certs")
+	chain, err := cert.ChainFromDir(certsPath, cfg.IA, func(err error) {
+		log.Warn("Unable to load certificate chain", "err", err)
+	})
+	if err != nil {
+		return common.NewBasicError("Unable to load certificate chain", err, "path", certsPath)
+	}
+	if chain == nil {
+		return common.NewBasicError("No certificate chain found", nil, "path", certsPath)
+	}
+	keyPath := filepath.Join(cfg.Dir, "keys", keyconf.SigKeyFile)
+	cfg.SignKey, err = keyconf.LoadKey(keyPath, chain.Leaf.SignAlgorithm)
+	if err != nil {
+		return common.NewBasicError("Unable to load signing key", err, "path", keyPath)
+	}
+	cfg.SignAlgorithm = chain.Leaf.SignAlgorithm
+	return nil
+}
+
 // initMacPool initializes the hop field mac pool.
 func (cfg *BRConf) initMacPool() error {
 	// Generate keys
@@ -15,12 +15,20 @@
 package brconf
 
 import (
+	"fmt"
 	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/config"
+	"github.com/scionproto/scion/go/lib/drkey"
 	"github.com/scionproto/scion/go/lib/env"
 	"github.com/scionproto/scion/go/lib/infra/modules/idiscovery"
+	"github.com/scionproto/scion/go/lib/util"
 )
 
 var _ config.Config = (*Config)(nil)
@@ -68,35 +76,283 @@ func (cfg *Config) ConfigName() string {
 	return "br_config"
 }
 
+const (
+	// DefaultSCMPAuthBatchWindow is the default time SCMP errors are batched
+	// before they are authenticated with a hash tree signature.
+	DefaultSCMPAuthBatchWindow = 10 * time.Millisecond
+	// DefaultBFDInterval is the default interval BFD packets are sent at.
+	DefaultBFDInterval = 10 * time.Millisecond
+	// DefaultBFDDetectMult is the default number of BFD intervals without a
+	// packet from the peer router after which an interface is down.
+	DefaultBFDDetectMult = 3
+	// DefaultCaptureMaxSize is the default maximum size of a capture file.
+	DefaultCaptureMaxSize = 100 << 20
+	// DefaultCaptureMaxDuration is the default duration of a packet capture.
+	DefaultCaptureMaxDuration = time.Minute
+	// DefaultWorkers is the default number of packet processing workers.
+	DefaultWorkers = 1
+)
+
 var _ config.Config = (*BR)(nil)
 
 // BR contains the border router specific parts of the configuration.
 type BR struct {
 	// Profile enables cpu and memory profiling.
 	Profile bool
+	// AdminAddr is the address the admin API is served on. The API is not
+	// authenticated. If empty, the API is disabled.
+	AdminAddr string
+	// Workers is the number of goroutines processing packets. With more than
+	// one worker, the packets read from each socket are distributed to the
+	// workers by flow.
+	Workers int
 	// RollbackFailAction indicates the action that should be taken
 	// if the rollback fails.
 	RollbackFailAction FailAction
+	// DRKeyEpochDuration is the duration of the DRKey epochs of the local AS.
+	// It must match the epoch duration of the certificate server.
+	DRKeyEpochDuration util.DurWrap
+	// SCMPAuthMode indicates how SCMP errors are authenticated.
+	SCMPAuthMode SCMPAuthMode
+	// SCMPAuthBatchWindow is the time SCMP errors are batched before they
+	// are authenticated with a single hash tree signature.
+	SCMPAuthBatchWindow util.DurWrap
+	// BFD configures bidirectional forwarding detection with the routers of
+	// neighboring ASes.
+	BFD BFD
+	// Capture configures the capture of processed packets to pcap files.
+	Capture Capture
+	// Policers limit the rate of the traffic received from neighboring ASes.
+	Policers []*Policer
 }
 
 func (cfg *BR) InitDefaults() {
+	if cfg.Workers == 0 {
+		cfg.Workers = DefaultWorkers
+	}
 	if cfg.RollbackFailAction != FailActionContinue {
 		cfg.RollbackFailAction = FailActionFatal
 	}
+	if cfg.DRKeyEpochDuration.Duration == 0 {
+		cfg.DRKeyEpochDuration.Duration = drkey.DefaultEpochDuration
+	}
+	if cfg.SCMPAuthMode == "" {
+		cfg.SCMPAuthMode = SCMPAuthDRKey
+	}
+	if cfg.SCMPAuthBatchWindow.Duration == 0 {
+		cfg.SCMPAuthBatchWindow.Duration = DefaultSCMPAuthBatchWindow
+	}
+	cfg.BFD.InitDefaults()
+	cfg.Capture.InitDefaults()
+	for _, p := range cfg.Policers {
+		p.InitDefaults()
+	}
 }
 
 func (cfg *BR) Validate() error {
+	if cfg.AdminAddr != "" {
+		if _, _, err := net.SplitHostPort(cfg.AdminAddr); err != nil {
+			return common.NewBasicError("Invalid AdminAddr", err, "value", cfg.AdminAddr)
+		}
+	}
+	if cfg.Workers < 1 {
+		return common.NewBasicError("Workers must be positive", nil, "value", cfg.Workers)
+	}
+	if cfg.DRKeyEpochDuration.Duration < time.Second {
+		return common.NewBasicError("DRKeyEpochDuration must be at least one second", nil,
+			"value", cfg.DRKeyEpochDuration)
+	}
+	if err := cfg.SCMPAuthMode.Validate(); err != nil {
+		return err
+	}
+	if cfg.SCMPAuthBatchWindow.Duration <= 0 {
+		return common.NewBasicError("SCMPAuthBatchWindow must be positive", nil,
+			"value", cfg.SCMPAuthBatchWindow)
+	}
+	if err := cfg.BFD.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Capture.Validate(); err != nil {
+		return common.NewBasicError("Invalid capture", err)
+	}
+	for i, p := range cfg.Policers {
+		if err := p.Validate(); err != nil {
+			return common.NewBasicError("Invalid policer", err, "idx", i)
+		}
+	}
 	return cfg.RollbackFailAction.Validate()
 }
 
 func (cfg *BR) Sample(dst io.Writer, path config.Path, _ config.CtxMap) {
 	config.WriteString(dst, brSample)
+	config.WriteString(dst, fmt.Sprintf("\n[%s]", strings.Join(path.Extend("BFD"), ".")))
+	config.WriteString(dst, bfdSample)
+	config.WriteString(dst, fmt.Sprintf("\n[%s]", strings.Join(path.Extend("Capture"), ".")))
+	config.WriteString(dst, captureSample)
+	config.WriteString(dst, fmt.Sprintf("\n[[%s]]", strings.Join(path.Extend("Policers"), ".")))
+	config.WriteString(dst, policerSample)
 }
 
 func (cfg *BR) ConfigName() string {
 	return "br"
 }
 
+// BFD configures bidirectional forwarding detection (BFD) on the external
+// interfaces. BFD detects a failed link within DetectMult intervals, and
+// triggers the revocation of the interface by the beacon service.
+type BFD struct {
+	// Enable enables BFD on all external interfaces. The routers of the
+	// neighboring ASes must enable it as well.
+	Enable bool
+	// Interval is the interval BFD packets are sent at, and the minimum
+	// interval they are expected to be received at.
+	Interval util.DurWrap
+	// DetectMult is the number of intervals without a BFD packet from the
+	// peer router after which the interface is considered down.
+	DetectMult uint8
+}
+
+func (cfg *BFD) InitDefaults() {
+	if cfg.Interval.Duration == 0 {
+		cfg.Interval.Duration = DefaultBFDInterval
+	}
+	if cfg.DetectMult == 0 {
+		cfg.DetectMult = DefaultBFDDetectMult
+	}
+}
+
+func (cfg *BFD) Validate() error {
+	if cfg.Interval.Duration < time.Millisecond {
+		return common.NewBasicError("BFD interval must be at least one millisecond", nil,
+			"value", cfg.Interval)
+	}
+	return nil
+}
+
+// Capture configures the capture of the packets processed by the router to
+// pcap files. A capture writes one file per interface, the internal interface
+// has ID 0. Captures can also be started and stopped through the admin API,
+// with this configuration.
+type Capture struct {
+	// Enable starts a capture when the router starts.
+	Enable bool
+	// Dir is the directory the capture files are written to.
+	Dir string
+	// Interfaces are the IDs of the captured interfaces. If empty, all
+	// interfaces are captured.
+	Interfaces []common.IFIDType
+	// Direction indicates whether received packets, forwarded packets, or
+	// both are captured.
+	Direction CaptureDirection
+	// SrcIA and DstIA filter the captured packets by source and destination.
+	// Zero ISD or AS numbers match all ISDs or ASes, respectively.
+	SrcIA addr.IA
+	DstIA addr.IA
+	// L4 filters the captured packets by L4 protocol. If empty, packets with
+	// any L4 protocol are captured.
+	L4 string
+	// MaxSize is the maximum size of a capture file in bytes. Once a file
+	// reaches it, no further packets are written to it. Captures started
+	// through the admin API can only lower it.
+	MaxSize uint64
+	// MaxDuration is the duration after which the capture is stopped.
+	// Captures started through the admin API can only lower it.
+	MaxDuration util.DurWrap
+}
+
+func (cfg *Capture) InitDefaults() {
+	if cfg.Dir == "" {
+		cfg.Dir = os.TempDir()
+	}
+	if cfg.Direction == "" {
+		cfg.Direction = CaptureDirIn
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = DefaultCaptureMaxSize
+	}
+	if cfg.MaxDuration.Duration == 0 {
+		cfg.MaxDuration.Duration = DefaultCaptureMaxDuration
+	}
+}
+
+func (cfg *Capture) Validate() error {
+	if err := cfg.Direction.Validate(); err != nil {
+		return err
+	}
+	if _, ok := captureL4Types[cfg.L4]; !ok {
+		return common.NewBasicError("Unknown L4 protocol", nil, "input", cfg.L4)
+	}
+	if cfg.MaxSize < common.MaxMTU {
+		return common.NewBasicError("MaxSize must be at least the maximum MTU", nil,
+			"min", common.MaxMTU, "value", cfg.MaxSize)
+	}
+	if cfg.MaxDuration.Duration <= 0 {
+		return common.NewBasicError("MaxDuration must be positive", nil,
+			"value", cfg.MaxDuration)
+	}
+	return nil
+}
+
+// Matches returns whether packets on interface ifid in direction dir, from
+// src to dst with L4 protocol l4 are captured.
+func (cfg *Capture) Matches(dir CaptureDirection, ifid common.IFIDType, src, dst addr.IA,
+	l4 common.L4ProtocolType) bool {
+
+	if cfg.Direction != CaptureDirBoth && cfg.Direction != dir {
+		return false
+	}
+	if len(cfg.Interfaces) > 0 && !containsIFID(cfg.Interfaces, ifid) {
+		return false
+	}
+	if cfg.L4 != "" && captureL4Types[cfg.L4] != l4 {
+		return false
+	}
+	return (cfg.SrcIA.I == 0 || cfg.SrcIA.I == src.I) &&
+		(cfg.SrcIA.A == 0 || cfg.SrcIA.A == src.A) &&
+		(cfg.DstIA.I == 0 || cfg.DstIA.I == dst.I) &&
+		(cfg.DstIA.A == 0 || cfg.DstIA.A == dst.A)
+}
+
+func containsIFID(ifids []common.IFIDType, ifid common.IFIDType) bool {
+	for _, id := range ifids {
+		if id == ifid {
+			return true
+		}
+	}
+	return false
+}
+
+// captureL4Types maps the L4 filters of captures to the L4 protocols.
+var captureL4Types = map[string]common.L4ProtocolType{
+	"":     common.L4None,
+	"SCMP": common.L4SCMP,
+	"TCP":  common.L4TCP,
+	"UDP":  common.L4UDP,
+	"BFD":  common.L4BFD,
+}
+
+type CaptureDirection string
+
+const (
+	// CaptureDirIn indicates that packets are captured as they are received.
+	CaptureDirIn CaptureDirection = "In"
+	// CaptureDirOut indicates that packets are captured as they are
+	// forwarded.
+	CaptureDirOut CaptureDirection = "Out"
+	// CaptureDirBoth indicates that packets are captured both as they are
+	// received and as they are forwarded.
+	CaptureDirBoth CaptureDirection = "Both"
+)
+
+func (d *CaptureDirection) Validate() error {
+	switch *d {
+	case CaptureDirIn, CaptureDirOut, CaptureDirBoth:
+		return nil
+	default:
+		return common.NewBasicError("Unknown CaptureDirection", nil, "input", *d)
+	}
+}
+
 var _ config.Config = (*Discovery)(nil)
 
 type Discovery struct {
@@ -111,6 +367,71 @@ func (cfg *Discovery) Sample(dst io.Writer, path config.Path, ctx config.CtxMap)
 	cfg.Config.Sample(dst, path, ctx)
 }
 
+// Policer is a token bucket policer for the traffic received from
+// neighboring ASes. A packet matches the policer if it is received on
+// interface IFID and its source is SrcIA. An IFID of 0 matches all external
+// interfaces. Zero ISD or AS numbers in SrcIA match all ISDs or ASes,
+// respectively.
+type Policer struct {
+	IFID  common.IFIDType
+	SrcIA addr.IA
+	// Rate is the sustained rate of matching traffic, in bytes per second.
+	Rate uint64
+	// Burst is the amount of matching traffic that can be received at once,
+	// in bytes.
+	Burst uint64
+	// Action indicates how packets exceeding the rate are handled.
+	Action PolicerAction
+}
+
+func (p *Policer) InitDefaults() {
+	if p.Action == "" {
+		p.Action = PolicerActionDrop
+	}
+}
+
+func (p *Policer) Validate() error {
+	if p.Rate == 0 {
+		return common.NewBasicError("Rate must be positive", nil)
+	}
+	if p.Burst < common.MinMTU {
+		return common.NewBasicError("Burst must be at least the minimum MTU", nil,
+			"min", common.MinMTU, "value", p.Burst)
+	}
+	return p.Action.Validate()
+}
+
+// Matches returns whether packets received on interface ifid from source src
+// match the policer.
+func (p *Policer) Matches(ifid common.IFIDType, src addr.IA) bool {
+	return (p.IFID == 0 || p.IFID == ifid) &&
+		(p.SrcIA.I == 0 || p.SrcIA.I == src.I) &&
+		(p.SrcIA.A == 0 || p.SrcIA.A == src.A)
+}
+
+func (p *Policer) String() string {
+	return fmt.Sprintf("IFID=%d SrcIA=%s", p.IFID, p.SrcIA)
+}
+
+type PolicerAction string
+
+const (
+	// PolicerActionDrop indicates that packets exceeding the rate are dropped.
+	PolicerActionDrop PolicerAction = "Drop"
+	// PolicerActionSCMP indicates that packets exceeding the rate are dropped,
+	// and answered with an SCMP AdminDenied error.
+	PolicerActionSCMP PolicerAction = "SCMP"
+)
+
+func (a *PolicerAction) Validate() error {
+	switch *a {
+	case PolicerActionDrop, PolicerActionSCMP:
+		return nil
+	default:
+		return common.NewBasicError("Unknown PolicerAction", nil, "input", *a)
+	}
+}
+
 type FailAction string
 
 const (
@@ -140,3 +461,39 @@ func (f *FailAction) UnmarshalText(text []byte) error {
 	}
 	return nil
 }
+
+type SCMPAuthMode string
+
+const (
+	// SCMPAuthNone indicates that SCMP errors are not authenticated.
+	SCMPAuthNone SCMPAuthMode = "None"
+	// SCMPAuthDRKey indicates that SCMP errors are authenticated with a MAC
+	// computed with the DRKey of the destination host.
+	SCMPAuthDRKey SCMPAuthMode = "DRKey"
+	// SCMPAuthHashTree indicates that SCMP errors are authenticated with a
+	// hash tree, whose root is signed with the AS signing key.
+	SCMPAuthHashTree SCMPAuthMode = "HashTree"
+)
+
+func (m *SCMPAuthMode) Validate() error {
+	switch *m {
+	case SCMPAuthNone, SCMPAuthDRKey, SCMPAuthHashTree:
+		return nil
+	default:
+		return common.NewBasicError("Unknown SCMPAuthMode", nil, "input", *m)
+	}
+}
+
+func (m *SCMPAuthMode) UnmarshalText(text []byte) error {
+	switch SCMPAuthMode(text) {
+	case SCMPAuthNone:
+		*m = SCMPAuthNone
+	case SCMPAuthDRKey:
+		*m = SCMPAuthDRKey
+	case SCMPAuthHashTree:
+		*m = SCMPAuthHashTree
+	default:
+		return common.NewBasicError("Unknown SCMPAuthMode", nil, "input", string(text))
+	}
+	return nil
+}
@@ -17,12 +17,16 @@ package brconf
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/drkey"
 	"github.com/scionproto/scion/go/lib/env/envtest"
 	"github.com/scionproto/scion/go/lib/infra/modules/idiscovery/idiscoverytest"
+	"github.com/scionproto/scion/go/lib/xtest"
 )
 
 func TestConfigSample(t *testing.T) {
@@ -51,6 +55,18 @@ func InitTestDiscoveryConfig(cfg *Discovery) {
 
 func InitTestBRConfig(cfg *BR) {
 	cfg.Profile = true
+	cfg.AdminAddr = "127.0.0.1:30460"
+	cfg.Workers = 4
+	cfg.DRKeyEpochDuration.Duration = time.Minute
+	cfg.SCMPAuthMode = SCMPAuthHashTree
+	cfg.SCMPAuthBatchWindow.Duration = time.Second
+	cfg.BFD.Enable = true
+	cfg.BFD.Interval.Duration = time.Second
+	cfg.BFD.DetectMult = 5
+	cfg.Capture.Enable = true
+	cfg.Capture.Direction = CaptureDirBoth
+	cfg.Capture.Interfaces = []common.IFIDType{1}
+	cfg.Capture.L4 = "SCMP"
 }
 
 func CheckTestConfig(cfg *Config, id string) {
@@ -66,5 +82,109 @@ func CheckTestDiscoveryConfig(cfg *Discovery) {
 
 func CheckTestBRConfig(cfg *BR) {
 	SoMsg("Profile correct", cfg.Profile, ShouldBeFalse)
+	SoMsg("AdminAddr correct", cfg.AdminAddr, ShouldBeEmpty)
+	SoMsg("Workers correct", cfg.Workers, ShouldEqual, DefaultWorkers)
 	SoMsg("RollbackFailAction correct", cfg.RollbackFailAction, ShouldEqual, FailActionFatal)
+	SoMsg("DRKeyEpochDuration correct", cfg.DRKeyEpochDuration.Duration, ShouldEqual,
+		drkey.DefaultEpochDuration)
+	SoMsg("SCMPAuthMode correct", cfg.SCMPAuthMode, ShouldEqual, SCMPAuthDRKey)
+	SoMsg("SCMPAuthBatchWindow correct", cfg.SCMPAuthBatchWindow.Duration, ShouldEqual,
+		DefaultSCMPAuthBatchWindow)
+	SoMsg("BFD.Enable correct", cfg.BFD.Enable, ShouldBeFalse)
+	SoMsg("BFD.Interval correct", cfg.BFD.Interval.Duration, ShouldEqual, DefaultBFDInterval)
+	SoMsg("BFD.DetectMult correct", cfg.BFD.DetectMult, ShouldEqual,
+		uint8(DefaultBFDDetectMult))
+	SoMsg("Capture.Enable correct", cfg.Capture.Enable, ShouldBeFalse)
+	SoMsg("Capture.Dir correct", cfg.Capture.Dir, ShouldEqual, "/tmp")
+	SoMsg("Capture.Interfaces correct", cfg.Capture.Interfaces, ShouldBeEmpty)
+	SoMsg("Capture.Direction correct", cfg.Capture.Direction, ShouldEqual, CaptureDirIn)
+	SoMsg("Capture.SrcIA correct", cfg.Capture.SrcIA.IsZero(), ShouldBeTrue)
+	SoMsg("Capture.DstIA correct", cfg.Capture.DstIA.IsZero(), ShouldBeTrue)
+	SoMsg("Capture.L4 correct", cfg.Capture.L4, ShouldBeEmpty)
+	SoMsg("Capture.MaxSize correct", cfg.Capture.MaxSize, ShouldEqual,
+		uint64(DefaultCaptureMaxSize))
+	SoMsg("Capture.MaxDuration correct", cfg.Capture.MaxDuration.Duration, ShouldEqual,
+		DefaultCaptureMaxDuration)
+	SoMsg("Policers correct", cfg.Policers, ShouldResemble, []*Policer{
+		{
+			IFID:   1,
+			SrcIA:  xtest.MustParseIA("1-ff00:0:110"),
+			Rate:   12500000,
+			Burst:  125000,
+			Action: PolicerActionDrop,
+		},
+	})
+}
+
+func TestPolicerMatches(t *testing.T) {
+	Convey("Policer matches interface and source", t, func() {
+		testCases := []struct {
+			Name    string
+			Policer *Policer
+			IFID    common.IFIDType
+			Src     string
+			Match   bool
+		}{
+			{"wildcard", &Policer{}, 2, "1-ff00:0:110", true},
+			{"interface", &Policer{IFID: 1}, 1, "1-ff00:0:110", true},
+			{"other interface", &Policer{IFID: 1}, 2, "1-ff00:0:110", false},
+			{"source", &Policer{SrcIA: xtest.MustParseIA("1-ff00:0:110")}, 2,
+				"1-ff00:0:110", true},
+			{"other source", &Policer{SrcIA: xtest.MustParseIA("1-ff00:0:110")}, 2,
+				"1-ff00:0:111", false},
+			{"source ISD", &Policer{SrcIA: xtest.MustParseIA("1-0")}, 2, "1-ff00:0:111", true},
+			{"other source ISD", &Policer{SrcIA: xtest.MustParseIA("1-0")}, 2,
+				"2-ff00:0:111", false},
+		}
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				SoMsg("match", tc.Policer.Matches(tc.IFID, xtest.MustParseIA(tc.Src)),
+					ShouldEqual, tc.Match)
+			})
+		}
+	})
+}
+
+func TestCaptureMatches(t *testing.T) {
+	Convey("Capture matches direction, interface, source, destination and L4", t, func() {
+		ia110 := xtest.MustParseIA("1-ff00:0:110")
+		ia111 := xtest.MustParseIA("1-ff00:0:111")
+		testCases := []struct {
+			Name    string
+			Capture *Capture
+			Dir     CaptureDirection
+			IFID    common.IFIDType
+			L4      common.L4ProtocolType
+			Match   bool
+		}{
+			{"wildcard", &Capture{Direction: CaptureDirIn}, CaptureDirIn, 1, common.L4UDP,
+				true},
+			{"other direction", &Capture{Direction: CaptureDirIn}, CaptureDirOut, 1,
+				common.L4UDP, false},
+			{"both directions", &Capture{Direction: CaptureDirBoth}, CaptureDirOut, 1,
+				common.L4UDP, true},
+			{"interface", &Capture{Direction: CaptureDirIn, Interfaces: []common.IFIDType{0, 1}},
+				CaptureDirIn, 0, common.L4UDP, true},
+			{"other interface", &Capture{Direction: CaptureDirIn,
+				Interfaces: []common.IFIDType{1}}, CaptureDirIn, 2, common.L4UDP, false},
+			{"source", &Capture{Direction: CaptureDirIn, SrcIA: ia110}, CaptureDirIn, 1,
+				common.L4UDP, true},
+			{"other source", &Capture{Direction: CaptureDirIn, SrcIA: ia111}, CaptureDirIn, 1,
+				common.L4UDP, false},
+			{"destination ISD", &Capture{Direction: CaptureDirIn,
+				DstIA: xtest.MustParseIA("1-0")}, CaptureDirIn, 1, common.L4UDP, true},
+			{"other destination", &Capture{Direction: CaptureDirIn, DstIA: ia110},
+				CaptureDirIn, 1, common.L4UDP, false},
+			{"SCMP only", &Capture{Direction: CaptureDirIn, L4: "SCMP"}, CaptureDirIn, 1,
+				common.L4SCMP, true},
+			{"SCMP only, UDP", &Capture{Direction: CaptureDirIn, L4: "SCMP"}, CaptureDirIn, 1,
+				common.L4UDP, false},
+		}
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				SoMsg("match", tc.Capture.Matches(tc.Dir, tc.IFID, ia110, ia111, tc.L4),
+					ShouldEqual, tc.Match)
+			})
+		}
+	})
 }
@@ -20,9 +20,106 @@ const brSample = `
 # Enable cpu and memory profiling. (default false)
 Profile = false
 
+# Address the admin API is served on, e.g. "127.0.0.1:30460". The API is not
+# authenticated, it must only be reachable by the operators of the router. If
+# empty, the API is disabled. (default "")
+AdminAddr = ""
+
+# Number of goroutines processing packets. With more than one worker, the
+# packets read from each socket are distributed to the workers by a hash of
+# their SCION source and destination addresses, which preserves the order of
+# the packets of each flow. (default 1)
+Workers = 1
+
 # Action that should be taken when an error occurs during a context rollback.
 # (Fatal | Continue) (default Fatal)
 RollbackFailAction = "Fatal"
+
+# Duration of the DRKey epochs of the local AS. SCMP errors are authenticated
+# with DRKeys, the duration must match the epoch duration of the certificate
+# server. (default 24h)
+DRKeyEpochDuration = "24h"
+
+# How SCMP errors are authenticated. DRKey authenticates each error with a MAC
+# computed with the DRKey of the destination host. HashTree batches errors and
+# authenticates them with a single signature created with the AS signing key.
+# (None | DRKey | HashTree) (default DRKey)
+SCMPAuthMode = "DRKey"
+
+# Time SCMP errors are batched before they are authenticated with a hash tree
+# signature. Only used if SCMPAuthMode is HashTree. (default 10ms)
+SCMPAuthBatchWindow = "10ms"
+`
+
+const bfdSample = `
+# Enable bidirectional forwarding detection with the routers of neighboring
+# ASes on all external interfaces. (default false)
+Enable = false
+
+# Interval at which BFD packets are sent and expected to be received.
+# (default 10ms)
+Interval = "10ms"
+
+# Number of intervals without a BFD packet from the peer router after which
+# an interface is considered down. (default 3)
+DetectMult = 3
+`
+
+const captureSample = `
+# Start a packet capture when the router starts. Captures can also be started
+# and stopped through the admin API, with this configuration. The API can only
+# select the captured interfaces and lower MaxSize and MaxDuration.
+# (default false)
+Enable = false
+
+# Directory the pcap files are written to. One file is written per interface.
+# (default the system temporary directory)
+Dir = "/tmp"
+
+# IDs of the captured interfaces. The internal interface has ID 0. If empty,
+# all interfaces are captured. (default [])
+Interfaces = []
+
+# Capture packets as they are received, as they are forwarded, or both.
+# (In | Out | Both) (default In)
+Direction = "In"
+
+# Source and destination ISD-AS of the captured packets. 0 in the ISD or AS
+# part matches any ISD or AS, respectively. (default "0-0")
+SrcIA = "0-0"
+DstIA = "0-0"
+
+# L4 protocol of the captured packets. If empty, all packets are captured.
+# ("" | SCMP | TCP | UDP | BFD) (default "")
+L4 = ""
+
+# Maximum size of a capture file in bytes. (default 104857600)
+MaxSize = 104857600
+
+# Duration after which the capture is stopped. (default 1m)
+MaxDuration = "1m"
+`
+
+const policerSample = `
+# Interface ID of the policed external interface. 0 polices all external
+# interfaces. (default 0)
+IFID = 1
+
+# Source ISD-AS of the policed traffic. 0 in the ISD or AS part matches any
+# ISD or AS, respectively. (default "0-0")
+SrcIA = "1-ff00:0:110"
+
+# Sustained rate of the policed traffic in bytes per second.
+Rate = 12500000
+
+# Amount of policed traffic that can be received at once in bytes. Must be at
+# least 1280.
+Burst = 125000
+
+# Action taken for packets exceeding the rate. SCMP additionally answers the
+# packets with SCMP AdminDenied errors, at most 10 per second. (Drop | SCMP)
+# (default Drop)
+Action = "Drop"
 `
 
 const discoverySample = `
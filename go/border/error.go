@@ -19,12 +19,19 @@
 package main
 
 import (
+	"time"
+
+	"github.com/scionproto/scion/go/border/brconf"
 	"github.com/scionproto/scion/go/border/rcmn"
 	"github.com/scionproto/scion/go/border/rpkt"
+	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/drkey"
 	"github.com/scionproto/scion/go/lib/layers"
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/spkt"
+	"github.com/scionproto/scion/go/lib/spse/scmp_auth"
 )
 
 type pktErrorArgs struct {
@@ -95,7 +102,30 @@ func (r *Router) doPktError(rp *rpkt.RtrPkt, perr error) {
 			}
 		}
 	}
-	reply, err := r.createSCMPErrorReply(rp, serr.CT, serr.Info)
+	sp, err := r.createSCMPError(rp, serr.CT, serr.Info)
+	if err != nil {
+		rp.Error("Error creating SCMP response", "err", err)
+		return
+	}
+	switch cfg.BR.SCMPAuthMode {
+	case brconf.SCMPAuthHashTree:
+		// The signer authenticates the error together with the other errors of
+		// its batch, and sends it afterwards.
+		r.scmpSigner.Enqueue(rp, sp)
+		return
+	case brconf.SCMPAuthDRKey:
+		if err := authSCMPError(rp.Ctx.Conf, sp); err != nil {
+			// Still send the error, it is up to the receiver to decide whether
+			// unauthenticated SCMP errors are accepted.
+			rp.Warn("Unable to authenticate SCMP error", "err", err)
+		}
+	}
+	sendSCMPError(rp, sp)
+}
+
+// sendSCMPError creates the reply to rp from the SCMP error sp and routes it.
+func sendSCMPError(rp *rpkt.RtrPkt, sp *spkt.ScnPkt) {
+	reply, err := rp.CreateReply(sp)
 	if err != nil {
 		rp.Error("Error creating SCMP response", "err", err)
 		return
@@ -103,9 +133,9 @@ func (r *Router) doPktError(rp *rpkt.RtrPkt, perr error) {
 	reply.Route()
 }
 
-// createSCMPErrorReply generates an SCMP error reply to the supplied packet.
-func (r *Router) createSCMPErrorReply(rp *rpkt.RtrPkt, ct scmp.ClassType,
-	info scmp.Info) (*rpkt.RtrPkt, error) {
+// createSCMPError generates an SCMP error reply to the supplied packet.
+func (r *Router) createSCMPError(rp *rpkt.RtrPkt, ct scmp.ClassType,
+	info scmp.Info) (*spkt.ScnPkt, error) {
 	// Create generic ScnPkt reply
 	sp, err := rp.CreateReplyScnPkt()
 	if err != nil {
@@ -128,13 +158,83 @@ func (r *Router) createSCMPErrorReply(rp *rpkt.RtrPkt, ct scmp.ClassType,
 	}
 	sp.HBHExt = append(sp.HBHExt, ext)
 	// Filter out any existing SCMP HBH headers, and trim the list to
-	// common.ExtnMaxHBH.
+	// common.ExtnMaxHBH. SIBRA headers are filtered out as well, as errors are
+	// not sent on the reservation of the offending packet.
 	for _, e := range oldHBH {
-		if len(sp.HBHExt) < cap(sp.HBHExt) && e.Type() != common.ExtnSCMPType {
+		if len(sp.HBHExt) < cap(sp.HBHExt) && e.Type() != common.ExtnSCMPType &&
+			e.Type() != common.ExtnSIBRAType {
+
 			sp.HBHExt = append(sp.HBHExt, e)
 		}
 	}
 	sp.Pld = scmp.PldFromQuotes(ct, info, rp.L4Type, rp.GetRaw)
 	sp.L4 = scmp.NewHdr(ct, sp.Pld.Len())
-	return rp.CreateReply(sp)
+	return sp, nil
+}
+
+// authSCMPError adds an SCMPAuthDRKey extension to the SCMP error sp. The MAC
+// is computed with the AS-to-host DRKey K_{IA->D:H_D}, where D:H_D is the
+// destination of the error, i.e., the source of the offending packet.
+func authSCMPError(conf *brconf.BRConf, sp *spkt.ScnPkt) error {
+	if conf.DRKeySV == nil {
+		return common.NewBasicError("DRKey secret value not initialized", nil)
+	}
+	key, err := deriveSCMPKey(conf, sp.DstIA, sp.DstHost, sp.L4.(*scmp.Hdr).Time())
+	if err != nil {
+		return err
+	}
+	mac, err := scmp_auth.ComputeDRKeyMAC(common.RawBytes(key.Key), sp)
+	if err != nil {
+		return common.NewBasicError("Unable to compute MAC", err)
+	}
+	extn := scmp_auth.NewDRKeyExtn()
+	if err := extn.SetDirection(scmp_auth.AsToHost); err != nil {
+		return err
+	}
+	if err := extn.SetMAC(mac); err != nil {
+		return err
+	}
+	setSPSEExtn(sp, extn)
+	return nil
+}
+
+// setSPSEExtn adds the SCIONPacketSecurity extension extn to the SCMP error
+// sp. Only one such extension is allowed per packet, any that has been kept
+// from the offending packet is dropped.
+func setSPSEExtn(sp *spkt.ScnPkt, extn common.Extension) {
+	e2e := make([]common.Extension, 0, len(sp.E2EExt)+1)
+	for _, e := range sp.E2EExt {
+		if e.Type() != common.ExtnSCIONPacketSecurityType {
+			e2e = append(e2e, e)
+		}
+	}
+	sp.E2EExt = append(e2e, extn)
+}
+
+// deriveSCMPKey derives the second-level DRKey K_{IA->dstIA:dstHost} of the
+// SCMP protocol that is valid at valTime.
+func deriveSCMPKey(conf *brconf.BRConf, dstIA addr.IA, dstHost addr.HostAddr,
+	valTime time.Time) (drkey.Lvl2Key, error) {
+
+	sv, err := conf.DRKeySV.GetSecretValue(valTime)
+	if err != nil {
+		return drkey.Lvl2Key{}, err
+	}
+	lvl1, err := drkey.DeriveLvl1(
+		drkey.Lvl1Meta{Epoch: sv.Epoch, SrcIA: conf.IA, DstIA: dstIA}, sv)
+	if err != nil {
+		return drkey.Lvl2Key{}, common.NewBasicError("Unable to derive first-level key", err)
+	}
+	meta := drkey.Lvl2Meta{
+		KeyType:  drkey.AS2Host,
+		Protocol: scmp_auth.DRKeyProtocol,
+		SrcIA:    conf.IA,
+		DstIA:    dstIA,
+		DstHost:  dstHost,
+	}
+	lvl2, err := drkey.DeriveLvl2(meta, lvl1)
+	if err != nil {
+		return drkey.Lvl2Key{}, common.NewBasicError("Unable to derive second-level key", err)
+	}
+	return lvl2, nil
 }
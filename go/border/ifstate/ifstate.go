@@ -56,8 +56,20 @@ func (s *ifStates) Store(key common.IFIDType, val *state) {
 	(*sync.Map)(s).Store(key, val)
 }
 
+func (s *ifStates) Range(f func(key common.IFIDType, val *state) bool) {
+	(*sync.Map)(s).Range(func(key, val interface{}) bool {
+		return f(key.(common.IFIDType), val.(*state))
+	})
+}
+
 var states ifStates
 
+// adminDown contains the interfaces that are administratively shut down.
+var adminDown sync.Map
+
+// linkDown contains the interfaces whose link BFD detected to be down.
+var linkDown sync.Map
+
 type state struct {
 	// info is a pointer to an Info object.
 	info unsafe.Pointer
@@ -153,3 +165,50 @@ func UpdateIfNew(ifID common.IFIDType, old, new *Info) {
 func DeleteState(ifID common.IFIDType) {
 	states.Delete(ifID)
 }
+
+// Range calls f for the state info of each interface, until f returns false.
+func Range(f func(info *Info) bool) {
+	states.Range(func(_ common.IFIDType, s *state) bool {
+		return f((*Info)(atomic.LoadPointer(&s.info)))
+	})
+}
+
+// SetAdminDown administratively shuts down the interface, or re-enables it.
+// The router drops all traffic over a shut down interface, including the
+// interface keepalives of the beacon service. The beacon service thus revokes
+// the interface, and the router replies with revocation SCMPs once it has
+// received the revocation.
+func SetAdminDown(ifID common.IFIDType, down bool) {
+	if down {
+		if _, loaded := adminDown.LoadOrStore(ifID, struct{}{}); !loaded {
+			log.Info("IFState: intf administratively shut down", "ifid", ifID)
+		}
+		return
+	}
+	if _, ok := adminDown.Load(ifID); ok {
+		adminDown.Delete(ifID)
+		log.Info("IFState: intf administratively enabled", "ifid", ifID)
+	}
+}
+
+// AdminDown returns whether the interface is administratively shut down.
+func AdminDown(ifID common.IFIDType) bool {
+	_, ok := adminDown.Load(ifID)
+	return ok
+}
+
+// SetLinkDown records whether BFD detected the link of the interface to be
+// down.
+func SetLinkDown(ifID common.IFIDType, down bool) {
+	if down {
+		linkDown.Store(ifID, struct{}{})
+		return
+	}
+	linkDown.Delete(ifID)
+}
+
+// LinkDown returns whether BFD detected the link of the interface to be down.
+func LinkDown(ifID common.IFIDType) bool {
+	_, ok := linkDown.Load(ifID)
+	return ok
+}
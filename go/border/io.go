@@ -76,6 +76,10 @@ func (r *Router) posixInput(s *rctx.Sock, stop, stopped chan struct{}) {
 		rp.Reset()
 		r.freePkts.Write(ringbuf.EntryList{rp}, true)
 	}
+	var batches []ringbuf.EntryList
+	for range r.workers {
+		batches = append(batches, make(ringbuf.EntryList, 0, inputBatchCnt))
+	}
 
 Top:
 	for {
@@ -132,9 +136,13 @@ Top:
 			inputBytes.Add(float64(msg.N))
 			inputPktSize.Observe(float64(msg.N))
 		}
-		for written := 0; written < pktsRead; {
-			wn, _ := s.Ring.Write(pkts[written:pktsRead], true)
-			written += wn
+		if r.workers != nil {
+			r.dispatch(pkts[:pktsRead], batches)
+		} else {
+			for written := 0; written < pktsRead; {
+				wn, _ := s.Ring.Write(pkts[written:pktsRead], true)
+				written += wn
+			}
 		}
 		// Move unused pkts to the start.
 		copied := copy(pkts, pkts[pktsRead:])
@@ -212,7 +220,7 @@ func (r *Router) posixOutput(s *rctx.Sock, _, stopped chan struct{}) {
 		var bytes int // Needs to be declared before goto
 		var t float64 // Needs to be declared before goto
 		var ok bool
-		if epkts, ok = r.posixPrepOutput(epkts, msgs, s.Ring, dst != nil); !ok {
+		if epkts, ok = r.posixPrepOutput(epkts, msgs, s, dst != nil); !ok {
 			break
 		}
 		toWrite := min(len(epkts), outputBatchCnt)
@@ -263,17 +271,23 @@ func (r *Router) posixOutput(s *rctx.Sock, _, stopped chan struct{}) {
 }
 
 // posixPrepOutput fetches new packets if epkts is empty, and sets the msgs
-// Buffers and Addr based on the corresponding entries in epkts.
+// Buffers and Addr based on the corresponding entries in epkts. Prioritized
+// packets are fetched before any others.
 func (r *Router) posixPrepOutput(epkts ringbuf.EntryList, msgs []ipv4.Message,
-	ring *ringbuf.Ring, connected bool) (ringbuf.EntryList, bool) {
+	s *rctx.Sock, connected bool) (ringbuf.EntryList, bool) {
 
-	if len(epkts) == 0 {
+	for len(epkts) == 0 {
 		epkts = epkts[:cap(epkts)]
-		n, _ := ring.Read(epkts, true)
-		if n < 0 {
-			return epkts[:0], false
+		n := 0
+		if s.PrioRing != nil {
+			n, _ = s.PrioRing.Read(epkts, false)
+		}
+		if n <= 0 {
+			if n, _ = s.Ring.Read(epkts, true); n < 0 {
+				return epkts[:0], false
+			}
 		}
-		epkts = epkts[:n]
+		epkts = removeWakeups(epkts[:n])
 	}
 	// setup msgs
 	for i := range epkts {
@@ -293,6 +307,19 @@ func (r *Router) posixPrepOutput(epkts ringbuf.EntryList, msgs []ipv4.Message,
 	return epkts, true
 }
 
+// removeWakeups removes the nil entries, which are only written to wake up the
+// writer, from epkts.
+func removeWakeups(epkts ringbuf.EntryList) ringbuf.EntryList {
+	n := 0
+	for _, e := range epkts {
+		if e != nil {
+			epkts[n] = e
+			n++
+		}
+	}
+	return epkts[:n]
+}
+
 func isConnRefused(err error) bool {
 	netErr, ok := err.(*net.OpError)
 	if !ok {
@@ -50,11 +50,40 @@ var (
 	// Processing metrics
 	ProcessPktTime    *prometheus.CounterVec
 	ProcessSockSrcDst *prometheus.CounterVec
+	ReprocessPkts     *prometheus.CounterVec
+
+	// Policer metrics
+	PolicerPkts  *prometheus.CounterVec
+	PolicerBytes *prometheus.CounterVec
+
+	// Reservation metrics
+	ReservationPkts  *prometheus.CounterVec
+	ReservationBytes *prometheus.CounterVec
+
+	// SVC resolution metrics
+	SVCResolutions *prometheus.CounterVec
+
+	// BFD metrics
+	BFDUp           *prometheus.GaugeVec
+	BFDRTT          *prometheus.GaugeVec
+	BFDStateChanges *prometheus.CounterVec
 
 	// Misc
 	IFState *prometheus.GaugeVec
 )
 
+// Policer results.
+const (
+	PolicerConform = "conform"
+	PolicerExceed  = "exceed"
+)
+
+// Results of handing packets back to the workers for processing.
+const (
+	ReprocessQueued  = "queued"
+	ReprocessDropped = "dropped"
+)
+
 // Init ensures all metrics are registered.
 func Init(elem string) {
 	namespace := "border"
@@ -108,6 +137,30 @@ func Init(elem string) {
 		"Total processing time for input packets, in seconds.", sockLabels)
 	ProcessSockSrcDst = newCVec("process_pkts_src_dst_total",
 		"Total number of packets from one sock to another.", []string{"inSock", "outSock"})
+	ReprocessPkts = newCVec("reprocess_pkts_total",
+		"Total number of packets handed back to the workers for processing.",
+		[]string{"result"})
+
+	BFDUp = newGVec("bfd_up", "BFD session with the peer router is up.", sockLabels)
+	BFDRTT = newGVec("bfd_rtt_seconds",
+		"Round trip time to the peer router measured by BFD, in seconds.", sockLabels)
+	BFDStateChanges = newCVec("bfd_state_changes_total",
+		"Total number of BFD session state changes.", []string{"sock", "state"})
+
+	policerLabels := []string{"ifid", "srcIA", "result"}
+	PolicerPkts = newCVec("policer_pkts_total",
+		"Total number of packets checked by a policer.", policerLabels)
+	PolicerBytes = newCVec("policer_bytes_total",
+		"Total number of bytes checked by a policer.", policerLabels)
+
+	ReservationPkts = newCVec("reservation_pkts_total",
+		"Total number of packets checked against their SIBRA reservation.", []string{"result"})
+	ReservationBytes = newCVec("reservation_bytes_total",
+		"Total number of bytes checked against their SIBRA reservation.", []string{"result"})
+
+	SVCResolutions = newCVec("svc_resolutions_total",
+		"Total number of anycast SVC addresses resolved to a service instance.",
+		[]string{"svc", "instance"})
 
 	// border_base_labels is a special metric that always has the value `1`,
 	// that is used to add labels to non-br metrics.
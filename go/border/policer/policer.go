@@ -0,0 +1,201 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policer limits the rate of the traffic the router receives from
+// neighboring ASes with token bucket policers, which are configured in brconf.
+//
+// A packet is checked against all policers that match its ingress interface
+// and source ISD-AS. It is accepted if it conforms to all of them, i.e., if
+// each of the policers has enough tokens for the packet. Otherwise, no tokens
+// are taken, and the packet is handled according to the action of the first
+// policer it exceeds. SCMP replies to exceeding packets are rate limited per
+// policer, such that the policers cannot be used to reflect traffic.
+//
+// In addition, traffic on SIBRA reservations is limited to the reserved
+// bandwidth with one token bucket per reservation, see PoliceReservation.
+package policer
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/metrics"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+const (
+	// scmpReplyRate is the number of SCMP replies per second a policer with
+	// the SCMP action sends at most.
+	scmpReplyRate = 10
+	// scmpReplyBurst is the number of SCMP replies a policer with the SCMP
+	// action sends at once at most.
+	scmpReplyBurst = 10
+)
+
+// policers contains the current []*Policer.
+var policers atomic.Value
+
+func init() {
+	policers.Store([]*Policer(nil))
+}
+
+// Init replaces the policers with ones created from cfgs. It is called again
+// when the config is reloaded. Policers whose configuration did not change
+// keep their buckets, such that a reload does not refill them. The buckets of
+// new policers are full.
+func Init(cfgs []*brconf.Policer) {
+	old := policers.Load().([]*Policer)
+	kept := make(map[*Policer]bool, len(old))
+	ps := make([]*Policer, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		var p *Policer
+		for _, o := range old {
+			if !kept[o] && *o.Policer == *cfg {
+				p = o
+				kept[o] = true
+				break
+			}
+		}
+		if p == nil {
+			p = New(cfg)
+		}
+		ps = append(ps, p)
+	}
+	policers.Store(ps)
+}
+
+// Police checks a packet of length n received on interface ifid from the
+// source src against the matching policers. It returns nil if the packet
+// conforms to all of them, otherwise the first policer the packet exceeds.
+func Police(ifid common.IFIDType, src addr.IA, n int) *Policer {
+	ps := policers.Load().([]*Policer)
+	if len(ps) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i, p := range ps {
+		if !p.Matches(ifid, src) {
+			continue
+		}
+		if !p.take(now, n) {
+			// Return the tokens taken by the policers before.
+			for _, prev := range ps[:i] {
+				if prev.Matches(ifid, src) {
+					prev.refund(n)
+				}
+			}
+			p.exceedPkts.Inc()
+			p.exceedBytes.Add(float64(n))
+			return p
+		}
+	}
+	for _, p := range ps {
+		if p.Matches(ifid, src) {
+			p.conformPkts.Inc()
+			p.conformBytes.Add(float64(n))
+		}
+	}
+	return nil
+}
+
+// Policer is a token bucket policer.
+type Policer struct {
+	*brconf.Policer
+	mtx sync.Mutex
+	// tokens is the number of bytes that can currently be received.
+	tokens float64
+	// last is the time tokens was last updated.
+	last time.Time
+	// scmpTokens is the number of SCMP replies that can currently be sent.
+	scmpTokens float64
+	// scmpLast is the time scmpTokens was last updated.
+	scmpLast time.Time
+
+	conformPkts  prometheus.Counter
+	conformBytes prometheus.Counter
+	exceedPkts   prometheus.Counter
+	exceedBytes  prometheus.Counter
+}
+
+// New creates a policer with a full bucket.
+func New(cfg *brconf.Policer) *Policer {
+	now := time.Now()
+	ifid, src := fmt.Sprint(cfg.IFID), cfg.SrcIA.String()
+	return &Policer{
+		Policer:      cfg,
+		tokens:       float64(cfg.Burst),
+		last:         now,
+		scmpTokens:   scmpReplyBurst,
+		scmpLast:     now,
+		conformPkts:  metrics.PolicerPkts.WithLabelValues(ifid, src, metrics.PolicerConform),
+		conformBytes: metrics.PolicerBytes.WithLabelValues(ifid, src, metrics.PolicerConform),
+		exceedPkts:   metrics.PolicerPkts.WithLabelValues(ifid, src, metrics.PolicerExceed),
+		exceedBytes:  metrics.PolicerBytes.WithLabelValues(ifid, src, metrics.PolicerExceed),
+	}
+}
+
+// take refills the bucket up to now, and takes n tokens from it. It returns
+// false, without taking any tokens, if the bucket contains less than n
+// tokens.
+func (p *Policer) take(now time.Time, n int) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if elapsed := now.Sub(p.last); elapsed > 0 {
+		p.tokens += elapsed.Seconds() * float64(p.Rate)
+		if p.tokens > float64(p.Burst) {
+			p.tokens = float64(p.Burst)
+		}
+		p.last = now
+	}
+	if p.tokens < float64(n) {
+		return false
+	}
+	p.tokens -= float64(n)
+	return true
+}
+
+// refund returns n tokens to the bucket.
+func (p *Policer) refund(n int) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.tokens += float64(n)
+	if p.tokens > float64(p.Burst) {
+		p.tokens = float64(p.Burst)
+	}
+}
+
+// AllowSCMP returns whether an SCMP reply may be sent at time now for a packet
+// that exceeded the policer.
+func (p *Policer) AllowSCMP(now time.Time) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if elapsed := now.Sub(p.scmpLast); elapsed > 0 {
+		p.scmpTokens += elapsed.Seconds() * scmpReplyRate
+		if p.scmpTokens > scmpReplyBurst {
+			p.scmpTokens = scmpReplyBurst
+		}
+		p.scmpLast = now
+	}
+	if p.scmpTokens < 1 {
+		return false
+	}
+	p.scmpTokens--
+	return true
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policer
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/metrics"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func init() {
+	metrics.Init("test")
+}
+
+func newTestPolicer(ifid common.IFIDType, src string, rate, burst uint64) *brconf.Policer {
+	return &brconf.Policer{
+		IFID:   ifid,
+		SrcIA:  xtest.MustParseIA(src),
+		Rate:   rate,
+		Burst:  burst,
+		Action: brconf.PolicerActionDrop,
+	}
+}
+
+func TestPolicerTake(t *testing.T) {
+	Convey("Policer enforces its rate and burst", t, func() {
+		p := New(newTestPolicer(1, "0-0", 1000, 1500))
+		now := p.last
+		SoMsg("full bucket", p.take(now, 1500), ShouldBeTrue)
+		SoMsg("empty bucket", p.take(now, 1), ShouldBeFalse)
+		now = now.Add(500 * time.Millisecond)
+		SoMsg("over refill", p.take(now, 501), ShouldBeFalse)
+		SoMsg("refill", p.take(now, 500), ShouldBeTrue)
+		now = now.Add(time.Hour)
+		SoMsg("over burst", p.take(now, 1501), ShouldBeFalse)
+		SoMsg("burst", p.take(now, 1500), ShouldBeTrue)
+		p.refund(1000)
+		SoMsg("refund", p.take(now, 1000), ShouldBeTrue)
+	})
+}
+
+func TestPolice(t *testing.T) {
+	Convey("Police checks packets against all matching policers", t, func() {
+		Init([]*brconf.Policer{
+			newTestPolicer(1, "0-0", 1, 1500),
+			newTestPolicer(0, "1-ff00:0:110", 1, 3000),
+		})
+		src := xtest.MustParseIA("1-ff00:0:110")
+		other := xtest.MustParseIA("1-ff00:0:111")
+		SoMsg("conform", Police(1, src, 1000), ShouldBeNil)
+		p := Police(1, src, 1000)
+		SoMsg("exceed interface", p, ShouldNotBeNil)
+		SoMsg("exceed interface ifid", p.IFID, ShouldEqual, common.IFIDType(1))
+		// The second packet must not have consumed tokens of the source policer.
+		SoMsg("conform source", Police(2, src, 2000), ShouldBeNil)
+		p = Police(2, src, 1)
+		SoMsg("exceed source", p, ShouldNotBeNil)
+		SoMsg("exceed source ia", p.SrcIA, ShouldResemble, src)
+		SoMsg("no match", Police(2, other, 5000), ShouldBeNil)
+		Init(nil)
+		SoMsg("no policers", Police(1, src, 5000), ShouldBeNil)
+	})
+}
+
+func TestInitKeepsBuckets(t *testing.T) {
+	Convey("Reloading the policers", t, func() {
+		src := xtest.MustParseIA("1-ff00:0:110")
+		Init([]*brconf.Policer{newTestPolicer(1, "0-0", 1, 1500)})
+		SoMsg("drain", Police(1, src, 1500), ShouldBeNil)
+		Convey("keeps the bucket of an unchanged policer", func() {
+			Init([]*brconf.Policer{newTestPolicer(1, "0-0", 1, 1500)})
+			SoMsg("exceed", Police(1, src, 1000), ShouldNotBeNil)
+		})
+		Convey("creates a full bucket for a changed policer", func() {
+			Init([]*brconf.Policer{newTestPolicer(1, "0-0", 1, 3000)})
+			SoMsg("conform", Police(1, src, 3000), ShouldBeNil)
+		})
+		Convey("removes deleted policers", func() {
+			Init(nil)
+			SoMsg("conform", Police(1, src, 5000), ShouldBeNil)
+		})
+		Reset(func() { Init(nil) })
+	})
+}
+
+func TestAllowSCMP(t *testing.T) {
+	Convey("SCMP replies are rate limited", t, func() {
+		p := New(newTestPolicer(1, "0-0", 1000, 1500))
+		now := p.scmpLast
+		for i := 0; i < scmpReplyBurst; i++ {
+			SoMsg("burst", p.AllowSCMP(now), ShouldBeTrue)
+		}
+		SoMsg("exceed", p.AllowSCMP(now), ShouldBeFalse)
+		now = now.Add(time.Second / scmpReplyRate)
+		SoMsg("refill", p.AllowSCMP(now), ShouldBeTrue)
+		SoMsg("exceed again", p.AllowSCMP(now), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policer
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/border/metrics"
+)
+
+const (
+	// ReservationBurst is the duration of traffic at the reserved bandwidth
+	// that a reservation bucket can hold.
+	ReservationBurst = 100 * time.Millisecond
+	// reservationGCInterval is the interval at which buckets of expired
+	// reservations are removed.
+	reservationGCInterval = 10 * time.Second
+)
+
+var reservations = struct {
+	sync.Mutex
+	m      map[uint64]*reservation
+	lastGC time.Time
+}{m: make(map[uint64]*reservation)}
+
+// PoliceReservation checks a packet of n bytes against the token bucket of the
+// SIBRA reservation with the given ID, which has a rate of bw bytes per second
+// and expires at exp. The bucket is created when the first packet of a
+// reservation is seen, and is updated when the reservation is renewed with a
+// different bandwidth or expiration time. Returns true if the packet conforms
+// to the reservation.
+func PoliceReservation(id uint64, bw uint64, exp time.Time, n int) bool {
+	now := time.Now()
+	r := getReservation(now, id, bw, exp)
+	ok := r.take(now, n)
+	result := metrics.PolicerConform
+	if !ok {
+		result = metrics.PolicerExceed
+	}
+	metrics.ReservationPkts.WithLabelValues(result).Inc()
+	metrics.ReservationBytes.WithLabelValues(result).Add(float64(n))
+	return ok
+}
+
+func getReservation(now time.Time, id uint64, bw uint64, exp time.Time) *reservation {
+	reservations.Lock()
+	defer reservations.Unlock()
+	if now.Sub(reservations.lastGC) > reservationGCInterval {
+		for resID, r := range reservations.m {
+			if now.After(r.exp) {
+				delete(reservations.m, resID)
+			}
+		}
+		reservations.lastGC = now
+	}
+	r, ok := reservations.m[id]
+	if !ok {
+		r = newReservation(now, bw, exp)
+		reservations.m[id] = r
+	} else {
+		r.update(bw, exp)
+	}
+	return r
+}
+
+// reservation is the token bucket of a SIBRA reservation.
+type reservation struct {
+	mtx sync.Mutex
+	// rate is the reserved bandwidth in bytes per second.
+	rate uint64
+	// burst is the size of the bucket in bytes.
+	burst uint64
+	// exp is the time the reservation expires.
+	exp time.Time
+	// tokens is the number of bytes that can currently be forwarded.
+	tokens float64
+	// last is the time tokens was last updated.
+	last time.Time
+}
+
+func newReservation(now time.Time, bw uint64, exp time.Time) *reservation {
+	r := &reservation{last: now}
+	r.update(bw, exp)
+	r.tokens = float64(r.burst)
+	return r
+}
+
+func (r *reservation) update(bw uint64, exp time.Time) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.rate == bw && r.exp.Equal(exp) {
+		return
+	}
+	r.rate = bw
+	r.exp = exp
+	r.burst = uint64(float64(bw) * ReservationBurst.Seconds())
+	if r.tokens > float64(r.burst) {
+		r.tokens = float64(r.burst)
+	}
+}
+
+func (r *reservation) take(now time.Time, n int) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if elapsed := now.Sub(r.last); elapsed > 0 {
+		r.tokens += elapsed.Seconds() * float64(r.rate)
+		if r.tokens > float64(r.burst) {
+			r.tokens = float64(r.burst)
+		}
+		r.last = now
+	}
+	// Packets larger than the bucket are accepted if the bucket is full, such
+	// that reservations with a small bandwidth are usable. The tokens then
+	// become negative, until the packet is paid off.
+	if r.tokens < math.Min(float64(n), float64(r.burst)) {
+		return false
+	}
+	r.tokens -= float64(n)
+	return true
+}
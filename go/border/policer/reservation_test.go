@@ -0,0 +1,67 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policer
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+func TestReservationTake(t *testing.T) {
+	Convey("Reservation enforces the reserved bandwidth", t, func() {
+		now := time.Now()
+		r := newReservation(now, 100000, now.Add(time.Minute))
+		SoMsg("burst", r.burst, ShouldEqual, uint64(10000))
+		SoMsg("full bucket", r.take(now, 10000), ShouldBeTrue)
+		SoMsg("empty bucket", r.take(now, 1), ShouldBeFalse)
+		now = now.Add(10 * time.Millisecond)
+		SoMsg("over refill", r.take(now, 1001), ShouldBeFalse)
+		SoMsg("refill", r.take(now, 1000), ShouldBeTrue)
+		Convey("and accepts packets larger than the bucket if it is full", func() {
+			r.update(10000, now.Add(time.Minute))
+			SoMsg("burst", r.burst, ShouldEqual, uint64(1000))
+			now = now.Add(100 * time.Millisecond)
+			SoMsg("large packet", r.take(now, 1500), ShouldBeTrue)
+			now = now.Add(50 * time.Millisecond)
+			SoMsg("debt", r.take(now, 1), ShouldBeFalse)
+			now = now.Add(50 * time.Millisecond)
+			SoMsg("paid off", r.take(now, 1), ShouldBeTrue)
+		})
+	})
+}
+
+func TestPoliceReservation(t *testing.T) {
+	Convey("PoliceReservation keeps one bucket per reservation", t, func() {
+		exp := time.Now().Add(time.Minute)
+		SoMsg("conform", PoliceReservation(1, 1, exp, common.MaxMTU), ShouldBeTrue)
+		SoMsg("exceed", PoliceReservation(1, 1, exp, common.MaxMTU), ShouldBeFalse)
+		SoMsg("other reservation", PoliceReservation(2, 1, exp, common.MaxMTU), ShouldBeTrue)
+		Convey("and removes the buckets of expired reservations", func() {
+			reservations.Lock()
+			reservations.m[3] = newReservation(time.Now(), 1, time.Now().Add(-time.Second))
+			reservations.lastGC = time.Time{}
+			reservations.Unlock()
+			PoliceReservation(1, 1, exp, 1)
+			reservations.Lock()
+			_, ok := reservations.m[3]
+			reservations.Unlock()
+			SoMsg("removed", ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package radmin implements the administrative API of the border router.
+//
+// The API is served as JSON over HTTP, on its own address (br.AdminAddr). The
+// requests are not authenticated, so the API is disabled unless the address is
+// configured:
+//
+//  GET  /api/v1/context                        Show the current router context.
+//  GET  /api/v1/interfaces                     List the external interfaces and their state.
+//  GET  /api/v1/interfaces/<ifid>              Show a single external interface.
+//  POST /api/v1/interfaces/<ifid>/shutdown     Administratively shut down an interface.
+//  POST /api/v1/interfaces/<ifid>/enable       Re-enable a shut down interface.
+//  GET  /api/v1/revocations                    List the cached revocations.
+//  GET  /api/v1/capture                        Show the running packet capture.
+//  POST /api/v1/capture/start                  Start a packet capture.
+//  POST /api/v1/capture/stop                   Stop the running packet capture.
+//
+// The router drops all traffic over a shut down interface, and notifies the
+// beacon service that the link is down, such that it revokes the interface.
+// Once the router has received the revocation, it replies to traffic using the
+// interface with revocation SCMPs. As the interface keepalives are dropped as
+// well, the interface stays revoked until it is re-enabled.
+//
+// A capture started through the API uses the capture configuration of the
+// router (br.Capture). The body of a capture start request can only select
+// the captured interfaces and lower the limits of the capture, e.g.:
+//
+//  {"Interfaces": [1], "MaxSize": 1048576, "MaxDuration": "30s"}
+//
+// Omitted fields take the configured values, limits above the configured ones
+// are lowered to them.
+package radmin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/ifstate"
+	"github.com/scionproto/scion/go/border/rcapture"
+	"github.com/scionproto/scion/go/border/rctx"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/httpapi"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+const prefix = "/api/v1/"
+
+const (
+	badRequest = http.StatusBadRequest
+	notFound   = http.StatusNotFound
+)
+
+// Init starts serving the API on address. Captures are started with the
+// capture configuration cfg. linkDown is called for every interface that is
+// shut down, to notify the beacon service.
+func Init(address string, cfg brconf.Capture, linkDown func(ifid common.IFIDType)) error {
+	return httpapi.Serve(address, newMux(cfg, linkDown))
+}
+
+func newMux(cfg brconf.Capture, linkDown func(ifid common.IFIDType)) *http.ServeMux {
+	h := &handler{capCfg: cfg, linkDown: linkDown}
+	mux := http.NewServeMux()
+	mux.Handle(prefix, httpapi.HandlerFunc(h.route))
+	return mux
+}
+
+type handler struct {
+	capCfg   brconf.Capture
+	linkDown func(ifid common.IFIDType)
+}
+
+func (h *handler) route(r *http.Request) (interface{}, error) {
+	ctx := rctx.Get()
+	if ctx == nil {
+		return nil, httpapi.NewError(http.StatusServiceUnavailable,
+			common.NewBasicError("Router context not set up", nil))
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "context":
+		if r.Method != http.MethodGet {
+			return nil, httpapi.ErrMethod(r.Method)
+		}
+		return ctxStatus(ctx), nil
+	case len(parts) == 1 && parts[0] == "interfaces":
+		if r.Method != http.MethodGet {
+			return nil, httpapi.ErrMethod(r.Method)
+		}
+		return intfStatuses(ctx), nil
+	case len(parts) == 1 && parts[0] == "revocations":
+		if r.Method != http.MethodGet {
+			return nil, httpapi.ErrMethod(r.Method)
+		}
+		return revStatuses(), nil
+	case len(parts) == 1 && parts[0] == "capture":
+		if r.Method != http.MethodGet {
+			return nil, httpapi.ErrMethod(r.Method)
+		}
+		return rcapture.Get(), nil
+	case len(parts) == 2 && parts[0] == "capture":
+		return h.capture(r, parts[1])
+	case len(parts) >= 2 && len(parts) <= 3 && parts[0] == "interfaces":
+		return h.intf(r, ctx, parts[1:])
+	}
+	return nil, httpapi.ErrPath(r.URL.Path)
+}
+
+func (h *handler) intf(r *http.Request, ctx *rctx.Ctx, parts []string) (interface{}, error) {
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, httpapi.NewError(badRequest, common.NewBasicError("Unable to parse IFID", err,
+			"ifid", parts[0]))
+	}
+	ifid := common.IFIDType(id)
+	intf, ok := ctx.Conf.Net.IFs[ifid]
+	if !ok {
+		return nil, httpapi.NewError(notFound, common.NewBasicError("Unknown interface", nil,
+			"ifid", ifid))
+	}
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			return nil, httpapi.ErrMethod(r.Method)
+		}
+		return intfStatus(intf), nil
+	}
+	if r.Method != http.MethodPost {
+		return nil, httpapi.ErrMethod(r.Method)
+	}
+	switch parts[1] {
+	case "shutdown":
+		ifstate.SetAdminDown(ifid, true)
+		h.linkDown(ifid)
+	case "enable":
+		ifstate.SetAdminDown(ifid, false)
+	default:
+		return nil, httpapi.ErrPath(r.URL.Path)
+	}
+	return intfStatus(intf), nil
+}
+
+func (h *handler) capture(r *http.Request, action string) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, httpapi.ErrMethod(r.Method)
+	}
+	switch action {
+	case "start":
+		cfg, err := h.captureConfig(r.Body)
+		if err != nil {
+			return nil, httpapi.NewError(badRequest, err)
+		}
+		return rcapture.Start(cfg)
+	case "stop":
+		return rcapture.Stop(), nil
+	}
+	return nil, httpapi.ErrPath(r.URL.Path)
+}
+
+// captureReq is the body of a capture start request.
+type captureReq struct {
+	Interfaces  []common.IFIDType
+	MaxSize     uint64
+	MaxDuration util.DurWrap
+}
+
+// captureConfig returns the configuration of a capture started with the
+// request body. The request can only select the captured interfaces and lower
+// the configured limits.
+func (h *handler) captureConfig(body io.Reader) (*brconf.Capture, error) {
+	req := &captureReq{}
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil && err != io.EOF {
+		return nil, common.NewBasicError("Unable to parse capture request", err)
+	}
+	cfg := h.capCfg
+	if len(req.Interfaces) > 0 {
+		cfg.Interfaces = req.Interfaces
+	}
+	if req.MaxSize != 0 && req.MaxSize < cfg.MaxSize {
+		cfg.MaxSize = req.MaxSize
+	}
+	if req.MaxDuration.Duration != 0 && req.MaxDuration.Duration < cfg.MaxDuration.Duration {
+		cfg.MaxDuration = req.MaxDuration
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, common.NewBasicError("Invalid capture request", err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/ifstate"
+	"github.com/scionproto/scion/go/border/netconf"
+	"github.com/scionproto/scion/go/border/rctx"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/util"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/proto"
+)
+
+func TestInterfaceAdmin(t *testing.T) {
+	rctx.Set(&rctx.Ctx{
+		Conf: &brconf.BRConf{
+			Net: &netconf.NetConf{
+				IFs: map[common.IFIDType]*netconf.Interface{
+					1: {
+						Id:       1,
+						RemoteIA: xtest.MustParseIA("1-ff00:0:112"),
+						Type:     proto.LinkType_child,
+					},
+				},
+			},
+		},
+	})
+	tests := []struct {
+		Name      string
+		Method    string
+		Path      string
+		Code      int
+		AdminDown bool
+		LinkDown  []common.IFIDType
+	}{
+		{
+			Name:      "shutdown notifies the beacon service",
+			Method:    http.MethodPost,
+			Path:      "/api/v1/interfaces/1/shutdown",
+			Code:      http.StatusOK,
+			AdminDown: true,
+			LinkDown:  []common.IFIDType{1},
+		},
+		{
+			Name:   "enable",
+			Method: http.MethodPost,
+			Path:   "/api/v1/interfaces/1/enable",
+			Code:   http.StatusOK,
+		},
+		{
+			Name:   "shutdown requires POST",
+			Method: http.MethodGet,
+			Path:   "/api/v1/interfaces/1/shutdown",
+			Code:   http.StatusMethodNotAllowed,
+		},
+		{
+			Name:   "unknown interface",
+			Method: http.MethodPost,
+			Path:   "/api/v1/interfaces/2/shutdown",
+			Code:   http.StatusNotFound,
+		},
+		{
+			Name:   "invalid interface",
+			Method: http.MethodPost,
+			Path:   "/api/v1/interfaces/one/shutdown",
+			Code:   http.StatusBadRequest,
+		},
+		{
+			Name:   "unknown action",
+			Method: http.MethodPost,
+			Path:   "/api/v1/interfaces/1/reset",
+			Code:   http.StatusNotFound,
+		},
+	}
+	Convey("Interface admin requests", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				defer ifstate.SetAdminDown(1, false)
+				var linkDown []common.IFIDType
+				mux := newMux(brconf.Capture{}, func(ifid common.IFIDType) {
+					linkDown = append(linkDown, ifid)
+				})
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest(test.Method, test.Path, nil))
+				SoMsg("code", w.Code, ShouldEqual, test.Code)
+				SoMsg("linkDown", linkDown, ShouldResemble, test.LinkDown)
+				SoMsg("adminDown", ifstate.AdminDown(1), ShouldEqual, test.AdminDown)
+				if test.Code != http.StatusOK {
+					return
+				}
+				status := &IntfStatus{}
+				SoMsg("decode", json.Unmarshal(w.Body.Bytes(), status), ShouldBeNil)
+				SoMsg("status", status.AdminDown, ShouldEqual, test.AdminDown)
+			})
+		}
+	})
+	Convey("The API is not served on the default mux", t, func() {
+		_, pattern := http.DefaultServeMux.Handler(
+			httptest.NewRequest(http.MethodGet, "/api/v1/interfaces", nil))
+		SoMsg("pattern", pattern, ShouldBeEmpty)
+	})
+}
+
+func TestCaptureConfig(t *testing.T) {
+	h := &handler{
+		capCfg: brconf.Capture{
+			Dir:         "/var/capture",
+			Direction:   brconf.CaptureDirIn,
+			L4:          "SCMP",
+			MaxSize:     1 << 20,
+			MaxDuration: util.DurWrap{Duration: time.Minute},
+		},
+	}
+	tests := []struct {
+		Name        string
+		Body        string
+		Interfaces  []common.IFIDType
+		MaxSize     uint64
+		MaxDuration time.Duration
+		Err         bool
+	}{
+		{
+			Name:        "empty request uses the config",
+			MaxSize:     1 << 20,
+			MaxDuration: time.Minute,
+		},
+		{
+			Name:        "interfaces and lower limits",
+			Body:        `{"Interfaces": [1, 2], "MaxSize": 65536, "MaxDuration": "10s"}`,
+			Interfaces:  []common.IFIDType{1, 2},
+			MaxSize:     65536,
+			MaxDuration: 10 * time.Second,
+		},
+		{
+			Name:        "higher limits are clamped",
+			Body:        `{"MaxSize": 1073741824, "MaxDuration": "1h"}`,
+			MaxSize:     1 << 20,
+			MaxDuration: time.Minute,
+		},
+		{
+			Name: "directory is rejected",
+			Body: `{"Dir": "/etc"}`,
+			Err:  true,
+		},
+		{
+			Name: "filters are rejected",
+			Body: `{"L4": "UDP"}`,
+			Err:  true,
+		},
+		{
+			Name: "too small file size",
+			Body: `{"MaxSize": 1}`,
+			Err:  true,
+		},
+	}
+	Convey("Capture requests", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				cfg, err := h.captureConfig(strings.NewReader(test.Body))
+				if test.Err {
+					SoMsg("err", err, ShouldNotBeNil)
+					return
+				}
+				SoMsg("err", err, ShouldBeNil)
+				SoMsg("Dir", cfg.Dir, ShouldEqual, "/var/capture")
+				SoMsg("L4", cfg.L4, ShouldEqual, "SCMP")
+				SoMsg("Interfaces", cfg.Interfaces, ShouldResemble, test.Interfaces)
+				SoMsg("MaxSize", cfg.MaxSize, ShouldEqual, test.MaxSize)
+				SoMsg("MaxDuration", cfg.MaxDuration.Duration, ShouldEqual, test.MaxDuration)
+			})
+		}
+	})
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radmin
+
+import (
+	"sort"
+	"time"
+
+	"github.com/scionproto/scion/go/border/ifstate"
+	"github.com/scionproto/scion/go/border/netconf"
+	"github.com/scionproto/scion/go/border/rctx"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+)
+
+// CtxStatus describes the current router context.
+type CtxStatus struct {
+	IA      addr.IA
+	MTU     int
+	Overlay string
+	// InternalAddr is the address of the router in the local AS.
+	InternalAddr string
+	// CtrlAddr is the address the router uses for control traffic.
+	CtrlAddr   string
+	Interfaces []*IntfStatus
+}
+
+// IntfStatus describes an external interface of the router.
+type IntfStatus struct {
+	IFID       common.IFIDType
+	RemoteIA   addr.IA
+	LinkType   string
+	LocalAddr  string
+	RemoteAddr string
+	MTU        int
+	BW         int
+	// Active is false if the interface is revoked.
+	Active bool
+	// AdminDown is true if the interface is administratively shut down.
+	AdminDown bool
+	// LinkDown is true if BFD detected the link to be down.
+	LinkDown   bool
+	Revocation *RevStatus `json:",omitempty"`
+}
+
+// RevStatus describes a cached revocation.
+type RevStatus struct {
+	IFID       common.IFIDType
+	IA         addr.IA
+	LinkType   string
+	Timestamp  time.Time
+	Expiration time.Time
+	// Active is false if the revocation has expired.
+	Active bool
+}
+
+func ctxStatus(ctx *rctx.Ctx) *CtxStatus {
+	status := &CtxStatus{
+		IA:         ctx.Conf.IA,
+		MTU:        ctx.Conf.Topo.MTU,
+		Overlay:    ctx.Conf.Topo.Overlay.String(),
+		Interfaces: intfStatuses(ctx),
+	}
+	if ctx.Conf.Net.LocAddr != nil {
+		status.InternalAddr = ctx.Conf.Net.LocAddr.String()
+	}
+	if ctx.Conf.Net.CtrlAddr != nil {
+		status.CtrlAddr = ctx.Conf.Net.CtrlAddr.String()
+	}
+	return status
+}
+
+// intfStatuses returns the state of the router's external interfaces, sorted
+// by IFID.
+func intfStatuses(ctx *rctx.Ctx) []*IntfStatus {
+	status := make([]*IntfStatus, 0, len(ctx.Conf.Net.IFs))
+	for _, intf := range ctx.Conf.Net.IFs {
+		status = append(status, intfStatus(intf))
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].IFID < status[j].IFID
+	})
+	return status
+}
+
+func intfStatus(intf *netconf.Interface) *IntfStatus {
+	status := &IntfStatus{
+		IFID:      intf.Id,
+		RemoteIA:  intf.RemoteIA,
+		LinkType:  intf.Type.String(),
+		MTU:       intf.MTU,
+		BW:        intf.BW,
+		Active:    true,
+		AdminDown: ifstate.AdminDown(intf.Id),
+		LinkDown:  ifstate.LinkDown(intf.Id),
+	}
+	if intf.IFAddr != nil {
+		status.LocalAddr = intf.IFAddr.String()
+	}
+	if intf.RemoteAddr != nil {
+		status.RemoteAddr = intf.RemoteAddr.String()
+	}
+	if info, ok := ifstate.LoadState(intf.Id); ok {
+		status.Active = info.Active
+		status.Revocation = revStatus(info)
+	}
+	return status
+}
+
+// revStatuses returns the cached revocations, sorted by IFID.
+func revStatuses() []*RevStatus {
+	status := []*RevStatus{}
+	ifstate.Range(func(info *ifstate.Info) bool {
+		if rev := revStatus(info); rev != nil {
+			status = append(status, rev)
+		}
+		return true
+	})
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].IFID < status[j].IFID
+	})
+	return status
+}
+
+func revStatus(info *ifstate.Info) *RevStatus {
+	if info.SRevInfo == nil {
+		return nil
+	}
+	revInfo, err := info.SRevInfo.RevInfo()
+	if err != nil {
+		log.Warn("Could not parse RevInfo for interface", "ifid", info.IfID, "err", err)
+		return nil
+	}
+	return &RevStatus{
+		IFID:       revInfo.IfID,
+		IA:         revInfo.IA(),
+		LinkType:   revInfo.LinkType.String(),
+		Timestamp:  revInfo.Timestamp(),
+		Expiration: revInfo.Expiration(),
+		Active:     revInfo.Active() == nil,
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rcapture captures the packets processed by the border router to pcap
+// files.
+//
+// A capture writes one file per interface, named after the router ID, the
+// interface and the start time of the capture. The internal interface has ID
+// 0. Packets are captured as raw IP packets, with the overlay IP and UDP
+// headers reconstructed from the overlay addresses, such that common tools
+// can dissect the SCION packets they contain. A capture is stopped once its
+// maximum duration has elapsed. A file that reaches the maximum size is
+// closed, and no further packets are captured on its interface.
+package rcapture
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/overlay"
+)
+
+const (
+	// snapLen is the snapshot length of the pcap files. Packets are never
+	// truncated.
+	snapLen = 1 << 16
+	// fileHdrLen and recordHdrLen are the lengths of the pcap file header
+	// and packet record header, respectively.
+	fileHdrLen   = 24
+	recordHdrLen = 16
+	// timeFmt is the format of the start time in file names.
+	timeFmt = "20060102T150405Z"
+)
+
+var (
+	// mu serializes starting and stopping captures.
+	mu sync.Mutex
+	// current holds the running capture, if any.
+	current atomic.Value
+	// brID is the ID of the router, which is part of the file names.
+	brID string
+)
+
+func init() {
+	current.Store((*capture)(nil))
+}
+
+// Init sets the ID of the router, which is part of the file names.
+func Init(id string) {
+	brID = id
+}
+
+// Info contains the attributes of a packet the capture filter matches on.
+type Info struct {
+	SrcIA addr.IA
+	DstIA addr.IA
+	L4    common.L4ProtocolType
+}
+
+// Status describes a capture.
+type Status struct {
+	Running bool
+	Started time.Time
+	Config  *brconf.Capture
+	Files   []FileStatus
+}
+
+// FileStatus describes the file of a captured interface.
+type FileStatus struct {
+	IFID    common.IFIDType
+	Path    string
+	Packets uint64
+	Bytes   uint64
+	// Full indicates that the file reached the maximum size.
+	Full  bool
+	Error string `json:",omitempty"`
+}
+
+// Start starts a capture with the configuration cfg. A running capture is
+// stopped first.
+func Start(cfg *brconf.Capture) (*Status, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, common.NewBasicError("Unable to create capture directory", err,
+			"dir", cfg.Dir)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	stop(get())
+	c := &capture{
+		cfg:     cfg,
+		started: time.Now(),
+		files:   make(map[common.IFIDType]*file),
+	}
+	c.timer = time.AfterFunc(cfg.MaxDuration.Duration, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		// Only stop the capture if it has not been replaced in the meantime.
+		if get() == c {
+			stop(c)
+		}
+	})
+	current.Store(c)
+	log.Info("Packet capture started", "dir", cfg.Dir, "interfaces", cfg.Interfaces,
+		"direction", cfg.Direction, "duration", cfg.MaxDuration)
+	return c.status(), nil
+}
+
+// Stop stops the running capture, if any, and returns its status.
+func Stop() *Status {
+	mu.Lock()
+	defer mu.Unlock()
+	c := get()
+	stop(c)
+	return c.status()
+}
+
+// Get returns the status of the running capture, if any.
+func Get() *Status {
+	return get().status()
+}
+
+// Running returns whether a capture is running. It is cheap enough to be
+// called for every packet.
+func Running() bool {
+	return get() != nil
+}
+
+// Packet captures the packet raw on interface ifid in direction dir, if it
+// matches the filter of the running capture. The overlay addresses src and
+// dst are used to reconstruct the overlay headers.
+func Packet(dir brconf.CaptureDirection, ifid common.IFIDType, info Info,
+	raw common.RawBytes, src, dst *overlay.OverlayAddr) {
+
+	c := get()
+	if c == nil || !c.cfg.Matches(dir, ifid, info.SrcIA, info.DstIA, info.L4) {
+		return
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := encode(buf, raw, src, dst); err != nil {
+		log.Debug("Unable to encode captured packet", "ifid", ifid, "err", err)
+		return
+	}
+	c.write(ifid, time.Now(), buf.Bytes())
+}
+
+func get() *capture {
+	return current.Load().(*capture)
+}
+
+// stop stops the capture c. It must be called with mu held.
+func stop(c *capture) {
+	if c == nil {
+		return
+	}
+	current.Store((*capture)(nil))
+	c.timer.Stop()
+	c.close()
+	log.Info("Packet capture stopped", "started", c.started)
+}
+
+type capture struct {
+	cfg     *brconf.Capture
+	started time.Time
+	timer   *time.Timer
+	// mu protects the fields below, and serializes writes to the files.
+	mu      sync.Mutex
+	stopped bool
+	files   map[common.IFIDType]*file
+}
+
+func (c *capture) write(ifid common.IFIDType, ts time.Time, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+	f, ok := c.files[ifid]
+	if !ok {
+		f = c.open(ifid)
+		c.files[ifid] = f
+	}
+	if f.w == nil {
+		return
+	}
+	if f.Bytes+recordHdrLen+uint64(len(b)) > c.cfg.MaxSize {
+		f.Full = true
+		f.close()
+		log.Info("Packet capture file full", "path", f.Path, "packets", f.Packets)
+		return
+	}
+	ci := gopacket.CaptureInfo{Timestamp: ts, CaptureLength: len(b), Length: len(b)}
+	if err := f.w.WritePacket(ci, b); err != nil {
+		f.fail(err)
+		return
+	}
+	f.Packets++
+	f.Bytes += recordHdrLen + uint64(len(b))
+}
+
+// open creates the file for interface ifid. Errors are recorded in the file
+// status, such that they are only logged once.
+func (c *capture) open(ifid common.IFIDType) *file {
+	intf := fmt.Sprintf("intf%d", ifid)
+	if ifid == 0 {
+		intf = "internal"
+	}
+	name := fmt.Sprintf("%s_%s_%s.pcap", brID, intf, c.started.UTC().Format(timeFmt))
+	f := &file{FileStatus: FileStatus{IFID: ifid, Path: filepath.Join(c.cfg.Dir, name)}}
+	fd, err := os.Create(f.Path)
+	if err != nil {
+		f.Error = err.Error()
+		log.Error("Unable to create packet capture file", "path", f.Path, "err", err)
+		return f
+	}
+	f.fd = fd
+	f.buf = bufio.NewWriter(fd)
+	w := pcapgo.NewWriter(f.buf)
+	if err := w.WriteFileHeader(snapLen, layers.LinkTypeRaw); err != nil {
+		f.fail(err)
+		return f
+	}
+	f.w = w
+	f.Bytes = fileHdrLen
+	return f
+}
+
+func (c *capture) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	for _, f := range c.files {
+		f.close()
+	}
+}
+
+func (c *capture) status() *Status {
+	if c == nil {
+		return &Status{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := &Status{Running: !c.stopped, Started: c.started, Config: c.cfg}
+	for _, f := range c.files {
+		s.Files = append(s.Files, f.FileStatus)
+	}
+	return s
+}
+
+type file struct {
+	FileStatus
+	fd  *os.File
+	buf *bufio.Writer
+	w   *pcapgo.Writer
+}
+
+func (f *file) fail(err error) {
+	f.Error = err.Error()
+	log.Error("Unable to write packet capture file", "path", f.Path, "err", err)
+	f.close()
+}
+
+func (f *file) close() {
+	if f.fd == nil {
+		return
+	}
+	if err := f.buf.Flush(); err != nil && f.Error == "" {
+		f.Error = err.Error()
+		log.Error("Unable to flush packet capture file", "path", f.Path, "err", err)
+	}
+	if err := f.fd.Close(); err != nil {
+		log.Error("Unable to close packet capture file", "path", f.Path, "err", err)
+	}
+	f.fd, f.buf, f.w = nil, nil, nil
+}
+
+// encode serializes the packet raw with the reconstructed overlay IP and UDP
+// headers to buf.
+func encode(buf gopacket.SerializeBuffer, raw common.RawBytes,
+	src, dst *overlay.OverlayAddr) error {
+
+	srcIP, srcPort := ipPort(src)
+	dstIP, dstPort := ipPort(dst)
+	udp := &layers.UDP{SrcPort: layers.UDPPort(srcPort), DstPort: layers.UDPPort(dstPort)}
+	var ip gopacket.NetworkLayer
+	if srcIP.To4() != nil || dstIP.To4() != nil || (srcIP == nil && dstIP == nil) {
+		ip = &layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Protocol: layers.IPProtocolUDP,
+			SrcIP:    orZero(srcIP.To4(), net.IPv4zero.To4()),
+			DstIP:    orZero(dstIP.To4(), net.IPv4zero.To4()),
+		}
+	} else {
+		ip = &layers.IPv6{
+			Version:    6,
+			HopLimit:   64,
+			NextHeader: layers.IPProtocolUDP,
+			SrcIP:      orZero(srcIP, net.IPv6zero),
+			DstIP:      orZero(dstIP, net.IPv6zero),
+		}
+	}
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		return err
+	}
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	return gopacket.SerializeLayers(buf, opts, ip.(gopacket.SerializableLayer), udp,
+		gopacket.Payload(raw))
+}
+
+func ipPort(a *overlay.OverlayAddr) (net.IP, uint16) {
+	if a == nil || a.L3() == nil {
+		return nil, 0
+	}
+	var port uint16
+	if a.L4() != nil {
+		port = a.L4().Port()
+	}
+	return a.L3().IP(), port
+}
+
+func orZero(ip, zero net.IP) net.IP {
+	if ip == nil {
+		return zero
+	}
+	return ip
+}
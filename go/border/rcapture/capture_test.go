@@ -0,0 +1,144 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rcapture
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/overlay"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestCapture(t *testing.T) {
+	Convey("Packets are captured to one file per interface", t, func() {
+		dir, err := ioutil.TempDir("", "rcapture")
+		xtest.FailOnErr(t, err)
+		defer os.RemoveAll(dir)
+		Init("br1-ff00_0_110-1")
+		cfg := newTestConfig(dir)
+		cfg.L4 = "UDP"
+		_, err = Start(cfg)
+		SoMsg("start err", err, ShouldBeNil)
+		SoMsg("running", Running(), ShouldBeTrue)
+		src := mustOverlayAddr(t, "192.0.2.1", 50000)
+		dst := mustOverlayAddr(t, "192.0.2.2", 50001)
+		udp := Info{L4: common.L4UDP}
+		Packet(brconf.CaptureDirIn, 1, udp, common.RawBytes{1, 2, 3}, src, dst)
+		Packet(brconf.CaptureDirIn, 1, Info{L4: common.L4SCMP}, common.RawBytes{4}, src, dst)
+		Packet(brconf.CaptureDirOut, 1, udp, common.RawBytes{5}, src, dst)
+		Packet(brconf.CaptureDirIn, 0, udp, common.RawBytes{6, 7}, src, dst)
+		s := Stop()
+		SoMsg("running", Running(), ShouldBeFalse)
+		SoMsg("status running", s.Running, ShouldBeFalse)
+		SoMsg("files", len(s.Files), ShouldEqual, 2)
+		for _, f := range s.Files {
+			pkts := readPackets(t, f.Path)
+			SoMsg("packets", uint64(len(pkts)), ShouldEqual, f.Packets)
+			SoMsg("error", f.Error, ShouldBeEmpty)
+			SoMsg("packet count", len(pkts), ShouldEqual, 1)
+			pkt := pkts[0]
+			ip, ok := pkt.NetworkLayer().(*layers.IPv4)
+			SoMsg("IPv4", ok, ShouldBeTrue)
+			SoMsg("src IP", ip.SrcIP.Equal(net.ParseIP("192.0.2.1")), ShouldBeTrue)
+			SoMsg("dst IP", ip.DstIP.Equal(net.ParseIP("192.0.2.2")), ShouldBeTrue)
+			udp, ok := pkt.TransportLayer().(*layers.UDP)
+			SoMsg("UDP", ok, ShouldBeTrue)
+			SoMsg("src port", udp.SrcPort, ShouldEqual, 50000)
+			SoMsg("dst port", udp.DstPort, ShouldEqual, 50001)
+			expected := []byte{1, 2, 3}
+			if f.IFID == 0 {
+				expected = []byte{6, 7}
+			}
+			SoMsg("payload", []byte(udp.Payload), ShouldResemble, expected)
+		}
+		Packet(brconf.CaptureDirIn, 1, udp, common.RawBytes{1}, src, dst)
+		SoMsg("stopped status", Get().Running, ShouldBeFalse)
+	})
+	Convey("Files are bounded in size", t, func() {
+		dir, err := ioutil.TempDir("", "rcapture")
+		xtest.FailOnErr(t, err)
+		defer os.RemoveAll(dir)
+		cfg := newTestConfig(dir)
+		cfg.MaxSize = 2000
+		_, err = Start(cfg)
+		SoMsg("start err", err, ShouldBeNil)
+		for i := 0; i < 10; i++ {
+			Packet(brconf.CaptureDirIn, 1, Info{}, make(common.RawBytes, 500), nil, nil)
+		}
+		s := Stop()
+		SoMsg("files", len(s.Files), ShouldEqual, 1)
+		f := s.Files[0]
+		SoMsg("full", f.Full, ShouldBeTrue)
+		SoMsg("packets", f.Packets, ShouldEqual, 3)
+		info, err := os.Stat(f.Path)
+		xtest.FailOnErr(t, err)
+		SoMsg("size", info.Size(), ShouldEqual, f.Bytes)
+		SoMsg("read packets", len(readPackets(t, f.Path)), ShouldEqual, 3)
+	})
+	Convey("A capture stops after the maximum duration", t, func() {
+		dir, err := ioutil.TempDir("", "rcapture")
+		xtest.FailOnErr(t, err)
+		defer os.RemoveAll(dir)
+		cfg := newTestConfig(dir)
+		cfg.MaxDuration.Duration = 10 * time.Millisecond
+		_, err = Start(cfg)
+		SoMsg("start err", err, ShouldBeNil)
+		time.Sleep(100 * time.Millisecond)
+		SoMsg("running", Running(), ShouldBeFalse)
+	})
+}
+
+func newTestConfig(dir string) *brconf.Capture {
+	cfg := &brconf.Capture{Dir: dir, Interfaces: []common.IFIDType{0, 1}}
+	cfg.InitDefaults()
+	return cfg
+}
+
+func mustOverlayAddr(t *testing.T, ip string, port uint16) *overlay.OverlayAddr {
+	t.Helper()
+	a, err := overlay.NewOverlayAddr(addr.HostFromIPStr(ip), addr.NewL4UDPInfo(port))
+	xtest.FailOnErr(t, err)
+	return a
+}
+
+func readPackets(t *testing.T, path string) []gopacket.Packet {
+	t.Helper()
+	f, err := os.Open(path)
+	xtest.FailOnErr(t, err)
+	defer f.Close()
+	r, err := pcapgo.NewReader(f)
+	xtest.FailOnErr(t, err)
+	var pkts []gopacket.Packet
+	for {
+		data, _, err := r.ReadPacketData()
+		if err != nil {
+			break
+		}
+		pkts = append(pkts, gopacket.NewPacket(data, layers.LayerTypeIPv4, gopacket.Default))
+	}
+	return pkts
+}
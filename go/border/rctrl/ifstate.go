@@ -19,10 +19,12 @@ import (
 
 	"github.com/scionproto/scion/go/border/rctx"
 	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/ctrl"
 	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
 	"github.com/scionproto/scion/go/lib/infra"
 	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/proto"
 )
 
 const (
@@ -47,19 +49,39 @@ func ifStateUpdate() {
 
 // genIFStateReq generates an Interface State request packet to the local beacon service.
 func genIFStateReq() {
-	cpld, err := ctrl.NewPathMgmtPld(&path_mgmt.IFStateReq{}, nil, nil)
+	sendToBS(&path_mgmt.IFStateReq{}, "IFStateReq")
+}
+
+// SendLinkDown notifies the local beacon service that the link of the
+// interface ifid is down, either detected by BFD or administratively shut
+// down, such that it revokes the interface.
+func SendLinkDown(ifid common.IFIDType) {
+	if snetConn == nil {
+		// The control plane is not set up yet.
+		return
+	}
+	infos := &path_mgmt.IFStateInfos{
+		Infos: []*path_mgmt.IFStateInfo{{IfID: ifid, Active: false}},
+	}
+	sendToBS(infos, "IFStateInfos")
+}
+
+// sendToBS sends the path management message msg to all instances of the local
+// beacon service. The name of the message is used for logging.
+func sendToBS(msg proto.Cerealizable, name string) {
+	cpld, err := ctrl.NewPathMgmtPld(msg, nil, nil)
 	if err != nil {
-		logger.Error("Generating IFStateReq Ctrl payload", "err", err)
+		logger.Error("Generating "+name+" Ctrl payload", "err", err)
 		return
 	}
 	scpld, err := cpld.SignedPld(infra.NullSigner)
 	if err != nil {
-		logger.Error("Generating IFStateReq signed Ctrl payload", "err", err)
+		logger.Error("Generating "+name+" signed Ctrl payload", "err", err)
 		return
 	}
 	pld, err := scpld.PackPld()
 	if err != nil {
-		logger.Error("Writing IFStateReq signed Ctrl payload", "err", err)
+		logger.Error("Writing "+name+" signed Ctrl payload", "err", err)
 		return
 	}
 	dst := &snet.Addr{
@@ -74,9 +96,9 @@ func genIFStateReq() {
 	for _, addr := range bsAddrs {
 		dst.NextHop = addr
 		if _, err := snetConn.WriteToSCION(pld, dst); err != nil {
-			logger.Error("Writing IFStateReq", "dst", dst, "err", err)
+			logger.Error("Writing "+name, "dst", dst, "err", err)
 			continue
 		}
-		logger.Debug("Sent IFStateReq", "dst", dst, "overlayDst", addr)
+		logger.Debug("Sent "+name, "dst", dst, "overlayDst", addr)
 	}
 }
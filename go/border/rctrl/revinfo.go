@@ -63,7 +63,7 @@ func fwdRevInfo(sRevInfo *path_mgmt.SignedRevInfo, dstHost addr.HostSVC) {
 		IA:   ia,
 		Host: &addr.AppAddr{L3: dstHost, L4: addr.NewL4UDPInfo(0)},
 	}
-	dst.NextHop, err = ctx.ResolveSVCAny(dstHost)
+	dst.NextHop, err = ctx.ResolveSVCAny(dstHost, ctx.Conf.IA, nil)
 	if err != nil {
 		logger.Error("Resolving SVC anycast", "err", err, "addr", dst)
 		return
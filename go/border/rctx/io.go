@@ -38,6 +38,9 @@ type SockFunc func(sock *Sock, stop, stopped chan struct{})
 type Sock struct {
 	// Ring is a ring-buffer that's written to by writers, and read from by readers.
 	Ring *ringbuf.Ring
+	// PrioRing is an optional ring-buffer for prioritized packets. The Writer
+	// drains it before reading from Ring.
+	PrioRing *ringbuf.Ring
 	// Conn is the underlying connection that this Sock represents.
 	Conn conn.Conn
 	// Dir is the direction that a packet is being read from/written to.
@@ -78,6 +81,20 @@ func NewSock(ring *ringbuf.Ring, conn conn.Conn, dir rcmn.Dir, ifid common.IFIDT
 	return s
 }
 
+// WriteOut hands entries to the Writer. If prio is set and the Sock has a
+// PrioRing, the entries are written to PrioRing, and a nil entry is written to
+// Ring to wake up the Writer in case it is waiting for entries from Ring. If
+// Ring is full, the Writer is busy and drains PrioRing before reading from Ring
+// again, so the wake-up entry is not needed.
+func (s *Sock) WriteOut(entries ringbuf.EntryList, prio bool) {
+	if !prio || s.PrioRing == nil {
+		s.Ring.Write(entries, true)
+		return
+	}
+	s.PrioRing.Write(entries, true)
+	s.Ring.Write(ringbuf.EntryList{nil}, false)
+}
+
 // Start starts the reader/writer goroutines (if any). Does nothing if they
 // have been started already.
 func (s *Sock) Start() {
@@ -121,6 +138,9 @@ func (s *Sock) Stop() {
 		// Close the ringbuf which in turn will make the Writer to close after it has processed
 		// all packets in the ringbuf.
 		// This is the only way to signal the Writer to finish.
+		if s.PrioRing != nil {
+			s.PrioRing.Close()
+		}
 		s.Ring.Close()
 		if s.Writer != nil {
 			<-s.writerStopped
@@ -132,6 +152,9 @@ func (s *Sock) Stop() {
 		s.running = false
 		log.Info("Sock routines stopped", "addr", s.Conn.LocalAddr())
 	} else if !s.started {
+		if s.PrioRing != nil {
+			s.PrioRing.Close()
+		}
 		s.Ring.Close()
 		if err := s.Conn.Close(); err != nil {
 			log.Error("Error stopping socket", "err", err)
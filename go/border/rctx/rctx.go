@@ -18,10 +18,13 @@
 package rctx
 
 import (
-	"math/rand"
+	"math"
 	"sync/atomic"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/metrics"
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/overlay"
@@ -58,11 +61,13 @@ func New(conf *brconf.BRConf) *Ctx {
 	return ctx
 }
 
-func (ctx *Ctx) ResolveSVC(svc addr.HostSVC) ([]*overlay.OverlayAddr, error) {
+func (ctx *Ctx) ResolveSVC(svc addr.HostSVC, srcIA addr.IA,
+	src addr.HostAddr) ([]*overlay.OverlayAddr, error) {
+
 	if svc.IsMulticast() {
 		return ctx.ResolveSVCMulti(svc)
 	}
-	resolvedAddr, err := ctx.ResolveSVCAny(svc)
+	resolvedAddr, err := ctx.ResolveSVCAny(svc, srcIA, src)
 	if err != nil {
 		return nil, err
 	}
@@ -70,19 +75,91 @@ func (ctx *Ctx) ResolveSVC(svc addr.HostSVC) ([]*overlay.OverlayAddr, error) {
 }
 
 // ResolveSVCAny resolves an anycast SVC address (i.e. a single instance of a local
-// infrastructure service).
-func (ctx *Ctx) ResolveSVCAny(svc addr.HostSVC) (*overlay.OverlayAddr, error) {
+// infrastructure service) for packets from source src in srcIA. src can be nil.
+// The instance is selected by rendezvous hashing of the source address, so
+// that the packets of one source reach the same instance as long as the set of
+// healthy instances does not change. Instances marked unhealthy in the topology
+// are skipped, and each instance receives a share of the sources proportional
+// to its weight. The router does not check the health of the instances itself;
+// the marks are set manually, and take effect when the topology is reloaded or
+// fetched from the discovery service.
+func (ctx *Ctx) ResolveSVCAny(svc addr.HostSVC, srcIA addr.IA,
+	src addr.HostAddr) (*overlay.OverlayAddr, error) {
+
 	names, elemMap, err := ctx.GetSVCNamesMap(svc)
 	if err != nil {
 		return nil, err
 	}
-	// XXX(kormat): just pick one randomly. TCP will remove the need to have
-	// consistent selection for a given source.
-	name := names[rand.Intn(len(names))]
+	name := ctx.selectSVCInstance(names, svcKey(srcIA, src))
+	if name == "" {
+		return nil, common.NewBasicError("No healthy instances found for SVC address",
+			scmp.NewError(scmp.C_Routing, scmp.T_R_UnreachHost, nil, nil), "svc", svc)
+	}
+	metrics.SVCResolutions.With(
+		prometheus.Labels{"svc": svc.BaseString(), "instance": name}).Inc()
 	elem := elemMap[name]
 	return elem.OverlayAddr(ctx.Conf.Topo.Overlay), nil
 }
 
+// selectSVCInstance returns the healthy instance with the highest weighted
+// rendezvous hash score for key, or the empty string if there is none.
+func (ctx *Ctx) selectSVCInstance(names []string, key common.RawBytes) string {
+	var best string
+	var bestScore float64
+	for _, name := range names {
+		inst := ctx.Conf.Topo.SvcInst(name)
+		if inst.Unhealthy {
+			continue
+		}
+		// Map the hash to a uniformly distributed value in (0,1). The score
+		// -w/ln(u) selects each instance with a probability proportional to
+		// its weight w.
+		h := mix64(fnv64a(fnv64a(fnvOffset64, key), []byte(name)))
+		u := (float64(h>>11) + 0.5) / (1 << 53)
+		score := -float64(inst.Weight) / math.Log(u)
+		if best == "" || score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best
+}
+
+// svcKey returns the key used to select the SVC instance for packets from
+// source src in srcIA.
+func svcKey(srcIA addr.IA, src addr.HostAddr) common.RawBytes {
+	key := make(common.RawBytes, addr.IABytes)
+	srcIA.Write(key)
+	if src != nil {
+		key = append(key, src.Pack()...)
+	}
+	return key
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// fnv64a continues the FNV-1a hash h over b.
+func fnv64a(h uint64, b []byte) uint64 {
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= fnvPrime64
+	}
+	return h
+}
+
+// mix64 is the finalizer of MurmurHash3. FNV-1a alone does not spread
+// differences in the last bytes of the input to the high bits of the hash.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
 // ResolveSVCMulti resolves a multicast SVC address (i.e. one packet per machine hosting
 // instances for a local infrastructure service).
 func (ctx *Ctx) ResolveSVCMulti(svc addr.HostSVC) ([]*overlay.OverlayAddr, error) {
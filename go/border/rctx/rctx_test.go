@@ -0,0 +1,90 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rctx
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/topology"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestSelectSVCInstance(t *testing.T) {
+	names := []string{"bs-1", "bs-2", "bs-3"}
+	newCtx := func(insts map[string]topology.SvcInst) *Ctx {
+		topo := topology.NewTopo()
+		topo.SvcInsts = insts
+		return New(&brconf.BRConf{Topo: topo})
+	}
+	keys := make([]common.RawBytes, 3000)
+	for i := range keys {
+		src := addr.HostFromIP(net.IP{10, 0, byte(i >> 8), byte(i)})
+		keys[i] = svcKey(xtest.MustParseIA("1-ff00:0:110"), src)
+	}
+	Convey("Sources are spread over all instances", t, func() {
+		ctx := newCtx(nil)
+		counts := make(map[string]int)
+		for _, key := range keys {
+			counts[ctx.selectSVCInstance(names, key)]++
+		}
+		for _, name := range names {
+			SoMsg(name, counts[name], ShouldBeBetween, 800, 1200)
+		}
+	})
+	Convey("The same source always selects the same instance", t, func() {
+		ctx := newCtx(nil)
+		for _, key := range keys[:100] {
+			SoMsg("selected", ctx.selectSVCInstance(names, key), ShouldEqual,
+				ctx.selectSVCInstance(names, key))
+		}
+	})
+	Convey("Unhealthy instances are skipped", t, func() {
+		ctx := newCtx(nil)
+		unhealthy := newCtx(map[string]topology.SvcInst{"bs-2": {Unhealthy: true}})
+		for i, key := range keys {
+			before := ctx.selectSVCInstance(names, key)
+			after := unhealthy.selectSVCInstance(names, key)
+			SoMsg(fmt.Sprintf("skipped %d", i), after, ShouldNotEqual, "bs-2")
+			if before != "bs-2" {
+				SoMsg(fmt.Sprintf("unchanged %d", i), after, ShouldEqual, before)
+			}
+		}
+	})
+	Convey("No instance is selected if all are unhealthy", t, func() {
+		ctx := newCtx(map[string]topology.SvcInst{
+			"bs-1": {Unhealthy: true},
+			"bs-2": {Unhealthy: true},
+			"bs-3": {Unhealthy: true},
+		})
+		SoMsg("selected", ctx.selectSVCInstance(names, keys[0]), ShouldBeEmpty)
+	})
+	Convey("Instances receive a share of the sources proportional to their weight", t, func() {
+		ctx := newCtx(map[string]topology.SvcInst{"bs-1": {Weight: 3}})
+		counts := make(map[string]int)
+		for _, key := range keys {
+			counts[ctx.selectSVCInstance(names, key)]++
+		}
+		SoMsg("bs-1", counts["bs-1"], ShouldBeBetween, 1600, 2000)
+		SoMsg("bs-2", counts["bs-2"], ShouldBeBetween, 450, 750)
+		SoMsg("bs-3", counts["bs-3"], ShouldBeBetween, 450, 750)
+	})
+}
@@ -20,14 +20,19 @@ package main
 import (
 	"sync"
 
+	"github.com/BurntSushi/toml"
+
+	"github.com/scionproto/scion/go/border/bfd"
 	"github.com/scionproto/scion/go/border/brconf"
 	"github.com/scionproto/scion/go/border/metrics"
+	"github.com/scionproto/scion/go/border/policer"
 	"github.com/scionproto/scion/go/border/rcmn"
 	"github.com/scionproto/scion/go/border/rctrl"
 	"github.com/scionproto/scion/go/border/rctx"
 	"github.com/scionproto/scion/go/border/rpkt"
 	"github.com/scionproto/scion/go/lib/assert"
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/env"
 	"github.com/scionproto/scion/go/lib/fatal"
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/ringbuf"
@@ -43,10 +48,15 @@ type Router struct {
 	confDir string
 	// freePkts is a ring-buffer of unused packets.
 	freePkts *ringbuf.Ring
+	// workers process the packets read from the sockets, if packet processing
+	// is sharded.
+	workers []*worker
 	// sRevInfoQ is a channel for handling SignedRevInfo payloads.
 	sRevInfoQ chan rpkt.RawSRevCallbackArgs
 	// pktErrorQ is a channel for handling packet errors
 	pktErrorQ chan pktErrorArgs
+	// scmpSigner authenticates SCMP errors with signed hash trees.
+	scmpSigner *scmpHashTreeSigner
 	// setCtxMtx serializes modifications to the router context. Topology updates
 	// can either be caused by a sighup reload, receiving an updated dynamic or
 	// static topology from the discovery service, or from dropping an expired
@@ -70,10 +80,15 @@ func (r *Router) Start() {
 		defer log.LogPanicAndExit()
 		r.PacketError()
 	}()
+	go func() {
+		defer log.LogPanicAndExit()
+		r.scmpSigner.Run()
+	}()
 	go func() {
 		defer log.LogPanicAndExit()
 		rctrl.Control(r.sRevInfoQ)
 	}()
+	r.startWorkers()
 	if err := r.startDiscovery(); err != nil {
 		fatal.Fatal(common.NewBasicError("Unable to start discovery", err))
 	}
@@ -89,18 +104,42 @@ func (r *Router) ReloadConfig() error {
 	if err := r.setupCtxFromConfig(config); err != nil {
 		return common.NewBasicError("Unable to set up new context", err)
 	}
+	if err := r.reloadPolicers(); err != nil {
+		return common.NewBasicError("Unable to reload policers", err)
+	}
+	return nil
+}
+
+// reloadPolicers rebuilds the policers from the config file. The other
+// settings in the config file only take effect on restart.
+func (r *Router) reloadPolicers() error {
+	var newCfg brconf.Config
+	if _, err := toml.DecodeFile(env.ConfigFile(), &newCfg); err != nil {
+		return err
+	}
+	newCfg.BR.InitDefaults()
+	if err := newCfg.BR.Validate(); err != nil {
+		return err
+	}
+	policer.Init(newCfg.BR.Policers)
+	log.Info("Policers reloaded", "policers", len(newCfg.BR.Policers))
 	return nil
 }
 
 func (r *Router) handleSock(s *rctx.Sock, stop, stopped chan struct{}) {
 	defer log.LogPanicAndExit()
 	defer close(stopped)
-	pkts := make(ringbuf.EntryList, processBufCnt)
 	log.Debug("handleSock starting", "sock", *s)
+	r.processRing(s.Ring)
+	log.Debug("handleSock stopping", "sock", *s)
+}
+
+// processRing processes the packets read from ring until it is closed.
+func (r *Router) processRing(ring *ringbuf.Ring) {
+	pkts := make(ringbuf.EntryList, processBufCnt)
 	for {
-		n, _ := s.Ring.Read(pkts, true)
+		n, _ := ring.Read(pkts, true)
 		if n < 0 {
-			log.Debug("handleSock stopping", "sock", *s)
 			return
 		}
 		for i := 0; i < n; i++ {
@@ -135,6 +174,18 @@ func (r *Router) processPacket(rp *rpkt.RtrPkt) {
 		r.handlePktError(rp, err, "Error parsing packet")
 		return
 	}
+	rp.CaptureIn()
+	if pld, ok, err := rp.BFDPld(); ok {
+		// BFD packets are terminated by the router.
+		if err != nil {
+			rp.Debug("Dropping invalid BFD packet", "err", err)
+			return
+		}
+		if err := bfd.Process(rp.Ingress.IfID, pld); err != nil {
+			rp.Debug("Error processing BFD packet", "err", err)
+		}
+		return
+	}
 	// Validation looks for errors in the packet that didn't break basic
 	// parsing.
 	valid, err := rp.Validate()
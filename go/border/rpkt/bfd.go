@@ -0,0 +1,115 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file handles the packets used for bidirectional forwarding detection
+// (BFD) with the routers of neighboring ASes.
+
+package rpkt
+
+import (
+	"github.com/scionproto/scion/go/border/rcmn"
+	"github.com/scionproto/scion/go/border/rctx"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/spkt"
+)
+
+// BFDPld returns the BFD control packet contained in the packet, if it is a
+// BFD packet. BFD packets do not contain a path, and are not forwarded. An
+// error is returned if the packet is not sent from the router of the
+// neighboring AS to the local interface it was received on.
+func (rp *RtrPkt) BFDPld() (common.RawBytes, bool, error) {
+	if rp.CmnHdr.NextHdr != common.L4BFD {
+		return nil, false, nil
+	}
+	if err := rp.validateBFDAddrs(); err != nil {
+		return nil, true, err
+	}
+	return rp.Raw[rp.CmnHdr.HdrLenBytes():], true, nil
+}
+
+// validateBFDAddrs checks that the packet contains no path, and that its
+// addresses are the endpoints of the external interface it was received on.
+func (rp *RtrPkt) validateBFDAddrs() error {
+	if rp.DirFrom != rcmn.DirExternal {
+		return common.NewBasicError("BFD packet not from external interface", nil)
+	}
+	if rp.idxs.path != rp.CmnHdr.HdrLenBytes() {
+		return common.NewBasicError("BFD packet contains path", nil)
+	}
+	intf, ok := rp.Ctx.Conf.Net.IFs[rp.Ingress.IfID]
+	if !ok {
+		return common.NewBasicError("Unknown interface", nil, "ifid", rp.Ingress.IfID)
+	}
+	dstIA, err := rp.DstIA()
+	if err != nil {
+		return err
+	}
+	srcIA, err := rp.SrcIA()
+	if err != nil {
+		return err
+	}
+	dstHost, err := rp.DstHost()
+	if err != nil {
+		return err
+	}
+	srcHost, err := rp.SrcHost()
+	if err != nil {
+		return err
+	}
+	localHost := intf.IFAddr.PublicOverlay(rp.Ctx.Conf.Topo.Overlay).L3()
+	if !dstIA.Equal(rp.Ctx.Conf.IA) || !dstHost.Equal(localHost) {
+		return common.NewBasicError("BFD packet not destined to local interface", nil,
+			"dstIA", dstIA, "dstHost", dstHost)
+	}
+	if !srcIA.Equal(intf.RemoteIA) || !srcHost.Equal(intf.RemoteAddr.L3()) {
+		return common.NewBasicError("BFD packet not sent by remote interface", nil,
+			"srcIA", srcIA, "srcHost", srcHost)
+	}
+	return nil
+}
+
+// NewBFDPkt creates a packet containing the BFD control packet pld, which is
+// sent to the router of the neighboring AS on the external interface ifid.
+func NewBFDPkt(ctx *rctx.Ctx, ifid common.IFIDType, pld common.RawBytes) (*RtrPkt, error) {
+	intf, ok := ctx.Conf.Net.IFs[ifid]
+	if !ok {
+		return nil, common.NewBasicError("Unknown interface", nil, "ifid", ifid)
+	}
+	sock, ok := ctx.ExtSockOut[ifid]
+	if !ok {
+		return nil, common.NewBasicError("No socket for interface", nil, "ifid", ifid)
+	}
+	sp := &spkt.ScnPkt{
+		DstIA:   intf.RemoteIA,
+		SrcIA:   ctx.Conf.IA,
+		DstHost: intf.RemoteAddr.L3(),
+		SrcHost: intf.IFAddr.PublicOverlay(ctx.Conf.Topo.Overlay).L3(),
+	}
+	rp, err := RtrPktFromScnPkt(sp, ctx)
+	if err != nil {
+		return nil, err
+	}
+	// RtrPktFromScnPkt trims the buffer to the end of the address header, as
+	// there is neither a path nor an L4 header.
+	hdrLen := len(rp.Raw)
+	rp.Raw = rp.Raw[:hdrLen+len(pld)]
+	copy(rp.Raw[hdrLen:], pld)
+	rp.L4Type = common.L4BFD
+	rp.idxs.pld = hdrLen
+	rp.CmnHdr.NextHdr = common.L4BFD
+	rp.CmnHdr.TotalLen = uint16(len(rp.Raw))
+	rp.CmnHdr.Write(rp.Raw)
+	rp.Egress = append(rp.Egress, EgressPair{S: sock})
+	return rp, nil
+}
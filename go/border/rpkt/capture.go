@@ -0,0 +1,76 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file handles the capture of received and forwarded packets.
+
+package rpkt
+
+import (
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/rcapture"
+	"github.com/scionproto/scion/go/border/rctx"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/overlay"
+)
+
+// CaptureIn captures the received packet, if a packet capture is running.
+func (rp *RtrPkt) CaptureIn() {
+	if !rcapture.Running() {
+		return
+	}
+	rp.capture(brconf.CaptureDirIn, rp.Ingress.IfID, rp.Ingress.Src, rp.Ingress.Dst)
+}
+
+// captureOut captures the packet as it is forwarded on socket s to dst, if a
+// packet capture is running.
+func (rp *RtrPkt) captureOut(s *rctx.Sock, dst *overlay.OverlayAddr) {
+	if !rcapture.Running() {
+		return
+	}
+	if dst == nil {
+		// Connected socket.
+		dst = s.Conn.RemoteAddr()
+	}
+	rp.capture(brconf.CaptureDirOut, s.Ifid, s.Conn.LocalAddr(), dst)
+}
+
+func (rp *RtrPkt) capture(dir brconf.CaptureDirection, ifid common.IFIDType,
+	src, dst *overlay.OverlayAddr) {
+
+	// Errors are ignored, the filter then only matches wildcard ISD-ASes.
+	srcIA, _ := rp.SrcIA()
+	dstIA, _ := rp.DstIA()
+	info := rcapture.Info{SrcIA: srcIA, DstIA: dstIA, L4: rp.l4Type()}
+	rcapture.Packet(dir, ifid, info, rp.Raw, src, dst)
+}
+
+// l4Type returns the L4 protocol of the packet, skipping all extension
+// headers. Unlike findL4, it does not modify the parsing state of the packet,
+// such that it is safe to use on packets in any stage of processing.
+func (rp *RtrPkt) l4Type() common.L4ProtocolType {
+	nextHdr := rp.CmnHdr.NextHdr
+	offset := rp.CmnHdr.HdrLenBytes()
+	for nextHdr == common.HopByHopClass || nextHdr == common.End2EndClass {
+		if offset+common.LineLen > len(rp.Raw) {
+			return common.L4None
+		}
+		hdrLen := int(rp.Raw[offset+1]) * common.LineLen
+		if hdrLen == 0 {
+			return common.L4None
+		}
+		nextHdr = common.L4ProtocolType(rp.Raw[offset])
+		offset += hdrLen
+	}
+	return nextHdr
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements the router's handling of the hop trace hop-by-hop
+// extension.
+
+package rpkt
+
+import (
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/layers"
+	"github.com/scionproto/scion/go/lib/log"
+)
+
+var _ rExtension = (*rHopTrace)(nil)
+
+// rHopTrace is the router's representation of the hop trace extension. The
+// router fills in an entry of the extension every time it forwards the
+// packet.
+type rHopTrace struct {
+	rp  *RtrPkt
+	raw common.RawBytes
+	log.Logger
+}
+
+func rHopTraceFromRaw(rp *RtrPkt, start, end int) (*rHopTrace, error) {
+	t := &rHopTrace{rp: rp, raw: rp.Raw[start:end]}
+	if _, err := layers.HopTraceSlots(t.raw); err != nil {
+		return nil, err
+	}
+	t.Logger = rp.Logger.New("ext", "HopTrace")
+	return t, nil
+}
+
+func (t *rHopTrace) RegisterHooks(h *hooks) error {
+	return nil
+}
+
+// record fills in the next free entry of the extension, for the packet being
+// forwarded on interface egress.
+func (t *rHopTrace) record(egress common.IFIDType) {
+	e := &layers.HopTraceEntry{
+		IA:        t.rp.Ctx.Conf.IA,
+		Ingress:   t.rp.Ingress.IfID,
+		Egress:    egress,
+		Timestamp: t.rp.TimeIn,
+		QueueTime: time.Since(t.rp.TimeIn),
+	}
+	ok, err := layers.HopTraceAppend(t.raw, e)
+	switch {
+	case err != nil:
+		t.Error("Unable to record hop trace entry", "err", err)
+	case !ok:
+		t.Debug("No free hop trace entry")
+	}
+}
+
+func (t *rHopTrace) Class() common.L4ProtocolType {
+	return common.HopByHopClass
+}
+
+func (t *rHopTrace) Type() common.ExtnType {
+	return common.ExtnHopTraceType
+}
+
+func (t *rHopTrace) Len() int {
+	return len(t.raw)
+}
+
+func (t *rHopTrace) String() string {
+	return "HopTrace"
+}
+
+func (t *rHopTrace) GetExtn() (common.Extension, error) {
+	return layers.ExtnHopTraceFromRaw(t.raw)
+}
+
+// recordHopTrace fills in an entry of the hop trace extension, if the packet
+// carries one, for the packet being forwarded on interface egress.
+func (rp *RtrPkt) recordHopTrace(egress common.IFIDType) {
+	for _, e := range rp.HBHExt {
+		if t, ok := e.(*rHopTrace); ok {
+			t.record(egress)
+			return
+		}
+	}
+}
@@ -133,36 +133,27 @@ func (s *rSPSExtn) RegisterHooks(h *hooks) error {
 }
 
 func (s *rSPSExtn) Validate() (HookResult, error) {
-	expectedLen := 0
-	switch {
-	case spse.AesCMac == s.SecMode:
-		expectedLen = spse.AesCMacTotalLength
-	case spse.HmacSha256 == s.SecMode:
-		expectedLen = spse.HmacSha256TotalLength
-	case spse.Ed25519 == s.SecMode:
-		expectedLen = spse.ED25519TotalLength
-	case spse.GcmAes128 == s.SecMode:
-		expectedLen = spse.GcmAes128TotalLength
-	default:
-		return HookError, common.NewBasicError("SecMode not supported", nil, "mode", s.SecMode)
+	l, h, err := s.limitsMetadata()
+	if err != nil {
+		return HookError, err
 	}
-
-	if len(s.raw) != expectedLen {
+	// The metadata consists of the timestamp, optionally followed by mode specific data.
+	if metaLen := h - l; metaLen < spse.TimestampLength ||
+		(metaLen-spse.TimestampLength)%common.LineLen != 0 {
 		return HookError, common.NewBasicError("Invalid header length", nil,
-			"expected", expectedLen, "actual", len(s.raw))
+			"mode", s.SecMode, "actual", len(s.raw))
 	}
-
 	return HookContinue, nil
 }
 
 // GetExtn returns the spse.Extn representation,
 // which does not have direct access to the underlying buffer.
 func (s *rSPSExtn) GetExtn() (common.Extension, error) {
-	extn, err := spse.NewExtn(s.SecMode)
+	meta, err := s.Metadata()
 	if err != nil {
 		return nil, err
 	}
-	meta, err := s.Metadata()
+	extn, err := spse.NewExtnWithMetaLen(s.SecMode, len(meta))
 	if err != nil {
 		return nil, err
 	}
@@ -184,24 +175,14 @@ func (s *rSPSExtn) String() string {
 	return extn.String()
 }
 
-// limitsMetadata returns the limits of the Metadata in the raw buffer
+// limitsMetadata returns the limits of the Metadata in the raw buffer. The
+// length of the metadata is inferred from the length of the extension.
 func (s *rSPSExtn) limitsMetadata() (int, int, error) {
-	size := 0
-	switch s.SecMode {
-	case spse.AesCMac:
-		size = spse.AesCMacMetaLength
-	case spse.HmacSha256:
-		size = spse.HmacSha256MetaLength
-	case spse.Ed25519:
-		size = spse.ED25519MetaLength
-	case spse.GcmAes128:
-		size = spse.GcmAes128MetaLength
-	default:
-		return 0, 0, common.NewBasicError("Invalid SecMode", nil,
-			"mode", s.SecMode, "func", "limitsMetadata")
+	_, l, err := s.limitsAuthenticator()
+	if err != nil {
+		return 0, 0, err
 	}
-	return spse.SecModeLength, spse.SecModeLength + size, nil
-
+	return spse.SecModeLength, l, nil
 }
 
 // limitsAuthenticator returns the limits of the Authenticator in the raw buffer
@@ -220,6 +201,9 @@ func (s *rSPSExtn) limitsAuthenticator() (int, int, error) {
 		return 0, 0, common.NewBasicError("Invalid SecMode", nil,
 			"mode", s.SecMode, "func", "limitsAuthenticator")
 	}
-	_, l, _ := s.limitsMetadata()
-	return l, l + size, nil
+	if len(s.raw) < spse.SecModeLength+size {
+		return 0, 0, common.NewBasicError("Invalid header length", nil,
+			"mode", s.SecMode, "actual", len(s.raw))
+	}
+	return len(s.raw) - size, len(s.raw), nil
 }
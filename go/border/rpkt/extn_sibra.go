@@ -0,0 +1,200 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements the router's handling of the SIBRA hop-by-hop
+// extension.
+
+package rpkt
+
+import (
+	"hash"
+	"time"
+
+	"github.com/scionproto/scion/go/border/policer"
+	"github.com/scionproto/scion/go/border/rcmn"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/layers"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/scmp"
+)
+
+var _ rExtension = (*rSIBRA)(nil)
+
+// rSIBRA is the router's representation of the SIBRA extension. Packets
+// carrying it are only forwarded if the opaque field of the local AS is valid
+// for the interfaces the packet traverses, and if the reservation is neither
+// expired nor exceeded. Such packets are prioritized over best-effort traffic
+// on egress.
+type rSIBRA struct {
+	rp  *RtrPkt
+	raw common.RawBytes
+	// pos is the index of the extension, used in SCMP errors.
+	pos  int
+	extn *layers.ExtnSIBRA
+	// advance is set if the packet leaves the local AS, in which case CurrHop
+	// is moved to the opaque field of the next AS when the packet is routed.
+	advance bool
+	log.Logger
+}
+
+func rSIBRAFromRaw(rp *RtrPkt, start, end, pos int) (*rSIBRA, error) {
+	s := &rSIBRA{rp: rp, raw: rp.Raw[start:end], pos: pos}
+	var err error
+	if s.extn, err = layers.ExtnSIBRAFromRaw(s.raw); err != nil {
+		return nil, common.NewBasicError("Unable to parse SIBRA extension",
+			scmp.NewError(scmp.C_Ext, scmp.T_E_BadHopByHop, s.info(), err))
+	}
+	if s.extn.Version != layers.SIBRAVersion {
+		return nil, common.NewBasicError("Unsupported SIBRA version",
+			scmp.NewError(scmp.C_Sibra, scmp.T_S_BadVersion, s.info(), nil),
+			"expected", layers.SIBRAVersion, "actual", s.extn.Version)
+	}
+	if s.extn.Setup && !s.extn.Request {
+		return nil, common.NewBasicError("SIBRA setup packet without request",
+			scmp.NewError(scmp.C_Sibra, scmp.T_S_SetupNoReq, s.info(), nil))
+	}
+	if s.extn.CurrSOF() == nil {
+		return nil, common.NewBasicError("SIBRA current hop out of range",
+			scmp.NewError(scmp.C_Sibra, scmp.T_S_BadSOF, s.info(), nil),
+			"currHop", s.extn.CurrHop, "numHops", len(s.extn.SOFs))
+	}
+	s.Logger = rp.Logger.New("ext", "SIBRA")
+	return s, nil
+}
+
+func (s *rSIBRA) RegisterHooks(h *hooks) error {
+	h.Validate = append(h.Validate, s.Validate)
+	return nil
+}
+
+// Validate checks the reservation and the opaque field of the local AS, and
+// polices the packet if it enters the local AS, or leaves its source AS.
+func (s *rSIBRA) Validate() (HookResult, error) {
+	rp := s.rp
+	if exp := s.extn.Info.ExpTime; time.Now().After(exp) {
+		return HookError, common.NewBasicError("SIBRA reservation expired",
+			scmp.NewError(scmp.C_Sibra, scmp.T_S_ExpiredRes, s.info(), nil), "expiry", exp)
+	}
+	srcIA, err := rp.SrcIA()
+	if err != nil {
+		return HookError, err
+	}
+	dstIA, err := rp.DstIA()
+	if err != nil {
+		return HookError, err
+	}
+	srcLocal := srcIA.Equal(rp.Ctx.Conf.IA)
+	dstLocal := dstIA.Equal(rp.Ctx.Conf.IA)
+	in, out := s.ifids()
+	switch rp.DirFrom {
+	case rcmn.DirExternal:
+		if in != rp.Ingress.IfID || (dstLocal && out != 0) {
+			return HookError, s.badSOF("ingress", rp.Ingress.IfID, "dstLocal", dstLocal)
+		}
+	case rcmn.DirLocal:
+		if dstLocal {
+			// The packet is destined to the router itself.
+			return HookContinue, nil
+		}
+		if rp.ifCurr == nil || out != *rp.ifCurr || (srcLocal && in != 0) {
+			return HookError, s.badSOF("egress", rp.ifCurr, "srcLocal", srcLocal)
+		}
+		s.advance = true
+	default:
+		return HookContinue, nil
+	}
+	hfmac := rp.Ctx.Conf.HFMacPool.Get().(hash.Hash)
+	err = s.extn.CurrSOF().Verify(hfmac, s.extn.ID, s.extn.Info)
+	rp.Ctx.Conf.HFMacPool.Put(hfmac)
+	if err != nil {
+		return HookError, common.NewBasicError("Invalid SIBRA opaque field",
+			scmp.NewError(scmp.C_Sibra, scmp.T_S_BadSOF, s.info(), err))
+	}
+	if rp.DirFrom == rcmn.DirExternal || srcLocal {
+		info := s.extn.Info
+		if !policer.PoliceReservation(s.extn.ID, info.BytesPerSec(), info.ExpTime, len(rp.Raw)) {
+			return HookError, common.NewBasicError("SIBRA reservation bandwidth exceeded",
+				scmp.NewError(scmp.C_Sibra, scmp.T_S_BWExceeded, s.info(), nil),
+				"id", s.extn.ID, "bw", info.BW)
+		}
+	}
+	rp.Reserved = true
+	return HookContinue, nil
+}
+
+// ifids returns the ingress and egress interfaces of the current opaque field
+// in the direction the packet travels.
+func (s *rSIBRA) ifids() (common.IFIDType, common.IFIDType) {
+	sof := s.extn.CurrSOF()
+	if s.extn.Forward {
+		return sof.Ingress, sof.Egress
+	}
+	return sof.Egress, sof.Ingress
+}
+
+// nextHop moves CurrHop to the opaque field of the next AS on the
+// reservation path.
+func (s *rSIBRA) nextHop() error {
+	next := int(s.extn.CurrHop) + 1
+	if !s.extn.Forward {
+		next = int(s.extn.CurrHop) - 1
+	}
+	if next < 0 || next >= len(s.extn.SOFs) {
+		return s.badSOF("next", next)
+	}
+	s.extn.CurrHop = uint8(next)
+	s.raw[2] = s.extn.CurrHop
+	return nil
+}
+
+func (s *rSIBRA) badSOF(logCtx ...interface{}) error {
+	return common.NewBasicError("SIBRA opaque field does not match path",
+		scmp.NewError(scmp.C_Sibra, scmp.T_S_BadSOF, s.info(), nil),
+		append([]interface{}{"currHop", s.extn.CurrHop, "sof", s.extn.CurrSOF()}, logCtx...)...)
+}
+
+func (s *rSIBRA) info() scmp.Info {
+	return &scmp.InfoExtIdx{Idx: uint8(s.pos)}
+}
+
+func (s *rSIBRA) Class() common.L4ProtocolType {
+	return common.HopByHopClass
+}
+
+func (s *rSIBRA) Type() common.ExtnType {
+	return common.ExtnSIBRAType
+}
+
+func (s *rSIBRA) Len() int {
+	return len(s.raw)
+}
+
+func (s *rSIBRA) String() string {
+	return s.extn.String()
+}
+
+func (s *rSIBRA) GetExtn() (common.Extension, error) {
+	return layers.ExtnSIBRAFromRaw(s.raw)
+}
+
+// routeSIBRA moves the SIBRA extension of the packet, if any, to the opaque
+// field of the next AS if the packet leaves the local AS.
+func (rp *RtrPkt) routeSIBRA() error {
+	for _, e := range rp.HBHExt {
+		if s, ok := e.(*rSIBRA); ok && s.advance {
+			return s.nextHop()
+		}
+	}
+	return nil
+}
@@ -45,6 +45,10 @@ func (rp *RtrPkt) extnParseHBH(extType common.ExtnType,
 		return rSCMPExtFromRaw(rp, start, end)
 	case extType == common.ExtnOneHopPathType:
 		return rOneHopPathFromRaw(rp)
+	case extType == common.ExtnHopTraceType:
+		return rHopTraceFromRaw(rp, start, end)
+	case extType == common.ExtnSIBRAType:
+		return rSIBRAFromRaw(rp, start, end, pos)
 	default:
 		// HBH not supported, so send an SCMP error in response.
 		return nil, common.NewBasicError(
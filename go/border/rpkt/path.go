@@ -76,8 +76,8 @@ func (rp *RtrPkt) validatePath(dirFrom rcmn.Dir) error {
 }
 
 // validateLocalIF makes sure a given interface ID exists in the local AS, and
-// that it isn't revoked. Note that revocations are ignored if the packet's
-// destination is this router.
+// that it isn't revoked or administratively shut down. Note that revocations
+// are ignored if the packet's destination is this router.
 func (rp *RtrPkt) validateLocalIF(ifid *common.IFIDType) error {
 	if ifid == nil {
 		return common.NewBasicError("validateLocalIF: Interface is nil", nil)
@@ -91,13 +91,26 @@ func (rp *RtrPkt) validateLocalIF(ifid *common.IFIDType) error {
 			"ifid", *ifid,
 		)
 	}
+	if err := rp.checkRevoked(*ifid); err != nil {
+		return err
+	}
+	if ifstate.AdminDown(*ifid) {
+		// This also drops the interface keepalives, such that the interface
+		// is revoked.
+		return common.NewBasicError(errIntfAdminDown, nil, "ifid", *ifid)
+	}
+	return nil
+}
+
+// checkRevoked returns an error containing an SCMP revocation if the interface
+// is revoked. Revocations are ignored if the OneHopExtension is present.
+func (rp *RtrPkt) checkRevoked(ifid common.IFIDType) error {
 	for _, e := range rp.HBHExt {
 		if e.Type() == common.ExtnOneHopPathType {
-			// Ignore revocations if OneHopExtension is present
 			return nil
 		}
 	}
-	state, ok := ifstate.LoadState(*ifid)
+	state, ok := ifstate.LoadState(ifid)
 	if !ok || state.Active {
 		// Interface is not revoked
 		return nil
@@ -105,12 +118,12 @@ func (rp *RtrPkt) validateLocalIF(ifid *common.IFIDType) error {
 	// Interface is revoked.
 	sRevInfo := state.SRevInfo
 	if sRevInfo == nil {
-		rp.Warn("No SRevInfo for revoked interface", "ifid", *ifid)
+		rp.Warn("No SRevInfo for revoked interface", "ifid", ifid)
 		return nil
 	}
 	revInfo, err := sRevInfo.RevInfo()
 	if err != nil {
-		rp.Warn("Could not parse RevInfo for interface", "ifid", *ifid, "err", err)
+		rp.Warn("Could not parse RevInfo for interface", "ifid", ifid, "err", err)
 		return nil
 	}
 	err = revInfo.Active()
@@ -121,12 +134,12 @@ func (rp *RtrPkt) validateLocalIF(ifid *common.IFIDType) error {
 		}
 		// If the BR does not have a revocation for the current epoch, it considers
 		// the interface as active until it receives a new revocation.
-		newState := ifstate.NewInfo(*ifid, true, nil, nil)
-		ifstate.UpdateIfNew(*ifid, state, newState)
+		newState := ifstate.NewInfo(ifid, true, nil, nil)
+		ifstate.UpdateIfNew(ifid, state, newState)
 		return nil
 	}
 	sinfo := scmp.NewInfoRevocation(
-		rp.CmnHdr.CurrInfoF, rp.CmnHdr.CurrHopF, *ifid,
+		rp.CmnHdr.CurrInfoF, rp.CmnHdr.CurrHopF, ifid,
 		rp.DirFrom == rcmn.DirExternal, state.RawSRev)
 	return common.NewBasicError(
 		errIntfRevoked,
@@ -0,0 +1,53 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file handles policing of packets received from neighboring ASes.
+
+package rpkt
+
+import (
+	"time"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/policer"
+	"github.com/scionproto/scion/go/border/rcmn"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scmp"
+)
+
+// police checks packets received from neighboring ASes against the configured
+// policers. Returns true and no error if the packet conforms to all matching
+// policers. If the packet exceeds a policer with the drop action, returns
+// false and no error. If it exceeds a policer with the SCMP action, returns an
+// error that triggers an SCMP AdminDenied reply, unless the policer already
+// sent too many replies. In that case, the packet is dropped silently.
+func (rp *RtrPkt) police() (bool, error) {
+	if rp.DirFrom != rcmn.DirExternal {
+		return true, nil
+	}
+	srcIA, err := rp.SrcIA()
+	if err != nil {
+		return false, err
+	}
+	p := policer.Police(rp.Ingress.IfID, srcIA, len(rp.Raw))
+	if p == nil {
+		return true, nil
+	}
+	if p.Action == brconf.PolicerActionSCMP && p.AllowSCMP(time.Now()) {
+		return false, common.NewBasicError("Policer rate exceeded",
+			scmp.NewError(scmp.C_Routing, scmp.T_R_AdminDenied, nil, nil), "policer", p)
+	}
+	rp.Debug("Dropping packet, policer rate exceeded", "policer", p)
+	return false, nil
+}
@@ -56,10 +56,17 @@ func (rp *RtrPkt) Route() error {
 		return common.NewBasicError("No routing information found", nil,
 			"egress", rp.Egress, "dirFrom", rp.DirFrom, "raw", rp.Raw)
 	}
+	if err := rp.routeSIBRA(); err != nil {
+		return err
+	}
+	rp.recordHopTrace(rp.Egress[0].S.Ifid)
 	rp.RefInc(len(rp.Egress))
 	// Call all egress functions.
 	for _, epair := range rp.Egress {
-		epair.S.Ring.Write(ringbuf.EntryList{&EgressRtrPkt{rp, epair.Dst}}, true)
+		// The packet must be captured before it is handed to the socket, as
+		// it may be released as soon as it is sent.
+		rp.captureOut(epair.S, epair.Dst)
+		epair.S.WriteOut(ringbuf.EntryList{&EgressRtrPkt{rp, epair.Dst}}, rp.Reserved)
 		inSock := rp.Ingress.Sock
 		if inSock == "" {
 			inSock = "self"
@@ -77,7 +84,15 @@ func (rp *RtrPkt) RouteResolveSVC() (HookResult, error) {
 		return HookError, common.NewBasicError("Destination host is NOT an SVC address", nil,
 			"actual", rp.dstHost, "type", fmt.Sprintf("%T", rp.dstHost))
 	}
-	addrs, err := rp.Ctx.ResolveSVC(svc)
+	srcIA, err := rp.SrcIA()
+	if err != nil {
+		return HookError, err
+	}
+	srcHost, err := rp.SrcHost()
+	if err != nil {
+		return HookError, err
+	}
+	addrs, err := rp.Ctx.ResolveSVC(svc, srcIA, srcHost)
 	if err != nil {
 		return HookError, err
 	}
@@ -240,6 +255,9 @@ func (rp *RtrPkt) forwardFromLocal() (HookResult, error) {
 }
 
 func (rp *RtrPkt) reprocess() (HookResult, error) {
+	// The packet is handed to the internal interface, and processed again
+	// as if it had been received on it.
+	rp.recordHopTrace(0)
 	// save
 	ctx := rp.Ctx
 	free := rp.Free
@@ -262,6 +280,10 @@ func (rp *RtrPkt) reprocess() (HookResult, error) {
 	rp.Ingress.Src = s.Conn.LocalAddr()
 	rp.Ingress.IfID = s.Ifid
 	rp.Ingress.Sock = s.Labels["sock"]
+	if callbacks.reprocessF != nil {
+		callbacks.reprocessF(rp)
+		return HookFinish, nil
+	}
 	// XXX This hook is meant to be called only when processing packets from external to external
 	// interface. Thus, the goroutine writing to the LocIn ringbuffer should always be the ones
 	// NOT reading from it to avoid deadlock, ie. goroutines handling packets from external
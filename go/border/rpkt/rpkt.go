@@ -44,13 +44,17 @@ const pktBufSize = 9 * 1024
 // callbacks is an anonymous struct used for functions supplied by the router
 // for various processing tasks.
 var callbacks struct {
-	rawSRevF func(RawSRevCallbackArgs)
+	rawSRevF   func(RawSRevCallbackArgs)
+	reprocessF func(*RtrPkt)
 }
 
 // Init takes callback functions provided by the router and stores them for use
-// by the rpkt package.
-func Init(rawSRevF func(RawSRevCallbackArgs)) {
+// by the rpkt package. reprocessF hands packets that are forwarded between two
+// external interfaces of the router back for processing. If it is nil, they
+// are written to the input ring of the internal interface.
+func Init(rawSRevF func(RawSRevCallbackArgs), reprocessF func(*RtrPkt)) {
 	callbacks.rawSRevF = rawSRevF
+	callbacks.reprocessF = reprocessF
 }
 
 // Router representation of SCION packet, including metadata.  The comments for the members have
@@ -117,6 +121,10 @@ type RtrPkt struct {
 	// SCMPError flags if the packet is an SCMP Error packet, in which case it should never trigger
 	// an error response packet. (PARSE, if SCMP extension header is present)
 	SCMPError bool
+	// Reserved flags if the packet is forwarded on a SIBRA reservation, in which case it is
+	// prioritized over best-effort traffic on egress. (PROCESS, if SIBRA extension header is
+	// present)
+	Reserved bool
 	// Logger is used to log messages associated with a packet. The Id field is automatically
 	// included in the output.
 	log.Logger
@@ -236,6 +244,7 @@ func (rp *RtrPkt) Reset() {
 	rp.pld = nil
 	rp.hooks = hooks{}
 	rp.SCMPError = false
+	rp.Reserved = false
 	rp.Logger = nil
 	rp.Ctx = nil
 	rp.refCnt = 1
@@ -25,6 +25,7 @@ import (
 const (
 	errCurrIntfInvalid = "Invalid current interface"
 	errIntfRevoked     = "Interface revoked"
+	errIntfAdminDown   = "Interface administratively down"
 	errHookResponse    = "Extension hook return value unrecognised"
 )
 
@@ -63,6 +64,9 @@ func (rp *RtrPkt) Validate() (bool, error) {
 			"totalLen", rp.CmnHdr.TotalLen, "actual", len(rp.Raw),
 		)
 	}
+	if ok, err := rp.police(); !ok || err != nil {
+		return false, err
+	}
 	// ValidatePath checks that ifCurr is valid
 	if err := rp.validatePath(rp.DirFrom); err != nil {
 		return false, err
@@ -0,0 +1,139 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file handles the authentication of SCMP errors with signed hash trees.
+
+package main
+
+import (
+	"time"
+
+	"github.com/scionproto/scion/go/border/rpkt"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/spkt"
+	"github.com/scionproto/scion/go/lib/spse/scmp_auth"
+)
+
+const (
+	// scmpSignerMaxBatch is the maximum number of SCMP errors that are
+	// authenticated with a single signature. It bounds the height of the hash
+	// tree, and thus the size of the extension.
+	scmpSignerMaxBatch = 64
+	// scmpSignerQueueLen is the number of SCMP errors that can be queued for
+	// authentication.
+	scmpSignerQueueLen = 2 * scmpSignerMaxBatch
+)
+
+type scmpSignerArgs struct {
+	rp *rpkt.RtrPkt
+	sp *spkt.ScnPkt
+}
+
+// scmpHashTreeSigner batches SCMP errors and authenticates each batch with a
+// hash tree. The root of the tree is signed with the AS signing key, and each
+// error carries the signature and its inclusion proof in an SCMPAuthHashTree
+// extension. This amortizes the cost of the signature over the batch.
+type scmpHashTreeSigner struct {
+	// window is the time a batch is kept open after its first error.
+	window time.Duration
+	q      chan scmpSignerArgs
+}
+
+func newSCMPHashTreeSigner(window time.Duration) *scmpHashTreeSigner {
+	return &scmpHashTreeSigner{
+		window: window,
+		q:      make(chan scmpSignerArgs, scmpSignerQueueLen),
+	}
+}
+
+// Enqueue queues the SCMP error sp, which is a reply to rp, for
+// authentication. The signer sends the error once its batch is signed.
+func (s *scmpHashTreeSigner) Enqueue(rp *rpkt.RtrPkt, sp *spkt.ScnPkt) {
+	rp.RefInc(1)
+	select {
+	case s.q <- scmpSignerArgs{rp: rp, sp: sp}:
+	default:
+		log.Debug("Dropping SCMP error, signer queue full")
+		rp.Release()
+	}
+}
+
+// Run collects batches of SCMP errors, and signs and sends them.
+func (s *scmpHashTreeSigner) Run() {
+	batch := make([]scmpSignerArgs, 0, scmpSignerMaxBatch)
+	// Run forever.
+	for args := range s.q {
+		batch = append(batch[:0], args)
+		timer := time.NewTimer(s.window)
+	Collect:
+		for len(batch) < scmpSignerMaxBatch {
+			select {
+			case args := <-s.q:
+				batch = append(batch, args)
+			case <-timer.C:
+				break Collect
+			}
+		}
+		timer.Stop()
+		s.signAndSend(batch)
+	}
+}
+
+// signAndSend authenticates the batch of SCMP errors and sends them. If the
+// authentication fails, the errors are sent unauthenticated.
+func (s *scmpHashTreeSigner) signAndSend(batch []scmpSignerArgs) {
+	extns, err := s.sign(batch)
+	if err != nil {
+		log.Warn("Unable to authenticate SCMP errors", "batch", len(batch), "err", err)
+	}
+	for i, args := range batch {
+		if extns != nil {
+			setSPSEExtn(args.sp, extns[i])
+		}
+		sendSCMPError(args.rp, args.sp)
+		args.rp.Release()
+	}
+}
+
+// sign builds the hash tree over the batch, signs its root and returns the
+// SCMPAuthHashTree extension of each SCMP error.
+func (s *scmpHashTreeSigner) sign(batch []scmpSignerArgs) ([]*scmp_auth.HashTreeExtn, error) {
+	conf := batch[0].rp.Ctx.Conf
+	if conf.SignKey == nil {
+		return nil, common.NewBasicError("Signing key not loaded", nil)
+	}
+	leaves := make([]common.RawBytes, len(batch))
+	for i, args := range batch {
+		var err error
+		if leaves[i], err = scmp_auth.HashTreeLeaf(args.sp); err != nil {
+			return nil, common.NewBasicError("Unable to compute leaf hash", err)
+		}
+	}
+	tree, err := scmp_auth.NewHashTree(leaves)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := scmp_auth.SignHashTree(tree, conf.SignKey, conf.SignAlgorithm)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to sign hash tree", err)
+	}
+	extns := make([]*scmp_auth.HashTreeExtn, len(batch))
+	for i := range batch {
+		if extns[i], err = tree.NewExtn(i, sig); err != nil {
+			return nil, err
+		}
+	}
+	return extns, nil
+}
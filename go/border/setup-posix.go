@@ -94,9 +94,10 @@ func (p posixLoc) addSock(r *Router, ctx *rctx.Ctx, labels prometheus.Labels) er
 	}
 	// Setup input goroutine.
 	ctx.LocSockIn = rctx.NewSock(ringbuf.New(64, nil, "locIn", mkRingLabels(labels)),
-		over, rcmn.DirLocal, 0, labels, r.posixInput, r.handleSock, PosixSock)
+		over, rcmn.DirLocal, 0, labels, r.posixInput, r.sockHandler(), PosixSock)
 	ctx.LocSockOut = rctx.NewSock(ringbuf.New(64, nil, "locOut", mkRingLabels(labels)),
 		over, rcmn.DirLocal, 0, labels, nil, r.posixOutput, PosixSock)
+	ctx.LocSockOut.PrioRing = ringbuf.New(64, nil, "locOutPrio", mkRingLabels(labels))
 	log.Debug("Done setting up new local socket.", "conn", over.LocalAddr())
 	return nil
 }
@@ -166,9 +167,11 @@ func (p posixExt) addIntf(r *Router, ctx *rctx.Ctx, intf *netconf.Interface,
 	}
 	// Setup input goroutine.
 	ctx.ExtSockIn[intf.Id] = rctx.NewSock(ringbuf.New(64, nil, "extIn", mkRingLabels(labels)),
-		c, rcmn.DirExternal, intf.Id, labels, r.posixInput, r.handleSock, PosixSock)
+		c, rcmn.DirExternal, intf.Id, labels, r.posixInput, r.sockHandler(), PosixSock)
 	ctx.ExtSockOut[intf.Id] = rctx.NewSock(ringbuf.New(64, nil, "extOut", mkRingLabels(labels)),
 		c, rcmn.DirExternal, intf.Id, labels, nil, r.posixOutput, PosixSock)
+	ctx.ExtSockOut[intf.Id].PrioRing = ringbuf.New(64, nil, "extOutPrio",
+		mkRingLabels(labels))
 	log.Debug("Done setting up new external socket.", "intf", intf)
 	return nil
 }
@@ -28,17 +28,23 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/syndtr/gocapability/capability"
 
+	"github.com/scionproto/scion/go/border/bfd"
 	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/ifstate"
 	"github.com/scionproto/scion/go/border/netconf"
+	"github.com/scionproto/scion/go/border/policer"
+	"github.com/scionproto/scion/go/border/radmin"
+	"github.com/scionproto/scion/go/border/rcapture"
+	"github.com/scionproto/scion/go/border/rctrl"
 	"github.com/scionproto/scion/go/border/rctx"
 	"github.com/scionproto/scion/go/border/rpkt"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/discovery"
+	"github.com/scionproto/scion/go/lib/drkey"
 	"github.com/scionproto/scion/go/lib/fatal"
 	"github.com/scionproto/scion/go/lib/infra/modules/idiscovery"
 	"github.com/scionproto/scion/go/lib/infra/modules/itopo"
 	"github.com/scionproto/scion/go/lib/log"
-	"github.com/scionproto/scion/go/lib/ringbuf"
 	"github.com/scionproto/scion/go/lib/topology"
 	"github.com/scionproto/scion/go/proto"
 )
@@ -64,14 +70,19 @@ var registeredExtSockOps = map[brconf.SockType]extSockOps{}
 // setup creates the router's channels and map, sets up the rpkt package, and
 // sets up a new router context. This function can only be called once during startup.
 func (r *Router) setup() error {
-	r.freePkts = ringbuf.New(1024, func() interface{} {
-		return rpkt.NewRtrPkt()
-	}, "free", prometheus.Labels{"ringId": "freePkts"})
+	if cfg.BR.Workers > 1 {
+		r.workers = newWorkers(cfg.BR.Workers)
+	}
+	r.freePkts = newFreePkts(cfg.BR.Workers)
 	r.sRevInfoQ = make(chan rpkt.RawSRevCallbackArgs, 16)
 	r.pktErrorQ = make(chan pktErrorArgs, 16)
+	r.scmpSigner = newSCMPHashTreeSigner(cfg.BR.SCMPAuthBatchWindow.Duration)
 
 	// Configure the rpkt package with the callbacks it needs.
-	rpkt.Init(r.RawSRevCallback)
+	rpkt.Init(r.RawSRevCallback, r.reprocessFunc())
+	// Set up the policers for traffic from neighboring ASes.
+	policer.Init(cfg.BR.Policers)
+	bfd.Init(cfg.BR.BFD, bfdStateChange)
 
 	// Load config.
 	var err error
@@ -92,10 +103,32 @@ func (r *Router) setup() error {
 	if err = r.clearCapabilities(); err != nil {
 		return err
 	}
+	// Start the packet capture, if enabled.
+	rcapture.Init(r.Id)
+	if cfg.BR.Capture.Enable {
+		if _, err := rcapture.Start(&cfg.BR.Capture); err != nil {
+			return err
+		}
+	}
+	// Serve the admin API, if enabled.
+	if cfg.BR.AdminAddr != "" {
+		if err := radmin.Init(cfg.BR.AdminAddr, cfg.BR.Capture, rctrl.SendLinkDown); err != nil {
+			return err
+		}
+	}
 	cfg.Metrics.StartPrometheus()
 	return nil
 }
 
+// bfdStateChange records the liveness of a link detected by BFD, and notifies
+// the beacon service of failed links.
+func bfdStateChange(ifid common.IFIDType, up bool) {
+	ifstate.SetLinkDown(ifid, !up)
+	if !up {
+		rctrl.SendLinkDown(ifid)
+	}
+}
+
 // clearCapabilities drops unnecessary capabilities after startup
 func (r *Router) clearCapabilities() error {
 	caps, err := capability.NewPid(0)
@@ -117,6 +150,13 @@ func (r *Router) loadNewConfig() (*brconf.BRConf, error) {
 	if config, err = brconf.Load(r.Id, r.confDir); err != nil {
 		return nil, common.NewBasicError("Failed to load topology config", err, "dir", r.confDir)
 	}
+	config.DRKeySV = drkey.NewSecretValueFactory(config.MasterKeys.Key0,
+		cfg.BR.DRKeyEpochDuration.Duration)
+	if cfg.BR.SCMPAuthMode == brconf.SCMPAuthHashTree {
+		if err = config.LoadSignKey(); err != nil {
+			return nil, err
+		}
+	}
 	log.Debug("Topology and AS config loaded", "IA", config.IA, "IfIDs", config.BR,
 		"dir", r.confDir)
 	return config, nil
@@ -207,6 +247,7 @@ func (r *Router) setupNewContext(ctx *rctx.Ctx, tx *itopo.Transaction) error {
 	}
 	rctx.Set(ctx)
 	startSocks(ctx)
+	bfd.Update(ctx)
 	// Tear down sockets for removed interfaces
 	r.teardownNet(ctx, oldCtx, sockConf)
 	return nil
@@ -287,15 +287,19 @@ func checkExtSocksRunning(key string, ctx *rctx.Ctx, running bool) {
 	}
 }
 
-// setupTest sets up a test router. The test router is initially set up with the
-// topology loaded from testdata.
-func setupTestRouter(t *testing.T) (*Router, *rctx.Ctx) {
-	// Init metrics.
+// initTestMetrics initializes the metrics once for all tests.
+func initTestMetrics() {
 	testInitOnce.Do(func() {
 		metrics.Init("br1-ff00_0_111-1")
 		// Reduce output displayed in goconvey.
 		log.Root().SetHandler(log.DiscardHandler())
 	})
+}
+
+// setupTest sets up a test router. The test router is initially set up with the
+// topology loaded from testdata.
+func setupTestRouter(t *testing.T) (*Router, *rctx.Ctx) {
+	initTestMetrics()
 	// The number of free packets has to be at least the number of posix
 	// input routines times inputBufCnt. Otherwise they might get stuck
 	// trying to prepare for reading from the connection.
@@ -357,7 +361,7 @@ func closeAllSocks(ctx *rctx.Ctx) {
 	}
 }
 
-func loadConfig(t *testing.T) *brconf.BRConf {
+func loadConfig(t testing.TB) *brconf.BRConf {
 	topo := loadTopo(t)
 	topoBr, ok := topo.BR["br1-ff00_0_111-1"]
 	if !ok {
@@ -373,7 +377,7 @@ func loadConfig(t *testing.T) *brconf.BRConf {
 	}
 }
 
-func loadTopo(t *testing.T) *topology.Topo {
+func loadTopo(t testing.TB) *topology.Topo {
 	topo, err := topology.LoadFromFile("testdata/topology.json")
 	xtest.FailOnErr(t, err)
 	return topo
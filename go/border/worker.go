@@ -0,0 +1,181 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file contains the sharded packet processing, which distributes the
+// packets read from the sockets to multiple workers.
+
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/scionproto/scion/go/border/metrics"
+	"github.com/scionproto/scion/go/border/rctx"
+	"github.com/scionproto/scion/go/border/rpkt"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/ringbuf"
+	"github.com/scionproto/scion/go/lib/spkt"
+)
+
+const (
+	// Capacity of the input ring of a worker.
+	workerRingCnt = 256
+	// Number of packet buffers allocated per worker for the pool of unused
+	// packets.
+	workerPktCnt = 1024
+)
+
+// worker processes the packets of the flows that hash to it.
+type worker struct {
+	// ring is a ring-buffer of packets to be processed by the worker.
+	ring *ringbuf.Ring
+}
+
+// newWorkers creates cnt workers.
+func newWorkers(cnt int) []*worker {
+	workers := make([]*worker, cnt)
+	for i := range workers {
+		labels := prometheus.Labels{"ringId": "worker" + strconv.Itoa(i)}
+		workers[i] = &worker{ring: ringbuf.New(workerRingCnt, nil, "proc", labels)}
+	}
+	return workers
+}
+
+// newFreePkts creates the pool of unused packets, which is shared by all
+// input goroutines. If packet processing is sharded, it holds workerPktCnt
+// packets per worker.
+func newFreePkts(workers int) *ringbuf.Ring {
+	cnt := 1024
+	if workers > 1 {
+		cnt = workers * workerPktCnt
+	}
+	return ringbuf.New(cnt, func() interface{} {
+		return rpkt.NewRtrPkt()
+	}, "free", prometheus.Labels{"ringId": "freePkts"})
+}
+
+// startWorkers starts the processing goroutine of each worker.
+func (r *Router) startWorkers() {
+	for i, w := range r.workers {
+		go func(i int, w *worker) {
+			defer log.LogPanicAndExit()
+			log.Debug("Worker starting", "id", i)
+			r.processRing(w.ring)
+			log.Debug("Worker stopping", "id", i)
+		}(i, w)
+	}
+}
+
+// sockHandler returns the function processing the packets read from an input
+// socket, which is nil if the packets are dispatched to the workers.
+func (r *Router) sockHandler() rctx.SockFunc {
+	if r.workers != nil {
+		return nil
+	}
+	return r.handleSock
+}
+
+// reprocessFunc returns the function handing the packets forwarded between
+// two external interfaces back for processing, which is nil if they are
+// written to the input ring of the internal interface. With workers, nothing
+// reads from that ring, so the packets are dispatched to the workers instead.
+func (r *Router) reprocessFunc() func(*rpkt.RtrPkt) {
+	if r.workers == nil {
+		return nil
+	}
+	return r.reprocess
+}
+
+// reprocess dispatches a packet that is processed again as if it had been
+// received on the internal interface. It is called by a worker, which is
+// usually also the worker the packet is dispatched to, so the packet is
+// dropped instead of blocking if the ring of the worker is full.
+func (r *Router) reprocess(rp *rpkt.RtrPkt) {
+	w := r.workers[r.workerIdx(rp)]
+	if n, _ := w.ring.Write(ringbuf.EntryList{rp}, false); n != 1 {
+		metrics.ReprocessPkts.WithLabelValues(metrics.ReprocessDropped).Inc()
+		rp.Release()
+		return
+	}
+	metrics.ReprocessPkts.WithLabelValues(metrics.ReprocessQueued).Inc()
+}
+
+// dispatch writes the packets to the rings of the workers their flows hash
+// to. The order of the packets of each worker is preserved, and thus the order
+// of the packets of each flow.
+func (r *Router) dispatch(pkts ringbuf.EntryList, batches []ringbuf.EntryList) {
+	for _, e := range pkts {
+		rp := e.(*rpkt.RtrPkt)
+		i := r.workerIdx(rp)
+		batches[i] = append(batches[i], rp)
+	}
+	for i, batch := range batches {
+		for written := 0; written < len(batch); {
+			wn, _ := r.workers[i].ring.Write(batch[written:], true)
+			if wn < 0 {
+				break
+			}
+			written += wn
+		}
+		for j := range batch {
+			batch[j] = nil
+		}
+		batches[i] = batch[:0]
+	}
+}
+
+// workerIdx returns the index of the worker the flow of the packet hashes to.
+func (r *Router) workerIdx(rp *rpkt.RtrPkt) int {
+	return int(flowHash(rp.Raw) % uint32(len(r.workers)))
+}
+
+// flowHash computes the hash of the SCION address header of the raw
+// packet, which identifies the flow the packet belongs to. Packets without a
+// valid common header all hash to 0.
+func flowHash(raw common.RawBytes) uint32 {
+	var cmn spkt.CmnHdr
+	if err := cmn.Parse(raw); err != nil {
+		return 0
+	}
+	dstLen, err := addr.HostLen(cmn.DstType)
+	if err != nil {
+		return 0
+	}
+	srcLen, err := addr.HostLen(cmn.SrcType)
+	if err != nil {
+		return 0
+	}
+	end := spkt.CmnHdrLen + 2*addr.IABytes + int(dstLen) + int(srcLen)
+	if end > len(raw) {
+		return 0
+	}
+	h := uint32(2166136261)
+	for _, b := range raw[spkt.CmnHdrLen:end] {
+		h ^= uint32(b)
+		h *= 16777619
+	}
+	// The low bits of an FNV-1a hash only depend on the low bits of the input
+	// bytes, so mix them with the finalizer of MurmurHash3 before the hash is
+	// reduced modulo the number of workers.
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,317 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"hash"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/border/brconf"
+	"github.com/scionproto/scion/go/border/policer"
+	"github.com/scionproto/scion/go/border/rctx"
+	"github.com/scionproto/scion/go/border/rpkt"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/hpkt"
+	"github.com/scionproto/scion/go/lib/l4"
+	"github.com/scionproto/scion/go/lib/ringbuf"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/lib/spkt"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+var (
+	benchSrcIA = xtest.MustParseIA("1-ff00:0:110")
+	// benchDst is the address of the local host the benchmark packets are
+	// delivered to.
+	benchDst = &net.UDPAddr{IP: net.IP{127, 0, 0, 3}, Port: 30041}
+	// benchNeighs are the addresses of the routers of the neighboring ASes,
+	// and benchIntfs the addresses of the interfaces themselves, keyed by
+	// interface. Interfaces 11 and 12 are parent links, 13 is a child link.
+	benchNeighs = map[common.IFIDType]*net.UDPAddr{
+		11: {IP: net.IP{127, 0, 0, 110}, Port: 50110},
+		12: {IP: net.IP{127, 0, 0, 120}, Port: 50120},
+		13: {IP: net.IP{127, 0, 0, 112}, Port: 50112},
+	}
+	benchIntfs = map[common.IFIDType]*net.UDPAddr{
+		11: {IP: net.IP{127, 0, 0, 11}, Port: 50011},
+		12: {IP: net.IP{127, 0, 0, 12}, Port: 50012},
+		13: {IP: net.IP{127, 0, 0, 13}, Port: 50013},
+	}
+)
+
+func TestFlowHash(t *testing.T) {
+	Convey("Packets of the same flow have the same hash", t, func() {
+		mac := newBenchMac(t)
+		a := mkBenchPkt(t, mac, 11, 0, net.IP{172, 16, 0, 1}, 64)
+		b := mkBenchPkt(t, mac, 11, 0, net.IP{172, 16, 0, 1}, 128)
+		c := mkBenchPkt(t, mac, 11, 0, net.IP{172, 16, 0, 2}, 64)
+		SoMsg("same flow", flowHash(a), ShouldEqual, flowHash(b))
+		SoMsg("other flow", flowHash(a), ShouldNotEqual, flowHash(c))
+	})
+	Convey("Truncated packets hash to 0", t, func() {
+		raw := mkBenchPkt(t, newBenchMac(t), 11, 0, net.IP{172, 16, 0, 1}, 64)
+		SoMsg("empty", flowHash(nil), ShouldEqual, 0)
+		SoMsg("common header", flowHash(raw[:spkt.CmnHdrLen]), ShouldEqual, 0)
+		SoMsg("address header", flowHash(raw[:spkt.CmnHdrLen+addr.IABytes]), ShouldEqual, 0)
+	})
+}
+
+func TestDispatch(t *testing.T) {
+	Convey("Dispatch preserves the order of the packets of each flow", t, func() {
+		initTestMetrics()
+		r := &Router{workers: newWorkers(3)}
+		mac := newBenchMac(t)
+		var pkts ringbuf.EntryList
+		for i := 0; i < 30; i++ {
+			rp := rpkt.NewRtrPkt()
+			raw := mkBenchPkt(t, mac, 11, 0, net.IP{172, 16, 0, byte(i % 5)}, 64)
+			rp.Raw = rp.Raw[:copy(rp.Raw, raw)]
+			rp.Id = fmt.Sprint(i)
+			pkts = append(pkts, rp)
+		}
+		batches := make([]ringbuf.EntryList, len(r.workers))
+		r.dispatch(pkts, batches)
+		var total int
+		for i, w := range r.workers {
+			out := make(ringbuf.EntryList, workerRingCnt)
+			n, _ := w.ring.Read(out, false)
+			if n <= 0 {
+				continue
+			}
+			last := -1
+			for _, e := range out[:n] {
+				rp := e.(*rpkt.RtrPkt)
+				SoMsg("worker", int(flowHash(rp.Raw)%3), ShouldEqual, i)
+				var id int
+				fmt.Sscan(rp.Id, &id)
+				SoMsg("order", id, ShouldBeGreaterThan, last)
+				last = id
+			}
+			total += n
+		}
+		SoMsg("all dispatched", total, ShouldEqual, len(pkts))
+	})
+}
+
+func TestForwardBetweenInterfaces(t *testing.T) {
+	Convey("Packets between two external interfaces are forwarded by the workers", t, func() {
+		mac, cleanF := setupForwardingRouter(t, 4)
+		defer cleanF()
+		send, err := net.DialUDP("udp4", benchNeighs[11], benchIntfs[11])
+		xtest.FailOnErr(t, err)
+		defer send.Close()
+		recv, err := net.ListenUDP("udp4", benchNeighs[13])
+		xtest.FailOnErr(t, err)
+		defer recv.Close()
+
+		const cnt = 64
+		for i := 0; i < cnt; i++ {
+			pkt := mkBenchPkt(t, mac, 11, 13, net.IP{172, 16, 0, byte(i % 16)}, 64)
+			_, err := send.Write(pkt)
+			xtest.FailOnErr(t, err)
+		}
+		var recvd int
+		buf := make([]byte, 2048)
+		for recvd < cnt {
+			recv.SetReadDeadline(time.Now().Add(time.Second))
+			if _, err := recv.Read(buf); err != nil {
+				break
+			}
+			recvd++
+		}
+		SoMsg("forwarded", recvd, ShouldEqual, cnt)
+	})
+}
+
+// BenchmarkForwarding measures the throughput of packets received from the
+// neighboring ASes on interfaces 11 and 12 and delivered to a local host, for
+// different numbers of workers. All packets are sent over loopback, one
+// sender per interface, and are distributed over benchFlows flows.
+func BenchmarkForwarding(b *testing.B) {
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			benchmarkForwarding(b, workers)
+		})
+	}
+}
+
+const (
+	benchFlows = 64
+	// benchWindow is the maximum number of packets in flight. The packets
+	// in flight must fit in the receive buffer of the local host, which is
+	// bounded by net.core.rmem_max.
+	benchWindow = 64
+	// benchRcvBuf is the requested size of the receive buffer of the local
+	// host.
+	benchRcvBuf = 1 << 20
+)
+
+func benchmarkForwarding(b *testing.B, workers int) {
+	mac, cleanF := setupForwardingRouter(b, workers)
+	defer cleanF()
+	recv, err := net.ListenUDP("udp4", benchDst)
+	xtest.FailOnErr(b, err)
+	defer recv.Close()
+	xtest.FailOnErr(b, recv.SetReadBuffer(benchRcvBuf))
+	var sends []*net.UDPConn
+	var pkts [][]common.RawBytes
+	for _, ifid := range []common.IFIDType{11, 12} {
+		send, err := net.DialUDP("udp4", benchNeighs[ifid], benchIntfs[ifid])
+		xtest.FailOnErr(b, err)
+		defer send.Close()
+		sends = append(sends, send)
+		flows := make([]common.RawBytes, benchFlows/2)
+		for i := range flows {
+			flows[i] = mkBenchPkt(b, mac, ifid, 0, net.IP{172, 16, byte(ifid), byte(i)}, 64)
+		}
+		pkts = append(pkts, flows)
+	}
+
+	var sent, recvd int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 2048)
+		for atomic.LoadInt64(&recvd) < int64(b.N) {
+			recv.SetReadDeadline(time.Now().Add(time.Second))
+			if _, err := recv.Read(buf); err != nil {
+				return
+			}
+			atomic.AddInt64(&recvd, 1)
+		}
+	}()
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for i := range sends {
+		wg.Add(1)
+		go func(send *net.UDPConn, pkts []common.RawBytes) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				n := atomic.AddInt64(&sent, 1)
+				if n > int64(b.N) {
+					return
+				}
+				for n-atomic.LoadInt64(&recvd) > benchWindow {
+					select {
+					case <-done:
+						return
+					default:
+						runtime.Gosched()
+					}
+				}
+				if _, err := send.Write(pkts[j%len(pkts)]); err != nil {
+					b.Errorf("Error sending packet: %s", err)
+					return
+				}
+			}
+		}(sends[i], pkts[i])
+	}
+	wg.Wait()
+	<-done
+	b.StopTimer()
+	if n := atomic.LoadInt64(&recvd); n < int64(b.N) {
+		b.Fatalf("Packets lost, sent %d, received %d", b.N, n)
+	}
+}
+
+// setupForwardingRouter sets up a router with the testdata topology and the
+// given number of workers, and starts its sockets. It returns the hop field
+// MAC of the router, and a function to stop the router.
+func setupForwardingRouter(t testing.TB, workers int) (hash.Hash, func()) {
+	initTestMetrics()
+	policer.Init(nil)
+	r := &Router{}
+	if workers > 1 {
+		r.workers = newWorkers(workers)
+		r.startWorkers()
+	}
+	r.freePkts = newFreePkts(workers)
+	rpkt.Init(nil, r.reprocessFunc())
+	conf := loadConfig(t)
+	conf.HFMacPool = &sync.Pool{
+		New: func() interface{} {
+			return newBenchMac(t)
+		},
+	}
+	ctx := rctx.New(conf)
+	xtest.FailOnErr(t, r.setupNet(ctx, nil, brconf.SockConf{Default: PosixSock}))
+	rctx.Set(ctx)
+	startSocks(ctx)
+	return newBenchMac(t), func() {
+		closeAllSocks(ctx)
+		for _, w := range r.workers {
+			w.ring.Close()
+		}
+	}
+}
+
+// mkBenchPkt creates a packet from the neighboring AS on interface ingress,
+// with source host src and a payload of length pldLen. If egress is 0, the
+// packet is destined to benchDst in the local AS. Otherwise, it is forwarded
+// on interface egress to the child AS 1-ff00:0:112.
+func mkBenchPkt(t testing.TB, mac hash.Hash, ingress, egress common.IFIDType, src net.IP,
+	pldLen int) common.RawBytes {
+
+	ts := uint32(time.Now().Unix())
+	prev := &spath.HopField{ConsEgress: 1000 + ingress, ExpTime: spath.DefaultHopFExpiry,
+		Mac: make(common.RawBytes, spath.MacLen)}
+	hopF := &spath.HopField{ConsIngress: ingress, ConsEgress: egress,
+		ExpTime: spath.DefaultHopFExpiry}
+	prevRaw := prev.Pack()
+	hopF.Mac = hopF.CalcMac(mac, ts, prevRaw[1:])
+	hops := []*spath.HopField{prev, hopF}
+	dstIA := xtest.MustParseIA("1-ff00:0:111")
+	if egress != 0 {
+		hops = append(hops, &spath.HopField{ConsIngress: 1000 + egress,
+			ExpTime: spath.DefaultHopFExpiry, Mac: make(common.RawBytes, spath.MacLen)})
+		dstIA = xtest.MustParseIA("1-ff00:0:112")
+	}
+	inf := &spath.InfoField{ConsDir: true, TsInt: ts, ISD: 1, Hops: uint8(len(hops))}
+	path := make(common.RawBytes, spath.InfoFieldLength+len(hops)*spath.HopFieldLength)
+	inf.Write(path)
+	for i, h := range hops {
+		h.Write(path[spath.InfoFieldLength+i*spath.HopFieldLength:])
+	}
+	pkt := &spkt.ScnPkt{
+		DstIA:   dstIA,
+		SrcIA:   benchSrcIA,
+		DstHost: addr.HostFromIP(benchDst.IP),
+		SrcHost: addr.HostFromIP(src),
+		Path: &spath.Path{Raw: path, InfOff: 0,
+			HopOff: spath.InfoFieldLength + spath.HopFieldLength},
+		L4:  &l4.UDP{SrcPort: 40000, DstPort: 40001},
+		Pld: make(common.RawBytes, pldLen),
+	}
+	raw := make(common.RawBytes, 2048)
+	n, err := hpkt.WriteScnPkt(pkt, raw)
+	xtest.FailOnErr(t, err)
+	return raw[:n]
+}
+
+func newBenchMac(t testing.TB) hash.Hash {
+	mac, err := scrypto.InitMac(make(common.RawBytes, 16))
+	xtest.FailOnErr(t, err)
+	return mac
+}
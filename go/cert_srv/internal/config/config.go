@@ -16,10 +16,13 @@ package config
 
 import (
 	"io"
+	"net"
 	"time"
 
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/config"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/drkeystorage"
 	"github.com/scionproto/scion/go/lib/env"
 	"github.com/scionproto/scion/go/lib/infra/modules/idiscovery"
 	"github.com/scionproto/scion/go/lib/scrypto/cert"
@@ -38,6 +41,13 @@ const (
 	ReissReqRate = 10 * time.Second
 	// ReissueReqTimeout is the default timeout of a reissue request.
 	ReissueReqTimeout = 5 * time.Second
+	// DRKeyEpochDuration is the default duration of a DRKey epoch.
+	DRKeyEpochDuration = drkey.DefaultEpochDuration
+	// DRKeyMaxReqAge is the default maximum age of an accepted DRKey request.
+	DRKeyMaxReqAge = 2 * time.Second
+	// DRKeyPrefetchLeadTime is the default time before the expiration of a
+	// first-level key at which the key of the next epoch is fetched.
+	DRKeyPrefetchLeadTime = time.Hour
 
 	ErrorKeyConf   = "Unable to load KeyConf"
 	ErrorCustomers = "Unable to load Customers"
@@ -54,6 +64,7 @@ type Config struct {
 	TrustDB   truststorage.TrustDBConf
 	Discovery idiscovery.Config
 	CS        CSConfig
+	DRKey     DRKeyConfig
 }
 
 func (cfg *Config) InitDefaults() {
@@ -65,6 +76,7 @@ func (cfg *Config) InitDefaults() {
 		&cfg.TrustDB,
 		&cfg.Discovery,
 		&cfg.CS,
+		&cfg.DRKey,
 	)
 }
 
@@ -77,6 +89,7 @@ func (cfg *Config) Validate() error {
 		&cfg.TrustDB,
 		&cfg.Discovery,
 		&cfg.CS,
+		&cfg.DRKey,
 	)
 }
 
@@ -89,6 +102,7 @@ func (cfg *Config) Sample(dst io.Writer, path config.Path, _ config.CtxMap) {
 		&cfg.TrustDB,
 		&cfg.Discovery,
 		&cfg.CS,
+		&cfg.DRKey,
 	)
 }
 
@@ -151,3 +165,86 @@ func (cfg *CSConfig) Sample(dst io.Writer, path config.Path, _ config.CtxMap) {
 func (cfg *CSConfig) ConfigName() string {
 	return "cs"
 }
+
+var _ config.Config = (*DRKeyConfig)(nil)
+
+// DRKeyConfig is the configuration of the DRKey key server.
+type DRKeyConfig struct {
+	// DRKeyDB is the database for first-level keys. DRKey is disabled if no
+	// connection is configured.
+	DRKeyDB drkeystorage.DRKeyDBConf
+	// EpochDuration is the duration of the DRKey epochs of this AS.
+	EpochDuration util.DurWrap
+	// MaxReqAge is the maximum age of an accepted DRKey request.
+	MaxReqAge util.DurWrap
+	// PrefetchLeadTime indicates how long before the expiration of a
+	// first-level key the key of the next epoch is fetched.
+	PrefetchLeadTime util.DurWrap
+	// Delegation lists, per protocol, the hosts of the local AS that are
+	// authorized to obtain all second-level keys of the protocol. Other hosts
+	// only obtain the keys they are an end host of.
+	Delegation map[string][]string
+}
+
+func (cfg *DRKeyConfig) InitDefaults() {
+	if cfg.EpochDuration.Duration == 0 {
+		cfg.EpochDuration.Duration = DRKeyEpochDuration
+	}
+	if cfg.MaxReqAge.Duration == 0 {
+		cfg.MaxReqAge.Duration = DRKeyMaxReqAge
+	}
+	if cfg.PrefetchLeadTime.Duration == 0 {
+		cfg.PrefetchLeadTime.Duration = DRKeyPrefetchLeadTime
+	}
+	config.InitAll(&cfg.DRKeyDB)
+}
+
+// Enabled returns true if a DRKey database is configured.
+func (cfg *DRKeyConfig) Enabled() bool {
+	return cfg.DRKeyDB.Connection() != ""
+}
+
+func (cfg *DRKeyConfig) Validate() error {
+	if cfg.EpochDuration.Duration < time.Second {
+		return common.NewBasicError("EpochDuration must be at least one second", nil,
+			"value", cfg.EpochDuration)
+	}
+	if cfg.MaxReqAge.Duration == 0 {
+		return common.NewBasicError("MaxReqAge must not be zero", nil)
+	}
+	if cfg.PrefetchLeadTime.Duration >= cfg.EpochDuration.Duration {
+		return common.NewBasicError("PrefetchLeadTime must be smaller than EpochDuration", nil,
+			"lead", cfg.PrefetchLeadTime, "epoch", cfg.EpochDuration)
+	}
+	for protocol, hosts := range cfg.Delegation {
+		for _, host := range hosts {
+			if net.ParseIP(host) == nil {
+				return common.NewBasicError("Invalid host in Delegation", nil,
+					"protocol", protocol, "host", host)
+			}
+		}
+	}
+	return config.ValidateAll(&cfg.DRKeyDB)
+}
+
+// DelegationHosts returns the parsed Delegation lists.
+func (cfg *DRKeyConfig) DelegationHosts() map[string][]net.IP {
+	delegation := make(map[string][]net.IP, len(cfg.Delegation))
+	for protocol, hosts := range cfg.Delegation {
+		for _, host := range hosts {
+			if ip := net.ParseIP(host); ip != nil {
+				delegation[protocol] = append(delegation[protocol], ip)
+			}
+		}
+	}
+	return delegation
+}
+
+func (cfg *DRKeyConfig) Sample(dst io.Writer, path config.Path, ctx config.CtxMap) {
+	config.WriteString(dst, drkeySample)
+	config.WriteSample(dst, path, ctx, &cfg.DRKeyDB)
+}
+
+func (cfg *DRKeyConfig) ConfigName() string {
+	return "drkey"
+}
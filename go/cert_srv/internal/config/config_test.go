@@ -23,6 +23,7 @@ import (
 	"github.com/BurntSushi/toml"
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/scionproto/scion/go/lib/drkeystorage/drkeystoragetest"
 	"github.com/scionproto/scion/go/lib/env/envtest"
 	"github.com/scionproto/scion/go/lib/infra/modules/idiscovery/idiscoverytest"
 	"github.com/scionproto/scion/go/lib/truststorage/truststoragetest"
@@ -70,6 +71,12 @@ func TestConfig_InitDefaults(t *testing.T) {
 			SoMsg("reissRate", cfg.CS.ReissueRate.Duration, ShouldEqual, ReissReqRate)
 			SoMsg("reissTimeout", cfg.CS.ReissueTimeout.Duration, ShouldEqual, ReissueReqTimeout)
 			SoMsg("autoRenewal", cfg.CS.AutomaticRenewal, ShouldBeFalse)
+			SoMsg("epochDuration", cfg.DRKey.EpochDuration.Duration, ShouldEqual,
+				DRKeyEpochDuration)
+			SoMsg("maxReqAge", cfg.DRKey.MaxReqAge.Duration, ShouldEqual, DRKeyMaxReqAge)
+			SoMsg("prefetchLeadTime", cfg.DRKey.PrefetchLeadTime.Duration, ShouldEqual,
+				DRKeyPrefetchLeadTime)
+			SoMsg("drkeyEnabled", cfg.DRKey.Enabled(), ShouldBeFalse)
 		})
 	})
 }
@@ -79,6 +86,7 @@ func InitTestConfig(cfg *Config) {
 	truststoragetest.InitTestConfig(&cfg.TrustDB)
 	idiscoverytest.InitTestConfig(&cfg.Discovery)
 	InitTestCSConfig(&cfg.CS)
+	drkeystoragetest.InitTestConfig(&cfg.DRKey.DRKeyDB)
 }
 
 func InitTestCSConfig(cfg *CSConfig) {
@@ -90,6 +98,7 @@ func CheckTestConfig(cfg *Config, id string) {
 	truststoragetest.CheckTestConfig(&cfg.TrustDB, id)
 	idiscoverytest.CheckTestConfig(&cfg.Discovery)
 	CheckTestCSConfig(&cfg.CS)
+	CheckTestDRKeyConfig(&cfg.DRKey, id)
 }
 
 func CheckTestCSConfig(cfg *CSConfig) {
@@ -101,3 +110,11 @@ func CheckTestCSConfig(cfg *CSConfig) {
 	SoMsg("IssuerReissLeadTime correct", cfg.IssuerReissueLeadTime.Duration, ShouldEqual,
 		IssuerReissTime)
 }
+
+func CheckTestDRKeyConfig(cfg *DRKeyConfig, id string) {
+	SoMsg("EpochDuration correct", cfg.EpochDuration.Duration, ShouldEqual, DRKeyEpochDuration)
+	SoMsg("MaxReqAge correct", cfg.MaxReqAge.Duration, ShouldEqual, DRKeyMaxReqAge)
+	SoMsg("PrefetchLeadTime correct", cfg.PrefetchLeadTime.Duration, ShouldEqual,
+		DRKeyPrefetchLeadTime)
+	drkeystoragetest.CheckTestConfig(&cfg.DRKeyDB, id)
+}
@@ -35,3 +35,20 @@ ReissueTimeout = "5s"
 # Whether automatic reissuing is enabled. (default false)
 AutomaticRenewal = false
 `
+
+const drkeySample = `
+# Duration of the DRKey epochs. The epochs are aligned to the Unix epoch.
+# (default 24h)
+EpochDuration = "24h"
+
+# Maximum age of an accepted DRKey request. (default 2s)
+MaxReqAge = "2s"
+
+# Time before the expiration of a first-level key at which the key of the
+# next epoch is fetched. Must be smaller than EpochDuration. (default 1h)
+PrefetchLeadTime = "1h"
+
+# Hosts of the local AS that are authorized to obtain all second-level keys of
+# a protocol, in addition to the end hosts of the keys. (default none)
+# Delegation = { scmp = ["127.0.0.1"] }
+`
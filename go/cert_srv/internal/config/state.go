@@ -88,6 +88,13 @@ func (s *State) GetDecryptKey() common.RawBytes {
 	return s.keyConf.DecryptKey
 }
 
+// GetMasterKey returns the AS master key 0 of the current key configuration.
+func (s *State) GetMasterKey() common.RawBytes {
+	s.keyConfLock.RLock()
+	defer s.keyConfLock.RUnlock()
+	return s.keyConf.Master.Key0
+}
+
 // GetOnRootKey returns the online root key of the current key configuration.
 func (s *State) GetOnRootKey() common.RawBytes {
 	s.keyConfLock.RLock()
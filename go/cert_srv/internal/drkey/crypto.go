@@ -0,0 +1,60 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/scrypto/cert"
+)
+
+// encryptLvl1 encrypts the key for the AS certified by dst. The returned
+// cipher is the concatenation of the random nonce and the encrypted key.
+func encryptLvl1(key drkey.DRKey, dst *cert.Chain, privKey common.RawBytes) (common.RawBytes,
+	error) {
+
+	nonce, err := scrypto.Nonce(scrypto.NaClBoxNonceSize)
+	if err != nil {
+		return nil, err
+	}
+	box, err := scrypto.Encrypt(common.RawBytes(key), nonce, dst.Leaf.SubjectEncKey, privKey,
+		dst.Leaf.EncAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, box...), nil
+}
+
+// decryptLvl1 decrypts a cipher created by encryptLvl1 that has been
+// encrypted by the AS certified by src.
+func decryptLvl1(cipher common.RawBytes, src *cert.Chain,
+	privKey common.RawBytes) (drkey.DRKey, error) {
+
+	if len(cipher) <= scrypto.NaClBoxNonceSize {
+		return nil, common.NewBasicError("Cipher too short", nil, "len", len(cipher))
+	}
+	nonce := cipher[:scrypto.NaClBoxNonceSize]
+	box := cipher[scrypto.NaClBoxNonceSize:]
+	key, err := scrypto.Decrypt(box, nonce, src.Leaf.SubjectEncKey, privKey,
+		src.Leaf.EncAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != drkey.KeyLength {
+		return nil, common.NewBasicError("Invalid key length", nil, "len", len(key))
+	}
+	return drkey.DRKey(key), nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/scrypto/cert"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestEncryptDecryptLvl1(t *testing.T) {
+	Convey("Encrypted key can be decrypted by the destination", t, func() {
+		srcPub, srcPriv, err := scrypto.GenKeyPair(scrypto.Curve25519xSalsa20Poly1305)
+		xtest.FailOnErr(t, err)
+		dstPub, dstPriv, err := scrypto.GenKeyPair(scrypto.Curve25519xSalsa20Poly1305)
+		xtest.FailOnErr(t, err)
+		src := newChain(srcPub)
+		dst := newChain(dstPub)
+		key := drkey.DRKey(xtest.MustParseHexString("c584cad32613547c64823c756651b6f5"))
+
+		cipher, err := encryptLvl1(key, dst, srcPriv)
+		SoMsg("encrypt err", err, ShouldBeNil)
+		decrypted, err := decryptLvl1(cipher, src, dstPriv)
+		SoMsg("decrypt err", err, ShouldBeNil)
+		SoMsg("key", decrypted, ShouldResemble, key)
+
+		Convey("Tampered cipher is rejected", func() {
+			cipher[len(cipher)-1] ^= 0xff
+			_, err := decryptLvl1(cipher, src, dstPriv)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("Short cipher is rejected", func() {
+			_, err := decryptLvl1(cipher[:scrypto.NaClBoxNonceSize], src, dstPriv)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestValidateTime(t *testing.T) {
+	now := time.Now()
+	Convey("Fresh request is accepted", t, func() {
+		SoMsg("err", validateTime(now, now, time.Second), ShouldBeNil)
+	})
+	Convey("Old request is rejected", t, func() {
+		SoMsg("err", validateTime(now.Add(-2*time.Second), now, time.Second), ShouldNotBeNil)
+	})
+	Convey("Future request is rejected", t, func() {
+		SoMsg("err", validateTime(now.Add(2*time.Second), now, time.Second), ShouldNotBeNil)
+	})
+}
+
+func TestLvl2Authorize(t *testing.T) {
+	local := xtest.MustParseIA("1-ff00:0:111")
+	remote := xtest.MustParseIA("1-ff00:0:112")
+	host := addr.HostFromIPStr("127.0.0.1")
+	other := addr.HostFromIPStr("127.0.0.2")
+	delegated := addr.HostFromIPStr("127.0.0.3")
+	h := &Lvl2ReqHandler{
+		IA:         local,
+		Delegation: map[string][]net.IP{"scmp": {delegated.IP()}},
+	}
+	tests := []struct {
+		Name       string
+		Meta       drkey.Lvl2Meta
+		Requester  addr.HostAddr
+		Authorized bool
+	}{
+		{
+			Name: "AS2AS denied without delegation",
+			Meta: drkey.Lvl2Meta{KeyType: drkey.AS2AS, Protocol: "scmp",
+				SrcIA: remote, DstIA: local},
+			Requester: host,
+		},
+		{
+			Name: "AS2AS allowed with delegation",
+			Meta: drkey.Lvl2Meta{KeyType: drkey.AS2AS, Protocol: "scmp",
+				SrcIA: remote, DstIA: local},
+			Requester:  delegated,
+			Authorized: true,
+		},
+		{
+			Name: "delegation is per protocol",
+			Meta: drkey.Lvl2Meta{KeyType: drkey.AS2AS, Protocol: "piskes",
+				SrcIA: remote, DstIA: local},
+			Requester: delegated,
+		},
+		{
+			Name: "AS2Host allowed for local destination host",
+			Meta: drkey.Lvl2Meta{KeyType: drkey.AS2Host, Protocol: "scmp",
+				SrcIA: remote, DstIA: local, DstHost: host},
+			Requester:  host,
+			Authorized: true,
+		},
+		{
+			Name: "AS2Host denied for other host",
+			Meta: drkey.Lvl2Meta{KeyType: drkey.AS2Host, Protocol: "scmp",
+				SrcIA: remote, DstIA: local, DstHost: host},
+			Requester: other,
+		},
+		{
+			Name: "AS2Host denied for remote destination",
+			Meta: drkey.Lvl2Meta{KeyType: drkey.AS2Host, Protocol: "scmp",
+				SrcIA: local, DstIA: remote, DstHost: host},
+			Requester: host,
+		},
+		{
+			Name: "Host2Host allowed for local source host",
+			Meta: drkey.Lvl2Meta{KeyType: drkey.Host2Host, Protocol: "scmp",
+				SrcIA: local, DstIA: remote, SrcHost: host, DstHost: other},
+			Requester:  host,
+			Authorized: true,
+		},
+		{
+			Name: "Host2Host denied for remote destination host",
+			Meta: drkey.Lvl2Meta{KeyType: drkey.Host2Host, Protocol: "scmp",
+				SrcIA: local, DstIA: remote, SrcHost: host, DstHost: other},
+			Requester: other,
+		},
+		{
+			Name: "unknown requester is denied",
+			Meta: drkey.Lvl2Meta{KeyType: drkey.AS2Host, Protocol: "scmp",
+				SrcIA: remote, DstIA: local, DstHost: host},
+		},
+	}
+	Convey("Lvl2 requests are authorized", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				err := h.authorize(test.Meta, test.Requester)
+				if test.Authorized {
+					SoMsg("err", err, ShouldBeNil)
+				} else {
+					SoMsg("err", err, ShouldNotBeNil)
+				}
+			})
+		}
+	})
+}
+
+func TestReplayCache(t *testing.T) {
+	now := time.Now()
+	Convey("Replay cache", t, func() {
+		var c replayCache
+		SoMsg("first", c.Add("a", now, now.Add(time.Second)), ShouldBeTrue)
+		SoMsg("replay", c.Add("a", now, now.Add(time.Second)), ShouldBeFalse)
+		SoMsg("other", c.Add("b", now, now.Add(time.Second)), ShouldBeTrue)
+		later := now.Add(time.Second)
+		SoMsg("expired", c.Add("a", later, later.Add(time.Second)), ShouldBeTrue)
+		SoMsg("pruned", c.seen, ShouldNotContainKey, "b")
+	})
+}
+
+func newChain(encKey common.RawBytes) *cert.Chain {
+	return &cert.Chain{
+		Leaf: &cert.Certificate{
+			SubjectEncKey: encKey,
+			EncAlgorithm:  scrypto.Curve25519xSalsa20Poly1305,
+		},
+	}
+}
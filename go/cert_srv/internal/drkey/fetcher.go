@@ -0,0 +1,211 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/scionproto/scion/go/cert_srv/internal/config"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/infra/messenger"
+	"github.com/scionproto/scion/go/lib/infra/modules/cleaner"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/periodic"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+// Lvl1Fetcher fetches first-level keys K_{SrcIA->IA} from remote certificate
+// servers and stores them in the DRKey database.
+type Lvl1Fetcher struct {
+	Msgr  infra.Messenger
+	State *config.State
+	IA    addr.IA
+	DB    drkey.Lvl1DB
+}
+
+// GetLvl1Key returns the first-level key K_{srcIA->IA} that is valid at
+// valTime. If no such key is stored, the key of the current epoch is fetched
+// from the certificate server of srcIA.
+func (f *Lvl1Fetcher) GetLvl1Key(ctx context.Context, srcIA addr.IA,
+	valTime time.Time) (drkey.Lvl1Key, error) {
+
+	meta := drkey.Lvl1Meta{SrcIA: srcIA, DstIA: f.IA}
+	key, err := f.DB.GetLvl1Key(ctx, meta, util.TimeToSecs(valTime))
+	if err == nil {
+		return key, nil
+	}
+	if err != sql.ErrNoRows {
+		return drkey.Lvl1Key{}, common.NewBasicError("Unable to query DRKey DB", err)
+	}
+	key, err = f.Fetch(ctx, srcIA, false)
+	if err != nil {
+		return drkey.Lvl1Key{}, err
+	}
+	if !key.Epoch.Contains(valTime) {
+		return drkey.Lvl1Key{}, common.NewBasicError("Fetched key not valid at requested time",
+			nil, "valTime", util.TimeToString(valTime), "epoch", key.Epoch)
+	}
+	return key, nil
+}
+
+// Fetch requests the first-level key K_{srcIA->IA} from the certificate
+// server of srcIA and stores it in the DRKey database. If prefetch is set,
+// the key of the next epoch is requested.
+func (f *Lvl1Fetcher) Fetch(ctx context.Context, srcIA addr.IA,
+	prefetch bool) (drkey.Lvl1Key, error) {
+
+	req, err := f.buildReq(ctx, srcIA, prefetch)
+	if err != nil {
+		return drkey.Lvl1Key{}, common.NewBasicError("Unable to build request", err)
+	}
+	csAddr := &snet.Addr{IA: srcIA, Host: addr.NewSVCUDPAppAddr(addr.SvcCS)}
+	rep, err := f.Msgr.RequestDRKeyLvl1(ctx, req, csAddr, messenger.NextId())
+	if err != nil {
+		return drkey.Lvl1Key{}, common.NewBasicError("Unable to request first-level key", err,
+			"addr", csAddr)
+	}
+	log.Trace("[DRKeyLvl1Fetcher] Received reply", "addr", csAddr, "rep", rep)
+	key, err := f.handleRep(ctx, srcIA, rep)
+	if err != nil {
+		return drkey.Lvl1Key{}, common.NewBasicError("Invalid first-level key reply", err,
+			"addr", csAddr)
+	}
+	if err := f.DB.InsertLvl1Key(ctx, key); err != nil {
+		return drkey.Lvl1Key{}, common.NewBasicError("Unable to store first-level key", err)
+	}
+	return key, nil
+}
+
+func (f *Lvl1Fetcher) buildReq(ctx context.Context, srcIA addr.IA,
+	prefetch bool) (*drkey_mgmt.Lvl1Req, error) {
+
+	chain, err := f.State.Store.GetChain(ctx, f.IA, scrypto.LatestVer)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to get local certificate chain", err)
+	}
+	req := drkey_mgmt.NewLvl1Req(srcIA, prefetch)
+	req.CertVer = uint32(chain.Leaf.Version)
+	req.TrcVer = uint32(chain.Leaf.TRCVersion)
+	req.Signature, err = scrypto.Sign(req.SignatureInput(), f.State.GetSigningKey(),
+		chain.Leaf.SignAlgorithm)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to sign request", err)
+	}
+	return req, nil
+}
+
+// handleRep verifies the reply and decrypts the contained first-level key.
+func (f *Lvl1Fetcher) handleRep(ctx context.Context, srcIA addr.IA,
+	rep *drkey_mgmt.Lvl1Rep) (drkey.Lvl1Key, error) {
+
+	if !rep.SrcIA().Equal(srcIA) {
+		return drkey.Lvl1Key{}, common.NewBasicError("Source mismatch", nil,
+			"expected", srcIA, "actual", rep.SrcIA())
+	}
+	epochBegin := rep.EpochBegin
+	if epochBegin == 0 {
+		// Certificate servers that do not set the epoch begin (e.g., the
+		// Python implementation) use epochs of the default duration.
+		epochBegin = util.TimeToSecs(util.SecsToTime(rep.ExpTime).Add(
+			-drkey.DefaultEpochDuration))
+	}
+	if epochBegin >= rep.ExpTime {
+		return drkey.Lvl1Key{}, common.NewBasicError("Invalid epoch", nil,
+			"begin", epochBegin, "end", rep.ExpTime)
+	}
+	dstChain, err := f.State.Store.GetChain(ctx, f.IA, scrypto.LatestVer)
+	if err != nil {
+		return drkey.Lvl1Key{}, common.NewBasicError("Unable to get local certificate chain", err)
+	}
+	if uint64(rep.CertVerDst) != dstChain.Leaf.Version {
+		return drkey.Lvl1Key{}, common.NewBasicError("Key encrypted for other certificate", nil,
+			"expected", dstChain.Leaf.Version, "actual", rep.CertVerDst)
+	}
+	srcChain, err := f.State.Store.GetValidChain(ctx, srcIA, uint64(rep.CertVerSrc), nil)
+	if err != nil {
+		return drkey.Lvl1Key{}, common.NewBasicError("Unable to get certificate chain", err,
+			"ia", srcIA, "ver", rep.CertVerSrc)
+	}
+	err = scrypto.Verify(rep.SignatureInput(), rep.Signature, srcChain.Leaf.SubjectSignKey,
+		srcChain.Leaf.SignAlgorithm)
+	if err != nil {
+		return drkey.Lvl1Key{}, common.NewBasicError("Invalid reply signature", err)
+	}
+	raw, err := decryptLvl1(rep.Cipher, srcChain, f.State.GetDecryptKey())
+	if err != nil {
+		return drkey.Lvl1Key{}, common.NewBasicError("Unable to decrypt first-level key", err)
+	}
+	return drkey.Lvl1Key{
+		Lvl1Meta: drkey.Lvl1Meta{
+			Epoch: drkey.NewEpoch(epochBegin, rep.ExpTime),
+			SrcIA: srcIA,
+			DstIA: f.IA,
+		},
+		Key: raw,
+	}, nil
+}
+
+var _ periodic.Task = (*Prefetcher)(nil)
+
+// Prefetcher is a periodic task that fetches the first-level keys of the next
+// epoch for all ASes that a currently valid key is stored for.
+type Prefetcher struct {
+	Fetcher *Lvl1Fetcher
+	// LeadTime indicates how long before the expiration of the current key
+	// the key of the next epoch is fetched.
+	LeadTime time.Duration
+}
+
+// Run fetches the keys of the next epoch that are not yet stored.
+func (p *Prefetcher) Run(ctx context.Context) {
+	now := time.Now()
+	ases, err := p.Fetcher.DB.GetValidLvl1SrcASes(ctx, util.TimeToSecs(now))
+	if err != nil {
+		log.Error("[DRKeyPrefetcher] Unable to get source ASes", "err", err)
+		return
+	}
+	future := util.TimeToSecs(now.Add(p.LeadTime))
+	for _, srcIA := range ases {
+		meta := drkey.Lvl1Meta{SrcIA: srcIA, DstIA: p.Fetcher.IA}
+		_, err := p.Fetcher.DB.GetLvl1Key(ctx, meta, future)
+		if err == nil {
+			continue
+		}
+		if err != sql.ErrNoRows {
+			log.Error("[DRKeyPrefetcher] Unable to query DRKey DB", "srcIA", srcIA, "err", err)
+			continue
+		}
+		if _, err := p.Fetcher.Fetch(ctx, srcIA, true); err != nil {
+			log.Error("[DRKeyPrefetcher] Unable to prefetch first-level key",
+				"srcIA", srcIA, "err", err)
+		}
+	}
+}
+
+// NewLvl1Cleaner returns a periodic task that removes expired first-level keys.
+func NewLvl1Cleaner(db drkey.Lvl1DB) *cleaner.Cleaner {
+	return cleaner.New(func(ctx context.Context) (int, error) {
+		n, err := db.RemoveOutdatedLvl1Keys(ctx, util.TimeToSecs(time.Now()))
+		return int(n), err
+	}, "drkey_lvl1")
+}
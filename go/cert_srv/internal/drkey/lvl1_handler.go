@@ -0,0 +1,173 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drkey implements the DRKey key server of the certificate server.
+//
+// The certificate server derives the secret value of its AS for every epoch
+// from the AS master key, serves first-level keys to remote certificate
+// servers and fetches (and prefetches) first-level keys from remote
+// certificate servers.
+package drkey
+
+import (
+	"context"
+	"time"
+
+	"github.com/scionproto/scion/go/cert_srv/internal/config"
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+const (
+	HandlerTimeout = 5 * time.Second
+)
+
+var _ infra.Handler = (*Lvl1ReqHandler)(nil)
+
+// Lvl1ReqHandler handles first-level DRKey requests from remote certificate
+// servers.
+//
+// The request must be signed with the signing key of the requesting AS. The
+// first-level key is encrypted with the encryption key of the requesting AS,
+// and the reply is signed with the signing key of the local AS.
+type Lvl1ReqHandler struct {
+	State *config.State
+	IA    addr.IA
+	// SVFactory derives the secret values of the local AS.
+	SVFactory *drkey.SecretValueFactory
+	// MaxReqAge is the maximum age of an acceptable request.
+	MaxReqAge time.Duration
+}
+
+func (h *Lvl1ReqHandler) Handle(r *infra.Request) *infra.HandlerResult {
+	peer, ok := r.Peer.(*snet.Addr)
+	if !ok {
+		log.Error("[DRKeyLvl1ReqHandler] Invalid peer address type",
+			"type", common.TypeOf(r.Peer))
+		return infra.MetricsErrInternal
+	}
+	req, ok := r.Message.(*drkey_mgmt.Lvl1Req)
+	if !ok {
+		log.Error("[DRKeyLvl1ReqHandler] Wrong message type",
+			"type", common.TypeOf(r.Message))
+		return infra.MetricsErrInternal
+	}
+	rw, ok := infra.ResponseWriterFromContext(r.Context())
+	if !ok {
+		log.Error("[DRKeyLvl1ReqHandler] No response writer")
+		return infra.MetricsErrInternal
+	}
+	ctx, cancelF := context.WithTimeout(r.Context(), HandlerTimeout)
+	defer cancelF()
+	log.Trace("[DRKeyLvl1ReqHandler] Received request", "addr", peer, "req", req)
+	rep, err := h.handle(ctx, peer.IA, req, time.Now())
+	if err != nil {
+		log.Error("[DRKeyLvl1ReqHandler] Dropping first-level key request",
+			"addr", peer, "req", req, "err", err)
+		return infra.MetricsErrInvalid
+	}
+	if err := rw.SendDRKeyLvl1Reply(ctx, rep); err != nil {
+		log.Error("[DRKeyLvl1ReqHandler] Unable to send reply", "addr", peer, "err", err)
+		return infra.MetricsErrMsger(err)
+	}
+	return infra.MetricsResultOk
+}
+
+// handle validates the request from dstIA and builds the reply.
+func (h *Lvl1ReqHandler) handle(ctx context.Context, dstIA addr.IA, req *drkey_mgmt.Lvl1Req,
+	now time.Time) (*drkey_mgmt.Lvl1Rep, error) {
+
+	if !req.SrcIA().Equal(h.IA) {
+		return nil, common.NewBasicError("Requested source is not this AS", nil,
+			"srcIA", req.SrcIA(), "expected", h.IA)
+	}
+	if err := validateTime(req.Time(), now, h.MaxReqAge); err != nil {
+		return nil, err
+	}
+	dstChain, err := h.State.Store.GetValidChain(ctx, dstIA, uint64(req.CertVer), nil)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to get certificate chain of requester", err,
+			"ia", dstIA, "ver", req.CertVer)
+	}
+	err = scrypto.Verify(req.SignatureInput(), req.Signature, dstChain.Leaf.SubjectSignKey,
+		dstChain.Leaf.SignAlgorithm)
+	if err != nil {
+		return nil, common.NewBasicError("Invalid request signature", err)
+	}
+	valTime := now
+	if req.Flags.Prefetch {
+		cur, err := h.SVFactory.GetSecretValue(now)
+		if err != nil {
+			return nil, err
+		}
+		valTime = cur.Epoch.End
+	}
+	sv, err := h.SVFactory.GetSecretValue(valTime)
+	if err != nil {
+		return nil, err
+	}
+	key, err := drkey.DeriveLvl1(drkey.Lvl1Meta{
+		Epoch: sv.Epoch,
+		SrcIA: h.IA,
+		DstIA: dstIA,
+	}, sv)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to derive first-level key", err)
+	}
+	cipher, err := encryptLvl1(key.Key, dstChain, h.State.GetDecryptKey())
+	if err != nil {
+		return nil, common.NewBasicError("Unable to encrypt first-level key", err)
+	}
+	srcChain, err := h.State.Store.GetChain(ctx, h.IA, scrypto.LatestVer)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to get local certificate chain", err)
+	}
+	rep := &drkey_mgmt.Lvl1Rep{
+		RawSrcIA:   h.IA.IAInt(),
+		Timestamp:  util.TimeToSecs(now),
+		EpochBegin: util.TimeToSecs(key.Epoch.Begin),
+		ExpTime:    util.TimeToSecs(key.Epoch.End),
+		Cipher:     cipher,
+		CertVerSrc: uint32(srcChain.Leaf.Version),
+		CertVerDst: uint32(dstChain.Leaf.Version),
+		TrcVer:     uint32(srcChain.Leaf.TRCVersion),
+	}
+	rep.Signature, err = scrypto.Sign(rep.SignatureInput(), h.State.GetSigningKey(),
+		srcChain.Leaf.SignAlgorithm)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to sign reply", err)
+	}
+	return rep, nil
+}
+
+// validateTime checks that the request timestamp ts is at most maxAge away
+// from now.
+func validateTime(ts, now time.Time, maxAge time.Duration) error {
+	if ts.After(now.Add(maxAge)) {
+		return common.NewBasicError("Request timestamp in the future", nil,
+			"ts", util.TimeToString(ts), "now", util.TimeToString(now))
+	}
+	if now.Sub(ts) > maxAge {
+		return common.NewBasicError("Request too old", nil,
+			"ts", util.TimeToString(ts), "maxAge", maxAge)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/snet"
+)
+
+var _ infra.Handler = (*Lvl2ReqHandler)(nil)
+
+// Lvl2ReqHandler handles second-level DRKey requests from hosts in the local AS.
+//
+// A host only obtains the keys that it is the end host of in the local AS,
+// unless it is listed in the delegation list of the protocol. Requests that
+// are older than MaxReqAge or that were already handled are dropped.
+//
+// If the local AS is the source of the requested key, the first-level key is
+// derived from the local secret value. If the local AS is the destination, the
+// first-level key is taken from the DRKey database, or fetched from the
+// certificate server of the source AS.
+type Lvl2ReqHandler struct {
+	IA addr.IA
+	// SVFactory derives the secret values of the local AS.
+	SVFactory *drkey.SecretValueFactory
+	// Fetcher provides the first-level keys of remote ASes.
+	Fetcher *Lvl1Fetcher
+	// Delegation lists, per protocol, the hosts that are authorized to obtain
+	// all second-level keys of the protocol.
+	Delegation map[string][]net.IP
+	// MaxReqAge is the maximum age of an acceptable request.
+	MaxReqAge time.Duration
+
+	replays replayCache
+}
+
+func (h *Lvl2ReqHandler) Handle(r *infra.Request) *infra.HandlerResult {
+	peer, ok := r.Peer.(*snet.Addr)
+	if !ok {
+		log.Error("[DRKeyLvl2ReqHandler] Invalid peer address type",
+			"type", common.TypeOf(r.Peer))
+		return infra.MetricsErrInternal
+	}
+	req, ok := r.Message.(*drkey_mgmt.Lvl2Req)
+	if !ok {
+		log.Error("[DRKeyLvl2ReqHandler] Wrong message type",
+			"type", common.TypeOf(r.Message))
+		return infra.MetricsErrInternal
+	}
+	rw, ok := infra.ResponseWriterFromContext(r.Context())
+	if !ok {
+		log.Error("[DRKeyLvl2ReqHandler] No response writer")
+		return infra.MetricsErrInternal
+	}
+	ctx, cancelF := context.WithTimeout(r.Context(), HandlerTimeout)
+	defer cancelF()
+	log.Trace("[DRKeyLvl2ReqHandler] Received request", "addr", peer, "req", req)
+	if !peer.IA.Equal(h.IA) {
+		log.Error("[DRKeyLvl2ReqHandler] Dropping request from remote AS", "addr", peer)
+		return infra.MetricsErrInvalid
+	}
+	now := time.Now()
+	if err := validateTime(req.Time(), now, h.MaxReqAge); err != nil {
+		log.Error("[DRKeyLvl2ReqHandler] Dropping request", "addr", peer, "err", err)
+		return infra.MetricsErrInvalid
+	}
+	// Timestamps up to MaxReqAge in the future are accepted, so a request
+	// must be remembered until its timestamp is older than MaxReqAge.
+	replayKey := fmt.Sprintf("%s %d %d", peer, r.ID, req.Timestamp)
+	if !h.replays.Add(replayKey, now, req.Time().Add(h.MaxReqAge)) {
+		log.Error("[DRKeyLvl2ReqHandler] Dropping replayed request", "addr", peer, "id", r.ID)
+		return infra.MetricsErrInvalid
+	}
+	meta, err := req.ToMeta()
+	if err != nil {
+		log.Error("[DRKeyLvl2ReqHandler] Dropping invalid request", "addr", peer, "err", err)
+		return infra.MetricsErrInvalid
+	}
+	if err := h.authorize(meta, peer.Host.L3); err != nil {
+		log.Error("[DRKeyLvl2ReqHandler] Dropping unauthorized request",
+			"addr", peer, "req", req, "err", err)
+		return infra.MetricsErrInvalid
+	}
+	key, err := h.deriveLvl2(ctx, meta, req.ValidityTime())
+	if err != nil {
+		log.Error("[DRKeyLvl2ReqHandler] Dropping second-level key request",
+			"addr", peer, "req", req, "err", err)
+		return infra.MetricsErrInvalid
+	}
+	rep := drkey_mgmt.NewLvl2RepFromKey(key, time.Now())
+	if err := rw.SendDRKeyLvl2Reply(ctx, rep); err != nil {
+		log.Error("[DRKeyLvl2ReqHandler] Unable to send reply", "addr", peer, "err", err)
+		return infra.MetricsErrMsger(err)
+	}
+	return infra.MetricsResultOk
+}
+
+// authorize checks that requester is authorized to obtain the key described
+// by meta. It must either be the end host of the key in the local AS, or be
+// listed in the delegation list of the protocol.
+func (h *Lvl2ReqHandler) authorize(meta drkey.Lvl2Meta, requester addr.HostAddr) error {
+	if requester == nil {
+		return common.NewBasicError("Unknown requester", nil)
+	}
+	for _, ip := range h.Delegation[meta.Protocol] {
+		if ip.Equal(requester.IP()) {
+			return nil
+		}
+	}
+	var endHosts []addr.HostAddr
+	if meta.KeyType == drkey.Host2Host && meta.SrcIA.Equal(h.IA) {
+		endHosts = append(endHosts, meta.SrcHost)
+	}
+	if meta.KeyType != drkey.AS2AS && meta.DstIA.Equal(h.IA) {
+		endHosts = append(endHosts, meta.DstHost)
+	}
+	for _, host := range endHosts {
+		if host != nil && host.Equal(requester) {
+			return nil
+		}
+	}
+	return common.NewBasicError("Requester is not authorized for key", nil,
+		"requester", requester, "meta", meta)
+}
+
+func (h *Lvl2ReqHandler) deriveLvl2(ctx context.Context, meta drkey.Lvl2Meta,
+	valTime time.Time) (drkey.Lvl2Key, error) {
+
+	lvl1, err := h.getLvl1Key(ctx, meta.SrcIA, meta.DstIA, valTime)
+	if err != nil {
+		return drkey.Lvl2Key{}, common.NewBasicError("Unable to get first-level key", err)
+	}
+	return drkey.DeriveLvl2(meta, lvl1)
+}
+
+func (h *Lvl2ReqHandler) getLvl1Key(ctx context.Context, srcIA, dstIA addr.IA,
+	valTime time.Time) (drkey.Lvl1Key, error) {
+
+	switch {
+	case srcIA.Equal(h.IA):
+		sv, err := h.SVFactory.GetSecretValue(valTime)
+		if err != nil {
+			return drkey.Lvl1Key{}, err
+		}
+		return drkey.DeriveLvl1(drkey.Lvl1Meta{Epoch: sv.Epoch, SrcIA: srcIA, DstIA: dstIA}, sv)
+	case dstIA.Equal(h.IA):
+		return h.Fetcher.GetLvl1Key(ctx, srcIA, valTime)
+	default:
+		return drkey.Lvl1Key{}, common.NewBasicError("Local AS is neither source nor destination",
+			nil, "srcIA", srcIA, "dstIA", dstIA)
+	}
+}
+
+// replayCache remembers handled requests until they expire. The zero value is
+// ready to use.
+type replayCache struct {
+	mutex sync.Mutex
+	seen  map[string]time.Time
+}
+
+// Add records the request identified by key until exp. It returns false if
+// the request was already recorded and did not expire yet.
+func (c *replayCache) Add(key string, now, exp time.Time) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.seen == nil {
+		c.seen = make(map[string]time.Time)
+	}
+	if prev, ok := c.seen[key]; ok && now.Before(prev) {
+		return false
+	}
+	for k, e := range c.seen {
+		if !now.Before(e) {
+			delete(c.seen, k)
+		}
+	}
+	c.seen[key] = exp
+	return true
+}
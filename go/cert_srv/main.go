@@ -25,9 +25,11 @@ import (
 	"github.com/BurntSushi/toml"
 
 	"github.com/scionproto/scion/go/cert_srv/internal/config"
+	"github.com/scionproto/scion/go/cert_srv/internal/drkey"
 	"github.com/scionproto/scion/go/cert_srv/internal/reiss"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/discovery"
+	drkeylib "github.com/scionproto/scion/go/lib/drkey"
 	"github.com/scionproto/scion/go/lib/env"
 	"github.com/scionproto/scion/go/lib/fatal"
 	"github.com/scionproto/scion/go/lib/infra"
@@ -48,6 +50,11 @@ var (
 	corePusher  *periodic.Runner
 	msgr        infra.Messenger
 	trustDB     trustdb.TrustDB
+	drkeyDB     drkeylib.Lvl1DB
+	// drkeyFetcher fetches first-level keys from remote ASes.
+	drkeyFetcher *drkey.Lvl1Fetcher
+	// drkeyRunners contains the DRKey prefetcher and cleaner.
+	drkeyRunners []*periodic.Runner
 )
 
 func init() {
@@ -82,6 +89,8 @@ func realMain() int {
 	startReissRunner()
 	// Start the periodic fetching from discovery service.
 	startDiscovery()
+	// Start the periodic DRKey tasks.
+	startDRKeyRunners()
 	// Start the messenger.
 	go func() {
 		defer log.LogPanicAndExit()
@@ -181,6 +190,26 @@ func startDiscovery() {
 	}
 }
 
+// startDRKeyRunners starts the periodic tasks that prefetch first-level keys
+// of the next epoch and remove expired first-level keys.
+func startDRKeyRunners() {
+	if !cfg.DRKey.Enabled() {
+		log.Info("DRKey disabled, not starting DRKey tasks.")
+		return
+	}
+	prefetcher := periodic.StartPeriodicTask(
+		&drkey.Prefetcher{
+			Fetcher:  drkeyFetcher,
+			LeadTime: cfg.DRKey.PrefetchLeadTime.Duration,
+		},
+		periodic.NewTicker(time.Minute),
+		time.Minute,
+	)
+	cleaner := periodic.StartPeriodicTask(drkey.NewLvl1Cleaner(drkeyDB),
+		periodic.NewTicker(time.Hour), time.Minute)
+	drkeyRunners = []*periodic.Runner{prefetcher, cleaner}
+}
+
 func stopReissRunner() {
 	if corePusher != nil {
 		corePusher.Kill()
@@ -193,6 +222,12 @@ func stopReissRunner() {
 func stop() {
 	stopReissRunner()
 	discRunners.Kill()
+	for _, r := range drkeyRunners {
+		r.Kill()
+	}
 	msgr.CloseServer()
 	trustDB.Close()
+	if drkeyDB != nil {
+		drkeyDB.Close()
+	}
 }
@@ -22,10 +22,12 @@ import (
 	"github.com/BurntSushi/toml"
 
 	"github.com/scionproto/scion/go/cert_srv/internal/config"
+	"github.com/scionproto/scion/go/cert_srv/internal/drkey"
 	"github.com/scionproto/scion/go/cert_srv/internal/metrics"
 	"github.com/scionproto/scion/go/cert_srv/internal/reiss"
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
+	drkeylib "github.com/scionproto/scion/go/lib/drkey"
 	"github.com/scionproto/scion/go/lib/env"
 	"github.com/scionproto/scion/go/lib/infra"
 	"github.com/scionproto/scion/go/lib/infra/infraenv"
@@ -113,6 +115,11 @@ func initState(cfg *config.Config, router snet.Router) error {
 	if err = setDefaultSignerVerifier(state, topo.ISD_AS); err != nil {
 		return common.NewBasicError("Unable to set default signer and verifier", err)
 	}
+	if cfg.DRKey.Enabled() {
+		if drkeyDB, err = cfg.DRKey.DRKeyDB.NewLvl1DB(); err != nil {
+			return common.NewBasicError("Unable to initialize DRKey DB", err)
+		}
+	}
 	return nil
 }
 
@@ -176,5 +183,28 @@ func setMessenger(cfg *config.Config, router snet.Router) error {
 			IA:    topo.ISD_AS,
 		})
 	}
+	if cfg.DRKey.Enabled() {
+		svFactory := drkeylib.NewSecretValueFactory(state.GetMasterKey(),
+			cfg.DRKey.EpochDuration.Duration)
+		drkeyFetcher = &drkey.Lvl1Fetcher{
+			Msgr:  msgr,
+			State: state,
+			IA:    topo.ISD_AS,
+			DB:    drkeyDB,
+		}
+		msgr.AddHandler(infra.DRKeyLvl1Request, &drkey.Lvl1ReqHandler{
+			State:     state,
+			IA:        topo.ISD_AS,
+			SVFactory: svFactory,
+			MaxReqAge: cfg.DRKey.MaxReqAge.Duration,
+		})
+		msgr.AddHandler(infra.DRKeyLvl2Request, &drkey.Lvl2ReqHandler{
+			IA:         topo.ISD_AS,
+			SVFactory:  svFactory,
+			Fetcher:    drkeyFetcher,
+			Delegation: cfg.DRKey.DelegationHosts(),
+			MaxReqAge:  cfg.DRKey.MaxReqAge.Duration,
+		})
+	}
 	return nil
 }
@@ -34,6 +34,10 @@ var (
 	// network. This is intended only for local use, and is not a recognized
 	// SCION extension.
 	ExtnE2EDebugType = ExtnType{End2EndClass, 254}
+	// ExtnHopTraceType is used to record the border routers a packet
+	// traverses, together with timestamps. Like ExtnE2EDebugType, it is
+	// intended only for local use, and is not a recognized SCION extension.
+	ExtnHopTraceType = ExtnType{HopByHopClass, 254}
 )
 
 func (e ExtnType) String() string {
@@ -52,6 +56,8 @@ func (e ExtnType) String() string {
 		return "SCIONPacketSecurity"
 	case ExtnE2EDebugType:
 		return "E2EDebug"
+	case ExtnHopTraceType:
+		return "HopTrace"
 	}
 	return fmt.Sprintf("UNKNOWN (%d)", e)
 }
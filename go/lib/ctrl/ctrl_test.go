@@ -0,0 +1,101 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctrl
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/proto"
+)
+
+func TestDRKeyMgmtPld(t *testing.T) {
+	ia := xtest.MustParseIA("1-ff00:0:111").IAInt()
+	tests := []struct {
+		Name string
+		Msg  proto.Cerealizable
+	}{
+		{
+			Name: "Lvl1Req",
+			Msg: &drkey_mgmt.Lvl1Req{
+				RawSrcIA:  ia,
+				Timestamp: 1000,
+				Signature: common.RawBytes{1, 2, 3},
+				CertVer:   2,
+				TrcVer:    3,
+				Flags:     drkey_mgmt.Lvl1ReqFlags{Prefetch: true},
+			},
+		},
+		{
+			Name: "Lvl1Rep",
+			Msg: &drkey_mgmt.Lvl1Rep{
+				RawSrcIA:   ia,
+				Timestamp:  1000,
+				ExpTime:    2000,
+				Cipher:     common.RawBytes{4, 5, 6},
+				Signature:  common.RawBytes{1, 2, 3},
+				CertVerSrc: 2,
+				CertVerDst: 3,
+				TrcVer:     4,
+				EpochBegin: 500,
+			},
+		},
+		{
+			Name: "Lvl2Req",
+			Msg: &drkey_mgmt.Lvl2Req{
+				Protocol: "scmp",
+				ReqType:  2,
+				ValTime:  1000,
+				RawSrcIA: ia,
+				RawDstIA: ia,
+				SrcHost:  drkey_mgmt.NewHost(addr.HostFromIPStr("127.0.0.1")),
+				DstHost:  drkey_mgmt.NewHost(addr.HostFromIPStr("127.0.0.2")),
+				Misc:     common.RawBytes{7},
+			},
+		},
+		{
+			Name: "Lvl2Rep",
+			Msg: &drkey_mgmt.Lvl2Rep{
+				Timestamp:  1000,
+				DRKey:      common.RawBytes{1, 2, 3, 4},
+				EpochBegin: 500,
+				EpochEnd:   2000,
+				Misc:       common.RawBytes{7},
+			},
+		},
+	}
+	Convey("DRKeyMgmt payloads survive a pack/unpack round trip", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				pld, err := NewDRKeyMgmtPld(test.Msg, nil, nil)
+				SoMsg("new err", err, ShouldBeNil)
+				raw, err := proto.PackRoot(pld)
+				SoMsg("pack err", err, ShouldBeNil)
+				other, err := NewPldFromRaw(raw)
+				SoMsg("unpack err", err, ShouldBeNil)
+				dpld, _, err := other.GetDRKeyMgmt()
+				SoMsg("drkey mgmt err", err, ShouldBeNil)
+				u, err := dpld.Union()
+				SoMsg("union err", err, ShouldBeNil)
+				SoMsg("msg", u, ShouldResemble, test.Msg)
+			})
+		}
+	})
+}
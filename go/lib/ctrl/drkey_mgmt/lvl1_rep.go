@@ -0,0 +1,93 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey_mgmt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/util"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var _ proto.Cerealizable = (*Lvl1Rep)(nil)
+
+// Lvl1Rep is the reply to a first-level DRKey request. The DRKey is encrypted
+// with the public encryption key of the requester.
+type Lvl1Rep struct {
+	// RawSrcIA is the source ISD-AS of the DRKey.
+	RawSrcIA addr.IAInt `capnp:"isdas"`
+	// Timestamp is the creation time of the reply in seconds since Unix Epoch.
+	Timestamp uint32
+	// ExpTime is the end of the validity period of the DRKey.
+	ExpTime uint32
+	// Cipher contains the encrypted DRKey.
+	Cipher common.RawBytes
+	// Signature authenticates the reply. See SignatureInput.
+	Signature common.RawBytes
+	// CertVerSrc is the version of the certificate used to create the signature.
+	CertVerSrc uint32
+	// CertVerDst is the version of the certificate that contains the public
+	// key used to encrypt the DRKey.
+	CertVerDst uint32
+	// TrcVer is the version of the TRC that verifies the signing certificate.
+	TrcVer uint32
+	// EpochBegin is the begin of the validity period of the DRKey. It is not
+	// set by all certificate servers.
+	EpochBegin uint32
+}
+
+func (c *Lvl1Rep) SrcIA() addr.IA {
+	return c.RawSrcIA.IA()
+}
+
+// Time returns the creation time of the reply.
+func (c *Lvl1Rep) Time() time.Time {
+	return util.SecsToTime(c.Timestamp)
+}
+
+// SignatureInput returns the bytes that are covered by the signature:
+// isdas (8B) | cipher | timestamp (8B) | expTime (8B) [| epochBegin (8B)].
+// The epoch begin is only part of the input if it is set, such that replies
+// of certificate servers that do not set it keep the same input.
+func (c *Lvl1Rep) SignatureInput() common.RawBytes {
+	l := addr.IABytes + len(c.Cipher) + 16
+	if c.EpochBegin != 0 {
+		l += 8
+	}
+	b := make(common.RawBytes, l)
+	c.SrcIA().Write(b)
+	off := addr.IABytes
+	off += copy(b[off:], c.Cipher)
+	common.Order.PutUint64(b[off:], uint64(c.Timestamp))
+	common.Order.PutUint64(b[off+8:], uint64(c.ExpTime))
+	if c.EpochBegin != 0 {
+		common.Order.PutUint64(b[off+16:], uint64(c.EpochBegin))
+	}
+	return b
+}
+
+func (c *Lvl1Rep) ProtoId() proto.ProtoIdType {
+	return proto.DRKeyRep_TypeID
+}
+
+func (c *Lvl1Rep) String() string {
+	return fmt.Sprintf("SrcIA: %s Timestamp: %s Epoch: [%s, %s] CertVerSrc: %d "+
+		"CertVerDst: %d TrcVer: %d", c.SrcIA(), util.TimeToString(c.Time()),
+		util.TimeToString(util.SecsToTime(c.EpochBegin)),
+		util.TimeToString(util.SecsToTime(c.ExpTime)), c.CertVerSrc, c.CertVerDst, c.TrcVer)
+}
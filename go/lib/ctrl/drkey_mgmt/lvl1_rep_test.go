@@ -0,0 +1,58 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey_mgmt
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestLvl1RepSignatureInput(t *testing.T) {
+	rep := &Lvl1Rep{
+		RawSrcIA:  xtest.MustParseIA("1-ff00:0:111").IAInt(),
+		Timestamp: 2,
+		ExpTime:   3,
+		Cipher:    common.RawBytes{0xca, 0xfe},
+	}
+	tests := []struct {
+		Name       string
+		EpochBegin uint32
+		Expected   string
+	}{
+		{
+			Name:     "without epoch begin",
+			Expected: "0001ff0000000111cafe00000000000000020000000000000003",
+		},
+		{
+			Name:       "with epoch begin",
+			EpochBegin: 1,
+			Expected: "0001ff0000000111cafe00000000000000020000000000000003" +
+				"0000000000000001",
+		},
+	}
+	Convey("The epoch begin is signed if it is set", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				rep.EpochBegin = test.EpochBegin
+				SoMsg("input", rep.SignatureInput(), ShouldResemble,
+					xtest.MustParseHexString(test.Expected))
+			})
+		}
+	})
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey_mgmt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/util"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var _ proto.Cerealizable = (*Lvl1Req)(nil)
+
+// Lvl1Req is a request for a first-level DRKey. It is sent by the CS of the
+// destination AS of the DRKey to the CS of the source AS of the DRKey.
+type Lvl1Req struct {
+	// RawSrcIA is the source ISD-AS of the requested DRKey.
+	RawSrcIA addr.IAInt `capnp:"isdas"`
+	// Timestamp is the creation time of the request in seconds since Unix Epoch.
+	Timestamp uint32
+	// Signature authenticates the request. See SignatureInput.
+	Signature common.RawBytes
+	// CertVer is the version of the certificate used to create the signature.
+	CertVer uint32
+	// TrcVer is the version of the TRC that verifies the certificate.
+	TrcVer uint32
+	Flags  Lvl1ReqFlags
+}
+
+type Lvl1ReqFlags struct {
+	// Prefetch indicates that the DRKey for the next epoch is requested.
+	Prefetch bool
+}
+
+// NewLvl1Req creates an unsigned first-level DRKey request.
+func NewLvl1Req(srcIA addr.IA, prefetch bool) *Lvl1Req {
+	return &Lvl1Req{
+		RawSrcIA:  srcIA.IAInt(),
+		Timestamp: util.TimeToSecs(time.Now()),
+		Flags:     Lvl1ReqFlags{Prefetch: prefetch},
+	}
+}
+
+func (c *Lvl1Req) SrcIA() addr.IA {
+	return c.RawSrcIA.IA()
+}
+
+// Time returns the creation time of the request.
+func (c *Lvl1Req) Time() time.Time {
+	return util.SecsToTime(c.Timestamp)
+}
+
+// SignatureInput returns the bytes that are covered by the signature:
+// isdas (8B) | prefetch (1B) | timestamp (8B).
+func (c *Lvl1Req) SignatureInput() common.RawBytes {
+	b := make(common.RawBytes, addr.IABytes+1+8)
+	c.SrcIA().Write(b)
+	if c.Flags.Prefetch {
+		b[addr.IABytes] = 1
+	}
+	common.Order.PutUint64(b[addr.IABytes+1:], uint64(c.Timestamp))
+	return b
+}
+
+func (c *Lvl1Req) ProtoId() proto.ProtoIdType {
+	return proto.DRKeyReq_TypeID
+}
+
+func (c *Lvl1Req) String() string {
+	return fmt.Sprintf("SrcIA: %s Timestamp: %s Prefetch: %v CertVer: %d TrcVer: %d",
+		c.SrcIA(), util.TimeToString(c.Time()), c.Flags.Prefetch, c.CertVer, c.TrcVer)
+}
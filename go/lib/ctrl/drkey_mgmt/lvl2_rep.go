@@ -0,0 +1,74 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey_mgmt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/util"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var _ proto.Cerealizable = (*Lvl2Rep)(nil)
+
+// Lvl2Rep is the reply to a Lvl2Req.
+type Lvl2Rep struct {
+	// Timestamp is the creation time of the reply in seconds since Unix Epoch.
+	Timestamp uint32
+	// DRKey is the derived second-level key.
+	DRKey common.RawBytes `capnp:"drkey"`
+	// EpochBegin is the begin of the validity period of the key.
+	EpochBegin uint32
+	// EpochEnd is the end of the validity period of the key.
+	EpochEnd uint32
+	// Misc contains additional protocol specific information.
+	Misc common.RawBytes
+}
+
+// NewLvl2RepFromKey constructs a level 2 reply from the key.
+func NewLvl2RepFromKey(key drkey.Lvl2Key, timestamp time.Time) *Lvl2Rep {
+	return &Lvl2Rep{
+		Timestamp:  util.TimeToSecs(timestamp),
+		DRKey:      common.RawBytes(key.Key),
+		EpochBegin: util.TimeToSecs(key.Epoch.Begin),
+		EpochEnd:   util.TimeToSecs(key.Epoch.End),
+	}
+}
+
+// ToKey returns the level 2 key with the given meta and the epoch of the reply.
+func (c *Lvl2Rep) ToKey(meta drkey.Lvl2Meta) drkey.Lvl2Key {
+	meta.Epoch = drkey.NewEpoch(c.EpochBegin, c.EpochEnd)
+	return drkey.Lvl2Key{
+		Lvl2Meta: meta,
+		Key:      drkey.DRKey(c.DRKey),
+	}
+}
+
+func (c *Lvl2Rep) Time() time.Time {
+	return util.SecsToTime(c.Timestamp)
+}
+
+func (c *Lvl2Rep) ProtoId() proto.ProtoIdType {
+	return proto.DRKeyLvl2Rep_TypeID
+}
+
+func (c *Lvl2Rep) String() string {
+	return fmt.Sprintf("Timestamp: %s Epoch: [%s, %s]", util.TimeToString(c.Time()),
+		util.TimeToString(util.SecsToTime(c.EpochBegin)),
+		util.TimeToString(util.SecsToTime(c.EpochEnd)))
+}
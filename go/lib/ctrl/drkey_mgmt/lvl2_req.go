@@ -0,0 +1,148 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey_mgmt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/util"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var _ proto.Cerealizable = (*Host)(nil)
+
+// Host represents a host address in a second-level DRKey request.
+type Host struct {
+	Type addr.HostAddrType
+	Host common.RawBytes
+}
+
+// NewHost returns a new Host from the host address. A nil address results
+// in an empty host.
+func NewHost(host addr.HostAddr) *Host {
+	if host == nil {
+		return &Host{Type: addr.HostTypeNone}
+	}
+	return &Host{Type: host.Type(), Host: host.Pack()}
+}
+
+// ToHostAddr returns the host address. For an empty host nil is returned.
+func (h *Host) ToHostAddr() (addr.HostAddr, error) {
+	if h == nil || h.Type == addr.HostTypeNone {
+		return nil, nil
+	}
+	return addr.HostFromRaw(h.Host, h.Type)
+}
+
+func (h *Host) ProtoId() proto.ProtoIdType {
+	return proto.DRKeyHost_TypeID
+}
+
+func (h *Host) String() string {
+	host, err := h.ToHostAddr()
+	if err != nil {
+		return fmt.Sprintf("Invalid host type: %s raw: %s", h.Type, h.Host)
+	}
+	return fmt.Sprintf("%v", host)
+}
+
+var _ proto.Cerealizable = (*Lvl2Req)(nil)
+
+// Lvl2Req is the request for a second-level DRKey sent to the local certificate server.
+type Lvl2Req struct {
+	// Protocol is the protocol identifier of the requested key.
+	Protocol string
+	// ReqType is the drkey.Lvl2KeyType of the requested key.
+	ReqType uint8
+	// ValTime is the point in time at which the key is valid, in seconds since Unix Epoch.
+	ValTime uint32
+	// RawSrcIA is the source ISD-AS of the requested key.
+	RawSrcIA addr.IAInt `capnp:"srcIA"`
+	// RawDstIA is the destination ISD-AS of the requested key.
+	RawDstIA addr.IAInt `capnp:"dstIA"`
+	SrcHost  *Host
+	DstHost  *Host
+	// Misc contains additional protocol specific information.
+	Misc common.RawBytes
+	// Timestamp is the creation time of the request in seconds since Unix Epoch.
+	Timestamp uint32
+}
+
+// NewLvl2ReqFromMeta constructs a level 2 request from the key meta. The epoch
+// of meta is ignored.
+func NewLvl2ReqFromMeta(meta drkey.Lvl2Meta, valTime time.Time) *Lvl2Req {
+	return &Lvl2Req{
+		Protocol:  meta.Protocol,
+		ReqType:   uint8(meta.KeyType),
+		ValTime:   util.TimeToSecs(valTime),
+		RawSrcIA:  meta.SrcIA.IAInt(),
+		RawDstIA:  meta.DstIA.IAInt(),
+		SrcHost:   NewHost(meta.SrcHost),
+		DstHost:   NewHost(meta.DstHost),
+		Timestamp: util.TimeToSecs(time.Now()),
+	}
+}
+
+// ToMeta returns the key meta of the requested key. The epoch is not set.
+func (c *Lvl2Req) ToMeta() (drkey.Lvl2Meta, error) {
+	srcHost, err := c.SrcHost.ToHostAddr()
+	if err != nil {
+		return drkey.Lvl2Meta{}, common.NewBasicError("Invalid source host", err)
+	}
+	dstHost, err := c.DstHost.ToHostAddr()
+	if err != nil {
+		return drkey.Lvl2Meta{}, common.NewBasicError("Invalid destination host", err)
+	}
+	return drkey.Lvl2Meta{
+		KeyType:  drkey.Lvl2KeyType(c.ReqType),
+		Protocol: c.Protocol,
+		SrcIA:    c.SrcIA(),
+		DstIA:    c.DstIA(),
+		SrcHost:  srcHost,
+		DstHost:  dstHost,
+	}, nil
+}
+
+func (c *Lvl2Req) SrcIA() addr.IA {
+	return c.RawSrcIA.IA()
+}
+
+func (c *Lvl2Req) DstIA() addr.IA {
+	return c.RawDstIA.IA()
+}
+
+func (c *Lvl2Req) ValidityTime() time.Time {
+	return util.SecsToTime(c.ValTime)
+}
+
+// Time returns the creation time of the request.
+func (c *Lvl2Req) Time() time.Time {
+	return util.SecsToTime(c.Timestamp)
+}
+
+func (c *Lvl2Req) ProtoId() proto.ProtoIdType {
+	return proto.DRKeyLvl2Req_TypeID
+}
+
+func (c *Lvl2Req) String() string {
+	return fmt.Sprintf("KeyType: %s Protocol: %q SrcIA: %s DstIA: %s SrcHost: %s "+
+		"DstHost: %s ValTime: %s Timestamp: %s", drkey.Lvl2KeyType(c.ReqType), c.Protocol,
+		c.SrcIA(), c.DstIA(), c.SrcHost, c.DstHost, util.TimeToString(c.ValidityTime()),
+		util.TimeToString(c.Time()))
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"context"
+	"io"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/infra/modules/db"
+)
+
+// Lvl1DB is the interface of a level 1 DRKey database.
+type Lvl1DB interface {
+	// GetLvl1Key returns the level 1 key that matches the source and
+	// destination of meta and is valid at valTime (in seconds since the Unix epoch).
+	GetLvl1Key(ctx context.Context, meta Lvl1Meta, valTime uint32) (Lvl1Key, error)
+	// InsertLvl1Key inserts the key. Inserting an already existing key is a no-op.
+	InsertLvl1Key(ctx context.Context, key Lvl1Key) error
+	// RemoveOutdatedLvl1Keys removes all keys that expired before cutoff and
+	// returns the number of removed keys.
+	RemoveOutdatedLvl1Keys(ctx context.Context, cutoff uint32) (int64, error)
+	// GetLvl1SrcASes returns all source ASes of the stored keys.
+	GetLvl1SrcASes(ctx context.Context) ([]addr.IA, error)
+	// GetValidLvl1SrcASes returns all source ASes for which a key valid at
+	// valTime is stored.
+	GetValidLvl1SrcASes(ctx context.Context, valTime uint32) ([]addr.IA, error)
+	io.Closer
+	db.LimitSetter
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+const (
+	// KeyLength is the length of a DRKey in bytes.
+	KeyLength = 16
+
+	// MaxProtocolLength is the maximum length of a protocol identifier.
+	MaxProtocolLength = 255
+
+	svSalt       = "Derive DRKey SV"
+	svIterations = 1000
+)
+
+// DeriveSV constructs a secret value for the given epoch from the AS master
+// secret. The derivation is deterministic, so that all certificate servers of
+// an AS derive the same secret value without coordination.
+func DeriveSV(meta SVMeta, asSecret common.RawBytes) (SV, error) {
+	if len(asSecret) == 0 {
+		return SV{}, common.NewBasicError("Empty AS secret", nil)
+	}
+	salt := make([]byte, len(svSalt)+8)
+	n := copy(salt, svSalt)
+	binary.BigEndian.PutUint32(salt[n:], util.TimeToSecs(meta.Epoch.Begin))
+	binary.BigEndian.PutUint32(salt[n+4:], util.TimeToSecs(meta.Epoch.End))
+	key := pbkdf2.Key(asSecret, salt, svIterations, KeyLength, sha256.New)
+	return SV{SVMeta: meta, Key: DRKey(key)}, nil
+}
+
+// DeriveLvl1 constructs a new level 1 DRKey K_{SrcIA->DstIA} from the secret
+// value of the source AS.
+//
+// The input is ISD (4B) | AS (4B), padded with zeros to a full block. For AS
+// numbers that fit into 4B, this is the same input as in the Python
+// implementation. The Python implementation cannot derive keys for larger AS
+// numbers; for these, the upper 16 bits are encoded in the first 2B of the
+// padding.
+func DeriveLvl1(meta Lvl1Meta, sv SV) (Lvl1Key, error) {
+	if !meta.Epoch.Equal(sv.Epoch) {
+		return Lvl1Key{}, common.NewBasicError("Epoch mismatch between meta and SV", nil,
+			"meta", meta.Epoch, "sv", sv.Epoch)
+	}
+	mac, err := scrypto.InitMac(common.RawBytes(sv.Key))
+	if err != nil {
+		return Lvl1Key{}, err
+	}
+	mac.Write(lvl1Input(meta.DstIA))
+	return Lvl1Key{Lvl1Meta: meta, Key: DRKey(mac.Sum(nil))}, nil
+}
+
+// DeriveLvl2 constructs a new level 2 DRKey from the level 1 key K_{SrcIA->DstIA}
+// according to the key type of meta:
+//
+// AS2AS: K_{A->B}^{p} = PRF_{K_{A->B}}(p)
+//
+// AS2Host: K_{A->B:H_B}^{p} = PRF_{K_{A->B}}(p | H_B)
+//
+// Host2Host: K_{A:H_A->B:H_B}^{p} = PRF_{K_{A->B}}(p | H_A | H_B)
+//
+// The input is encoded as described in doc/DRKeyInfra.md.
+func DeriveLvl2(meta Lvl2Meta, key Lvl1Key) (Lvl2Key, error) {
+	if !meta.SrcIA.Equal(key.SrcIA) || !meta.DstIA.Equal(key.DstIA) {
+		return Lvl2Key{}, common.NewBasicError("ISD-AS mismatch between meta and lvl1 key",
+			nil, "meta", meta, "lvl1", key.Lvl1Meta)
+	}
+	input, err := lvl2Input(meta)
+	if err != nil {
+		return Lvl2Key{}, err
+	}
+	mac, err := scrypto.InitMac(common.RawBytes(key.Key))
+	if err != nil {
+		return Lvl2Key{}, err
+	}
+	mac.Write(input)
+	meta.Epoch = key.Epoch
+	return Lvl2Key{Lvl2Meta: meta, Key: DRKey(mac.Sum(nil))}, nil
+}
+
+func lvl1Input(dstIA addr.IA) []byte {
+	input := make([]byte, KeyLength)
+	binary.BigEndian.PutUint32(input, uint32(dstIA.I))
+	binary.BigEndian.PutUint32(input[4:], uint32(dstIA.A))
+	binary.BigEndian.PutUint16(input[8:], uint16(dstIA.A>>32))
+	return input
+}
+
+// lvl2Input encodes the input of the level 2 derivation:
+//
+// AS2AS: protoLen (1B) | protocol | keyType (1B)
+//
+// AS2Host: protoLen (1B) | protocol | keyType (1B) | dstHostLen (1B) | dstHost
+//
+// Host2Host: protoLen (1B) | protocol | keyType (1B) | srcHostLen (1B) |
+// dstHostLen (1B) | srcHost | dstHost
+func lvl2Input(meta Lvl2Meta) ([]byte, error) {
+	if len(meta.Protocol) == 0 || len(meta.Protocol) > MaxProtocolLength {
+		return nil, common.NewBasicError("Invalid protocol length", nil,
+			"len", len(meta.Protocol))
+	}
+	input := []byte{byte(len(meta.Protocol))}
+	input = append(input, meta.Protocol...)
+	input = append(input, byte(meta.KeyType))
+	switch meta.KeyType {
+	case AS2AS:
+		return input, nil
+	case AS2Host:
+		dst, err := packHost(meta.DstHost)
+		if err != nil {
+			return nil, err
+		}
+		input = append(input, byte(len(dst)))
+		return append(input, dst...), nil
+	case Host2Host:
+		src, err := packHost(meta.SrcHost)
+		if err != nil {
+			return nil, err
+		}
+		dst, err := packHost(meta.DstHost)
+		if err != nil {
+			return nil, err
+		}
+		input = append(input, byte(len(src)), byte(len(dst)))
+		input = append(input, src...)
+		return append(input, dst...), nil
+	default:
+		return nil, common.NewBasicError("Unknown key type", nil, "type", meta.KeyType)
+	}
+}
+
+func packHost(host addr.HostAddr) ([]byte, error) {
+	if host == nil {
+		return nil, common.NewBasicError("Host address required", nil)
+	}
+	return host.Pack(), nil
+}
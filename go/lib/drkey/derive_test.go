@@ -0,0 +1,211 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestEpochAt(t *testing.T) {
+	Convey("EpochAt aligns epochs to the duration", t, func() {
+		e := EpochAt(time.Unix(90000, 0), 24*time.Hour)
+		SoMsg("begin", e.Begin.Unix(), ShouldEqual, 86400)
+		SoMsg("end", e.End.Unix(), ShouldEqual, 172800)
+		SoMsg("contains", e.Contains(time.Unix(90000, 0)), ShouldBeTrue)
+		SoMsg("contains begin", e.Contains(e.Begin), ShouldBeTrue)
+		SoMsg("excludes end", e.Contains(e.End), ShouldBeFalse)
+	})
+}
+
+func TestDeriveSV(t *testing.T) {
+	asSecret := common.RawBytes{0, 1, 2, 3, 4, 5, 6, 7}
+	Convey("DeriveSV", t, func() {
+		meta := SVMeta{Epoch: NewEpoch(0, 1)}
+		sv, err := DeriveSV(meta, asSecret)
+		SoMsg("err", err, ShouldBeNil)
+		expected := DRKey(xtest.MustParseHexString("591e3f41169df69400dcea5666fa2c14"))
+		SoMsg("key", sv.Key, ShouldResemble, expected)
+		Convey("is deterministic", func() {
+			other, err := DeriveSV(meta, asSecret)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("equal", other.Equal(sv), ShouldBeTrue)
+		})
+		Convey("differs between epochs", func() {
+			other, err := DeriveSV(SVMeta{Epoch: NewEpoch(1, 2)}, asSecret)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("key", other.Key.Equal(sv.Key), ShouldBeFalse)
+		})
+		Convey("fails with empty secret", func() {
+			_, err := DeriveSV(meta, nil)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestDeriveLvl1(t *testing.T) {
+	epoch := NewEpoch(0, 1)
+	sv := SV{
+		SVMeta: SVMeta{Epoch: epoch},
+		Key:    DRKey(xtest.MustParseHexString("591e3f41169df69400dcea5666fa2c14")),
+	}
+	// The expected keys are AES-CMAC of the inputs computed with OpenSSL. The
+	// input of the BGP AS is the same as the input of derive_drkey_raw in
+	// python/lib/drkey/suite.py, which cannot encode 48-bit AS numbers.
+	tests := []struct {
+		Name     string
+		DstIA    string
+		Input    string
+		Expected string
+	}{
+		{
+			Name:     "BGP AS",
+			DstIA:    "1-64512",
+			Input:    "000000010000fc000000000000000000",
+			Expected: "72e5602f042b38870944210b29ea9187",
+		},
+		{
+			Name:     "48-bit AS (not supported by Python)",
+			DstIA:    "1-ff00:0:112",
+			Input:    "0000000100000112ff00000000000000",
+			Expected: "14ad9c02c967beea079b36e79f572613",
+		},
+	}
+	Convey("DeriveLvl1", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				meta := Lvl1Meta{
+					Epoch: epoch,
+					SrcIA: xtest.MustParseIA("1-ff00:0:111"),
+					DstIA: xtest.MustParseIA(test.DstIA),
+				}
+				SoMsg("input", common.RawBytes(lvl1Input(meta.DstIA)), ShouldResemble,
+					xtest.MustParseHexString(test.Input))
+				key, err := DeriveLvl1(meta, sv)
+				SoMsg("err", err, ShouldBeNil)
+				SoMsg("key", key.Key, ShouldResemble,
+					DRKey(xtest.MustParseHexString(test.Expected)))
+				SoMsg("meta", key.Lvl1Meta, ShouldResemble, meta)
+			})
+		}
+		Convey("fails on epoch mismatch", func() {
+			meta := Lvl1Meta{
+				Epoch: NewEpoch(1, 2),
+				SrcIA: xtest.MustParseIA("1-ff00:0:111"),
+				DstIA: xtest.MustParseIA("1-ff00:0:112"),
+			}
+			_, err := DeriveLvl1(meta, sv)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestDeriveLvl2(t *testing.T) {
+	lvl1 := Lvl1Key{
+		Lvl1Meta: Lvl1Meta{
+			Epoch: NewEpoch(0, 1),
+			SrcIA: xtest.MustParseIA("1-ff00:0:111"),
+			DstIA: xtest.MustParseIA("1-ff00:0:112"),
+		},
+		Key: DRKey(xtest.MustParseHexString("3d3650ec33967db70ba774de7140f6f5")),
+	}
+	srcHost := addr.HostFromIPStr("127.0.0.1")
+	dstHost := addr.HostFromIPStr("127.0.0.2")
+	// The inputs follow the encoding in doc/DRKeyInfra.md, the expected keys
+	// are AES-CMAC of the inputs computed with OpenSSL.
+	tests := []struct {
+		Name     string
+		KeyType  Lvl2KeyType
+		Input    string
+		Expected string
+	}{
+		{
+			Name:     "AS2AS",
+			KeyType:  AS2AS,
+			Input:    "0473636d7000",
+			Expected: "71d6c74635658a0cdfc6150855592b41",
+		},
+		{
+			Name:     "AS2Host",
+			KeyType:  AS2Host,
+			Input:    "0473636d7001047f000002",
+			Expected: "e328a2b10f12b5e31e74392e70924444",
+		},
+		{
+			Name:     "Host2Host",
+			KeyType:  Host2Host,
+			Input:    "0473636d700204047f0000017f000002",
+			Expected: "b6c2148ef248f7c75deb42eac972a8a3",
+		},
+	}
+	Convey("DeriveLvl2", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				meta := Lvl2Meta{
+					KeyType:  test.KeyType,
+					Protocol: "scmp",
+					SrcIA:    lvl1.SrcIA,
+					DstIA:    lvl1.DstIA,
+					SrcHost:  srcHost,
+					DstHost:  dstHost,
+				}
+				input, err := lvl2Input(meta)
+				SoMsg("input err", err, ShouldBeNil)
+				SoMsg("input", common.RawBytes(input), ShouldResemble,
+					xtest.MustParseHexString(test.Input))
+				key, err := DeriveLvl2(meta, lvl1)
+				SoMsg("err", err, ShouldBeNil)
+				SoMsg("key", key.Key, ShouldResemble,
+					DRKey(xtest.MustParseHexString(test.Expected)))
+				SoMsg("epoch", key.Epoch, ShouldResemble, lvl1.Epoch)
+			})
+		}
+		Convey("fails without host", func() {
+			meta := Lvl2Meta{
+				KeyType:  AS2Host,
+				Protocol: "scmp",
+				SrcIA:    lvl1.SrcIA,
+				DstIA:    lvl1.DstIA,
+			}
+			_, err := DeriveLvl2(meta, lvl1)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("fails on ISD-AS mismatch", func() {
+			meta := Lvl2Meta{
+				KeyType:  AS2AS,
+				Protocol: "scmp",
+				SrcIA:    lvl1.DstIA,
+				DstIA:    lvl1.SrcIA,
+			}
+			_, err := DeriveLvl2(meta, lvl1)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("fails without protocol", func() {
+			meta := Lvl2Meta{
+				KeyType: AS2AS,
+				SrcIA:   lvl1.SrcIA,
+				DstIA:   lvl1.DstIA,
+			}
+			_, err := DeriveLvl2(meta, lvl1)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drkey contains the types and the key derivation functions of the
+// dynamically recreatable key (DRKey) infrastructure.
+//
+// Every AS A derives a secret value SV_A per epoch from its master key. The
+// first-level key K_{A->B} between A and B is derived from SV_A and the
+// address of B, and is fetched by the certificate server of B from the
+// certificate server of A. See doc/DRKeyInfra.md for the full design.
+package drkey
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+// Epoch represents a validity period of a DRKey.
+type Epoch struct {
+	Begin time.Time
+	End   time.Time
+}
+
+// NewEpoch constructs an Epoch from its begin and end times (in seconds since the Unix epoch).
+func NewEpoch(begin, end uint32) Epoch {
+	return Epoch{
+		Begin: util.SecsToTime(begin),
+		End:   util.SecsToTime(end),
+	}
+}
+
+// EpochAt returns the epoch of length d that contains t. Epochs are aligned to
+// the Unix epoch, such that every AS using the same duration agrees on the
+// epoch boundaries.
+func EpochAt(t time.Time, d time.Duration) Epoch {
+	secs := int64(d / time.Second)
+	begin := t.Unix() - t.Unix()%secs
+	return Epoch{
+		Begin: time.Unix(begin, 0),
+		End:   time.Unix(begin+secs, 0),
+	}
+}
+
+// Contains indicates whether the time point is inside this Epoch.
+func (e Epoch) Contains(t time.Time) bool {
+	return !t.Before(e.Begin) && t.Before(e.End)
+}
+
+// Equal returns true if both epochs have the same begin and end times.
+func (e Epoch) Equal(other Epoch) bool {
+	return e.Begin.Equal(other.Begin) && e.End.Equal(other.End)
+}
+
+func (e Epoch) String() string {
+	return fmt.Sprintf("[%s, %s)", util.TimeToString(e.Begin), util.TimeToString(e.End))
+}
+
+// DRKey represents a raw binary key.
+type DRKey common.RawBytes
+
+// Equal returns true if both keys are identical.
+func (k DRKey) Equal(other DRKey) bool {
+	return bytes.Equal(k, other)
+}
+
+// String does not reveal the key material.
+func (k DRKey) String() string {
+	return "[redacted key]"
+}
+
+// SVMeta represents the information about a DRKey secret value.
+type SVMeta struct {
+	Epoch Epoch
+}
+
+// SV represents a DRKey secret value.
+type SV struct {
+	SVMeta
+	Key DRKey
+}
+
+// Equal returns true if both secret values are identical.
+func (sv SV) Equal(other SV) bool {
+	return sv.Epoch.Equal(other.Epoch) && sv.Key.Equal(other.Key)
+}
+
+// Lvl1Meta represents the information about a level 1 DRKey other than the key itself.
+type Lvl1Meta struct {
+	Epoch Epoch
+	SrcIA addr.IA
+	DstIA addr.IA
+}
+
+// Equal returns true if both meta are identical.
+func (m Lvl1Meta) Equal(other Lvl1Meta) bool {
+	return m.Epoch.Equal(other.Epoch) && m.SrcIA.Equal(other.SrcIA) &&
+		m.DstIA.Equal(other.DstIA)
+}
+
+func (m Lvl1Meta) String() string {
+	return fmt.Sprintf("%s->%s %s", m.SrcIA, m.DstIA, m.Epoch)
+}
+
+// Lvl1Key represents a level 1 DRKey.
+type Lvl1Key struct {
+	Lvl1Meta
+	Key DRKey
+}
+
+// Equal returns true if both level 1 keys are identical.
+func (k Lvl1Key) Equal(other Lvl1Key) bool {
+	return k.Lvl1Meta.Equal(other.Lvl1Meta) && k.Key.Equal(other.Key)
+}
@@ -0,0 +1,186 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drkeydbsqlite implements the drkey.Lvl1DB interface with a sqlite backed DB.
+package drkeydbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/infra/modules/db"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+const (
+	Path          = "drkeyDB.sqlite3"
+	SchemaVersion = 1
+	Schema        = `
+	CREATE TABLE DRKeyLvl1 (
+		SrcIsdID INTEGER NOT NULL,
+		SrcAsID INTEGER NOT NULL,
+		DstIsdID INTEGER NOT NULL,
+		DstAsID INTEGER NOT NULL,
+		EpochBegin INTEGER NOT NULL,
+		EpochEnd INTEGER NOT NULL,
+		Key BLOB NOT NULL,
+		PRIMARY KEY (SrcIsdID, SrcAsID, DstIsdID, DstAsID, EpochBegin)
+	);
+	`
+)
+
+const (
+	getLvl1KeyStr = `
+			SELECT EpochBegin, EpochEnd, Key FROM DRKeyLvl1
+			WHERE SrcIsdID=? AND SrcAsID=? AND DstIsdID=? AND DstAsID=?
+			AND EpochBegin<=? AND ?<EpochEnd
+		`
+	insertLvl1KeyStr = `
+			INSERT OR IGNORE INTO DRKeyLvl1 (SrcIsdID, SrcAsID, DstIsdID, DstAsID,
+			EpochBegin, EpochEnd, Key)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`
+	removeOutdatedLvl1KeysStr = `
+			DELETE FROM DRKeyLvl1 WHERE ? >= EpochEnd
+		`
+	getLvl1SrcASesStr = `
+			SELECT DISTINCT SrcIsdID, SrcAsID FROM DRKeyLvl1
+		`
+	getValidLvl1SrcASesStr = `
+			SELECT DISTINCT SrcIsdID, SrcAsID FROM DRKeyLvl1
+			WHERE EpochBegin<=? AND ?<EpochEnd
+		`
+)
+
+var _ drkey.Lvl1DB = (*Backend)(nil)
+
+// Backend implements a level 1 DRKey DB with sqlite.
+type Backend struct {
+	sync.RWMutex
+	db *sql.DB
+}
+
+// New creates a database and initializes the schema if necessary.
+func New(path string) (*Backend, error) {
+	sqlDB, err := db.NewSqlite(path, Schema, SchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{db: sqlDB}, nil
+}
+
+func (b *Backend) SetMaxOpenConns(maxOpenConns int) {
+	b.db.SetMaxOpenConns(maxOpenConns)
+}
+
+func (b *Backend) SetMaxIdleConns(maxIdleConns int) {
+	b.db.SetMaxIdleConns(maxIdleConns)
+}
+
+// Close closes the database connection.
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
+
+// GetLvl1Key returns the level 1 key for the source and destination of meta
+// that is valid at valTime. If no such key exists, sql.ErrNoRows is returned.
+func (b *Backend) GetLvl1Key(ctx context.Context, meta drkey.Lvl1Meta,
+	valTime uint32) (drkey.Lvl1Key, error) {
+
+	b.RLock()
+	defer b.RUnlock()
+	var epochBegin, epochEnd uint32
+	var key []byte
+	err := b.db.QueryRowContext(ctx, getLvl1KeyStr, meta.SrcIA.I, meta.SrcIA.A,
+		meta.DstIA.I, meta.DstIA.A, valTime, valTime).Scan(&epochBegin, &epochEnd, &key)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			err = db.NewReadError("getting lvl1 key", err)
+		}
+		return drkey.Lvl1Key{}, err
+	}
+	return drkey.Lvl1Key{
+		Lvl1Meta: drkey.Lvl1Meta{
+			Epoch: drkey.NewEpoch(epochBegin, epochEnd),
+			SrcIA: meta.SrcIA,
+			DstIA: meta.DstIA,
+		},
+		Key: drkey.DRKey(key),
+	}, nil
+}
+
+// InsertLvl1Key inserts a level 1 key. Inserting an existing key is a no-op.
+func (b *Backend) InsertLvl1Key(ctx context.Context, key drkey.Lvl1Key) error {
+	b.Lock()
+	defer b.Unlock()
+	_, err := b.db.ExecContext(ctx, insertLvl1KeyStr, key.SrcIA.I, key.SrcIA.A,
+		key.DstIA.I, key.DstIA.A, util.TimeToSecs(key.Epoch.Begin),
+		util.TimeToSecs(key.Epoch.End), []byte(key.Key))
+	if err != nil {
+		return db.NewWriteError("inserting lvl1 key", err)
+	}
+	return nil
+}
+
+// RemoveOutdatedLvl1Keys removes all keys that expired at or before cutoff.
+func (b *Backend) RemoveOutdatedLvl1Keys(ctx context.Context, cutoff uint32) (int64, error) {
+	b.Lock()
+	defer b.Unlock()
+	res, err := b.db.ExecContext(ctx, removeOutdatedLvl1KeysStr, cutoff)
+	if err != nil {
+		return 0, db.NewWriteError("removing outdated lvl1 keys", err)
+	}
+	return res.RowsAffected()
+}
+
+// GetLvl1SrcASes returns all source ASes of the stored keys.
+func (b *Backend) GetLvl1SrcASes(ctx context.Context) ([]addr.IA, error) {
+	b.RLock()
+	defer b.RUnlock()
+	return b.queryIAs(ctx, getLvl1SrcASesStr)
+}
+
+// GetValidLvl1SrcASes returns all source ASes that have a key valid at valTime.
+func (b *Backend) GetValidLvl1SrcASes(ctx context.Context, valTime uint32) ([]addr.IA, error) {
+	b.RLock()
+	defer b.RUnlock()
+	return b.queryIAs(ctx, getValidLvl1SrcASesStr, valTime, valTime)
+}
+
+func (b *Backend) queryIAs(ctx context.Context, query string,
+	args ...interface{}) ([]addr.IA, error) {
+
+	rows, err := b.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, db.NewReadError("querying source ASes", err)
+	}
+	defer rows.Close()
+	var ias []addr.IA
+	for rows.Next() {
+		var ia addr.IA
+		if err := rows.Scan(&ia.I, &ia.A); err != nil {
+			return nil, db.NewReadError("scanning source AS", err)
+		}
+		ias = append(ias, ia)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, db.NewReadError("iterating source ASes", err)
+	}
+	return ias, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkeydbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+var (
+	ctxTimeout = time.Second
+)
+
+func TestLvl1Keys(t *testing.T) {
+	Convey("Lvl1 keys can be inserted, queried and removed", t, func() {
+		ctx, cancelF := context.WithTimeout(context.Background(), ctxTimeout)
+		defer cancelF()
+		db := newDatabase(t)
+		defer db.Close()
+		srcIA := xtest.MustParseIA("1-ff00:0:111")
+		dstIA := xtest.MustParseIA("1-ff00:0:112")
+		key := drkey.Lvl1Key{
+			Lvl1Meta: drkey.Lvl1Meta{
+				Epoch: drkey.NewEpoch(10, 20),
+				SrcIA: srcIA,
+				DstIA: dstIA,
+			},
+			Key: drkey.DRKey(xtest.MustParseHexString("c584cad32613547c64823c756651b6f5")),
+		}
+		err := db.InsertLvl1Key(ctx, key)
+		SoMsg("Insert err", err, ShouldBeNil)
+		// Inserting twice is a no-op.
+		err = db.InsertLvl1Key(ctx, key)
+		SoMsg("Insert twice err", err, ShouldBeNil)
+
+		newKey, err := db.GetLvl1Key(ctx, key.Lvl1Meta, 10)
+		SoMsg("Get err", err, ShouldBeNil)
+		SoMsg("Get key", newKey.Equal(key), ShouldBeTrue)
+		_, err = db.GetLvl1Key(ctx, key.Lvl1Meta, 20)
+		SoMsg("Get expired err", err, ShouldEqual, sql.ErrNoRows)
+
+		ases, err := db.GetLvl1SrcASes(ctx)
+		SoMsg("SrcASes err", err, ShouldBeNil)
+		SoMsg("SrcASes", ases, ShouldResemble, []addr.IA{srcIA})
+		ases, err = db.GetValidLvl1SrcASes(ctx, 25)
+		SoMsg("ValidSrcASes err", err, ShouldBeNil)
+		SoMsg("ValidSrcASes", ases, ShouldBeEmpty)
+
+		n, err := db.RemoveOutdatedLvl1Keys(ctx, 19)
+		SoMsg("Remove not outdated err", err, ShouldBeNil)
+		SoMsg("Remove not outdated count", n, ShouldEqual, 0)
+		n, err = db.RemoveOutdatedLvl1Keys(ctx, 20)
+		SoMsg("Remove err", err, ShouldBeNil)
+		SoMsg("Remove count", n, ShouldEqual, 1)
+		_, err = db.GetLvl1Key(ctx, key.Lvl1Meta, 10)
+		SoMsg("Get removed err", err, ShouldEqual, sql.ErrNoRows)
+	})
+}
+
+func newDatabase(t *testing.T) *Backend {
+	db, err := New(":memory:")
+	xtest.FailOnErr(t, err)
+	return db
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"fmt"
+
+	"github.com/scionproto/scion/go/lib/addr"
+)
+
+// Lvl2KeyType represents the different types of level 2 DRKeys (AS->AS, AS->host, host->host).
+type Lvl2KeyType uint8
+
+const (
+	// AS2AS is the key type of K_{A->B}^{p}.
+	AS2AS Lvl2KeyType = iota
+	// AS2Host is the key type of K_{A->B:H_B}^{p}.
+	AS2Host
+	// Host2Host is the key type of K_{A:H_A->B:H_B}^{p}.
+	Host2Host
+)
+
+func (t Lvl2KeyType) String() string {
+	switch t {
+	case AS2AS:
+		return "AS2AS"
+	case AS2Host:
+		return "AS2Host"
+	case Host2Host:
+		return "Host2Host"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", uint8(t))
+	}
+}
+
+// Lvl2Meta represents the information about a level 2 DRKey other than the key itself.
+type Lvl2Meta struct {
+	KeyType  Lvl2KeyType
+	Protocol string
+	Epoch    Epoch
+	SrcIA    addr.IA
+	DstIA    addr.IA
+	SrcHost  addr.HostAddr
+	DstHost  addr.HostAddr
+}
+
+// Equal returns true if both meta are identical.
+func (m Lvl2Meta) Equal(other Lvl2Meta) bool {
+	return m.KeyType == other.KeyType && m.Protocol == other.Protocol &&
+		m.Epoch.Equal(other.Epoch) && m.SrcIA.Equal(other.SrcIA) &&
+		m.DstIA.Equal(other.DstIA) && hostEqual(m.SrcHost, other.SrcHost) &&
+		hostEqual(m.DstHost, other.DstHost)
+}
+
+func (m Lvl2Meta) String() string {
+	return fmt.Sprintf("%s %q %s:%s->%s:%s %s", m.KeyType, m.Protocol, m.SrcIA, m.SrcHost,
+		m.DstIA, m.DstHost, m.Epoch)
+}
+
+// Lvl2Key represents a level 2 DRKey.
+type Lvl2Key struct {
+	Lvl2Meta
+	Key DRKey
+}
+
+// Equal returns true if both level 2 keys are identical.
+func (k Lvl2Key) Equal(other Lvl2Key) bool {
+	return k.Lvl2Meta.Equal(other.Lvl2Meta) && k.Key.Equal(other.Key)
+}
+
+func hostEqual(a, b addr.HostAddr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+// DefaultEpochDuration is the default duration of a DRKey epoch.
+const DefaultEpochDuration = 24 * time.Hour
+
+// SecretValueFactory derives the secret values of the local AS from the AS
+// master key.
+type SecretValueFactory struct {
+	masterKey     common.RawBytes
+	epochDuration time.Duration
+	mutex         sync.Mutex
+	cache         map[int64]SV
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+}
+
+// NewSecretValueFactory returns a factory that derives secret values with
+// epochs of the given duration from the master key.
+func NewSecretValueFactory(masterKey common.RawBytes,
+	epochDuration time.Duration) *SecretValueFactory {
+
+	return &SecretValueFactory{
+		masterKey:     masterKey,
+		epochDuration: epochDuration,
+		cache:         make(map[int64]SV),
+		now:           time.Now,
+	}
+}
+
+// GetSecretValue returns the secret value for the epoch that contains t.
+func (f *SecretValueFactory) GetSecretValue(t time.Time) (SV, error) {
+	epoch := EpochAt(t, f.epochDuration)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if sv, ok := f.cache[epoch.Begin.Unix()]; ok {
+		return sv, nil
+	}
+	sv, err := DeriveSV(SVMeta{Epoch: epoch}, f.masterKey)
+	if err != nil {
+		return SV{}, common.NewBasicError("Unable to derive secret value", err)
+	}
+	// Only the secret values of the current and the next epoch are cached.
+	// Requests for other epochs must not evict them.
+	current := EpochAt(f.now(), f.epochDuration)
+	for begin, cached := range f.cache {
+		if !cached.Epoch.End.After(current.Begin) {
+			delete(f.cache, begin)
+		}
+	}
+	if epoch.Begin.Equal(current.Begin) || epoch.Begin.Equal(current.End) {
+		f.cache[epoch.Begin.Unix()] = sv
+	}
+	return sv, nil
+}
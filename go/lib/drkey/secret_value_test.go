@@ -0,0 +1,64 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+func TestSecretValueFactory(t *testing.T) {
+	Convey("GetSecretValue", t, func() {
+		f := NewSecretValueFactory(common.RawBytes{0, 1, 2, 3, 4, 5, 6, 7}, time.Hour)
+		now := time.Unix(10*3600+5, 0)
+		f.now = func() time.Time { return now }
+		sv, err := f.GetSecretValue(now)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("begin", sv.Epoch.Begin.Unix(), ShouldEqual, 10*3600)
+		SoMsg("end", sv.Epoch.End.Unix(), ShouldEqual, 11*3600)
+		Convey("returns the same value within the epoch", func() {
+			other, err := f.GetSecretValue(now.Add(30 * time.Minute))
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("equal", other.Equal(sv), ShouldBeTrue)
+		})
+		Convey("returns a different value in the next epoch", func() {
+			next, err := f.GetSecretValue(sv.Epoch.End)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("begin", next.Epoch.Begin, ShouldResemble, sv.Epoch.End)
+			SoMsg("key", next.Key.Equal(sv.Key), ShouldBeFalse)
+			SoMsg("current kept", f.cache, ShouldContainKey, sv.Epoch.Begin.Unix())
+			SoMsg("next cached", f.cache, ShouldContainKey, next.Epoch.Begin.Unix())
+		})
+		Convey("does not cache other epochs", func() {
+			_, err := f.GetSecretValue(now.Add(-2 * time.Hour))
+			SoMsg("err", err, ShouldBeNil)
+			_, err = f.GetSecretValue(now.Add(5 * time.Hour))
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("cache", len(f.cache), ShouldEqual, 1)
+			SoMsg("current kept", f.cache, ShouldContainKey, sv.Epoch.Begin.Unix())
+		})
+		Convey("evicts values of ended epochs", func() {
+			now = now.Add(time.Hour)
+			next, err := f.GetSecretValue(now)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("cache", len(f.cache), ShouldEqual, 1)
+			SoMsg("next cached", f.cache, ShouldContainKey, next.Epoch.Begin.Unix())
+		})
+	})
+}
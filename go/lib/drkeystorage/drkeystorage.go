@@ -0,0 +1,149 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drkeystorage provides a "factory" for the DRKey database.
+// A config containing the backend type and the connection string
+// are used to create a specific DRKey db.
+package drkeystorage
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/config"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/drkey/drkeydbsqlite"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+type Backend string
+
+const (
+	BackendNone   Backend = ""
+	BackendSqlite Backend = "sqlite"
+)
+
+const (
+	BackendKey      = "backend"
+	ConnectionKey   = "connection"
+	MaxOpenConnsKey = "maxopenconns"
+	MaxIdleConnsKey = "maxidleconns"
+)
+
+var _ (config.Config) = (*DRKeyDBConf)(nil)
+
+// DRKeyDBConf is the configuration for the connection to the DRKey database.
+type DRKeyDBConf map[string]string
+
+// InitDefaults choses the sqlite backend if no backend is set.
+func (cfg *DRKeyDBConf) InitDefaults() {
+	if *cfg == nil {
+		*cfg = make(DRKeyDBConf)
+	}
+	m := *cfg
+	util.LowerKeys(m)
+	if cfg.Backend() == BackendNone {
+		m[BackendKey] = string(BackendSqlite)
+	}
+}
+
+func (cfg *DRKeyDBConf) Backend() Backend {
+	return Backend((*cfg)[BackendKey])
+}
+
+func (cfg *DRKeyDBConf) Connection() string {
+	return (*cfg)[ConnectionKey]
+}
+
+func (cfg *DRKeyDBConf) MaxOpenConns() (int, bool) {
+	val, ok, _ := cfg.parsedInt(MaxOpenConnsKey)
+	return val, ok
+}
+
+func (cfg *DRKeyDBConf) MaxIdleConns() (int, bool) {
+	val, ok, _ := cfg.parsedInt(MaxIdleConnsKey)
+	return val, ok
+}
+
+func (cfg *DRKeyDBConf) parsedInt(key string) (int, bool, error) {
+	val, ok := (*cfg)[key]
+	if !ok || val == "" {
+		return 0, false, nil
+	}
+	i, err := strconv.Atoi(val)
+	return i, true, err
+}
+
+func (cfg *DRKeyDBConf) Validate() error {
+	if err := cfg.validateLimits(); err != nil {
+		return err
+	}
+	switch cfg.Backend() {
+	case BackendSqlite:
+		return nil
+	case BackendNone:
+		return common.NewBasicError("No backend set", nil)
+	}
+	return common.NewBasicError("Unsupported backend", nil, "backend", cfg.Backend())
+}
+
+func (cfg *DRKeyDBConf) validateLimits() error {
+	if _, _, err := cfg.parsedInt(MaxOpenConnsKey); err != nil {
+		return common.NewBasicError("Invalid MaxOpenConns", nil, "value", (*cfg)[MaxOpenConnsKey])
+	}
+	if _, _, err := cfg.parsedInt(MaxIdleConnsKey); err != nil {
+		return common.NewBasicError("Invalid MaxIdleConns", nil, "value", (*cfg)[MaxIdleConnsKey])
+	}
+	return nil
+}
+
+func (cfg *DRKeyDBConf) Sample(dst io.Writer, path config.Path, ctx config.CtxMap) {
+	config.WriteString(dst, fmt.Sprintf(drkeyDBSample, ctx[config.ID]))
+}
+
+func (cfg *DRKeyDBConf) ConfigName() string {
+	return "drkeyDB"
+}
+
+// NewLvl1DB creates a level 1 DRKey database from the given config.
+func (cfg *DRKeyDBConf) NewLvl1DB() (drkey.Lvl1DB, error) {
+	log.Info("Connecting DRKeyDB", "backend", cfg.Backend(), "connection", cfg.Connection())
+	var err error
+	var db drkey.Lvl1DB
+
+	switch cfg.Backend() {
+	case BackendSqlite:
+		db, err = drkeydbsqlite.New(cfg.Connection())
+	default:
+		return nil, common.NewBasicError("Unsupported backend", nil, "backend", cfg.Backend())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	setConnLimits(cfg, db)
+	return db, nil
+}
+
+func setConnLimits(cfg *DRKeyDBConf, db drkey.Lvl1DB) {
+	if m, ok := cfg.MaxOpenConns(); ok {
+		db.SetMaxOpenConns(m)
+	}
+	if m, ok := cfg.MaxIdleConns(); ok {
+		db.SetMaxIdleConns(m)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkeystoragetest
+
+import (
+	"fmt"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/drkeystorage"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+func InitTestConfig(cfg *drkeystorage.DRKeyDBConf) {
+	if *cfg == nil {
+		*cfg = make(drkeystorage.DRKeyDBConf)
+	}
+	(*cfg)[drkeystorage.MaxOpenConnsKey] = "maxOpenConns"
+	(*cfg)[drkeystorage.MaxIdleConnsKey] = "maxIdleConns"
+}
+
+func CheckTestConfig(cfg *drkeystorage.DRKeyDBConf, id string) {
+	util.LowerKeys(*cfg)
+	SoMsg("MaxOpenConns", isSet(cfg.MaxOpenConns()), ShouldBeFalse)
+	SoMsg("MaxIdleConns", isSet(cfg.MaxIdleConns()), ShouldBeFalse)
+	SoMsg("Backend correct", cfg.Backend(), ShouldEqual, drkeystorage.BackendSqlite)
+	SoMsg("Connection correct", cfg.Connection(), ShouldEqual,
+		fmt.Sprintf("/var/lib/scion/drkeydb/%s.drkey.db", id))
+}
+
+func isSet(_ int, set bool) bool {
+	return set
+}
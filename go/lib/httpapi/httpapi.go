@@ -0,0 +1,102 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpapi contains the common parts of the JSON over HTTP management
+// APIs of the SCION services.
+//
+// An API is implemented as a HandlerFunc, which returns the value to encode
+// as the JSON response. Errors created with NewError are replied with their
+// HTTP status code, all other errors with 500 Internal Server Error.
+//
+// The APIs are not authenticated. They are therefore served on a dedicated
+// address with Serve, and never registered on the default HTTP mux, which is
+// exposed on the metrics address.
+package httpapi
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/fatal"
+	"github.com/scionproto/scion/go/lib/log"
+)
+
+// HandlerFunc handles an API request and returns the response value.
+type HandlerFunc func(r *http.Request) (interface{}, error)
+
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp, err := f(r)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if herr, ok := err.(*Error); ok {
+			code = herr.Code
+			err = herr.Err
+		}
+		log.Debug("API request failed", "method", r.Method, "url", r.URL, "err", err)
+		http.Error(w, err.Error(), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(resp); err != nil {
+		log.Error("Unable to write API response", "err", err)
+	}
+}
+
+// Error is an error that is replied with an HTTP status code.
+type Error struct {
+	Code int
+	Err  error
+}
+
+// NewError returns an error that is replied with status code.
+func NewError(code int, err error) error {
+	return &Error{Code: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// ErrMethod returns the error for a request with an unsupported method.
+func ErrMethod(method string) error {
+	return NewError(http.StatusMethodNotAllowed,
+		common.NewBasicError("Method not allowed", nil, "method", method))
+}
+
+// ErrPath returns the error for a request to an unknown path.
+func ErrPath(path string) error {
+	return NewError(http.StatusNotFound, common.NewBasicError("Unknown API path", nil,
+		"path", path))
+}
+
+// Serve starts serving handler on address. Errors after the listener is set
+// up are fatal.
+func Serve(address string, handler http.Handler) error {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return common.NewBasicError("Unable to listen on API address", err, "addr", address)
+	}
+	log.Info("Serving API", "addr", ln.Addr())
+	go func() {
+		defer log.LogPanicAndExit()
+		if err := http.Serve(ln, handler); err != nil {
+			fatal.Fatal(common.NewBasicError("API serve error", err, "addr", address))
+		}
+	}()
+	return nil
+}
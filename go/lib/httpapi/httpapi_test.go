@@ -0,0 +1,74 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHandlerFunc(t *testing.T) {
+	tests := []struct {
+		Name string
+		Resp interface{}
+		Err  error
+		Code int
+		Body string
+	}{
+		{
+			Name: "response is encoded as JSON",
+			Resp: struct{ A int }{A: 1},
+			Code: http.StatusOK,
+			Body: "{\n    \"A\": 1\n}\n",
+		},
+		{
+			Name: "API error is replied with its code",
+			Err:  NewError(http.StatusNotFound, errors.New("not here")),
+			Code: http.StatusNotFound,
+			Body: "not here\n",
+		},
+		{
+			Name: "method error",
+			Err:  ErrMethod(http.MethodPatch),
+			Code: http.StatusMethodNotAllowed,
+		},
+		{
+			Name: "other error is an internal error",
+			Err:  errors.New("broken"),
+			Code: http.StatusInternalServerError,
+			Body: "broken\n",
+		},
+	}
+	Convey("HandlerFunc replies", t, func() {
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				h := HandlerFunc(func(r *http.Request) (interface{}, error) {
+					return test.Resp, test.Err
+				})
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", strings.NewReader("")))
+				SoMsg("code", w.Code, ShouldEqual, test.Code)
+				if test.Body != "" {
+					SoMsg("body", w.Body.String(), ShouldEqual, test.Body)
+				}
+			})
+		}
+	})
+}
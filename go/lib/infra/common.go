@@ -25,6 +25,7 @@ import (
 	"github.com/scionproto/scion/go/lib/ctrl"
 	"github.com/scionproto/scion/go/lib/ctrl/ack"
 	"github.com/scionproto/scion/go/lib/ctrl/cert_mgmt"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
 	"github.com/scionproto/scion/go/lib/ctrl/ifid"
 	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
 	"github.com/scionproto/scion/go/lib/ctrl/seg"
@@ -146,6 +147,10 @@ const (
 	ChainIssueRequest
 	ChainIssueReply
 	Ack
+	DRKeyLvl1Request
+	DRKeyLvl1Reply
+	DRKeyLvl2Request
+	DRKeyLvl2Reply
 )
 
 func (mt MessageType) String() string {
@@ -192,6 +197,14 @@ func (mt MessageType) String() string {
 		return "ChainIssueReply"
 	case Ack:
 		return "Ack"
+	case DRKeyLvl1Request:
+		return "DRKeyLvl1Request"
+	case DRKeyLvl1Reply:
+		return "DRKeyLvl1Reply"
+	case DRKeyLvl2Request:
+		return "DRKeyLvl2Request"
+	case DRKeyLvl2Reply:
+		return "DRKeyLvl2Reply"
 	default:
 		return fmt.Sprintf("Unknown (%d)", mt)
 	}
@@ -243,6 +256,14 @@ func (mt MessageType) MetricLabel() string {
 		return "chain_issue_push"
 	case Ack:
 		return "ack_push"
+	case DRKeyLvl1Request:
+		return "drkey_lvl1_req"
+	case DRKeyLvl1Reply:
+		return "drkey_lvl1_push"
+	case DRKeyLvl2Request:
+		return "drkey_lvl2_req"
+	case DRKeyLvl2Reply:
+		return "drkey_lvl2_push"
 	default:
 		return "unknown_mt"
 	}
@@ -328,6 +349,18 @@ type Messenger interface {
 	SendChainIssueReply(ctx context.Context, msg *cert_mgmt.ChainIssRep, a net.Addr,
 		id uint64) error
 	SendBeacon(ctx context.Context, msg *seg.Beacon, a net.Addr, id uint64) error
+	// RequestDRKeyLvl1 sends a drkey_mgmt.Lvl1Req to address a, blocks until it
+	// receives a reply and returns the reply.
+	RequestDRKeyLvl1(ctx context.Context, msg *drkey_mgmt.Lvl1Req, a net.Addr,
+		id uint64) (*drkey_mgmt.Lvl1Rep, error)
+	// SendDRKeyLvl1 sends a reliable drkey_mgmt.Lvl1Rep to address a.
+	SendDRKeyLvl1(ctx context.Context, msg *drkey_mgmt.Lvl1Rep, a net.Addr, id uint64) error
+	// RequestDRKeyLvl2 sends a drkey_mgmt.Lvl2Req to address a, blocks until it
+	// receives a reply and returns the reply.
+	RequestDRKeyLvl2(ctx context.Context, msg *drkey_mgmt.Lvl2Req, a net.Addr,
+		id uint64) (*drkey_mgmt.Lvl2Rep, error)
+	// SendDRKeyLvl2 sends a reliable drkey_mgmt.Lvl2Rep to address a.
+	SendDRKeyLvl2(ctx context.Context, msg *drkey_mgmt.Lvl2Rep, a net.Addr, id uint64) error
 	UpdateSigner(signer Signer, types []MessageType)
 	UpdateVerifier(verifier Verifier)
 	AddHandler(msgType MessageType, h Handler)
@@ -342,6 +375,8 @@ type ResponseWriter interface {
 	SendChainIssueReply(ctx context.Context, msg *cert_mgmt.ChainIssRep) error
 	SendSegReply(ctx context.Context, msg *path_mgmt.SegReply) error
 	SendIfStateInfoReply(ctx context.Context, msg *path_mgmt.IFStateInfos) error
+	SendDRKeyLvl1Reply(ctx context.Context, msg *drkey_mgmt.Lvl1Rep) error
+	SendDRKeyLvl2Reply(ctx context.Context, msg *drkey_mgmt.Lvl2Rep) error
 }
 
 func ResponseWriterFromContext(ctx context.Context) (ResponseWriter, bool) {
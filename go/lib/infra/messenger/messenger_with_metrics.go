@@ -24,6 +24,7 @@ import (
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/ctrl/ack"
 	"github.com/scionproto/scion/go/lib/ctrl/cert_mgmt"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
 	"github.com/scionproto/scion/go/lib/ctrl/ifid"
 	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
 	"github.com/scionproto/scion/go/lib/ctrl/seg"
@@ -218,6 +219,42 @@ func (m *MessengerWithMetrics) SendBeacon(ctx context.Context, msg *seg.Beacon,
 	return err
 }
 
+func (m *MessengerWithMetrics) RequestDRKeyLvl1(ctx context.Context, msg *drkey_mgmt.Lvl1Req,
+	a net.Addr, id uint64) (*drkey_mgmt.Lvl1Rep, error) {
+
+	opMetrics := metricStartOp(infra.DRKeyLvl1Request)
+	reply, err := m.messenger.RequestDRKeyLvl1(ctx, msg, a, id)
+	opMetrics.publishResult(ctx, err)
+	return reply, err
+}
+
+func (m *MessengerWithMetrics) SendDRKeyLvl1(ctx context.Context, msg *drkey_mgmt.Lvl1Rep,
+	a net.Addr, id uint64) error {
+
+	opMetrics := metricStartOp(infra.DRKeyLvl1Reply)
+	err := m.messenger.SendDRKeyLvl1(ctx, msg, a, id)
+	opMetrics.publishResult(ctx, err)
+	return err
+}
+
+func (m *MessengerWithMetrics) RequestDRKeyLvl2(ctx context.Context, msg *drkey_mgmt.Lvl2Req,
+	a net.Addr, id uint64) (*drkey_mgmt.Lvl2Rep, error) {
+
+	opMetrics := metricStartOp(infra.DRKeyLvl2Request)
+	reply, err := m.messenger.RequestDRKeyLvl2(ctx, msg, a, id)
+	opMetrics.publishResult(ctx, err)
+	return reply, err
+}
+
+func (m *MessengerWithMetrics) SendDRKeyLvl2(ctx context.Context, msg *drkey_mgmt.Lvl2Rep,
+	a net.Addr, id uint64) error {
+
+	opMetrics := metricStartOp(infra.DRKeyLvl2Reply)
+	err := m.messenger.SendDRKeyLvl2(ctx, msg, a, id)
+	opMetrics.publishResult(ctx, err)
+	return err
+}
+
 func (m *MessengerWithMetrics) AddHandler(msgType infra.MessageType, handler infra.Handler) {
 	handlerWithMetrics := func(request *infra.Request) *infra.HandlerResult {
 		inCallsTotal.With(prometheus.Labels{
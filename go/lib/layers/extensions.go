@@ -18,6 +18,8 @@ import (
 	"fmt"
 
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/spse"
+	"github.com/scionproto/scion/go/lib/spse/scmp_auth"
 )
 
 func ExtensionFactory(class common.L4ProtocolType, extension *Extension) (common.Extension, error) {
@@ -28,6 +30,10 @@ func ExtensionFactory(class common.L4ProtocolType, extension *Extension) (common
 			return NewExtnSCMPFromLayer(extension)
 		case common.ExtnOneHopPathType.Type:
 			return NewExtnOHPFromLayer(extension)
+		case common.ExtnSIBRAType.Type:
+			return NewExtnSIBRAFromLayer(extension)
+		case common.ExtnHopTraceType.Type:
+			return NewExtnHopTraceFromLayer(extension)
 		default:
 			return NewExtnUnknownFromLayer(common.HopByHopClass, extension)
 		}
@@ -35,6 +41,8 @@ func ExtensionFactory(class common.L4ProtocolType, extension *Extension) (common
 		switch extension.Type {
 		case common.ExtnE2EDebugType.Type:
 			return NewExtnE2EDebugFromLayer(extension)
+		case common.ExtnSCIONPacketSecurityType.Type:
+			return NewExtnSPSEFromLayer(extension)
 		default:
 			return NewExtnUnknownFromLayer(common.End2EndClass, extension)
 		}
@@ -43,6 +51,30 @@ func ExtensionFactory(class common.L4ProtocolType, extension *Extension) (common
 	}
 }
 
+// NewExtnSPSEFromLayer parses a SCIONPacketSecurity extension. Extensions with
+// unknown security modes are returned as unknown extensions.
+func NewExtnSPSEFromLayer(extension *Extension) (common.Extension, error) {
+	if len(extension.Data) == 0 {
+		return NewExtnUnknownFromLayer(common.End2EndClass, extension)
+	}
+	var extn common.Extension
+	var err error
+	switch spse.SecMode(extension.Data[0]) {
+	case spse.ScmpAuthDRKey:
+		extn, err = scmp_auth.NewDRKeyExtnFromRaw(extension.Data)
+	case spse.ScmpAuthHashTree:
+		extn, err = scmp_auth.NewHashTreeExtnFromRaw(extension.Data)
+	case spse.AesCMac, spse.HmacSha256, spse.Ed25519, spse.GcmAes128:
+		extn, err = spse.NewExtnFromRaw(extension.Data)
+	default:
+		return NewExtnUnknownFromLayer(common.End2EndClass, extension)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return extn, nil
+}
+
 var _ common.Extension = (*ExtnOHP)(nil)
 
 type ExtnOHP struct{}
@@ -0,0 +1,330 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package layers
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+var _ common.Extension = (*ExtnSIBRA)(nil)
+
+const ErrorSIBRABadMac = "Bad SIBRA opaque field MAC"
+
+const (
+	// SIBRAVersion is the supported version of the SIBRA extension.
+	SIBRAVersion = 0
+	// SIBRASOFLen is the length of a SIBRA opaque field.
+	SIBRASOFLen = common.LineLen
+	// SIBRAMacLen is the length of the MAC of a SIBRA opaque field.
+	SIBRAMacLen = 4
+	// SIBRAMaxHops is the maximum number of opaque fields of a SIBRA
+	// extension, which is limited by the length field of the extension header.
+	SIBRAMaxHops = (math.MaxUint8*common.LineLen - sibraHdrLen - common.ExtnSubHdrLen) /
+		SIBRASOFLen
+	// sibraHdrLen is the length of the fields preceding the opaque fields.
+	sibraHdrLen = common.ExtnFirstLineLen + 2*common.LineLen
+	// sibraMacInputLen is the length of the MAC input of an opaque field.
+	sibraMacInputLen = 4 * common.LineLen
+)
+
+const (
+	// SIBRAFlagForward indicates that the packet travels in the direction of
+	// the reservation.
+	SIBRAFlagForward = 0x01
+	// SIBRAFlagSetup indicates that the packet sets up a reservation.
+	SIBRAFlagSetup = 0x02
+	// SIBRAFlagRequest indicates that the packet carries a reservation
+	// request.
+	SIBRAFlagRequest = 0x04
+)
+
+// ExtnSIBRA is the SIBRA (COLIBRI) reservation extension. Packets carrying it
+// are forwarded on a bandwidth reservation, which is authorized by one opaque
+// field (SOF) per AS on the reservation path. Every SOF is authenticated by
+// the AS that issued it, with the key of the AS that is also used for the hop
+// field MACs.
+//
+// The extension data has the following layout:
+//
+//  Version (1B) | Flags (1B) | CurrHop (1B) | NumHops (1B) | padding (1B) |
+//  ReservationID (8B) | ResInfo (8B) | SOF 0 (8B) | ... | SOF NumHops-1 (8B)
+//
+// The SOFs are ordered in the direction of the reservation. CurrHop is the
+// index of the SOF of the AS the packet is currently in. See SIBRAResInfo and
+// SIBRASOF for the layout of the reservation info and the SOFs.
+type ExtnSIBRA struct {
+	Version uint8
+	// Forward indicates that the packet travels in the direction of the
+	// reservation. Otherwise, the SOFs are traversed in reverse order.
+	Forward bool
+	// Setup indicates that the packet sets up a reservation.
+	Setup bool
+	// Request indicates that the packet carries a reservation request.
+	Request bool
+	CurrHop uint8
+	// ID identifies the reservation.
+	ID uint64
+	// Info describes the reservation.
+	Info *SIBRAResInfo
+	// SOFs are the opaque fields of the ASes on the reservation path.
+	SOFs []*SIBRASOF
+}
+
+func NewExtnSIBRAFromLayer(extension *Extension) (*ExtnSIBRA, error) {
+	return ExtnSIBRAFromRaw(extension.Data)
+}
+
+func ExtnSIBRAFromRaw(b common.RawBytes) (*ExtnSIBRA, error) {
+	s := &ExtnSIBRA{}
+	if err := s.DecodeFromBytes(b); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *ExtnSIBRA) DecodeFromBytes(b common.RawBytes) error {
+	numHops, err := SIBRANumHops(b)
+	if err != nil {
+		return err
+	}
+	s.Version = b[0]
+	flags := b[1]
+	s.Forward = flags&SIBRAFlagForward != 0
+	s.Setup = flags&SIBRAFlagSetup != 0
+	s.Request = flags&SIBRAFlagRequest != 0
+	s.CurrHop = b[2]
+	s.ID = common.Order.Uint64(b[common.ExtnFirstLineLen:])
+	s.Info = SIBRAResInfoFromRaw(b[common.ExtnFirstLineLen+common.LineLen:])
+	s.SOFs = make([]*SIBRASOF, numHops)
+	for i := range s.SOFs {
+		s.SOFs[i] = SIBRASOFFromRaw(b[sibraSOFOffset(i):])
+	}
+	return nil
+}
+
+// SIBRANumHops returns the number of opaque fields of the raw SIBRA extension
+// data b, and checks that it is consistent with the length of b.
+func SIBRANumHops(b common.RawBytes) (int, error) {
+	if len(b) < sibraHdrLen {
+		return 0, common.NewBasicError("Bad length for SIBRA extension", nil,
+			"min", sibraHdrLen, "actual", len(b))
+	}
+	numHops := int(b[3])
+	if expected := sibraSOFOffset(numHops); len(b) != expected {
+		return 0, common.NewBasicError("Bad length for SIBRA extension", nil,
+			"numHops", numHops, "expected", expected, "actual", len(b))
+	}
+	return numHops, nil
+}
+
+func sibraSOFOffset(i int) int {
+	return sibraHdrLen + i*SIBRASOFLen
+}
+
+func (s *ExtnSIBRA) Write(b common.RawBytes) error {
+	if len(s.SOFs) > SIBRAMaxHops {
+		return common.NewBasicError("Too many SIBRA opaque fields", nil,
+			"max", SIBRAMaxHops, "actual", len(s.SOFs))
+	}
+	if len(b) < s.Len() {
+		return common.NewBasicError("Buffer too short", nil,
+			"expected", s.Len(), "actual", len(b))
+	}
+	b[0] = s.Version
+	b[1] = s.flags()
+	b[2] = s.CurrHop
+	b[3] = uint8(len(s.SOFs))
+	b[4] = 0
+	common.Order.PutUint64(b[common.ExtnFirstLineLen:], s.ID)
+	s.Info.Write(b[common.ExtnFirstLineLen+common.LineLen:])
+	for i, sof := range s.SOFs {
+		sof.Write(b[sibraSOFOffset(i):])
+	}
+	return nil
+}
+
+func (s *ExtnSIBRA) flags() uint8 {
+	var flags uint8
+	if s.Forward {
+		flags |= SIBRAFlagForward
+	}
+	if s.Setup {
+		flags |= SIBRAFlagSetup
+	}
+	if s.Request {
+		flags |= SIBRAFlagRequest
+	}
+	return flags
+}
+
+func (s *ExtnSIBRA) Pack() (common.RawBytes, error) {
+	b := make(common.RawBytes, s.Len())
+	if err := s.Write(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *ExtnSIBRA) Copy() common.Extension {
+	c := *s
+	info := *s.Info
+	c.Info = &info
+	c.SOFs = make([]*SIBRASOF, len(s.SOFs))
+	for i, sof := range s.SOFs {
+		c.SOFs[i] = &SIBRASOF{Ingress: sof.Ingress, Egress: sof.Egress,
+			Mac: append(common.RawBytes(nil), sof.Mac...)}
+	}
+	return &c
+}
+
+func (s *ExtnSIBRA) Reverse() (bool, error) {
+	// The reply travels on the same reservation in the opposite direction.
+	s.Forward = !s.Forward
+	return true, nil
+}
+
+// CurrSOF returns the opaque field of the AS the packet is currently in, or
+// nil if CurrHop is out of range.
+func (s *ExtnSIBRA) CurrSOF() *SIBRASOF {
+	if int(s.CurrHop) >= len(s.SOFs) {
+		return nil
+	}
+	return s.SOFs[s.CurrHop]
+}
+
+func (s *ExtnSIBRA) Len() int {
+	return sibraSOFOffset(len(s.SOFs))
+}
+
+func (s *ExtnSIBRA) Class() common.L4ProtocolType {
+	return common.HopByHopClass
+}
+
+func (s *ExtnSIBRA) Type() common.ExtnType {
+	return common.ExtnSIBRAType
+}
+
+func (s *ExtnSIBRA) String() string {
+	sofs := make([]string, len(s.SOFs))
+	for i, sof := range s.SOFs {
+		sofs[i] = sof.String()
+	}
+	return fmt.Sprintf("SIBRA(%dB): Version: %d Flags: 0x%02x ID: %016x %s CurrHop: %d "+
+		"SOFs: [%s]", s.Len(), s.Version, s.flags(), s.ID, s.Info, s.CurrHop,
+		strings.Join(sofs, ", "))
+}
+
+// SIBRAResInfo describes a SIBRA reservation. It has the following layout:
+//
+//  ExpTime (4B) | BW (4B)
+//
+// ExpTime is encoded in seconds since the Unix epoch.
+type SIBRAResInfo struct {
+	// ExpTime is the time the reservation expires.
+	ExpTime time.Time
+	// BW is the reserved bandwidth in kilobits per second.
+	BW uint32
+}
+
+func SIBRAResInfoFromRaw(b common.RawBytes) *SIBRAResInfo {
+	return &SIBRAResInfo{
+		ExpTime: util.SecsToTime(common.Order.Uint32(b)),
+		BW:      common.Order.Uint32(b[4:]),
+	}
+}
+
+func (i *SIBRAResInfo) Write(b common.RawBytes) {
+	common.Order.PutUint32(b, util.TimeToSecs(i.ExpTime))
+	common.Order.PutUint32(b[4:], i.BW)
+}
+
+// BytesPerSec returns the reserved bandwidth in bytes per second.
+func (i *SIBRAResInfo) BytesPerSec() uint64 {
+	return uint64(i.BW) * 1000 / 8
+}
+
+func (i *SIBRAResInfo) String() string {
+	return fmt.Sprintf("ExpTime: %s BW: %dkbps", util.TimeToString(i.ExpTime), i.BW)
+}
+
+// SIBRASOF is a SIBRA opaque field. It has the following layout:
+//
+//  Ingress (2B) | Egress (2B) | MAC (4B)
+//
+// Ingress and Egress are the interfaces of the AS in the direction of the
+// reservation. They are 0 in the first and last AS of the reservation,
+// respectively.
+type SIBRASOF struct {
+	Ingress common.IFIDType
+	Egress  common.IFIDType
+	Mac     common.RawBytes
+}
+
+func SIBRASOFFromRaw(b common.RawBytes) *SIBRASOF {
+	return &SIBRASOF{
+		Ingress: common.IFIDType(common.Order.Uint16(b)),
+		Egress:  common.IFIDType(common.Order.Uint16(b[2:])),
+		Mac:     append(common.RawBytes(nil), b[4:SIBRASOFLen]...),
+	}
+}
+
+func (s *SIBRASOF) Write(b common.RawBytes) {
+	common.Order.PutUint16(b, uint16(s.Ingress))
+	common.Order.PutUint16(b[2:], uint16(s.Egress))
+	copy(b[4:SIBRASOFLen], s.Mac)
+}
+
+// CalcMac calculates the MAC of the opaque field for the reservation with the
+// given ID and info.
+//
+// MAC input block format:
+//
+//  ReservationID (8B) | ResInfo (8B) | Ingress (2B) | Egress (2B) | 0 (12B)
+func (s *SIBRASOF) CalcMac(mac hash.Hash, id uint64, info *SIBRAResInfo) common.RawBytes {
+	all := make(common.RawBytes, sibraMacInputLen)
+	common.Order.PutUint64(all, id)
+	info.Write(all[8:])
+	common.Order.PutUint16(all[16:], uint16(s.Ingress))
+	common.Order.PutUint16(all[18:], uint16(s.Egress))
+
+	mac.Reset()
+	// Write must not return an error: https://godoc.org/hash#Hash
+	if _, err := mac.Write(all); err != nil {
+		panic(err)
+	}
+	tmp := make([]byte, 0, mac.Size())
+	return mac.Sum(tmp)[:SIBRAMacLen]
+}
+
+// Verify checks the MAC of the opaque field.
+func (s *SIBRASOF) Verify(mac hash.Hash, id uint64, info *SIBRAResInfo) error {
+	expected := s.CalcMac(mac, id, info)
+	if !bytes.Equal(s.Mac, expected) {
+		return common.NewBasicError(ErrorSIBRABadMac, nil, "expected", expected, "actual", s.Mac)
+	}
+	return nil
+}
+
+func (s *SIBRASOF) String() string {
+	return fmt.Sprintf("%d>%d %s", s.Ingress, s.Egress, s.Mac)
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package layers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scrypto"
+)
+
+func TestExtnSIBRA(t *testing.T) {
+	Convey("SIBRA extension", t, func() {
+		extn := &ExtnSIBRA{
+			Forward: true,
+			Request: true,
+			CurrHop: 1,
+			ID:      0x0102030405060708,
+			Info:    &SIBRAResInfo{ExpTime: time.Unix(1546300800, 0), BW: 8000},
+			SOFs: []*SIBRASOF{
+				{Ingress: 0, Egress: 1, Mac: common.RawBytes{1, 2, 3, 4}},
+				{Ingress: 2, Egress: 3, Mac: common.RawBytes{5, 6, 7, 8}},
+				{Ingress: 4, Egress: 0, Mac: common.RawBytes{9, 10, 11, 12}},
+			},
+		}
+		Convey("has a length aligned to the line length", func() {
+			SoMsg("len", (extn.Len()+common.ExtnSubHdrLen)%common.LineLen, ShouldEqual, 0)
+		})
+		Convey("is decoded from its encoding", func() {
+			b, err := extn.Pack()
+			SoMsg("pack err", err, ShouldBeNil)
+			decoded, err := ExtnSIBRAFromRaw(b)
+			SoMsg("decode err", err, ShouldBeNil)
+			SoMsg("extn", decoded, ShouldResemble, extn)
+			SoMsg("curr SOF", decoded.CurrSOF(), ShouldResemble, extn.SOFs[1])
+		})
+		Convey("is created by the extension factory", func() {
+			b, err := extn.Pack()
+			SoMsg("pack err", err, ShouldBeNil)
+			hdr := []byte{0, uint8((len(b) + common.ExtnSubHdrLen) / common.LineLen),
+				common.ExtnSIBRAType.Type}
+			layer := mustCreateExtensionLayer(append(hdr, b...))
+			decoded, err := ExtensionFactory(common.HopByHopClass, layer)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("extn", decoded, ShouldResemble, extn)
+		})
+		Convey("reverses the direction", func() {
+			keep, err := extn.Reverse()
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("keep", keep, ShouldBeTrue)
+			SoMsg("forward", extn.Forward, ShouldBeFalse)
+		})
+	})
+	Convey("SIBRA opaque field MAC", t, func() {
+		mac, err := scrypto.InitMac(make(common.RawBytes, 16))
+		SoMsg("mac err", err, ShouldBeNil)
+		info := &SIBRAResInfo{ExpTime: time.Unix(1546300800, 0), BW: 8000}
+		sof := &SIBRASOF{Ingress: 2, Egress: 3}
+		sof.Mac = sof.CalcMac(mac, 42, info)
+		SoMsg("mac len", len(sof.Mac), ShouldEqual, SIBRAMacLen)
+		SoMsg("valid", sof.Verify(mac, 42, info), ShouldBeNil)
+		SoMsg("other reservation", sof.Verify(mac, 43, info), ShouldNotBeNil)
+		SoMsg("other bandwidth", sof.Verify(mac, 42,
+			&SIBRAResInfo{ExpTime: info.ExpTime, BW: 16000}), ShouldNotBeNil)
+		sof.Egress = 4
+		SoMsg("other interface", sof.Verify(mac, 42, info), ShouldNotBeNil)
+	})
+	Convey("Invalid SIBRA extensions are rejected", t, func() {
+		_, err := ExtnSIBRAFromRaw(make(common.RawBytes, sibraHdrLen-1))
+		SoMsg("too short", err, ShouldNotBeNil)
+		b := make(common.RawBytes, sibraSOFOffset(1))
+		b[3] = 2
+		_, err = ExtnSIBRAFromRaw(b)
+		SoMsg("bad number of hops", err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,244 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package layers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+var _ common.Extension = (*ExtnHopTrace)(nil)
+
+const (
+	// HopTraceEntryLen is the length of a hop trace entry.
+	HopTraceEntryLen = 3 * common.LineLen
+	// HopTraceMaxSlots is the maximum number of entries of a hop trace
+	// extension, which is limited by the length field of the extension
+	// header.
+	HopTraceMaxSlots = (math.MaxUint8 - 1) * common.LineLen / HopTraceEntryLen
+	// hopTraceHdrLen is the length of the fields preceding the entries.
+	hopTraceHdrLen = common.ExtnFirstLineLen
+)
+
+// ExtnHopTrace is the hop trace extension. The sender allocates space for a
+// number of entries, which are filled in by the border routers forwarding the
+// packet. Each router appends one entry every time it forwards the packet,
+// i.e., routers connecting two interfaces of the same AS add a single entry,
+// while the ingress and egress routers of an AS add one entry each. Hosts
+// replying to the packet keep the extension, such that the reply records the
+// return path as well.
+//
+// The extension data has the following layout:
+//
+//  Used (1B) | ID (4B) | Entry 0 (24B) | ... | Entry Slots-1 (24B)
+//
+// Used is the number of entries filled in. See HopTraceEntry for the layout
+// of the entries.
+type ExtnHopTrace struct {
+	// ID identifies the traced packet.
+	ID uint32
+	// Entries are the entries filled in by the border routers.
+	Entries []*HopTraceEntry
+	// Slots is the number of entries the extension has space for.
+	Slots int
+}
+
+// NewExtnHopTrace creates a hop trace extension with space for slots entries.
+func NewExtnHopTrace(id uint32, slots int) (*ExtnHopTrace, error) {
+	if slots < 1 || slots > HopTraceMaxSlots {
+		return nil, common.NewBasicError("Invalid number of hop trace slots", nil,
+			"min", 1, "max", HopTraceMaxSlots, "actual", slots)
+	}
+	return &ExtnHopTrace{ID: id, Slots: slots}, nil
+}
+
+func NewExtnHopTraceFromLayer(extension *Extension) (*ExtnHopTrace, error) {
+	return ExtnHopTraceFromRaw(extension.Data)
+}
+
+func ExtnHopTraceFromRaw(b common.RawBytes) (*ExtnHopTrace, error) {
+	t := &ExtnHopTrace{}
+	if err := t.DecodeFromBytes(b); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *ExtnHopTrace) DecodeFromBytes(b common.RawBytes) error {
+	slots, err := HopTraceSlots(b)
+	if err != nil {
+		return err
+	}
+	used := int(b[0])
+	if used > slots {
+		return common.NewBasicError("Too many hop trace entries", nil,
+			"slots", slots, "used", used)
+	}
+	t.ID = common.Order.Uint32(b[1:hopTraceHdrLen])
+	t.Slots = slots
+	t.Entries = make([]*HopTraceEntry, used)
+	for i := range t.Entries {
+		t.Entries[i] = HopTraceEntryFromRaw(b[hopTraceOffset(i):])
+	}
+	return nil
+}
+
+// HopTraceSlots returns the number of entries the raw hop trace extension data
+// b has space for.
+func HopTraceSlots(b common.RawBytes) (int, error) {
+	if len(b) < hopTraceHdrLen || (len(b)-hopTraceHdrLen)%HopTraceEntryLen != 0 {
+		return 0, common.NewBasicError("Bad length for hop trace extension", nil,
+			"actual", len(b), "entryLen", HopTraceEntryLen)
+	}
+	return (len(b) - hopTraceHdrLen) / HopTraceEntryLen, nil
+}
+
+// HopTraceAppend fills in the next free entry of the raw hop trace extension
+// data b with e. It returns false if all entries are in use.
+func HopTraceAppend(b common.RawBytes, e *HopTraceEntry) (bool, error) {
+	slots, err := HopTraceSlots(b)
+	if err != nil {
+		return false, err
+	}
+	used := int(b[0])
+	if used >= slots {
+		return false, nil
+	}
+	e.Write(b[hopTraceOffset(used):])
+	b[0]++
+	return true, nil
+}
+
+func hopTraceOffset(i int) int {
+	return hopTraceHdrLen + i*HopTraceEntryLen
+}
+
+func (t *ExtnHopTrace) Write(b common.RawBytes) error {
+	if len(t.Entries) > t.Slots {
+		return common.NewBasicError("Too many hop trace entries", nil,
+			"slots", t.Slots, "used", len(t.Entries))
+	}
+	if len(b) < t.Len() {
+		return common.NewBasicError("Buffer too short", nil,
+			"expected", t.Len(), "actual", len(b))
+	}
+	b[0] = uint8(len(t.Entries))
+	common.Order.PutUint32(b[1:hopTraceHdrLen], t.ID)
+	for i, e := range t.Entries {
+		e.Write(b[hopTraceOffset(i):])
+	}
+	// Zero the unused entries.
+	unused := b[hopTraceOffset(len(t.Entries)):t.Len()]
+	for i := range unused {
+		unused[i] = 0
+	}
+	return nil
+}
+
+func (t *ExtnHopTrace) Pack() (common.RawBytes, error) {
+	b := make(common.RawBytes, t.Len())
+	if err := t.Write(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (t *ExtnHopTrace) Copy() common.Extension {
+	c := &ExtnHopTrace{ID: t.ID, Slots: t.Slots, Entries: make([]*HopTraceEntry, len(t.Entries))}
+	for i, e := range t.Entries {
+		entry := *e
+		c.Entries[i] = &entry
+	}
+	return c
+}
+
+func (t *ExtnHopTrace) Reverse() (bool, error) {
+	// The extension is kept, such that the reverse path is traced as well.
+	return true, nil
+}
+
+func (t *ExtnHopTrace) Len() int {
+	return hopTraceOffset(t.Slots)
+}
+
+func (t *ExtnHopTrace) Class() common.L4ProtocolType {
+	return common.HopByHopClass
+}
+
+func (t *ExtnHopTrace) Type() common.ExtnType {
+	return common.ExtnHopTraceType
+}
+
+func (t *ExtnHopTrace) String() string {
+	entries := make([]string, len(t.Entries))
+	for i, e := range t.Entries {
+		entries[i] = e.String()
+	}
+	return fmt.Sprintf("HopTrace(%dB): ID: %d Slots: %d Entries: [%s]", t.Len(), t.ID,
+		t.Slots, strings.Join(entries, ", "))
+}
+
+// HopTraceEntry is an entry of the hop trace extension, filled in by a border
+// router forwarding the packet. It has the following layout:
+//
+//  IA (8B) | Ingress (2B) | Egress (2B) | Timestamp (8B) | QueueTime (4B)
+//
+// Timestamp is encoded in nanoseconds since the Unix epoch, QueueTime in
+// nanoseconds.
+type HopTraceEntry struct {
+	// IA is the ISD-AS of the router.
+	IA addr.IA
+	// Ingress and Egress are the interfaces the packet was received on and
+	// forwarded to, respectively. The internal interface has ID 0.
+	Ingress common.IFIDType
+	Egress  common.IFIDType
+	// Timestamp is the time the router received the packet.
+	Timestamp time.Time
+	// QueueTime is the time the packet spent in the router until it was
+	// handed to the egress socket.
+	QueueTime time.Duration
+}
+
+func HopTraceEntryFromRaw(b common.RawBytes) *HopTraceEntry {
+	return &HopTraceEntry{
+		IA:        addr.IAFromRaw(b),
+		Ingress:   common.IFIDType(common.Order.Uint16(b[8:])),
+		Egress:    common.IFIDType(common.Order.Uint16(b[10:])),
+		Timestamp: time.Unix(0, int64(common.Order.Uint64(b[12:]))),
+		QueueTime: time.Duration(common.Order.Uint32(b[20:])),
+	}
+}
+
+func (e *HopTraceEntry) Write(b common.RawBytes) {
+	e.IA.Write(b)
+	common.Order.PutUint16(b[8:], uint16(e.Ingress))
+	common.Order.PutUint16(b[10:], uint16(e.Egress))
+	common.Order.PutUint64(b[12:], uint64(e.Timestamp.UnixNano()))
+	queue := e.QueueTime
+	if queue > math.MaxUint32 {
+		queue = math.MaxUint32
+	}
+	common.Order.PutUint32(b[20:], uint32(queue))
+}
+
+func (e *HopTraceEntry) String() string {
+	return fmt.Sprintf("%s %d>%d %s (+%s)", e.IA, e.Ingress, e.Egress,
+		e.Timestamp.Format(common.TimeFmt), e.QueueTime)
+}
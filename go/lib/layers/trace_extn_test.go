@@ -0,0 +1,86 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package layers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestExtnHopTrace(t *testing.T) {
+	entry := &HopTraceEntry{
+		IA:        xtest.MustParseIA("1-ff00:0:110"),
+		Ingress:   1,
+		Egress:    42,
+		Timestamp: time.Unix(1546300800, 123456789),
+		QueueTime: 1500 * time.Nanosecond,
+	}
+	Convey("Hop trace extension", t, func() {
+		extn, err := NewExtnHopTrace(7, 3)
+		SoMsg("err", err, ShouldBeNil)
+		extn.Entries = []*HopTraceEntry{entry}
+		Convey("has a length aligned to the line length", func() {
+			SoMsg("len", (extn.Len()+common.ExtnSubHdrLen)%common.LineLen, ShouldEqual, 0)
+		})
+		Convey("is decoded from its encoding", func() {
+			b, err := extn.Pack()
+			SoMsg("pack err", err, ShouldBeNil)
+			decoded, err := ExtnHopTraceFromRaw(b)
+			SoMsg("decode err", err, ShouldBeNil)
+			SoMsg("extn", decoded, ShouldResemble, extn)
+		})
+		Convey("entries are appended until the slots are used", func() {
+			b, err := extn.Pack()
+			SoMsg("pack err", err, ShouldBeNil)
+			for i := 0; i < 2; i++ {
+				ok, err := HopTraceAppend(b, entry)
+				SoMsg("append err", err, ShouldBeNil)
+				SoMsg("appended", ok, ShouldBeTrue)
+			}
+			ok, err := HopTraceAppend(b, entry)
+			SoMsg("append err", err, ShouldBeNil)
+			SoMsg("appended", ok, ShouldBeFalse)
+			decoded, err := ExtnHopTraceFromRaw(b)
+			SoMsg("decode err", err, ShouldBeNil)
+			SoMsg("entries", decoded.Entries, ShouldResemble,
+				[]*HopTraceEntry{entry, entry, entry})
+		})
+		Convey("is created by the extension factory", func() {
+			b, err := extn.Pack()
+			SoMsg("pack err", err, ShouldBeNil)
+			hdr := []byte{0, uint8((len(b) + common.ExtnSubHdrLen) / common.LineLen),
+				common.ExtnHopTraceType.Type}
+			layer := mustCreateExtensionLayer(append(hdr, b...))
+			decoded, err := ExtensionFactory(common.HopByHopClass, layer)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("extn", decoded, ShouldResemble, extn)
+		})
+	})
+	Convey("Invalid hop trace extensions are rejected", t, func() {
+		_, err := NewExtnHopTrace(1, HopTraceMaxSlots+1)
+		SoMsg("too many slots", err, ShouldNotBeNil)
+		_, err = ExtnHopTraceFromRaw(make(common.RawBytes, hopTraceHdrLen+1))
+		SoMsg("bad length", err, ShouldNotBeNil)
+		b := make(common.RawBytes, hopTraceOffset(1))
+		b[0] = 2
+		_, err = ExtnHopTraceFromRaw(b)
+		SoMsg("too many entries", err, ShouldNotBeNil)
+	})
+}
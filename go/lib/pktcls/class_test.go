@@ -72,6 +72,47 @@ func TestClassMap(t *testing.T) {
 				),
 			},
 		},
+		{
+			Name:     "IPv6 and L4",
+			FileName: "class_3",
+			Classes: ClassMap{
+				"voip": NewClass(
+					"voip",
+					NewCondAllOf(
+						NewCondIPv6(&IPv6MatchDSCP{0x2e}),
+						NewCondIPv6(&IPv6MatchProtocol{17}),
+						NewCondL4(&L4MatchDstPort{Min: 16384, Max: 32767}),
+					),
+				),
+				"web": NewClass(
+					"web",
+					NewCondAnyOf(
+						NewCondIPv6(&IPv6MatchSource{
+							&net.IPNet{
+								IP:   net.ParseIP("2001:db8::"),
+								Mask: net.CIDRMask(32, 128),
+							},
+						}),
+						NewCondIPv6(&IPv6MatchDestination{
+							&net.IPNet{
+								IP:   net.ParseIP("2001:db8:1::"),
+								Mask: net.CIDRMask(48, 128),
+							},
+						}),
+						NewCondL4(&L4MatchSrcPort{Min: 443, Max: 443}),
+					),
+				),
+				"bulk": NewClass(
+					"bulk",
+					NewCondAllOf(
+						NewCondIPv6(&IPv6MatchTrafficClass{0x20}),
+						NewCondIPv6(&IPv6MatchLength{Min: 1000, Max: 1500}),
+						NewCondIPv4(&IPv4MatchProtocol{6}),
+						NewCondIPv4(&IPv4MatchLength{Min: 1000, Max: 1500}),
+					),
+				),
+			},
+		},
 		{
 			Name:     "nil ClassMap stays nil",
 			FileName: "class_2",
@@ -222,6 +263,71 @@ func TestBadJSON(t *testing.T) {
 			},
 			"Name": "Unable to parse source operand string"
 		}
+		`, `
+		{
+			"CondIPv6": {
+				"MatchSource": {
+					"Net": "10.0.0.0/8"
+				}
+			},
+			"Name": "IPv4 network in IPv6 condition"
+		}
+		`, `
+		{
+			"CondIPv6": {
+				"MatchToS": {
+					"TOS": "0x80"
+				}
+			},
+			"Name": "IPv4 predicate in IPv6 condition"
+		}
+		`, `
+		{
+			"CondIPv6": {
+				"MatchDSCP": {
+					"DSCP": "0x40"
+				}
+			},
+			"Name": "DSCP operand out of range"
+		}
+		`, `
+		{
+			"CondL4": {
+				"MatchDstPort": {
+					"Min": "80"
+				}
+			},
+			"Name": "No port range maximum"
+		}
+		`, `
+		{
+			"CondL4": {
+				"MatchSrcPort": {
+					"Min": "1024",
+					"Max": "80"
+				}
+			},
+			"Name": "Port range minimum larger than maximum"
+		}
+		`, `
+		{
+			"CondL4": {
+				"MatchSrcPort": {
+					"Min": "0",
+					"Max": "65536"
+				}
+			},
+			"Name": "Port out of range"
+		}
+		`, `
+		{
+			"CondL4": {
+				"MatchSource": {
+					"Net": "10.0.0.0/8"
+				}
+			},
+			"Name": "IPv4 predicate in L4 condition"
+		}
 	`}
 	Convey("Marshaling bad JSON should return errors", t, func() {
 		for i, tc := range testCases {
@@ -178,11 +178,7 @@ func NewCondIPv4(p IPv4Predicate) *CondIPv4 {
 }
 
 func (c *CondIPv4) Eval(v interface{}) bool {
-	if v == nil {
-		return false
-	}
-	pkt := v.(*Packet)
-	// Protect against typed nils
+	pkt := toPacket(v)
 	if pkt == nil {
 		return false
 	}
@@ -203,6 +199,95 @@ func (c *CondIPv4) MarshalJSON() ([]byte, error) {
 
 func (c *CondIPv4) UnmarshalJSON(b []byte) error {
 	var err error
-	c.Predicate, err = unmarshalPredicate(b)
+	c.Predicate, err = unmarshalIPv4Predicate(b)
+	return err
+}
+
+var _ Cond = (*CondIPv6)(nil)
+
+// CondIPv6 conditions return true if the embedded IPv6 predicate returns true.
+type CondIPv6 struct {
+	Predicate IPv6Predicate
+}
+
+func NewCondIPv6(p IPv6Predicate) *CondIPv6 {
+	return &CondIPv6{Predicate: p}
+}
+
+func (c *CondIPv6) Eval(v interface{}) bool {
+	pkt := toPacket(v)
+	if pkt == nil {
+		return false
+	}
+	parsedPkt, ok := pkt.parsedPkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
+	if !ok || parsedPkt == nil {
+		return false
+	}
+	return c.Predicate.Eval(parsedPkt)
+}
+
+func (c *CondIPv6) Type() string {
+	return TypeCondIPv6
+}
+
+func (c *CondIPv6) MarshalJSON() ([]byte, error) {
+	return marshalInterface(c.Predicate)
+}
+
+func (c *CondIPv6) UnmarshalJSON(b []byte) error {
+	var err error
+	c.Predicate, err = unmarshalIPv6Predicate(b)
 	return err
 }
+
+var _ Cond = (*CondL4)(nil)
+
+// CondL4 conditions return true if the packet is a TCP or UDP packet (over IPv4
+// or IPv6) and the embedded L4 predicate returns true.
+type CondL4 struct {
+	Predicate L4Predicate
+}
+
+func NewCondL4(p L4Predicate) *CondL4 {
+	return &CondL4{Predicate: p}
+}
+
+func (c *CondL4) Eval(v interface{}) bool {
+	pkt := toPacket(v)
+	if pkt == nil {
+		return false
+	}
+	switch l := pkt.parsedPkt.TransportLayer().(type) {
+	case *layers.TCP:
+		return c.Predicate.Eval(uint16(l.SrcPort), uint16(l.DstPort))
+	case *layers.UDP:
+		return c.Predicate.Eval(uint16(l.SrcPort), uint16(l.DstPort))
+	default:
+		return false
+	}
+}
+
+func (c *CondL4) Type() string {
+	return TypeCondL4
+}
+
+func (c *CondL4) MarshalJSON() ([]byte, error) {
+	return marshalInterface(c.Predicate)
+}
+
+func (c *CondL4) UnmarshalJSON(b []byte) error {
+	var err error
+	c.Predicate, err = unmarshalL4Predicate(b)
+	return err
+}
+
+// toPacket returns the packet contained in v, or nil if v does not contain a
+// packet.
+func toPacket(v interface{}) *Packet {
+	if v == nil {
+		return nil
+	}
+	// Protect against typed nils
+	pkt, _ := v.(*Packet)
+	return pkt
+}
@@ -155,6 +155,145 @@ func TestIPCond(t *testing.T) {
 	})
 }
 
+func TestIPv6L4Cond(t *testing.T) {
+	src := net.ParseIP("2001:db8:1::1")
+	dst := net.ParseIP("2001:db8:2::1")
+	udp6 := newTestPacketFromLayers(
+		&layers.IPv6{
+			Version:      6,
+			TrafficClass: 0xb8,
+			NextHeader:   layers.IPProtocolUDP,
+			HopLimit:     64,
+			SrcIP:        src,
+			DstIP:        dst,
+		},
+		&layers.UDP{SrcPort: 5004, DstPort: 20000},
+		gopacket.Payload(make([]byte, 100)),
+	)
+	tcp4 := newTestPacketFromLayers(
+		&layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Protocol: layers.IPProtocolTCP,
+			SrcIP:    net.IP{192, 168, 1, 1},
+			DstIP:    net.IP{10, 0, 0, 2},
+		},
+		&layers.TCP{SrcPort: 50000, DstPort: 443},
+		gopacket.Payload(make([]byte, 1200)),
+	)
+	testCases := []struct {
+		Name    string
+		Cond    Cond
+		Packet  *Packet
+		ExpEval bool
+	}{
+		{
+			Name: "Match IPv6 source and destination",
+			Cond: NewCondAllOf(
+				NewCondIPv6(&IPv6MatchSource{
+					&net.IPNet{IP: net.ParseIP("2001:db8:1::"), Mask: net.CIDRMask(48, 128)},
+				}),
+				NewCondIPv6(&IPv6MatchDestination{
+					&net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)},
+				}),
+			),
+			Packet:  udp6,
+			ExpEval: true,
+		},
+		{
+			Name: "IPv6 destination mismatch",
+			Cond: NewCondIPv6(&IPv6MatchDestination{
+				&net.IPNet{IP: net.ParseIP("2001:db8:1::"), Mask: net.CIDRMask(48, 128)},
+			}),
+			Packet:  udp6,
+			ExpEval: false,
+		},
+		{
+			Name: "Match IPv6 traffic class, DSCP, protocol and length",
+			Cond: NewCondAllOf(
+				NewCondIPv6(&IPv6MatchTrafficClass{0xb8}),
+				NewCondIPv6(&IPv6MatchDSCP{0x2e}),
+				NewCondIPv6(&IPv6MatchProtocol{uint8(layers.IPProtocolUDP)}),
+				NewCondIPv6(&IPv6MatchLength{Min: 148, Max: 148}),
+			),
+			Packet:  udp6,
+			ExpEval: true,
+		},
+		{
+			Name:    "IPv6 length out of range",
+			Cond:    NewCondIPv6(&IPv6MatchLength{Min: 149, Max: 1500}),
+			Packet:  udp6,
+			ExpEval: false,
+		},
+		{
+			Name:    "IPv6 condition on IPv4 packet",
+			Cond:    NewCondNot(NewCondIPv6(&IPv6MatchProtocol{uint8(layers.IPProtocolTCP)})),
+			Packet:  tcp4,
+			ExpEval: true,
+		},
+		{
+			Name:    "IPv4 condition on IPv6 packet",
+			Cond:    NewCondIPv4(&IPv4MatchProtocol{uint8(layers.IPProtocolUDP)}),
+			Packet:  udp6,
+			ExpEval: false,
+		},
+		{
+			Name: "Match IPv4 protocol and length",
+			Cond: NewCondAllOf(
+				NewCondIPv4(&IPv4MatchProtocol{uint8(layers.IPProtocolTCP)}),
+				NewCondIPv4(&IPv4MatchLength{Min: 1000, Max: 1500}),
+			),
+			Packet:  tcp4,
+			ExpEval: true,
+		},
+		{
+			Name: "Match UDP ports",
+			Cond: NewCondAllOf(
+				NewCondL4(&L4MatchSrcPort{Min: 5004, Max: 5005}),
+				NewCondL4(&L4MatchDstPort{Min: 16384, Max: 32767}),
+			),
+			Packet:  udp6,
+			ExpEval: true,
+		},
+		{
+			Name:    "Match TCP destination port",
+			Cond:    NewCondL4(&L4MatchDstPort{Min: 443, Max: 443}),
+			Packet:  tcp4,
+			ExpEval: true,
+		},
+		{
+			Name:    "TCP source port out of range",
+			Cond:    NewCondL4(&L4MatchSrcPort{Min: 0, Max: 1023}),
+			Packet:  tcp4,
+			ExpEval: false,
+		},
+		{
+			Name:    "L4 condition without transport layer",
+			Cond:    NewCondL4(&L4MatchSrcPort{Min: 0, Max: 65535}),
+			Packet:  newTestPacket(&layers.IPv4{}, []byte{1, 1, 1, 1}),
+			ExpEval: false,
+		},
+	}
+
+	Convey("TestIPv6L4Cond", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				SoMsg("eval", tc.Cond.Eval(tc.Packet), ShouldEqual, tc.ExpEval)
+			})
+		}
+	})
+}
+
+func newTestPacketFromLayers(l ...gopacket.SerializableLayer) *Packet {
+	buf := gopacket.NewSerializeBuffer()
+	gopacket.SerializeLayers(
+		buf,
+		gopacket.SerializeOptions{FixLengths: true},
+		l...,
+	)
+	return NewPacket(buf.Bytes())
+}
+
 func newTestPacket(ipv4 *layers.IPv4, pld []byte) *Packet {
 	buf := gopacket.NewSerializeBuffer()
 	gopacket.SerializeLayers(
@@ -49,6 +49,24 @@ const (
 	TypeIPv4MatchDestination = "MatchDestination"
 	TypeIPv4MatchToS         = "MatchToS"
 	TypeIPv4MatchDSCP        = "MatchDSCP"
+	TypeIPv4MatchProtocol    = "MatchProtocol"
+	TypeIPv4MatchLength      = "MatchLength"
+	TypeCondIPv6             = "CondIPv6"
+	TypeCondL4               = "CondL4"
+	TypeL4MatchSrcPort       = "MatchSrcPort"
+	TypeL4MatchDstPort       = "MatchDstPort"
+)
+
+// IPv6 predicates share their type names with the corresponding IPv4
+// predicates. They are only unmarshaled inside a CondIPv6, see
+// unmarshalIPv6Predicate.
+const (
+	TypeIPv6MatchSource       = "MatchSource"
+	TypeIPv6MatchDestination  = "MatchDestination"
+	TypeIPv6MatchTrafficClass = "MatchTrafficClass"
+	TypeIPv6MatchDSCP         = "MatchDSCP"
+	TypeIPv6MatchProtocol     = "MatchProtocol"
+	TypeIPv6MatchLength       = "MatchLength"
 )
 
 // generic container for marshaling custom data
@@ -95,6 +113,14 @@ func unmarshalInterface(b []byte) (Typer, error) {
 			var c CondIPv4
 			err := json.Unmarshal(*v, &c)
 			return &c, err
+		case TypeCondIPv6:
+			var c CondIPv6
+			err := json.Unmarshal(*v, &c)
+			return &c, err
+		case TypeCondL4:
+			var c CondL4
+			err := json.Unmarshal(*v, &c)
+			return &c, err
 		case TypeIPv4MatchSource:
 			var p IPv4MatchSource
 			err := json.Unmarshal(*v, &p)
@@ -111,6 +137,22 @@ func unmarshalInterface(b []byte) (Typer, error) {
 			var p IPv4MatchDSCP
 			err := json.Unmarshal(*v, &p)
 			return &p, err
+		case TypeIPv4MatchProtocol:
+			var p IPv4MatchProtocol
+			err := json.Unmarshal(*v, &p)
+			return &p, err
+		case TypeIPv4MatchLength:
+			var p IPv4MatchLength
+			err := json.Unmarshal(*v, &p)
+			return &p, err
+		case TypeL4MatchSrcPort:
+			var p L4MatchSrcPort
+			err := json.Unmarshal(*v, &p)
+			return &p, err
+		case TypeL4MatchDstPort:
+			var p L4MatchDstPort
+			err := json.Unmarshal(*v, &p)
+			return &p, err
 		default:
 			return nil, common.NewBasicError("Unknown type", nil, "type", k)
 		}
@@ -144,15 +186,64 @@ func unmarshalAction(b []byte) (Action, error) {
 	return a, nil
 }
 
-// unmarshal extracts an IPv4Predicate from a JSON encoding
-func unmarshalPredicate(b []byte) (IPv4Predicate, error) {
+// unmarshalIPv4Predicate extracts an IPv4Predicate from a JSON encoding
+func unmarshalIPv4Predicate(b []byte) (IPv4Predicate, error) {
 	t, err := unmarshalInterface(b)
 	if err != nil {
 		return nil, err
 	}
 	p, ok := t.(IPv4Predicate)
 	if !ok {
-		return nil, common.NewBasicError("Unable to extract Cond from interface", nil)
+		return nil, common.NewBasicError("Unable to extract IPv4Predicate from interface", nil)
+	}
+	return p, nil
+}
+
+// unmarshalIPv6Predicate extracts an IPv6Predicate from a JSON encoding. The
+// type names of IPv6 predicates overlap with those of IPv4 predicates, so they
+// cannot be handled by unmarshalInterface.
+func unmarshalIPv6Predicate(b []byte) (IPv6Predicate, error) {
+	var container map[string]*json.RawMessage
+	err := json.Unmarshal(b, &container)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range container {
+		var p IPv6Predicate
+		switch k {
+		case TypeIPv6MatchSource:
+			p = &IPv6MatchSource{}
+		case TypeIPv6MatchDestination:
+			p = &IPv6MatchDestination{}
+		case TypeIPv6MatchTrafficClass:
+			p = &IPv6MatchTrafficClass{}
+		case TypeIPv6MatchDSCP:
+			p = &IPv6MatchDSCP{}
+		case TypeIPv6MatchProtocol:
+			p = &IPv6MatchProtocol{}
+		case TypeIPv6MatchLength:
+			p = &IPv6MatchLength{}
+		default:
+			return nil, common.NewBasicError("Unknown type", nil, "type", k)
+		}
+		if v == nil {
+			return nil, common.NewBasicError("Missing predicate operand", nil, "type", k)
+		}
+		err := json.Unmarshal(*v, p)
+		return p, err
+	}
+	return nil, common.NewBasicError("Unable to extract IPv6Predicate from interface", nil)
+}
+
+// unmarshalL4Predicate extracts an L4Predicate from a JSON encoding
+func unmarshalL4Predicate(b []byte) (L4Predicate, error) {
+	t, err := unmarshalInterface(b)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := t.(L4Predicate)
+	if !ok {
+		return nil, common.NewBasicError("Unable to extract L4Predicate from interface", nil)
 	}
 	return p, nil
 }
@@ -218,3 +309,30 @@ func unmarshalUintField(b []byte, name, field string, width int) (uint64, error)
 	}
 	return i, nil
 }
+
+// marshalRange encodes the bounds of a range predicate as decimal numbers in
+// quoted strings.
+func marshalRange(min, max uint64) ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"Min": strconv.FormatUint(min, 10),
+			"Max": strconv.FormatUint(max, 10),
+		},
+	)
+}
+
+func unmarshalRange(b []byte, name string, width int) (uint64, uint64, error) {
+	min, err := unmarshalUintField(b, name, "Min", width)
+	if err != nil {
+		return 0, 0, err
+	}
+	max, err := unmarshalUintField(b, name, "Max", width)
+	if err != nil {
+		return 0, 0, err
+	}
+	if min > max {
+		return 0, 0, common.NewBasicError("Range minimum larger than maximum", nil,
+			"name", name, "min", min, "max", max)
+	}
+	return min, max, nil
+}
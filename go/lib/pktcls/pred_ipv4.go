@@ -167,3 +167,65 @@ func (m *IPv4MatchDSCP) UnmarshalJSON(b []byte) error {
 	m.DSCP = uint8(i)
 	return nil
 }
+
+var _ IPv4Predicate = (*IPv4MatchProtocol)(nil)
+
+// IPv4MatchProtocol checks whether the protocol field matches.
+type IPv4MatchProtocol struct {
+	Protocol uint8
+}
+
+func (m *IPv4MatchProtocol) Type() string {
+	return "MatchProtocol"
+}
+
+func (m *IPv4MatchProtocol) Eval(p *layers.IPv4) bool {
+	return m.Protocol == uint8(p.Protocol)
+}
+
+func (m *IPv4MatchProtocol) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"Protocol": fmt.Sprintf("%d", m.Protocol),
+		},
+	)
+}
+
+func (m *IPv4MatchProtocol) UnmarshalJSON(b []byte) error {
+	i, err := unmarshalUintField(b, "MatchProtocol", "Protocol", 8)
+	if err != nil {
+		return err
+	}
+	m.Protocol = uint8(i)
+	return nil
+}
+
+var _ IPv4Predicate = (*IPv4MatchLength)(nil)
+
+// IPv4MatchLength checks whether the total length of the packet, including the
+// IPv4 header, is between Min and Max (inclusive).
+type IPv4MatchLength struct {
+	Min uint16
+	Max uint16
+}
+
+func (m *IPv4MatchLength) Type() string {
+	return "MatchLength"
+}
+
+func (m *IPv4MatchLength) Eval(p *layers.IPv4) bool {
+	return m.Min <= p.Length && p.Length <= m.Max
+}
+
+func (m *IPv4MatchLength) MarshalJSON() ([]byte, error) {
+	return marshalRange(uint64(m.Min), uint64(m.Max))
+}
+
+func (m *IPv4MatchLength) UnmarshalJSON(b []byte) error {
+	min, max, err := unmarshalRange(b, "MatchLength", 16)
+	if err != nil {
+		return err
+	}
+	m.Min, m.Max = uint16(min), uint16(max)
+	return nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pktcls
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+// ipv6HdrLen is the length of the fixed IPv6 header.
+const ipv6HdrLen = 40
+
+// IPv6Predicate describes a single test on various IPv6 packet fields.
+type IPv6Predicate interface {
+	// Eval returns true if the IPv6 packet matched the predicate
+	Eval(*layers.IPv6) bool
+	Typer
+}
+
+var _ IPv6Predicate = (*IPv6MatchSource)(nil)
+
+// IPv6MatchSource checks whether the source IPv6 address is contained in Net.
+type IPv6MatchSource struct {
+	Net *net.IPNet
+}
+
+func (m *IPv6MatchSource) Type() string {
+	return TypeIPv6MatchSource
+}
+
+func (m *IPv6MatchSource) Eval(p *layers.IPv6) bool {
+	return m.Net.Contains(p.SrcIP)
+}
+
+func (m *IPv6MatchSource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"Net": m.Net.String(),
+		},
+	)
+}
+
+func (m *IPv6MatchSource) UnmarshalJSON(b []byte) error {
+	network, err := unmarshalIPv6Net(b, "MatchSource")
+	if err != nil {
+		return err
+	}
+	m.Net = network
+	return nil
+}
+
+var _ IPv6Predicate = (*IPv6MatchDestination)(nil)
+
+// IPv6MatchDestination checks whether the destination IPv6 address is
+// contained in Net.
+type IPv6MatchDestination struct {
+	Net *net.IPNet
+}
+
+func (m *IPv6MatchDestination) Type() string {
+	return TypeIPv6MatchDestination
+}
+
+func (m *IPv6MatchDestination) Eval(p *layers.IPv6) bool {
+	return m.Net.Contains(p.DstIP)
+}
+
+func (m *IPv6MatchDestination) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"Net": m.Net.String(),
+		},
+	)
+}
+
+func (m *IPv6MatchDestination) UnmarshalJSON(b []byte) error {
+	network, err := unmarshalIPv6Net(b, "MatchDestination")
+	if err != nil {
+		return err
+	}
+	m.Net = network
+	return nil
+}
+
+var _ IPv6Predicate = (*IPv6MatchTrafficClass)(nil)
+
+// IPv6MatchTrafficClass checks whether the traffic class field matches.
+type IPv6MatchTrafficClass struct {
+	TrafficClass uint8
+}
+
+func (m *IPv6MatchTrafficClass) Type() string {
+	return TypeIPv6MatchTrafficClass
+}
+
+func (m *IPv6MatchTrafficClass) Eval(p *layers.IPv6) bool {
+	return m.TrafficClass == p.TrafficClass
+}
+
+func (m *IPv6MatchTrafficClass) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"TrafficClass": fmt.Sprintf("%#x", m.TrafficClass),
+		},
+	)
+}
+
+func (m *IPv6MatchTrafficClass) UnmarshalJSON(b []byte) error {
+	// Format is 0x hex number in quoted string
+	i, err := unmarshalUintField(b, "MatchTrafficClass", "TrafficClass", 8)
+	if err != nil {
+		return err
+	}
+	m.TrafficClass = uint8(i)
+	return nil
+}
+
+var _ IPv6Predicate = (*IPv6MatchDSCP)(nil)
+
+// IPv6MatchDSCP checks whether the DSCP subset of the traffic class field
+// matches.
+type IPv6MatchDSCP struct {
+	DSCP uint8
+}
+
+func (m *IPv6MatchDSCP) Type() string {
+	return TypeIPv6MatchDSCP
+}
+
+func (m *IPv6MatchDSCP) Eval(p *layers.IPv6) bool {
+	return m.DSCP == p.TrafficClass>>2
+}
+
+func (m *IPv6MatchDSCP) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"DSCP": fmt.Sprintf("%#x", m.DSCP),
+		},
+	)
+}
+
+func (m *IPv6MatchDSCP) UnmarshalJSON(b []byte) error {
+	// Format is 0x hex number in quoted string
+	i, err := unmarshalUintField(b, "MatchDSCP", "DSCP", 6)
+	if err != nil {
+		return err
+	}
+	m.DSCP = uint8(i)
+	return nil
+}
+
+var _ IPv6Predicate = (*IPv6MatchProtocol)(nil)
+
+// IPv6MatchProtocol checks whether the next header field of the fixed IPv6
+// header matches. Extension headers are not skipped.
+type IPv6MatchProtocol struct {
+	Protocol uint8
+}
+
+func (m *IPv6MatchProtocol) Type() string {
+	return TypeIPv6MatchProtocol
+}
+
+func (m *IPv6MatchProtocol) Eval(p *layers.IPv6) bool {
+	return m.Protocol == uint8(p.NextHeader)
+}
+
+func (m *IPv6MatchProtocol) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"Protocol": fmt.Sprintf("%d", m.Protocol),
+		},
+	)
+}
+
+func (m *IPv6MatchProtocol) UnmarshalJSON(b []byte) error {
+	i, err := unmarshalUintField(b, "MatchProtocol", "Protocol", 8)
+	if err != nil {
+		return err
+	}
+	m.Protocol = uint8(i)
+	return nil
+}
+
+var _ IPv6Predicate = (*IPv6MatchLength)(nil)
+
+// IPv6MatchLength checks whether the length of the packet, including the fixed
+// IPv6 header, is between Min and Max (inclusive).
+type IPv6MatchLength struct {
+	Min uint16
+	Max uint16
+}
+
+func (m *IPv6MatchLength) Type() string {
+	return TypeIPv6MatchLength
+}
+
+func (m *IPv6MatchLength) Eval(p *layers.IPv6) bool {
+	l := int(p.Length) + ipv6HdrLen
+	return int(m.Min) <= l && l <= int(m.Max)
+}
+
+func (m *IPv6MatchLength) MarshalJSON() ([]byte, error) {
+	return marshalRange(uint64(m.Min), uint64(m.Max))
+}
+
+func (m *IPv6MatchLength) UnmarshalJSON(b []byte) error {
+	min, max, err := unmarshalRange(b, "MatchLength", 16)
+	if err != nil {
+		return err
+	}
+	m.Min, m.Max = uint16(min), uint16(max)
+	return nil
+}
+
+func unmarshalIPv6Net(b []byte, name string) (*net.IPNet, error) {
+	s, err := unmarshalStringField(b, name, "Net")
+	if err != nil {
+		return nil, err
+	}
+	ip, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to parse "+name+" operand", err)
+	}
+	if ip.To4() != nil {
+		return nil, common.NewBasicError("Operand is not an IPv6 network", nil,
+			"name", name, "net", s)
+	}
+	return network, nil
+}
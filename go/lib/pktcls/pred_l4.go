@@ -0,0 +1,83 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pktcls
+
+// L4Predicate describes a single test on the ports of a TCP or UDP packet.
+type L4Predicate interface {
+	// Eval returns true if the source and destination ports matched the
+	// predicate
+	Eval(srcPort, dstPort uint16) bool
+	Typer
+}
+
+var _ L4Predicate = (*L4MatchSrcPort)(nil)
+
+// L4MatchSrcPort checks whether the source port is between Min and Max
+// (inclusive).
+type L4MatchSrcPort struct {
+	Min uint16
+	Max uint16
+}
+
+func (m *L4MatchSrcPort) Type() string {
+	return TypeL4MatchSrcPort
+}
+
+func (m *L4MatchSrcPort) Eval(srcPort, _ uint16) bool {
+	return m.Min <= srcPort && srcPort <= m.Max
+}
+
+func (m *L4MatchSrcPort) MarshalJSON() ([]byte, error) {
+	return marshalRange(uint64(m.Min), uint64(m.Max))
+}
+
+func (m *L4MatchSrcPort) UnmarshalJSON(b []byte) error {
+	min, max, err := unmarshalRange(b, "MatchSrcPort", 16)
+	if err != nil {
+		return err
+	}
+	m.Min, m.Max = uint16(min), uint16(max)
+	return nil
+}
+
+var _ L4Predicate = (*L4MatchDstPort)(nil)
+
+// L4MatchDstPort checks whether the destination port is between Min and Max
+// (inclusive).
+type L4MatchDstPort struct {
+	Min uint16
+	Max uint16
+}
+
+func (m *L4MatchDstPort) Type() string {
+	return TypeL4MatchDstPort
+}
+
+func (m *L4MatchDstPort) Eval(_, dstPort uint16) bool {
+	return m.Min <= dstPort && dstPort <= m.Max
+}
+
+func (m *L4MatchDstPort) MarshalJSON() ([]byte, error) {
+	return marshalRange(uint64(m.Min), uint64(m.Max))
+}
+
+func (m *L4MatchDstPort) UnmarshalJSON(b []byte) error {
+	min, max, err := unmarshalRange(b, "MatchDstPort", 16)
+	if err != nil {
+		return err
+	}
+	m.Min, m.Max = uint16(min), uint16(max)
+	return nil
+}
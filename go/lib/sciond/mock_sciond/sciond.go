@@ -10,6 +10,7 @@ import (
 	addr "github.com/scionproto/scion/go/lib/addr"
 	common "github.com/scionproto/scion/go/lib/common"
 	path_mgmt "github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	drkey "github.com/scionproto/scion/go/lib/drkey"
 	sciond "github.com/scionproto/scion/go/lib/sciond"
 	proto "github.com/scionproto/scion/go/proto"
 	reflect "reflect"
@@ -121,6 +122,21 @@ func (mr *MockConnectorMockRecorder) Close(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockConnector)(nil).Close), arg0)
 }
 
+// DRKeyLvl2 mocks base method
+func (m *MockConnector) DRKeyLvl2(arg0 context.Context, arg1 drkey.Lvl2Meta, arg2 time.Time) (*sciond.DRKeyLvl2Reply, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DRKeyLvl2", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*sciond.DRKeyLvl2Reply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DRKeyLvl2 indicates an expected call of DRKeyLvl2
+func (mr *MockConnectorMockRecorder) DRKeyLvl2(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DRKeyLvl2", reflect.TypeOf((*MockConnector)(nil).DRKeyLvl2), arg0, arg1, arg2)
+}
+
 // IFInfo mocks base method
 func (m *MockConnector) IFInfo(arg0 context.Context, arg1 []common.IFIDType) (*sciond.IFInfoReply, error) {
 	m.ctrl.T.Helper()
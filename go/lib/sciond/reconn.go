@@ -21,6 +21,7 @@ import (
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	"github.com/scionproto/scion/go/lib/drkey"
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/proto"
 )
@@ -125,6 +126,17 @@ func (c *reconnector) RevNotification(ctx context.Context,
 	return conn.RevNotification(ctx, sRevInfo)
 }
 
+func (c *reconnector) DRKeyLvl2(ctx context.Context, meta drkey.Lvl2Meta,
+	valTime time.Time) (*DRKeyLvl2Reply, error) {
+
+	conn, err := c.ctxAwareConnect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+	return conn.DRKeyLvl2(ctx, meta, valTime)
+}
+
 func (c *reconnector) Close(ctx context.Context) error {
 	return nil
 }
@@ -39,7 +39,9 @@ import (
 
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
 	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	"github.com/scionproto/scion/go/lib/drkey"
 	"github.com/scionproto/scion/go/lib/infra/disp"
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/sock/reliable"
@@ -126,6 +128,9 @@ type Connector interface {
 	RevNotificationFromRaw(ctx context.Context, b []byte) (*RevReply, error)
 	// RevNotification sends a RevocationInfo message to SCIOND.
 	RevNotification(ctx context.Context, sRevInfo *path_mgmt.SignedRevInfo) (*RevReply, error)
+	// DRKeyLvl2 requests from SCIOND the second-level DRKey described by meta
+	// that is valid at valTime. The epoch in meta is ignored.
+	DRKeyLvl2(ctx context.Context, meta drkey.Lvl2Meta, valTime time.Time) (*DRKeyLvl2Reply, error)
 	// Close shuts down the connection to a SCIOND server.
 	Close(ctx context.Context) error
 }
@@ -352,6 +357,26 @@ func (c *connector) RevNotification(ctx context.Context,
 	return reply.(*Pld).RevReply, nil
 }
 
+func (c *connector) DRKeyLvl2(ctx context.Context, meta drkey.Lvl2Meta,
+	valTime time.Time) (*DRKeyLvl2Reply, error) {
+
+	c.Lock()
+	defer c.Unlock()
+	reply, err := c.dispatcher.Request(
+		ctx,
+		&Pld{
+			Id:           c.nextID(),
+			Which:        proto.SCIONDMsg_Which_drkeyLvl2Req,
+			DRKeyLvl2Req: drkey_mgmt.NewLvl2ReqFromMeta(meta, valTime),
+		},
+		nil,
+	)
+	if err != nil {
+		return nil, common.NewBasicError("[sciond-API] Failed to get DRKeyLvl2", err)
+	}
+	return reply.(*Pld).DRKeyLvl2Reply, nil
+}
+
 func (c *connector) Close(ctx context.Context) error {
 	return c.dispatcher.Close(ctx)
 }
@@ -23,6 +23,7 @@ import (
 
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
 	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
 	"github.com/scionproto/scion/go/lib/hostinfo"
 	"github.com/scionproto/scion/go/lib/util"
@@ -59,6 +60,30 @@ func (c PathErrorCode) String() string {
 	}
 }
 
+type DRKeyErrorCode uint16
+
+const (
+	DRKeyErrorOk DRKeyErrorCode = iota
+	DRKeyErrorCSTimeout
+	DRKeyErrorInternal
+	DRKeyErrorBadRequest
+)
+
+func (c DRKeyErrorCode) String() string {
+	switch c {
+	case DRKeyErrorOk:
+		return "OK"
+	case DRKeyErrorCSTimeout:
+		return "SCIOND timed out while requesting the DRKey"
+	case DRKeyErrorInternal:
+		return "SCIOND experienced an internal error"
+	case DRKeyErrorBadRequest:
+		return "Bad DRKey request"
+	default:
+		return fmt.Sprintf("Unknown error (%v)", uint16(c))
+	}
+}
+
 var _ proto.Cerealizable = (*Pld)(nil)
 
 type Pld struct {
@@ -74,6 +99,8 @@ type Pld struct {
 	IfInfoReply        *IFInfoReply
 	ServiceInfoRequest *ServiceInfoRequest
 	ServiceInfoReply   *ServiceInfoReply
+	DRKeyLvl2Req       *drkey_mgmt.Lvl2Req `capnp:"drkeyLvl2Req"`
+	DRKeyLvl2Reply     *DRKeyLvl2Reply     `capnp:"drkeyLvl2Reply"`
 }
 
 func NewPldFromRaw(b common.RawBytes) (*Pld, error) {
@@ -118,6 +145,10 @@ func (p *Pld) union() (interface{}, error) {
 		return p.ServiceInfoRequest, nil
 	case proto.SCIONDMsg_Which_serviceInfoReply:
 		return p.ServiceInfoReply, nil
+	case proto.SCIONDMsg_Which_drkeyLvl2Req:
+		return p.DRKeyLvl2Req, nil
+	case proto.SCIONDMsg_Which_drkeyLvl2Reply:
+		return p.DRKeyLvl2Reply, nil
 	}
 	return nil, common.NewBasicError("Unsupported SCIOND union type", nil, "type", p.Which)
 }
@@ -365,3 +396,12 @@ type ServiceInfoReplyEntry struct {
 	Ttl         uint32
 	HostInfos   []hostinfo.HostInfo
 }
+
+type DRKeyLvl2Reply struct {
+	ErrorCode DRKeyErrorCode
+	Rep       *drkey_mgmt.Lvl2Rep
+}
+
+func (r *DRKeyLvl2Reply) String() string {
+	return fmt.Sprintf("ErrorCode=%v Rep=%v", r.ErrorCode, r.Rep)
+}
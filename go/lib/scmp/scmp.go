@@ -102,6 +102,9 @@ const (
 const (
 	T_S_BadVersion Type = iota
 	T_S_SetupNoReq
+	T_S_BadSOF
+	T_S_ExpiredRes
+	T_S_BWExceeded
 )
 
 var typeNameMap = map[Class][]string{
@@ -115,7 +118,7 @@ var typeNameMap = map[Class][]string{
 		"BAD_INFO_FIELD", "BAD_HOP_FIELD",
 	},
 	C_Ext:   {"TOO_MANY_HOPBYHOP", "BAD_EXT_ORDER", "BAD_HOPBYHOP", "BAD_END2END"},
-	C_Sibra: {"BAD_VERSION", "SETUP_NO_REQ"},
+	C_Sibra: {"BAD_VERSION", "SETUP_NO_REQ", "BAD_SOF", "EXPIRED_RES", "BW_EXCEEDED"},
 }
 
 func (t Type) Name(c Class) string {
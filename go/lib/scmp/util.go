@@ -35,7 +35,7 @@ func classTypeQuotes(ct ClassType) []RawBlock {
 		return quoteBasic
 	case ct.Class == C_Path:
 		return quotePath
-	case ct.Class == C_Ext:
+	case ct.Class == C_Ext || ct.Class == C_Sibra:
 		return quoteExts
 	default:
 		return nil
@@ -68,10 +68,8 @@ func ParseInfo(b common.RawBytes, ct ClassType) (Info, error) {
 		return InfoRevocationFromRaw(b)
 	case ct.Class == C_Path:
 		return InfoPathOffsetsFromRaw(b)
-	case ct.Class == C_Ext:
+	case ct.Class == C_Ext || ct.Class == C_Sibra:
 		return InfoExtIdxFromRaw(b)
-	case ct.Class == C_Sibra:
-		// TODO(kormat): not defined/handled yet.
 	}
 	return nil, nil
 }
@@ -32,7 +32,7 @@ type scionConnBase struct {
 	// Reference to SCION networking context
 	scionNet *SCIONNetwork
 
-	// Describes L3 and L4 protocol; currently only udp4 is implemented
+	// Describes L3 and L4 protocol; currently only udp4 and udp6 are implemented
 	net string
 }
 
@@ -22,6 +22,7 @@ import (
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/pathmgr"
 	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/spath"
 )
 
 const (
@@ -38,12 +39,31 @@ var _ Error = (*OpError)(nil)
 
 type OpError struct {
 	scmp *scmp.Hdr
+	// info is the info field of the SCMP message, if it is passed to the
+	// caller.
+	info scmp.Info
+	// path is the path the SCMP message was received on, if it is passed to
+	// the caller.
+	path *spath.Path
 }
 
 func (e *OpError) SCMP() *scmp.Hdr {
 	return e.scmp
 }
 
+// Info returns the info field of the SCMP message. It is only set for oversize
+// packet errors, for which it contains the MTU.
+func (e *OpError) Info() scmp.Info {
+	return e.info
+}
+
+// Path returns the path the SCMP message was received on. It is only set for
+// oversize packet errors, such that the sender can tell which of its paths
+// the error refers to.
+func (e *OpError) Path() *spath.Path {
+	return e.path
+}
+
 func (e *OpError) Error() string {
 	return e.scmp.String()
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/overlay"
 	"github.com/scionproto/scion/go/lib/pathmgr"
+	"github.com/scionproto/scion/go/lib/sciond"
 	"github.com/scionproto/scion/go/lib/scmp"
 	"github.com/scionproto/scion/go/lib/sock/reliable"
 )
@@ -80,19 +81,62 @@ type SCMPHandler interface {
 //
 // If the resolver is nil, revocations are not forwarded to any resolver.
 // However, they are still sent back to the caller during read operations.
+// The revocations are not authenticated, see NewSCMPAuthHandler.
 func NewSCMPHandler(pr pathmgr.Resolver) SCMPHandler {
 	return &scmpHandler{
 		pathResolver: pr,
 	}
 }
 
+// NewSCMPOversizeHandler creates an SCMP handler similar to the one returned by
+// NewSCMPHandler, that in addition passes oversize packet errors back to the
+// caller. The errors contain the reported MTU and the path they were received
+// on. Oversize packet errors are not authenticated, callers should only act on
+// them if they refer to a path that is in use.
+func NewSCMPOversizeHandler(pr pathmgr.Resolver) SCMPHandler {
+	return &scmpHandler{
+		pathResolver: pr,
+		oversize:     true,
+	}
+}
+
+// NewSCMPAuthHandler creates an SCMP handler that verifies the authentication
+// of received revocations before acting on them. It can be used instead of the
+// default handler via NewCustomNetworkWithPR. DRKeys are fetched via
+// sciondConn, the certificate chains to verify hash tree signatures are
+// fetched from chains. If chains is nil, revocations authenticated with a hash
+// tree signature are never acted upon.
+//
+// Verification happens in the background, such that reads are not blocked by
+// fetching keys or certificates. Thus, revocations are never passed back to
+// the caller; the resolver is informed once the authentication has been
+// verified. Revocations without authentication are dropped.
+func NewSCMPAuthHandler(pr pathmgr.Resolver, sciondConn sciond.Connector,
+	chains ChainProvider) SCMPHandler {
+
+	return &scmpHandler{
+		pathResolver: pr,
+		verifier:     &scmpVerifier{sciondConn: sciondConn, chains: chains},
+		verifySlots:  make(chan struct{}, maxPendingSCMPVerifications),
+	}
+}
+
 // scmpHandler handles SCMP messages received from the network.
-// If a resolver is configured, it is informed of any received revocations. All
-// revocations are passed back to the caller embedded in the error, so
-// applications can handle them manually.
+// If a resolver is configured, it is informed of any received revocations.
+// Unless revocations are verified, they are passed back to the caller embedded
+// in the error, so applications can handle them manually.
 type scmpHandler struct {
 	// pathResolver manages revocations received via SCMP. If nil, nothing is informed.
 	pathResolver pathmgr.Resolver
+	// verifier verifies the authentication of revocations. If nil, revocations
+	// are not verified. Otherwise, they are not passed back to the caller.
+	verifier *scmpVerifier
+	// verifySlots bounds the number of verifications running in the
+	// background.
+	verifySlots chan struct{}
+	// oversize indicates whether oversize packet errors are passed back to
+	// the caller.
+	oversize bool
 }
 
 func (h *scmpHandler) Handle(pkt *SCIONPacket) error {
@@ -101,13 +145,36 @@ func (h *scmpHandler) Handle(pkt *SCIONPacket) error {
 		return common.NewBasicError("scmp handler invoked with non-scmp packet", nil, "pkt", pkt)
 	}
 
-	// Only handle revocations for now
+	// Only handle revocations and oversize packet errors for now
 	if hdr.Class == scmp.C_Path && hdr.Type == scmp.T_P_RevokedIF {
 		return h.handleSCMPRev(hdr, pkt)
 	}
+	if h.oversize && hdr.Class == scmp.C_Routing && hdr.Type == scmp.T_R_OversizePkt {
+		return h.handleSCMPOversize(hdr, pkt)
+	}
 	return nil
 }
 
+// handleSCMPOversize passes oversize packet errors back to the caller, such
+// that applications can reduce the size of the packets they send.
+func (h *scmpHandler) handleSCMPOversize(hdr *scmp.Hdr, pkt *SCIONPacket) error {
+	scmpPayload, ok := pkt.Payload.(*scmp.Payload)
+	if !ok {
+		return common.NewBasicError("Unable to type assert payload to SCMP payload", nil,
+			"type", common.TypeOf(pkt.Payload))
+	}
+	info, ok := scmpPayload.Info.(*scmp.InfoPktSize)
+	if !ok {
+		return common.NewBasicError("Unable to type assert SCMP Info to SCMP Pkt Size Info", nil,
+			"type", common.TypeOf(scmpPayload.Info))
+	}
+	opErr := &OpError{scmp: hdr, info: info.Copy()}
+	if pkt.Path != nil {
+		opErr.path = pkt.Path.Copy()
+	}
+	return opErr
+}
+
 func (h *scmpHandler) handleSCMPRev(hdr *scmp.Hdr, pkt *SCIONPacket) error {
 	scmpPayload, ok := pkt.Payload.(*scmp.Payload)
 	if !ok {
@@ -120,8 +187,43 @@ func (h *scmpHandler) handleSCMPRev(hdr *scmp.Hdr, pkt *SCIONPacket) error {
 			"type", common.TypeOf(scmpPayload.Info))
 	}
 	log.Info("Received SCMP revocation", "header", hdr.String(), "payload", scmpPayload.String())
+	if h.verifier == nil {
+		if h.pathResolver != nil {
+			h.pathResolver.RevokeRaw(context.TODO(), info.RawSRev)
+		}
+		return &OpError{scmp: hdr}
+	}
+	if !hasSCMPAuth(pkt.Extensions) {
+		log.Warn("Dropping unauthenticated SCMP revocation")
+		return nil
+	}
+	select {
+	case h.verifySlots <- struct{}{}:
+	default:
+		log.Warn("Dropping SCMP revocation, too many pending verifications")
+		return nil
+	}
+	// The packet buffers are reused by the connection once Handle returns.
+	cpy, err := copySCMPPacket(pkt)
+	if err != nil {
+		<-h.verifySlots
+		return common.NewBasicError("Unable to copy SCMP revocation", err)
+	}
+	go h.verifyRev(cpy)
+	return nil
+}
+
+// verifyRev verifies the authentication of the revocation in pkt and informs
+// the resolver if it is valid.
+func (h *scmpHandler) verifyRev(pkt *SCIONPacket) {
+	defer log.LogPanicAndExit()
+	defer func() { <-h.verifySlots }()
+	if err := h.verifier.Verify(pkt); err != nil {
+		log.Warn("Dropping SCMP revocation", "err", err)
+		return
+	}
 	if h.pathResolver != nil {
+		info := pkt.Payload.(*scmp.Payload).Info.(*scmp.InfoRevocation)
 		h.pathResolver.RevokeRaw(context.TODO(), info.RawSRev)
 	}
-	return &OpError{scmp: hdr}
 }
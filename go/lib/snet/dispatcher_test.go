@@ -0,0 +1,59 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snet
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestSCMPHandlerOversize(t *testing.T) {
+	Convey("SCMP oversize packet errors", t, func() {
+		ct := scmp.ClassType{Class: scmp.C_Routing, Type: scmp.T_R_OversizePkt}
+		info := &scmp.InfoPktSize{Size: 1500, MTU: 1400}
+		pld := scmp.PldFromQuotes(ct, info, common.L4UDP,
+			func(scmp.RawBlock) common.RawBytes { return nil })
+		path := spath.New(xtest.MustParseHexString("0100000000000000"))
+		pkt := &SCIONPacket{
+			SCIONPacketInfo: SCIONPacketInfo{
+				Path:     path,
+				L4Header: scmp.NewHdr(ct, pld.Len()),
+				Payload:  pld,
+			},
+		}
+		Convey("are ignored by the default handler", func() {
+			SoMsg("err", NewSCMPHandler(nil).Handle(pkt), ShouldBeNil)
+		})
+		Convey("are returned by the oversize handler", func() {
+			err := NewSCMPOversizeHandler(nil).Handle(pkt)
+			opErr, ok := err.(*OpError)
+			SoMsg("OpError", ok, ShouldBeTrue)
+			SoMsg("info", opErr.Info(), ShouldResemble, info)
+			SoMsg("path", opErr.Path(), ShouldResemble, path)
+			Convey("without referencing the packet", func() {
+				info.MTU = 1280
+				path.Raw[0] = 0
+				SoMsg("info", opErr.Info().(*scmp.InfoPktSize).MTU, ShouldEqual, 1400)
+				SoMsg("path", opErr.Path().Raw[0], ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -86,8 +86,8 @@ func (c *scionConnReader) read(b []byte) (int, *Addr, error) {
 	}
 
 	var remote *Addr
-	// On UDP4 network we can get either UDP traffic or SCMP messages
-	if c.base.net == "udp4" {
+	// On UDP networks we can get either UDP traffic or SCMP messages
+	if c.base.net == "udp4" || c.base.net == "udp6" {
 		// Extract remote address
 		remote = &Addr{
 			IA: pkt.Source.IA,
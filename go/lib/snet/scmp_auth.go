@@ -0,0 +1,196 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snet
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/scrypto/cert"
+	"github.com/scionproto/scion/go/lib/spkt"
+	"github.com/scionproto/scion/go/lib/spse/scmp_auth"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+const (
+	// ErrorSCMPNotAuthenticated indicates that an SCMP message does not carry
+	// an authentication extension.
+	ErrorSCMPNotAuthenticated = "SCMP message not authenticated"
+
+	// scmpAuthTimeout is the time allocated to fetching the DRKey or the
+	// certificate chain that is needed to verify an SCMP message.
+	scmpAuthTimeout = 2 * time.Second
+	// maxPendingSCMPVerifications is the maximum number of SCMP messages that
+	// are verified concurrently by an SCMP handler. Further messages are not
+	// acted upon.
+	maxPendingSCMPVerifications = 16
+)
+
+// ChainProvider provides the certificate chains that are needed to verify
+// SCMP messages authenticated with a signed hash tree. It is implemented by
+// the trust store.
+type ChainProvider interface {
+	GetValidChain(ctx context.Context, ia addr.IA, version uint64,
+		server net.Addr) (*cert.Chain, error)
+}
+
+// scmpVerifier verifies the authentication of SCMP messages. The AS-to-host
+// DRKeys are fetched from SCIOND, the certificate chains needed to verify
+// hash tree signatures are fetched from the chain provider.
+type scmpVerifier struct {
+	sciondConn sciond.Connector
+	chains     ChainProvider
+}
+
+// Verify checks the SCMPAuthDRKey or SCMPAuthHashTree extension of the SCMP
+// packet pkt. If the packet carries no such extension, an error with message
+// ErrorSCMPNotAuthenticated is returned.
+func (v *scmpVerifier) Verify(pkt *SCIONPacket) error {
+	hdr, ok := pkt.L4Header.(*scmp.Hdr)
+	if !ok {
+		return common.NewBasicError("Packet has non-SCMP L4", nil,
+			"type", common.TypeOf(pkt.L4Header))
+	}
+	sp := &spkt.ScnPkt{
+		DstIA:   pkt.Destination.IA,
+		SrcIA:   pkt.Source.IA,
+		DstHost: pkt.Destination.Host,
+		SrcHost: pkt.Source.Host,
+		L4:      hdr,
+		Pld:     pkt.Payload,
+	}
+	for _, e := range pkt.Extensions {
+		switch extn := e.(type) {
+		case *scmp_auth.DRKeyExtn:
+			return v.verifyDRKey(sp, hdr, extn)
+		case *scmp_auth.HashTreeExtn:
+			return v.verifyHashTree(sp, hdr, extn)
+		}
+	}
+	return common.NewBasicError(ErrorSCMPNotAuthenticated, nil)
+}
+
+func (v *scmpVerifier) verifyDRKey(sp *spkt.ScnPkt, hdr *scmp.Hdr,
+	extn *scmp_auth.DRKeyExtn) error {
+
+	if v.sciondConn == nil {
+		return common.NewBasicError("Unable to verify DRKey authentication, no SCIOND", nil)
+	}
+	if extn.Direction != scmp_auth.AsToHost {
+		return common.NewBasicError("Unsupported authentication direction", nil,
+			"dir", extn.Direction)
+	}
+	meta := drkey.Lvl2Meta{
+		KeyType:  drkey.AS2Host,
+		Protocol: scmp_auth.DRKeyProtocol,
+		SrcIA:    sp.SrcIA,
+		DstIA:    sp.DstIA,
+		DstHost:  sp.DstHost,
+	}
+	ctx, cancelF := context.WithTimeout(context.Background(), scmpAuthTimeout)
+	defer cancelF()
+	reply, err := v.sciondConn.DRKeyLvl2(ctx, meta, hdr.Time())
+	if err != nil {
+		return common.NewBasicError("Unable to fetch DRKey", err)
+	}
+	if reply.ErrorCode != sciond.DRKeyErrorOk || reply.Rep == nil {
+		return common.NewBasicError("Unable to fetch DRKey", nil, "code", reply.ErrorCode)
+	}
+	return scmp_auth.VerifyDRKeyMAC(reply.Rep.DRKey, sp, extn.MAC)
+}
+
+func (v *scmpVerifier) verifyHashTree(sp *spkt.ScnPkt, hdr *scmp.Hdr,
+	extn *scmp_auth.HashTreeExtn) error {
+
+	if v.chains == nil {
+		return common.NewBasicError("Unable to verify hash tree signature, no trust store", nil)
+	}
+	ctx, cancelF := context.WithTimeout(context.Background(), scmpAuthTimeout)
+	defer cancelF()
+	chain, err := v.chains.GetValidChain(ctx, sp.SrcIA, scrypto.LatestVer, nil)
+	if err != nil {
+		return common.NewBasicError("Unable to fetch certificate chain", err, "ia", sp.SrcIA)
+	}
+	leaf := chain.Leaf
+	if !leaf.Subject.Equal(sp.SrcIA) {
+		return common.NewBasicError("Certificate subject does not match source", nil,
+			"subject", leaf.Subject, "src", sp.SrcIA)
+	}
+	ts := util.TimeToSecs(hdr.Time())
+	if ts < leaf.IssuingTime || ts > leaf.ExpirationTime {
+		return common.NewBasicError("Certificate not valid at SCMP timestamp", nil,
+			"ts", hdr.Time(), "issuing", util.SecsToTime(leaf.IssuingTime),
+			"expiration", util.SecsToTime(leaf.ExpirationTime))
+	}
+	return scmp_auth.VerifyHashTree(sp, extn, leaf.SubjectSignKey, leaf.SignAlgorithm)
+}
+
+// hasSCMPAuth returns true if extns contains an SCMP authentication extension.
+func hasSCMPAuth(extns []common.Extension) bool {
+	for _, e := range extns {
+		switch e.(type) {
+		case *scmp_auth.DRKeyExtn, *scmp_auth.HashTreeExtn:
+			return true
+		}
+	}
+	return false
+}
+
+// copySCMPPacket returns a deep copy of the parts of the SCMP packet pkt that
+// are needed to verify its authentication.
+func copySCMPPacket(pkt *SCIONPacket) (*SCIONPacket, error) {
+	hdr, ok := pkt.L4Header.(*scmp.Hdr)
+	if !ok {
+		return nil, common.NewBasicError("Packet has non-SCMP L4", nil,
+			"type", common.TypeOf(pkt.L4Header))
+	}
+	pld, ok := pkt.Payload.(*scmp.Payload)
+	if !ok {
+		return nil, common.NewBasicError("Packet has non-SCMP payload", nil,
+			"type", common.TypeOf(pkt.Payload))
+	}
+	cpyPld, err := pld.Copy()
+	if err != nil {
+		return nil, err
+	}
+	if pld.Info != nil {
+		cpyPld.(*scmp.Payload).Info = pld.Info.Copy()
+	}
+	cpy := &SCIONPacket{
+		SCIONPacketInfo: SCIONPacketInfo{
+			Destination: SCIONAddress{IA: pkt.Destination.IA},
+			Source:      SCIONAddress{IA: pkt.Source.IA},
+			L4Header:    hdr.Copy(),
+			Payload:     cpyPld,
+		},
+	}
+	if pkt.Destination.Host != nil {
+		cpy.Destination.Host = pkt.Destination.Host.Copy()
+	}
+	if pkt.Source.Host != nil {
+		cpy.Source.Host = pkt.Source.Host.Copy()
+	}
+	for _, e := range pkt.Extensions {
+		cpy.Extensions = append(cpy.Extensions, e.Copy())
+	}
+	return cpy, nil
+}
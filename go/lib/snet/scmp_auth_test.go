@@ -0,0 +1,270 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snet
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/pathmgr/mock_pathmgr"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/sciond/mock_sciond"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/scrypto/cert"
+	"github.com/scionproto/scion/go/lib/spkt"
+	"github.com/scionproto/scion/go/lib/spse/scmp_auth"
+	"github.com/scionproto/scion/go/lib/util"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestSCMPVerifier(t *testing.T) {
+	Convey("Verify", t, func() {
+		mctrl := gomock.NewController(t)
+		defer mctrl.Finish()
+		conn := mock_sciond.NewMockConnector(mctrl)
+		v := &scmpVerifier{sciondConn: conn}
+		key := xtest.MustParseHexString("e328a2b10f12b5e31e74392e70924444")
+		ct := scmp.ClassType{Class: scmp.C_Path, Type: scmp.T_P_RevokedIF}
+		pld := scmp.PldFromQuotes(ct, nil, common.L4UDP,
+			func(scmp.RawBlock) common.RawBytes { return nil })
+		pkt := &SCIONPacket{
+			SCIONPacketInfo: SCIONPacketInfo{
+				Destination: SCIONAddress{
+					IA:   xtest.MustParseIA("1-ff00:0:112"),
+					Host: addr.HostFromIPStr("127.0.0.2"),
+				},
+				Source: SCIONAddress{
+					IA:   xtest.MustParseIA("1-ff00:0:111"),
+					Host: addr.HostFromIPStr("127.0.0.1"),
+				},
+				L4Header: scmp.NewHdr(ct, pld.Len()),
+				Payload:  pld,
+			},
+		}
+		mac, err := scmp_auth.ComputeDRKeyMAC(key, &spkt.ScnPkt{
+			DstIA:   pkt.Destination.IA,
+			SrcIA:   pkt.Source.IA,
+			DstHost: pkt.Destination.Host,
+			SrcHost: pkt.Source.Host,
+			L4:      pkt.L4Header,
+			Pld:     pkt.Payload,
+		})
+		xtest.FailOnErr(t, err)
+		extn := scmp_auth.NewDRKeyExtn()
+		xtest.FailOnErr(t, extn.SetDirection(scmp_auth.AsToHost))
+		xtest.FailOnErr(t, extn.SetMAC(mac))
+		reply := &sciond.DRKeyLvl2Reply{Rep: &drkey_mgmt.Lvl2Rep{DRKey: key}}
+
+		Convey("Packet without extension is not authenticated", func() {
+			err := v.Verify(pkt)
+			SoMsg("err", common.GetErrorMsg(err), ShouldEqual, ErrorSCMPNotAuthenticated)
+		})
+		Convey("Valid MAC is accepted", func() {
+			pkt.Extensions = []common.Extension{extn}
+			conn.EXPECT().DRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any()).Return(reply, nil)
+			SoMsg("err", v.Verify(pkt), ShouldBeNil)
+		})
+		Convey("Invalid MAC is rejected", func() {
+			extn.MAC[0] ^= 0xff
+			pkt.Extensions = []common.Extension{extn}
+			conn.EXPECT().DRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any()).Return(reply, nil)
+			err := v.Verify(pkt)
+			SoMsg("err", err, ShouldNotBeNil)
+			SoMsg("msg", common.GetErrorMsg(err), ShouldNotEqual, ErrorSCMPNotAuthenticated)
+		})
+		Convey("Failure to fetch the key is an error", func() {
+			pkt.Extensions = []common.Extension{extn}
+			conn.EXPECT().DRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+				&sciond.DRKeyLvl2Reply{ErrorCode: sciond.DRKeyErrorInternal}, nil)
+			SoMsg("err", v.Verify(pkt), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestSCMPVerifierHashTree(t *testing.T) {
+	Convey("Verify hash tree signature", t, func() {
+		pub, priv, err := scrypto.GenKeyPair(scrypto.Ed25519)
+		xtest.FailOnErr(t, err)
+		srcIA := xtest.MustParseIA("1-ff00:0:111")
+		now := util.TimeToSecs(time.Now())
+		chains := &testChainProvider{chain: &cert.Chain{Leaf: &cert.Certificate{
+			Subject:        srcIA,
+			SubjectSignKey: pub,
+			SignAlgorithm:  scrypto.Ed25519,
+			IssuingTime:    now - 60,
+			ExpirationTime: now + 60,
+		}}}
+		v := &scmpVerifier{chains: chains}
+		ct := scmp.ClassType{Class: scmp.C_Path, Type: scmp.T_P_RevokedIF}
+		pld := scmp.PldFromQuotes(ct, nil, common.L4UDP,
+			func(scmp.RawBlock) common.RawBytes { return nil })
+		pkt := &SCIONPacket{
+			SCIONPacketInfo: SCIONPacketInfo{
+				Destination: SCIONAddress{
+					IA:   xtest.MustParseIA("1-ff00:0:112"),
+					Host: addr.HostFromIPStr("127.0.0.2"),
+				},
+				Source: SCIONAddress{
+					IA:   srcIA,
+					Host: addr.HostFromIPStr("127.0.0.1"),
+				},
+				L4Header: scmp.NewHdr(ct, pld.Len()),
+				Payload:  pld,
+			},
+		}
+		leaf, err := scmp_auth.HashTreeLeaf(&spkt.ScnPkt{
+			DstIA:   pkt.Destination.IA,
+			SrcIA:   pkt.Source.IA,
+			DstHost: pkt.Destination.Host,
+			SrcHost: pkt.Source.Host,
+			L4:      pkt.L4Header,
+			Pld:     pkt.Payload,
+		})
+		xtest.FailOnErr(t, err)
+		other := xtest.MustParseHexString("f91d279f3ca1f2b177aa2f4304cbd750")
+		tree, err := scmp_auth.NewHashTree([]common.RawBytes{other, leaf})
+		xtest.FailOnErr(t, err)
+		sig, err := scmp_auth.SignHashTree(tree, priv, scrypto.Ed25519)
+		xtest.FailOnErr(t, err)
+		extn, err := tree.NewExtn(1, sig)
+		xtest.FailOnErr(t, err)
+		pkt.Extensions = []common.Extension{extn}
+
+		Convey("Valid proof is accepted", func() {
+			SoMsg("err", v.Verify(pkt), ShouldBeNil)
+		})
+		Convey("Invalid proof is rejected", func() {
+			extn.Hashes[0] ^= 0xff
+			SoMsg("err", v.Verify(pkt), ShouldNotBeNil)
+		})
+		Convey("Expired certificate is rejected", func() {
+			chains.chain.Leaf.ExpirationTime = now - 1
+			SoMsg("err", v.Verify(pkt), ShouldNotBeNil)
+		})
+		Convey("Missing trust store is an error", func() {
+			v.chains = nil
+			err := v.Verify(pkt)
+			SoMsg("err", err, ShouldNotBeNil)
+			SoMsg("msg", common.GetErrorMsg(err), ShouldNotEqual, ErrorSCMPNotAuthenticated)
+		})
+	})
+}
+
+func TestSCMPAuthHandler(t *testing.T) {
+	Convey("Authenticating SCMP handler", t, func() {
+		mctrl := gomock.NewController(t)
+		defer mctrl.Finish()
+		conn := mock_sciond.NewMockConnector(mctrl)
+		resolver := mock_pathmgr.NewMockResolver(mctrl)
+		h := NewSCMPAuthHandler(resolver, conn, nil)
+		key := xtest.MustParseHexString("e328a2b10f12b5e31e74392e70924444")
+		rawSRev := common.RawBytes("revocation")
+		ct := scmp.ClassType{Class: scmp.C_Path, Type: scmp.T_P_RevokedIF}
+		info := &scmp.InfoRevocation{InfoPathOffsets: &scmp.InfoPathOffsets{}, RawSRev: rawSRev}
+		pld := scmp.PldFromQuotes(ct, info, common.L4UDP,
+			func(scmp.RawBlock) common.RawBytes { return nil })
+		pkt := &SCIONPacket{
+			SCIONPacketInfo: SCIONPacketInfo{
+				Destination: SCIONAddress{
+					IA:   xtest.MustParseIA("1-ff00:0:112"),
+					Host: addr.HostFromIPStr("127.0.0.2"),
+				},
+				Source: SCIONAddress{
+					IA:   xtest.MustParseIA("1-ff00:0:111"),
+					Host: addr.HostFromIPStr("127.0.0.1"),
+				},
+				L4Header: scmp.NewHdr(ct, pld.Len()),
+				Payload:  pld,
+			},
+		}
+		mac, err := scmp_auth.ComputeDRKeyMAC(key, &spkt.ScnPkt{
+			DstIA:   pkt.Destination.IA,
+			SrcIA:   pkt.Source.IA,
+			DstHost: pkt.Destination.Host,
+			SrcHost: pkt.Source.Host,
+			L4:      pkt.L4Header,
+			Pld:     pkt.Payload,
+		})
+		xtest.FailOnErr(t, err)
+		extn := scmp_auth.NewDRKeyExtn()
+		xtest.FailOnErr(t, extn.SetDirection(scmp_auth.AsToHost))
+		xtest.FailOnErr(t, extn.SetMAC(mac))
+		reply := &sciond.DRKeyLvl2Reply{Rep: &drkey_mgmt.Lvl2Rep{DRKey: key}}
+
+		Convey("Unauthenticated revocation is dropped", func() {
+			SoMsg("err", h.Handle(pkt), ShouldBeNil)
+		})
+		Convey("Forged revocation is not acted upon", func() {
+			pkt.Extensions = []common.Extension{extn}
+			forged := &sciond.DRKeyLvl2Reply{Rep: &drkey_mgmt.Lvl2Rep{
+				DRKey: xtest.MustParseHexString("71d6c74635658a0cdfc6150855592b41")}}
+			conn.EXPECT().DRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any()).Return(forged, nil)
+			SoMsg("err", h.Handle(pkt), ShouldBeNil)
+			// The resolver must not be informed once the verification is done.
+			slots := h.(*scmpHandler).verifySlots
+			for deadline := time.Now().Add(time.Second); len(slots) > 0; {
+				if time.Now().After(deadline) {
+					t.Fatal("Verification not done")
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+		Convey("Authenticated revocation is verified in the background", func() {
+			pkt.Extensions = []common.Extension{extn}
+			fetch := make(chan struct{})
+			revoked := make(chan struct{})
+			conn.EXPECT().DRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ drkey.Lvl2Meta, _ time.Time) (
+					*sciond.DRKeyLvl2Reply, error) {
+
+					<-fetch
+					return reply, nil
+				})
+			resolver.EXPECT().RevokeRaw(gomock.Any(), common.RawBytes("revocation")).Do(
+				func(context.Context, common.RawBytes) { close(revoked) })
+			SoMsg("err", h.Handle(pkt), ShouldBeNil)
+			// Mutating the packet after Handle returns must not affect
+			// the verification.
+			pkt.Destination.Host = addr.HostFromIPStr("127.0.0.3")
+			info.RawSRev[0] ^= 0xff
+			close(fetch)
+			select {
+			case <-revoked:
+			case <-time.After(time.Second):
+				t.Fatal("Resolver not informed")
+			}
+		})
+	})
+}
+
+type testChainProvider struct {
+	chain *cert.Chain
+}
+
+func (p *testChainProvider) GetValidChain(_ context.Context, _ addr.IA, _ uint64,
+	_ net.Addr) (*cert.Chain, error) {
+
+	return p.chain, nil
+}
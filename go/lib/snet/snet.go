@@ -182,7 +182,7 @@ func getResolver(sciondPath string) (pathmgr.Resolver, error) {
 }
 
 // DialSCION returns a SCION connection to raddr. Nil values for laddr are not
-// supported yet.  Parameter network must be "udp4". The returned connection's
+// supported yet.  Parameter network must be "udp4" or "udp6". The returned connection's
 // Read and Write methods can be used to receive and send SCION packets.
 //
 // A timeout of 0 means infinite timeout.
@@ -193,7 +193,7 @@ func (n *SCIONNetwork) DialSCION(network string, laddr, raddr *Addr,
 }
 
 // DialSCIONWithBindSVC returns a SCION connection to raddr. Nil values for laddr are not
-// supported yet.  Parameter network must be "udp4". The returned connection's
+// supported yet.  Parameter network must be "udp4" or "udp6". The returned connection's
 // Read and Write methods can be used to receive and send SCION packets.
 //
 // A timeout of 0 means infinite timeout.
@@ -215,7 +215,7 @@ func (n *SCIONNetwork) DialSCIONWithBindSVC(network string, laddr, raddr, baddr
 // ListenSCION registers laddr with the dispatcher. Nil values for laddr are
 // not supported yet. The returned connection's ReadFrom and WriteTo methods
 // can be used to receive and send SCION packets with per-packet addressing.
-// Parameter network must be "udp4".
+// Parameter network must be "udp4" or "udp6".
 //
 // A timeout of 0 means infinite timeout.
 func (n *SCIONNetwork) ListenSCION(network string, laddr *Addr,
@@ -227,12 +227,60 @@ func (n *SCIONNetwork) ListenSCION(network string, laddr *Addr,
 // ListenSCIONWithBindSVC registers laddr with the dispatcher. Nil values for laddr are
 // not supported yet. The returned connection's ReadFrom and WriteTo methods
 // can be used to receive and send SCION packets with per-packet addressing.
-// Parameter network must be "udp4".
+// Parameter network must be "udp4" or "udp6".
 //
 // A timeout of 0 means infinite timeout.
 func (n *SCIONNetwork) ListenSCIONWithBindSVC(network string, laddr, baddr *Addr,
 	svc addr.HostSVC, timeout time.Duration) (Conn, error) {
 
+	return n.listen(network, laddr, baddr, svc, nil, timeout)
+}
+
+// DialSCIONWithSPSE is similar to DialSCION, except that the packets of the
+// returned connection are protected with the SCION packet security extension
+// (SPSE) as configured in cfg. Every sent packet is authenticated, received
+// packets that fail verification or are replayed are dropped. SCMP
+// messages are not verified, see NewSCMPAuthHandler.
+//
+// A timeout of 0 means infinite timeout.
+func (n *SCIONNetwork) DialSCIONWithSPSE(network string, laddr, raddr *Addr, cfg SPSEConfig,
+	timeout time.Duration) (Conn, error) {
+
+	if raddr == nil {
+		return nil, common.NewBasicError("Unable to dial to nil remote", nil)
+	}
+	conn, err := n.listen(network, laddr, nil, addr.SvcNone, &cfg, timeout)
+	if err != nil {
+		return nil, err
+	}
+	snetConn := conn.(*SCIONConn)
+	snetConn.raddr = raddr.Copy()
+	return conn, nil
+}
+
+// ListenSCIONWithSPSE is similar to ListenSCION, except that the packets of
+// the returned connection are protected with the SCION packet security
+// extension (SPSE) as configured in cfg. Every sent packet is authenticated,
+// received packets that fail verification or are replayed are dropped. SCMP
+// messages are not verified, see NewSCMPAuthHandler.
+//
+// A timeout of 0 means infinite timeout.
+func (n *SCIONNetwork) ListenSCIONWithSPSE(network string, laddr *Addr, cfg SPSEConfig,
+	timeout time.Duration) (Conn, error) {
+
+	return n.listen(network, laddr, nil, addr.SvcNone, &cfg, timeout)
+}
+
+// listen registers laddr with the dispatcher. If spseCfg is not nil, the
+// packets of the connection are protected with SPSE.
+func (n *SCIONNetwork) listen(network string, laddr, baddr *Addr, svc addr.HostSVC,
+	spseCfg *SPSEConfig, timeout time.Duration) (Conn, error) {
+
+	if spseCfg != nil {
+		if err := spseCfg.Validate(); err != nil {
+			return nil, common.NewBasicError("Invalid SPSE configuration", err)
+		}
+	}
 	// FIXME(scrye): If no local address is specified, we want to
 	// bind to the address of the outbound interface on a random
 	// free port. However, the current dispatcher version cannot
@@ -248,6 +296,10 @@ func (n *SCIONNetwork) ListenSCIONWithBindSVC(network string, laddr, baddr *Addr
 		l3Type = addr.HostTypeIPv4
 		l4Type = common.L4UDP
 		defL4 = addr.NewL4UDPInfo(0)
+	case "udp6":
+		l3Type = addr.HostTypeIPv6
+		l4Type = common.L4UDP
+		defL4 = addr.NewL4UDPInfo(0)
 	default:
 		return nil, common.NewBasicError("Network not implemented", nil, "net", network)
 	}
@@ -320,6 +372,14 @@ func (n *SCIONNetwork) ListenSCIONWithBindSVC(network string, laddr, baddr *Addr
 		conn.laddr.Host.L4 = addr.NewL4UDPInfo(port)
 	}
 	log.Debug("Registered with dispatcher", "addr", conn.laddr)
+	if spseCfg != nil {
+		spseConn, err := newSPSEPacketConn(packetConn, *spseCfg)
+		if err != nil {
+			packetConn.Close()
+			return nil, err
+		}
+		packetConn = spseConn
+	}
 	return newSCIONConn(conn, n.pathResolver, packetConn), nil
 }
 
@@ -0,0 +1,477 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snet
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/overlay"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/spse"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+const (
+	// DefaultSPSEReplayWindow is the default maximum difference between the
+	// timestamp of a received packet and the local time.
+	DefaultSPSEReplayWindow = 5 * time.Second
+	// SPSEDRKeyProtocol is the protocol identifier of the second-level
+	// DRKeys that are used to authenticate packets.
+	SPSEDRKeyProtocol = "spse"
+
+	// spseMetaLength is the length of the SPSE metadata added by snet. It
+	// consists of the timestamp and a sequence number, which makes the
+	// authenticators of packets with identical content distinct.
+	spseMetaLength = spse.TimestampLength + 8
+	// spseKeyTimeout is the time allocated to fetching a key.
+	spseKeyTimeout = 2 * time.Second
+	// spseKeyRetryInterval is the time after which a key that could not be
+	// fetched is fetched again.
+	spseKeyRetryInterval = time.Second
+	// maxPendingSPSEKeyFetches is the maximum number of keys that are
+	// fetched concurrently in the background.
+	maxPendingSPSEKeyFetches = 16
+)
+
+// SPSEConfig configures the SCION packet security extension (SPSE) protection
+// of a connection.
+type SPSEConfig struct {
+	// SecMode is the security mode used to authenticate packets. Supported
+	// modes are AES-CMAC, HMAC-SHA256 and Ed25519.
+	SecMode spse.SecMode
+	// Keys provides the keys to authenticate outgoing and verify incoming
+	// packets.
+	Keys SPSEKeySource
+	// ReplayWindow is the maximum difference between the timestamp of a
+	// received packet and the local time. If it is zero,
+	// DefaultSPSEReplayWindow is used.
+	ReplayWindow time.Duration
+}
+
+// Validate checks the configuration.
+func (cfg *SPSEConfig) Validate() error {
+	switch cfg.SecMode {
+	case spse.AesCMac, spse.HmacSha256, spse.Ed25519:
+	default:
+		return common.NewBasicError("Unsupported SecMode", nil, "mode", cfg.SecMode)
+	}
+	if cfg.Keys == nil {
+		return common.NewBasicError("No key source", nil)
+	}
+	if cfg.ReplayWindow < 0 {
+		return common.NewBasicError("Negative replay window", nil, "window", cfg.ReplayWindow)
+	}
+	return nil
+}
+
+func (cfg *SPSEConfig) replayWindow() time.Duration {
+	if cfg.ReplayWindow == 0 {
+		return DefaultSPSEReplayWindow
+	}
+	return cfg.ReplayWindow
+}
+
+// SPSEKeySource provides the keys for the SPSE protection of packets from
+// src to dst.
+type SPSEKeySource interface {
+	// AuthKey returns the key to authenticate a packet that is sent at time t.
+	AuthKey(ctx context.Context, src, dst SCIONAddress, t time.Time) (common.RawBytes, error)
+	// VerifyKey returns the key to verify a packet that was sent at time t.
+	VerifyKey(ctx context.Context, src, dst SCIONAddress, t time.Time) (common.RawBytes, error)
+}
+
+var _ SPSEKeySource = PreSharedKey(nil)
+
+// PreSharedKey is a symmetric key that is shared with all remote hosts. It can
+// be used with the AES-CMAC and HMAC-SHA256 modes.
+type PreSharedKey common.RawBytes
+
+func (k PreSharedKey) AuthKey(_ context.Context, _, _ SCIONAddress,
+	_ time.Time) (common.RawBytes, error) {
+
+	return common.RawBytes(k), nil
+}
+
+func (k PreSharedKey) VerifyKey(_ context.Context, _, _ SCIONAddress,
+	_ time.Time) (common.RawBytes, error) {
+
+	return common.RawBytes(k), nil
+}
+
+var _ SPSEKeySource = (*SignKeys)(nil)
+
+// SignKeys is the key pair used with the Ed25519 mode. Outgoing packets are
+// signed with the local private key, incoming packets are verified with the
+// public key of the remote host.
+type SignKeys struct {
+	// SignKey is the private key of the local host.
+	SignKey common.RawBytes
+	// RemoteKey is the public key of the remote host.
+	RemoteKey common.RawBytes
+}
+
+func (k *SignKeys) AuthKey(_ context.Context, _, _ SCIONAddress,
+	_ time.Time) (common.RawBytes, error) {
+
+	return k.SignKey, nil
+}
+
+func (k *SignKeys) VerifyKey(_ context.Context, _, _ SCIONAddress,
+	_ time.Time) (common.RawBytes, error) {
+
+	return k.RemoteKey, nil
+}
+
+var _ SPSEKeySource = (*DRKeySource)(nil)
+
+// DRKeySource provides the host-to-host DRKeys K_{SrcIA:SrcHost->DstIA:DstHost}
+// of the packets. The keys are fetched from SCIOND and cached until the end
+// of their epoch. It can be used with the AES-CMAC and HMAC-SHA256 modes.
+//
+// Keys to authenticate packets are fetched synchronously. Keys to verify
+// packets are fetched in the background, such that packets from unknown
+// sources do not block the reader. Until the key is available, VerifyKey
+// returns an error and the packets are dropped. At most
+// maxPendingSPSEKeyFetches keys are fetched concurrently, and a key is not
+// fetched again for spseKeyRetryInterval after a fetch failed.
+type DRKeySource struct {
+	sciondConn sciond.Connector
+	cache      *cache.Cache
+	// failed contains the keys for which the last fetch failed.
+	failed *cache.Cache
+	// fetchSlots bounds the number of keys fetched in the background.
+	fetchSlots chan struct{}
+	mtx        sync.Mutex
+	// pending contains the keys that are fetched in the background.
+	pending map[string]struct{}
+}
+
+// NewDRKeySource creates a key source that fetches keys from SCIOND.
+func NewDRKeySource(sciondConn sciond.Connector) *DRKeySource {
+	return &DRKeySource{
+		sciondConn: sciondConn,
+		cache:      cache.New(cache.NoExpiration, time.Minute),
+		failed:     cache.New(spseKeyRetryInterval, time.Minute),
+		fetchSlots: make(chan struct{}, maxPendingSPSEKeyFetches),
+		pending:    make(map[string]struct{}),
+	}
+}
+
+func (s *DRKeySource) AuthKey(ctx context.Context, src, dst SCIONAddress,
+	t time.Time) (common.RawBytes, error) {
+
+	meta := spseDRKeyMeta(src, dst)
+	if key, ok := s.cached(meta, t); ok {
+		return key, nil
+	}
+	return s.fetch(ctx, meta, t)
+}
+
+func (s *DRKeySource) VerifyKey(ctx context.Context, src, dst SCIONAddress,
+	t time.Time) (common.RawBytes, error) {
+
+	meta := spseDRKeyMeta(src, dst)
+	if key, ok := s.cached(meta, t); ok {
+		return key, nil
+	}
+	s.fetchInBackground(meta, t)
+	return nil, common.NewBasicError("DRKey not available", nil, "meta", meta)
+}
+
+func (s *DRKeySource) cached(meta drkey.Lvl2Meta, t time.Time) (common.RawBytes, bool) {
+	if v, ok := s.cache.Get(meta.String()); ok {
+		if key := v.(drkey.Lvl2Key); key.Epoch.Contains(t) {
+			return common.RawBytes(key.Key), true
+		}
+	}
+	return nil, false
+}
+
+// fetchInBackground fetches the key, unless it is already being fetched, the
+// last fetch failed recently, or too many keys are being fetched.
+func (s *DRKeySource) fetchInBackground(meta drkey.Lvl2Meta, t time.Time) {
+	cacheKey := meta.String()
+	if _, ok := s.failed.Get(cacheKey); ok {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.pending[cacheKey]; ok {
+		return
+	}
+	select {
+	case s.fetchSlots <- struct{}{}:
+	default:
+		return
+	}
+	s.pending[cacheKey] = struct{}{}
+	go func() {
+		defer log.LogPanicAndExit()
+		defer func() {
+			s.mtx.Lock()
+			delete(s.pending, cacheKey)
+			s.mtx.Unlock()
+			<-s.fetchSlots
+		}()
+		ctx, cancelF := context.WithTimeout(context.Background(), spseKeyTimeout)
+		defer cancelF()
+		if _, err := s.fetch(ctx, meta, t); err != nil {
+			log.Debug("Unable to fetch SPSE DRKey", "meta", meta, "err", err)
+		}
+	}()
+}
+
+func (s *DRKeySource) fetch(ctx context.Context, meta drkey.Lvl2Meta,
+	t time.Time) (common.RawBytes, error) {
+
+	cacheKey := meta.String()
+	reply, err := s.sciondConn.DRKeyLvl2(ctx, meta, t)
+	if err != nil {
+		s.failed.SetDefault(cacheKey, struct{}{})
+		return nil, common.NewBasicError("Unable to fetch DRKey", err)
+	}
+	if reply.ErrorCode != sciond.DRKeyErrorOk || reply.Rep == nil {
+		s.failed.SetDefault(cacheKey, struct{}{})
+		return nil, common.NewBasicError("Unable to fetch DRKey", nil, "code", reply.ErrorCode)
+	}
+	key := reply.Rep.ToKey(meta)
+	if ttl := time.Until(key.Epoch.End); key.Epoch.Contains(time.Now()) && ttl > 0 {
+		s.cache.Set(cacheKey, key, ttl)
+	}
+	return common.RawBytes(key.Key), nil
+}
+
+func spseDRKeyMeta(src, dst SCIONAddress) drkey.Lvl2Meta {
+	return drkey.Lvl2Meta{
+		KeyType:  drkey.Host2Host,
+		Protocol: SPSEDRKeyProtocol,
+		SrcIA:    src.IA,
+		DstIA:    dst.IA,
+		SrcHost:  src.Host,
+		DstHost:  dst.Host,
+	}
+}
+
+var _ PacketConn = (*spsePacketConn)(nil)
+
+// spsePacketConn adds the SPSE extension to every outgoing packet and
+// verifies the SPSE extension of incoming data packets. Incoming packets that
+// are not authenticated, are outside of the replay window or are replayed are
+// dropped.
+//
+// SCMP messages are not verified, they are passed to the caller as they are
+// handled by the SCMP handler of the underlying connection. To authenticate
+// SCMP revocations, use the handler returned by NewSCMPAuthHandler.
+type spsePacketConn struct {
+	PacketConn
+	cfg    SPSEConfig
+	seq    uint64
+	replay *replayCache
+}
+
+func newSPSEPacketConn(conn PacketConn, cfg SPSEConfig) (*spsePacketConn, error) {
+	var seed [8]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return nil, common.NewBasicError("Unable to initialize sequence number", err)
+	}
+	return &spsePacketConn{
+		PacketConn: conn,
+		cfg:        cfg,
+		seq:        common.Order.Uint64(seed[:]),
+		replay:     newReplayCache(2 * cfg.replayWindow()),
+	}, nil
+}
+
+func (c *spsePacketConn) WriteTo(pkt *SCIONPacket, ov *overlay.OverlayAddr) error {
+	extn, err := spse.NewExtnWithMetaLen(c.cfg.SecMode, spseMetaLength)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	extn.SetTimestamp(now)
+	common.Order.PutUint64(extn.Metadata[spse.TimestampLength:],
+		atomic.AddUint64(&c.seq, 1))
+	input, err := spseInput(&pkt.SCIONPacketInfo)
+	if err != nil {
+		return err
+	}
+	ctx, cancelF := context.WithTimeout(context.Background(), spseKeyTimeout)
+	defer cancelF()
+	key, err := c.cfg.Keys.AuthKey(ctx, pkt.Source, pkt.Destination, now)
+	if err != nil {
+		return common.NewBasicError("Unable to get authentication key", err)
+	}
+	if err := extn.Authenticate(input, key); err != nil {
+		return common.NewBasicError("Unable to authenticate packet", err)
+	}
+	pkt.Extensions = append(removeSPSEExtns(pkt.Extensions), extn)
+	return c.PacketConn.WriteTo(pkt, ov)
+}
+
+func (c *spsePacketConn) ReadFrom(pkt *SCIONPacket, ov *overlay.OverlayAddr) error {
+	for {
+		pkt.Extensions = pkt.Extensions[:0]
+		if err := c.PacketConn.ReadFrom(pkt, ov); err != nil {
+			return err
+		}
+		if _, ok := pkt.L4Header.(*scmp.Hdr); ok {
+			// SCMP messages are not protected by SPSE, see spsePacketConn.
+			return nil
+		}
+		if err := c.verify(pkt, time.Now()); err != nil {
+			log.Debug("Dropping packet that failed SPSE verification",
+				"src", pkt.Source, "err", err)
+			continue
+		}
+		return nil
+	}
+}
+
+func (c *spsePacketConn) verify(pkt *SCIONPacket, now time.Time) error {
+	extn := findSPSEExtn(pkt.Extensions)
+	if extn == nil {
+		return common.NewBasicError("Packet not authenticated", nil)
+	}
+	if extn.SecMode != c.cfg.SecMode {
+		return common.NewBasicError("Unexpected SecMode", nil,
+			"expected", c.cfg.SecMode, "actual", extn.SecMode)
+	}
+	if len(extn.Metadata) != spseMetaLength {
+		return common.NewBasicError("Invalid metadata length", nil,
+			"expected", spseMetaLength, "actual", len(extn.Metadata))
+	}
+	ts := extn.Timestamp()
+	if diff := now.Sub(ts); diff > c.cfg.replayWindow() || -diff > c.cfg.replayWindow() {
+		return common.NewBasicError("Timestamp outside of replay window", nil,
+			"ts", util.TimeToString(ts), "window", c.cfg.replayWindow())
+	}
+	input, err := spseInput(&pkt.SCIONPacketInfo)
+	if err != nil {
+		return err
+	}
+	ctx, cancelF := context.WithTimeout(context.Background(), spseKeyTimeout)
+	defer cancelF()
+	key, err := c.cfg.Keys.VerifyKey(ctx, pkt.Source, pkt.Destination, ts)
+	if err != nil {
+		return common.NewBasicError("Unable to get verification key", err)
+	}
+	if err := extn.Verify(input, key); err != nil {
+		return err
+	}
+	if !c.replay.Insert(string(extn.Authenticator), now) {
+		return common.NewBasicError("Replayed packet", nil)
+	}
+	return nil
+}
+
+// spseInput returns the packet input that is authenticated by the SPSE
+// extension. It consists of the address header, the L4 header with the
+// checksum set to zero and the payload. The forwarding path and the
+// extensions are not covered, as they are modified in transit.
+func spseInput(pkt *SCIONPacketInfo) (common.RawBytes, error) {
+	if pkt.Destination.Host == nil || pkt.Source.Host == nil || pkt.L4Header == nil ||
+		pkt.Payload == nil {
+		return nil, common.NewBasicError("Incomplete SCION packet", nil)
+	}
+	pkt.L4Header.SetPldLen(pkt.Payload.Len())
+	rawHdr, err := pkt.L4Header.Pack(true)
+	if err != nil {
+		return nil, err
+	}
+	b := make(common.RawBytes, 2*addr.IABytes+pkt.Destination.Host.Size()+
+		pkt.Source.Host.Size()+len(rawHdr)+pkt.Payload.Len())
+	off := 0
+	pkt.Destination.IA.Write(b[off:])
+	off += addr.IABytes
+	pkt.Source.IA.Write(b[off:])
+	off += addr.IABytes
+	off += copy(b[off:], pkt.Destination.Host.Pack())
+	off += copy(b[off:], pkt.Source.Host.Pack())
+	off += copy(b[off:], rawHdr)
+	if _, err := pkt.Payload.WritePld(b[off:]); err != nil {
+		return nil, common.NewBasicError("Unable to write payload", err)
+	}
+	return b, nil
+}
+
+func findSPSEExtn(extns []common.Extension) *spse.Extn {
+	for _, e := range extns {
+		if extn, ok := e.(*spse.Extn); ok {
+			return extn
+		}
+	}
+	return nil
+}
+
+func removeSPSEExtns(extns []common.Extension) []common.Extension {
+	filtered := extns[:0]
+	for _, e := range extns {
+		if _, ok := e.(*spse.Extn); !ok {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// replayCache remembers the authenticators of received packets for the
+// duration of ttl.
+type replayCache struct {
+	mtx     sync.Mutex
+	ttl     time.Duration
+	seen    map[string]struct{}
+	entries []replayEntry
+}
+
+type replayEntry struct {
+	auth   string
+	expiry time.Time
+}
+
+func newReplayCache(ttl time.Duration) *replayCache {
+	return &replayCache{
+		ttl:  ttl,
+		seen: make(map[string]struct{}),
+	}
+}
+
+// Insert adds auth to the cache. It returns false if auth is already present.
+func (r *replayCache) Insert(auth string, now time.Time) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	// Entries are inserted in order of their expiry, expired entries are
+	// therefore at the start of the list.
+	expired := 0
+	for ; expired < len(r.entries) && now.After(r.entries[expired].expiry); expired++ {
+		delete(r.seen, r.entries[expired].auth)
+	}
+	r.entries = r.entries[expired:]
+	if _, ok := r.seen[auth]; ok {
+		return false
+	}
+	r.seen[auth] = struct{}{}
+	r.entries = append(r.entries, replayEntry{auth: auth, expiry: now.Add(r.ttl)})
+	return true
+}
@@ -0,0 +1,212 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/l4"
+	"github.com/scionproto/scion/go/lib/overlay"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/sciond/mock_sciond"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/spse"
+	"github.com/scionproto/scion/go/lib/util"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestSPSEPacketConn(t *testing.T) {
+	Convey("SPSE protected packet conn", t, func() {
+		key := PreSharedKey(xtest.MustParseHexString("0123456789abcdef0123456789abcdef"))
+		pub, priv, err := scrypto.GenKeyPair(scrypto.Ed25519)
+		xtest.FailOnErr(t, err)
+		tests := []struct {
+			Name   string
+			Sender SPSEConfig
+			Recv   SPSEConfig
+		}{
+			{
+				Name:   "AES-CMAC",
+				Sender: SPSEConfig{SecMode: spse.AesCMac, Keys: key},
+				Recv:   SPSEConfig{SecMode: spse.AesCMac, Keys: key},
+			},
+			{
+				Name:   "HMAC-SHA256",
+				Sender: SPSEConfig{SecMode: spse.HmacSha256, Keys: key},
+				Recv:   SPSEConfig{SecMode: spse.HmacSha256, Keys: key},
+			},
+			{
+				Name:   "Ed25519",
+				Sender: SPSEConfig{SecMode: spse.Ed25519, Keys: &SignKeys{SignKey: priv}},
+				Recv:   SPSEConfig{SecMode: spse.Ed25519, Keys: &SignKeys{RemoteKey: pub}},
+			},
+		}
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				lo := &loopbackPacketConn{}
+				sender, err := newSPSEPacketConn(lo, test.Sender)
+				xtest.FailOnErr(t, err)
+				recv, err := newSPSEPacketConn(lo, test.Recv)
+				xtest.FailOnErr(t, err)
+				ov := &overlay.OverlayAddr{}
+
+				Convey("Authenticated packets are received", func() {
+					SoMsg("write 1", sender.WriteTo(newSPSETestPacket("hello"), ov), ShouldBeNil)
+					SoMsg("write 2", sender.WriteTo(newSPSETestPacket("hello"), ov), ShouldBeNil)
+					for i := 0; i < 2; i++ {
+						pkt := &SCIONPacket{}
+						SoMsg("read", recv.ReadFrom(pkt, ov), ShouldBeNil)
+						SoMsg("pld", pkt.Payload, ShouldResemble, common.RawBytes("hello"))
+					}
+				})
+				Convey("Modified packets are dropped", func() {
+					xtest.FailOnErr(t, sender.WriteTo(newSPSETestPacket("hello"), ov))
+					lo.pkts[0].Payload = common.RawBytes("bye")
+					SoMsg("read", recv.ReadFrom(&SCIONPacket{}, ov), ShouldNotBeNil)
+				})
+				Convey("Replayed packets are dropped", func() {
+					xtest.FailOnErr(t, sender.WriteTo(newSPSETestPacket("hello"), ov))
+					lo.pkts = append(lo.pkts, lo.pkts[0])
+					SoMsg("read 1", recv.ReadFrom(&SCIONPacket{}, ov), ShouldBeNil)
+					SoMsg("read 2", recv.ReadFrom(&SCIONPacket{}, ov), ShouldNotBeNil)
+				})
+				Convey("Packets outside of the replay window are dropped", func() {
+					xtest.FailOnErr(t, sender.WriteTo(newSPSETestPacket("hello"), ov))
+					err := recv.verify(&SCIONPacket{SCIONPacketInfo: lo.pkts[0]},
+						time.Now().Add(2*DefaultSPSEReplayWindow))
+					SoMsg("err", err, ShouldNotBeNil)
+				})
+				Convey("Unauthenticated packets are dropped", func() {
+					xtest.FailOnErr(t, lo.WriteTo(newSPSETestPacket("hello"), ov))
+					SoMsg("read", recv.ReadFrom(&SCIONPacket{}, ov), ShouldNotBeNil)
+				})
+			})
+		}
+		Convey("Invalid configurations are rejected", func() {
+			cfgs := []SPSEConfig{
+				{SecMode: spse.GcmAes128, Keys: key},
+				{SecMode: spse.AesCMac},
+				{SecMode: spse.AesCMac, Keys: key, ReplayWindow: -time.Second},
+			}
+			for _, cfg := range cfgs {
+				SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestDRKeySource(t *testing.T) {
+	Convey("DRKey source", t, func() {
+		mctrl := gomock.NewController(t)
+		defer mctrl.Finish()
+		conn := mock_sciond.NewMockConnector(mctrl)
+		s := NewDRKeySource(conn)
+		pkt := newSPSETestPacket("")
+		now := time.Now()
+		epoch := drkey.EpochAt(now, time.Hour)
+		key := xtest.MustParseHexString("b6c2148ef248f7c75deb42eac972a8a3")
+		reply := &sciond.DRKeyLvl2Reply{Rep: &drkey_mgmt.Lvl2Rep{
+			DRKey:      key,
+			EpochBegin: util.TimeToSecs(epoch.Begin),
+			EpochEnd:   util.TimeToSecs(epoch.End),
+		}}
+		Convey("Keys to authenticate are fetched right away", func() {
+			conn.EXPECT().DRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any()).Return(reply, nil)
+			k, err := s.AuthKey(context.Background(), pkt.Source, pkt.Destination, now)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("key", k, ShouldResemble, key)
+		})
+		Convey("Keys to verify are fetched in the background", func() {
+			conn.EXPECT().DRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any()).Return(reply, nil)
+			_, err := s.VerifyKey(context.Background(), pkt.Source, pkt.Destination, now)
+			SoMsg("first err", err, ShouldNotBeNil)
+			waitForDRKeyFetches(t, s)
+			k, err := s.VerifyKey(context.Background(), pkt.Source, pkt.Destination, now)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("key", k, ShouldResemble, key)
+		})
+		Convey("Failed fetches are not retried right away", func() {
+			conn.EXPECT().DRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+				nil, common.NewBasicError("no key", nil))
+			_, err := s.VerifyKey(context.Background(), pkt.Source, pkt.Destination, now)
+			SoMsg("first err", err, ShouldNotBeNil)
+			waitForDRKeyFetches(t, s)
+			_, err = s.VerifyKey(context.Background(), pkt.Source, pkt.Destination, now)
+			SoMsg("second err", err, ShouldNotBeNil)
+			waitForDRKeyFetches(t, s)
+		})
+	})
+}
+
+func waitForDRKeyFetches(t *testing.T, s *DRKeySource) {
+	for deadline := time.Now().Add(time.Second); len(s.fetchSlots) > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("DRKey fetch not done")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newSPSETestPacket(pld string) *SCIONPacket {
+	return &SCIONPacket{
+		SCIONPacketInfo: SCIONPacketInfo{
+			Destination: SCIONAddress{
+				IA:   xtest.MustParseIA("1-ff00:0:112"),
+				Host: addr.HostFromIPStr("127.0.0.2"),
+			},
+			Source: SCIONAddress{
+				IA:   xtest.MustParseIA("1-ff00:0:111"),
+				Host: addr.HostFromIPStr("127.0.0.1"),
+			},
+			L4Header: &l4.UDP{SrcPort: 40000, DstPort: 40001},
+			Payload:  common.RawBytes(pld),
+		},
+	}
+}
+
+var _ PacketConn = (*loopbackPacketConn)(nil)
+
+// loopbackPacketConn returns the written packets on read. If no packets are
+// queued, ReadFrom returns an error.
+type loopbackPacketConn struct {
+	pkts []SCIONPacketInfo
+}
+
+func (c *loopbackPacketConn) WriteTo(pkt *SCIONPacket, _ *overlay.OverlayAddr) error {
+	c.pkts = append(c.pkts, pkt.SCIONPacketInfo)
+	return nil
+}
+
+func (c *loopbackPacketConn) ReadFrom(pkt *SCIONPacket, _ *overlay.OverlayAddr) error {
+	if len(c.pkts) == 0 {
+		return common.NewBasicError("No packet queued", nil)
+	}
+	pkt.SCIONPacketInfo, c.pkts = c.pkts[0], c.pkts[1:]
+	return nil
+}
+
+func (c *loopbackPacketConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *loopbackPacketConn) SetWriteDeadline(t time.Time) error { return nil }
+func (c *loopbackPacketConn) SetDeadline(t time.Time) error      { return nil }
+func (c *loopbackPacketConn) Close() error                       { return nil }
@@ -0,0 +1,114 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spse
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+// Timestamp returns the timestamp contained in the metadata.
+func (s *Extn) Timestamp() time.Time {
+	return util.SecsToTime(common.Order.Uint32(s.Metadata[:TimestampLength]))
+}
+
+// SetTimestamp sets the timestamp in the metadata.
+func (s *Extn) SetTimestamp(t time.Time) {
+	common.Order.PutUint32(s.Metadata[:TimestampLength], util.TimeToSecs(t))
+}
+
+// AuthInput returns the authenticated input of the extension. It consists of
+// the security mode, the metadata and the packet input, which is provided by
+// the caller.
+func (s *Extn) AuthInput(input common.RawBytes) common.RawBytes {
+	b := make(common.RawBytes, 0, SecModeLength+len(s.Metadata)+len(input))
+	b = append(b, uint8(s.SecMode))
+	b = append(b, s.Metadata...)
+	return append(b, input...)
+}
+
+// Authenticate computes the authenticator over the packet input and stores
+// it in the extension. For the AES-CMAC and HMAC-SHA256 modes, key is the
+// symmetric key. For the Ed25519 mode, key is the private signing key. The
+// GCM-AES128 mode is not supported, as it requires a unique nonce per packet.
+func (s *Extn) Authenticate(input, key common.RawBytes) error {
+	authInput := s.AuthInput(input)
+	var auth common.RawBytes
+	var err error
+	switch s.SecMode {
+	case AesCMac:
+		auth, err = cmacSum(key, authInput)
+	case HmacSha256:
+		auth = hmacSum(key, authInput)
+	case Ed25519:
+		auth, err = scrypto.Sign(authInput, key, scrypto.Ed25519)
+	default:
+		return common.NewBasicError("Unsupported SecMode", nil, "mode", s.SecMode)
+	}
+	if err != nil {
+		return err
+	}
+	return s.SetAuthenticator(auth)
+}
+
+// Verify checks the authenticator over the packet input. For the AES-CMAC
+// and HMAC-SHA256 modes, key is the symmetric key. For the Ed25519 mode, key
+// is the public verifying key.
+func (s *Extn) Verify(input, key common.RawBytes) error {
+	authInput := s.AuthInput(input)
+	var expected common.RawBytes
+	var err error
+	switch s.SecMode {
+	case AesCMac:
+		expected, err = cmacSum(key, authInput)
+	case HmacSha256:
+		expected = hmacSum(key, authInput)
+	case Ed25519:
+		if err := scrypto.Verify(authInput, s.Authenticator, key, scrypto.Ed25519); err != nil {
+			return common.NewBasicError("Invalid authenticator", err)
+		}
+		return nil
+	default:
+		return common.NewBasicError("Unsupported SecMode", nil, "mode", s.SecMode)
+	}
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(expected, s.Authenticator) != 1 {
+		return common.NewBasicError("Invalid authenticator", nil)
+	}
+	return nil
+}
+
+func cmacSum(key, input common.RawBytes) (common.RawBytes, error) {
+	mac, err := scrypto.InitMac(key)
+	if err != nil {
+		return nil, err
+	}
+	mac.Write(input)
+	return mac.Sum(nil)[:AesCMacAuthLength], nil
+}
+
+func hmacSum(key, input common.RawBytes) common.RawBytes {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(input)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spse
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestExtnAuthenticate(t *testing.T) {
+	Convey("Authenticate and verify SPSE extensions", t, func() {
+		input := common.RawBytes("immutable header and payload")
+		symKey := xtest.MustParseHexString("0123456789abcdef0123456789abcdef")
+		pub, priv, err := scrypto.GenKeyPair(scrypto.Ed25519)
+		So(err, ShouldBeNil)
+		tests := []struct {
+			mode       SecMode
+			authKey    common.RawBytes
+			verifyKey  common.RawBytes
+			metaLength int
+		}{
+			{AesCMac, symKey, symKey, AesCMacMetaLength},
+			{HmacSha256, symKey, symKey, HmacSha256MetaLength + common.LineLen},
+			{Ed25519, priv, pub, ED25519MetaLength},
+		}
+		for _, test := range tests {
+			Convey(test.mode.String(), func() {
+				extn, err := NewExtnWithMetaLen(test.mode, test.metaLength)
+				So(err, ShouldBeNil)
+				now := time.Now()
+				extn.SetTimestamp(now)
+				So(extn.Timestamp().Unix(), ShouldEqual, now.Unix())
+				So(extn.Authenticate(input, test.authKey), ShouldBeNil)
+				Convey("Valid authenticator is accepted", func() {
+					So(extn.Verify(input, test.verifyKey), ShouldBeNil)
+				})
+				Convey("Parsed extension is accepted", func() {
+					raw := make(common.RawBytes, extn.Len())
+					So(extn.Write(raw), ShouldBeNil)
+					parsed, err := NewExtnFromRaw(raw)
+					So(err, ShouldBeNil)
+					So(parsed.Metadata, ShouldResemble, extn.Metadata)
+					So(parsed.Verify(input, test.verifyKey), ShouldBeNil)
+				})
+				Convey("Modified input is rejected", func() {
+					So(extn.Verify(input[1:], test.verifyKey), ShouldNotBeNil)
+				})
+				Convey("Modified timestamp is rejected", func() {
+					extn.SetTimestamp(now.Add(time.Hour))
+					So(extn.Verify(input, test.verifyKey), ShouldNotBeNil)
+				})
+			})
+		}
+		Convey("GCM-AES128 is not supported", func() {
+			extn, err := NewExtn(GcmAes128)
+			So(err, ShouldBeNil)
+			So(extn.Authenticate(input, symKey), ShouldNotBeNil)
+		})
+		Convey("Invalid metadata length is rejected", func() {
+			_, err := NewExtnWithMetaLen(AesCMac, TimestampLength+1)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -92,7 +92,25 @@ func NewDRKeyExtn() *DRKeyExtn {
 	return s
 }
 
-func (s DRKeyExtn) SetDirection(dir Dir) error {
+// NewDRKeyExtnFromRaw parses the SCMPAuthDRKey extension from raw bytes.
+func NewDRKeyExtnFromRaw(b common.RawBytes) (*DRKeyExtn, error) {
+	if len(b) != DRKeyTotalLength {
+		return nil, common.NewBasicError("Invalid SCMPAuthDRKey extension length", nil,
+			"expected", DRKeyTotalLength, "actual", len(b))
+	}
+	if mode := spse.SecMode(b[0]); mode != spse.ScmpAuthDRKey {
+		return nil, common.NewBasicError("Invalid SecMode", nil,
+			"expected", spse.ScmpAuthDRKey, "actual", mode)
+	}
+	s := NewDRKeyExtn()
+	if err := s.SetDirection(Dir(b[DirectionOffset])); err != nil {
+		return nil, err
+	}
+	copy(s.MAC, b[MACOffset:DRKeyTotalLength])
+	return s, nil
+}
+
+func (s *DRKeyExtn) SetDirection(dir Dir) error {
 	if dir > HostToHostReversed {
 		return common.NewBasicError("Invalid direction", nil, "dir", dir)
 	}
@@ -100,7 +118,7 @@ func (s DRKeyExtn) SetDirection(dir Dir) error {
 	return nil
 }
 
-func (s DRKeyExtn) SetMAC(mac common.RawBytes) error {
+func (s *DRKeyExtn) SetMAC(mac common.RawBytes) error {
 	if len(mac) != MACLength {
 		return common.NewBasicError("Invalid MAC size", nil,
 			"expected", MACLength, "actual", len(mac))
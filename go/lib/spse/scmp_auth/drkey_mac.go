@@ -0,0 +1,54 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scmp_auth
+
+import (
+	"crypto/subtle"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/spkt"
+)
+
+// DRKeyProtocol is the protocol identifier of the second-level DRKeys that
+// are used to authenticate SCMP messages.
+const DRKeyProtocol = "scmp"
+
+// ComputeDRKeyMAC computes the DRKey MAC of the SCMP packet sp with key.
+func ComputeDRKeyMAC(key common.RawBytes, sp *spkt.ScnPkt) (common.RawBytes, error) {
+	input, err := AuthInput(sp)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := scrypto.InitMac(key)
+	if err != nil {
+		return nil, err
+	}
+	mac.Write(input)
+	return mac.Sum(nil)[:MACLength], nil
+}
+
+// VerifyDRKeyMAC verifies that mac is the DRKey MAC of the SCMP packet sp
+// computed with key.
+func VerifyDRKeyMAC(key common.RawBytes, sp *spkt.ScnPkt, mac common.RawBytes) error {
+	expected, err := ComputeDRKeyMAC(key, sp)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(expected, mac) != 1 {
+		return common.NewBasicError("Invalid DRKey MAC", nil)
+	}
+	return nil
+}
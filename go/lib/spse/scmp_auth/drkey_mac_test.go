@@ -0,0 +1,66 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scmp_auth
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/spkt"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestDRKeyMAC(t *testing.T) {
+	Convey("DRKey MAC of an SCMP packet", t, func() {
+		key := xtest.MustParseHexString("e328a2b10f12b5e31e74392e70924444")
+		ct := scmp.ClassType{Class: scmp.C_General, Type: scmp.T_G_EchoReply}
+		pld := scmp.PldFromQuotes(ct, &scmp.InfoEcho{Id: 1, Seq: 2}, common.L4SCMP,
+			func(scmp.RawBlock) common.RawBytes { return nil })
+		sp := &spkt.ScnPkt{
+			DstIA:   xtest.MustParseIA("1-ff00:0:112"),
+			SrcIA:   xtest.MustParseIA("1-ff00:0:111"),
+			DstHost: addr.HostFromIPStr("127.0.0.2"),
+			SrcHost: addr.HostFromIPStr("127.0.0.1"),
+			L4:      scmp.NewHdr(ct, pld.Len()),
+			Pld:     pld,
+		}
+		mac, err := ComputeDRKeyMAC(key, sp)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("len", len(mac), ShouldEqual, MACLength)
+		Convey("Valid MAC is accepted", func() {
+			SoMsg("err", VerifyDRKeyMAC(key, sp, mac), ShouldBeNil)
+		})
+		Convey("Checksum is not covered", func() {
+			sp.L4.SetCSum(common.RawBytes{0xff, 0xff})
+			SoMsg("err", VerifyDRKeyMAC(key, sp, mac), ShouldBeNil)
+		})
+		Convey("Modified payload is rejected", func() {
+			pld.Info = &scmp.InfoEcho{Id: 1, Seq: 3}
+			SoMsg("err", VerifyDRKeyMAC(key, sp, mac), ShouldNotBeNil)
+		})
+		Convey("Modified source is rejected", func() {
+			sp.SrcIA = xtest.MustParseIA("1-ff00:0:110")
+			SoMsg("err", VerifyDRKeyMAC(key, sp, mac), ShouldNotBeNil)
+		})
+		Convey("Wrong key is rejected", func() {
+			other := xtest.MustParseHexString("f91d279f3ca1f2b177aa2f4304cbd750")
+			SoMsg("err", VerifyDRKeyMAC(other, sp, mac), ShouldNotBeNil)
+		})
+	})
+}
@@ -42,13 +42,14 @@ type HashTreeExtn struct {
 	*spse.BaseExtn
 	// Height is the height of the hash tree. Max height is 16.
 	Height uint8
-	// Order is a bit vector. The bit at index i is associated with hash i.
+	// Order is a bit vector. The bit at index i is associated with hash i,
+	// where index 0 is the least significant bit of the big-endian value.
 	// 0 (1) indicates hash i shall be used as left (right) input.
 	Order common.RawBytes
 	// Signature is the signature of the root hash.
 	Signature common.RawBytes
-	// Hashes are the hashes to verify the proof.
-	// At index 0 is the leaf hash. At index height is the root hash.
+	// Hashes are the hashes to verify the proof. At index i is the sibling
+	// of the node at height i on the path from the leaf to the root.
 	Hashes common.RawBytes
 }
 
@@ -82,6 +83,30 @@ func NewHashTreeExtn(height uint8) (*HashTreeExtn, error) {
 	return extn, nil
 }
 
+// NewHashTreeExtnFromRaw parses the SCMPAuthHashTree extension from raw bytes.
+func NewHashTreeExtnFromRaw(b common.RawBytes) (*HashTreeExtn, error) {
+	if len(b) < HashesOffset {
+		return nil, common.NewBasicError("Invalid SCMPAuthHashTree extension length", nil,
+			"expected min", HashesOffset, "actual", len(b))
+	}
+	if mode := spse.SecMode(b[0]); mode != spse.ScmpAuthHashTree {
+		return nil, common.NewBasicError("Invalid SecMode", nil,
+			"expected", spse.ScmpAuthHashTree, "actual", mode)
+	}
+	s, err := NewHashTreeExtn(b[HeightOffset])
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != s.Len() {
+		return nil, common.NewBasicError("Invalid SCMPAuthHashTree extension length", nil,
+			"expected", s.Len(), "actual", len(b))
+	}
+	copy(s.Order, b[OrderOffset:SignatureOffset])
+	copy(s.Signature, b[SignatureOffset:HashesOffset])
+	copy(s.Hashes, b[HashesOffset:])
+	return s, nil
+}
+
 func (s HashTreeExtn) SetOrder(order common.RawBytes) error {
 	if len(order) != OrderLength {
 		return common.NewBasicError("Invalid order length", nil,
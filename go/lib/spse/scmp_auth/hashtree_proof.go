@@ -0,0 +1,202 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scmp_auth
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/spkt"
+)
+
+const (
+	// MaxLeaves is the maximum number of leaves in a hash tree.
+	MaxLeaves = 1 << MaxHeight
+
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// hashTreeSigPrefix is prepended to the root hash to create the signature
+// input. It separates the hash tree signatures from other signatures created
+// with the AS signing key.
+var hashTreeSigPrefix = []byte("SCMPAuthHashTree")
+
+// HashTree is a Merkle tree over the leaf hashes of a batch of SCMP packets.
+// Missing leaves are padded with the last leaf hash, such that the tree is
+// complete.
+type HashTree struct {
+	// levels contains the hashes of each level. Index 0 contains the leaf
+	// hashes, index height contains the root hash.
+	levels [][]common.RawBytes
+	// leaves is the number of leaves before padding.
+	leaves int
+}
+
+// NewHashTree builds a hash tree over the provided leaf hashes.
+func NewHashTree(leaves []common.RawBytes) (*HashTree, error) {
+	if len(leaves) == 0 || len(leaves) > MaxLeaves {
+		return nil, common.NewBasicError("Invalid number of leaves", nil,
+			"leaves", len(leaves), "max", MaxLeaves)
+	}
+	for i, leaf := range leaves {
+		if len(leaf) != HashLength {
+			return nil, common.NewBasicError("Invalid leaf hash length", nil,
+				"idx", i, "expected", HashLength, "actual", len(leaf))
+		}
+	}
+	t := &HashTree{leaves: len(leaves)}
+	level := leaves
+	for {
+		t.levels = append(t.levels, level)
+		if len(level) == 1 {
+			return t, nil
+		}
+		if len(level)%2 == 1 {
+			level = append(level[:len(level):len(level)], level[len(level)-1])
+		}
+		next := make([]common.RawBytes, len(level)/2)
+		for i := range next {
+			next[i] = nodeHash(level[2*i], level[2*i+1])
+		}
+		level = next
+	}
+}
+
+// Height returns the height of the tree.
+func (t *HashTree) Height() uint8 {
+	return uint8(len(t.levels) - 1)
+}
+
+// Root returns the root hash of the tree.
+func (t *HashTree) Root() common.RawBytes {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// NewExtn creates the SCMPAuthHashTree extension containing the inclusion
+// proof of the leaf at index idx and the signature of the root hash.
+func (t *HashTree) NewExtn(idx int, signature common.RawBytes) (*HashTreeExtn, error) {
+	if idx < 0 || idx >= t.leaves {
+		return nil, common.NewBasicError("Invalid leaf index", nil,
+			"idx", idx, "leaves", t.leaves)
+	}
+	extn, err := NewHashTreeExtn(t.Height())
+	if err != nil {
+		return nil, err
+	}
+	if err := extn.SetSignature(signature); err != nil {
+		return nil, err
+	}
+	var order uint16
+	for h := 0; h < int(t.Height()); h++ {
+		level := t.levels[h]
+		sibling := idx ^ 1
+		if sibling >= len(level) {
+			// The last node of an odd level is paired with itself.
+			sibling = idx
+		}
+		if idx%2 == 0 {
+			// The sibling is the right input.
+			order |= 1 << uint(h)
+		}
+		copy(extn.Hashes[h*HashLength:], level[sibling])
+		idx /= 2
+	}
+	binary.BigEndian.PutUint16(extn.Order, order)
+	return extn, nil
+}
+
+// HashTreeLeaf returns the leaf hash of the SCMP packet sp.
+func HashTreeLeaf(sp *spkt.ScnPkt) (common.RawBytes, error) {
+	input, err := AuthInput(sp)
+	if err != nil {
+		return nil, err
+	}
+	return hash(leafPrefix, input), nil
+}
+
+// HashTreeSigInput returns the signature input for the root hash.
+func HashTreeSigInput(root common.RawBytes) common.RawBytes {
+	input := make(common.RawBytes, 0, len(hashTreeSigPrefix)+len(root))
+	input = append(input, hashTreeSigPrefix...)
+	return append(input, root...)
+}
+
+// SignHashTree signs the root hash of the hash tree.
+func SignHashTree(t *HashTree, signKey common.RawBytes, signAlgo string) (common.RawBytes,
+	error) {
+
+	return scrypto.Sign(HashTreeSigInput(t.Root()), signKey, signAlgo)
+}
+
+// RootFromProof computes the root hash from the leaf hash and the inclusion
+// proof contained in the extension.
+func (s *HashTreeExtn) RootFromProof(leaf common.RawBytes) (common.RawBytes, error) {
+	if len(leaf) != HashLength {
+		return nil, common.NewBasicError("Invalid leaf hash length", nil,
+			"expected", HashLength, "actual", len(leaf))
+	}
+	if len(s.Hashes) != int(s.Height)*HashLength {
+		return nil, common.NewBasicError("Invalid hashes length", nil,
+			"expected", int(s.Height)*HashLength, "actual", len(s.Hashes))
+	}
+	order := binary.BigEndian.Uint16(s.Order)
+	curr := leaf
+	for h := 0; h < int(s.Height); h++ {
+		sibling := s.Hashes[h*HashLength : (h+1)*HashLength]
+		if order&(1<<uint(h)) == 0 {
+			curr = nodeHash(sibling, curr)
+		} else {
+			curr = nodeHash(curr, sibling)
+		}
+	}
+	return curr, nil
+}
+
+// VerifyHashTree verifies that the extension contains a valid inclusion
+// proof of the SCMP packet sp, and that the root hash is signed with the
+// private key corresponding to verifyKey.
+func VerifyHashTree(sp *spkt.ScnPkt, extn *HashTreeExtn, verifyKey common.RawBytes,
+	signAlgo string) error {
+
+	leaf, err := HashTreeLeaf(sp)
+	if err != nil {
+		return err
+	}
+	root, err := extn.RootFromProof(leaf)
+	if err != nil {
+		return err
+	}
+	if err := scrypto.Verify(HashTreeSigInput(root), extn.Signature, verifyKey,
+		signAlgo); err != nil {
+		return common.NewBasicError("Invalid hash tree signature", err)
+	}
+	return nil
+}
+
+func nodeHash(left, right common.RawBytes) common.RawBytes {
+	input := make(common.RawBytes, 0, 2*HashLength)
+	input = append(input, left...)
+	return hash(nodePrefix, append(input, right...))
+}
+
+func hash(prefix byte, input common.RawBytes) common.RawBytes {
+	h := sha256.New()
+	h.Write([]byte{prefix})
+	h.Write(input)
+	return h.Sum(nil)[:HashLength]
+}
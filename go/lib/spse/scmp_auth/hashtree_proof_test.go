@@ -0,0 +1,125 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scmp_auth
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/spkt"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestHashTree(t *testing.T) {
+	Convey("Hash tree inclusion proofs", t, func() {
+		pub, priv, err := scrypto.GenKeyPair(scrypto.Ed25519)
+		xtest.FailOnErr(t, err)
+		for _, n := range []int{1, 2, 3, 4, 5, 8, 9} {
+			Convey(fmt.Sprintf("Tree with %d leaves", n), func() {
+				pkts := make([]*spkt.ScnPkt, n)
+				leaves := make([]common.RawBytes, n)
+				for i := range pkts {
+					pkts[i] = newTestSCMPPkt(uint16(i))
+					leaves[i], err = HashTreeLeaf(pkts[i])
+					xtest.FailOnErr(t, err)
+				}
+				tree, err := NewHashTree(leaves)
+				SoMsg("err", err, ShouldBeNil)
+				sig, err := SignHashTree(tree, priv, scrypto.Ed25519)
+				SoMsg("sign err", err, ShouldBeNil)
+				for i := range pkts {
+					extn, err := tree.NewExtn(i, sig)
+					SoMsg("extn err", err, ShouldBeNil)
+					SoMsg("height", extn.Height, ShouldEqual, tree.Height())
+					root, err := extn.RootFromProof(leaves[i])
+					SoMsg("root err", err, ShouldBeNil)
+					SoMsg("root", root, ShouldResemble, tree.Root())
+					SoMsg("verify", VerifyHashTree(pkts[i], extn, pub, scrypto.Ed25519),
+						ShouldBeNil)
+				}
+				_, err = tree.NewExtn(n, sig)
+				SoMsg("out of range", err, ShouldNotBeNil)
+			})
+		}
+		Convey("Tampering is detected", func() {
+			pkts := []*spkt.ScnPkt{newTestSCMPPkt(0), newTestSCMPPkt(1), newTestSCMPPkt(2)}
+			leaves := make([]common.RawBytes, len(pkts))
+			for i := range pkts {
+				leaves[i], err = HashTreeLeaf(pkts[i])
+				xtest.FailOnErr(t, err)
+			}
+			tree, err := NewHashTree(leaves)
+			xtest.FailOnErr(t, err)
+			sig, err := SignHashTree(tree, priv, scrypto.Ed25519)
+			xtest.FailOnErr(t, err)
+			extn, err := tree.NewExtn(1, sig)
+			xtest.FailOnErr(t, err)
+			Convey("Proof for a different packet is rejected", func() {
+				SoMsg("err", VerifyHashTree(pkts[0], extn, pub, scrypto.Ed25519),
+					ShouldNotBeNil)
+			})
+			Convey("Modified order is rejected", func() {
+				extn.Order[1] ^= 0x01
+				SoMsg("err", VerifyHashTree(pkts[1], extn, pub, scrypto.Ed25519),
+					ShouldNotBeNil)
+			})
+			Convey("Wrong key is rejected", func() {
+				otherPub, _, err := scrypto.GenKeyPair(scrypto.Ed25519)
+				xtest.FailOnErr(t, err)
+				SoMsg("err", VerifyHashTree(pkts[1], extn, otherPub, scrypto.Ed25519),
+					ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestNewHashTreeExtnFromRaw(t *testing.T) {
+	Convey("Parsing a packed extension yields the same extension", t, func() {
+		extn, err := NewHashTreeExtn(2)
+		xtest.FailOnErr(t, err)
+		extn.SetOrder(common.RawBytes{0x00, 0x02})
+		extn.Signature[0] = 0xaa
+		extn.Hashes[HashLength] = 0xbb
+		raw, err := extn.Pack()
+		xtest.FailOnErr(t, err)
+		parsed, err := NewHashTreeExtnFromRaw(raw)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("extn", parsed, ShouldResemble, extn)
+		Convey("Truncated extension is rejected", func() {
+			_, err := NewHashTreeExtnFromRaw(raw[:len(raw)-1])
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+	})
+}
+
+func newTestSCMPPkt(seq uint16) *spkt.ScnPkt {
+	ct := scmp.ClassType{Class: scmp.C_General, Type: scmp.T_G_EchoReply}
+	pld := scmp.PldFromQuotes(ct, &scmp.InfoEcho{Id: 1, Seq: seq}, common.L4SCMP,
+		func(scmp.RawBlock) common.RawBytes { return nil })
+	return &spkt.ScnPkt{
+		DstIA:   xtest.MustParseIA("1-ff00:0:112"),
+		SrcIA:   xtest.MustParseIA("1-ff00:0:111"),
+		DstHost: addr.HostFromIPStr("127.0.0.2"),
+		SrcHost: addr.HostFromIPStr("127.0.0.1"),
+		L4:      scmp.NewHdr(ct, pld.Len()),
+		Pld:     pld,
+	}
+}
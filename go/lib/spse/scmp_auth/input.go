@@ -0,0 +1,56 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scmp_auth
+
+import (
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/spkt"
+)
+
+// AuthInput returns the authenticated input of the SCMP packet sp, which is
+// covered by both the DRKey MAC and the hash tree leaf. The input consists of
+// the address header, the SCMP header with the checksum set to zero and the
+// SCMP payload. The forwarding path and the extensions are not covered, as
+// they are modified in transit.
+func AuthInput(sp *spkt.ScnPkt) (common.RawBytes, error) {
+	hdr, ok := sp.L4.(*scmp.Hdr)
+	if !ok {
+		return nil, common.NewBasicError("Packet has non-SCMP L4", nil,
+			"type", common.TypeOf(sp.L4))
+	}
+	if sp.DstHost == nil || sp.SrcHost == nil || sp.Pld == nil {
+		return nil, common.NewBasicError("Incomplete SCMP packet", nil)
+	}
+	rawHdr, err := hdr.Pack(true)
+	if err != nil {
+		return nil, err
+	}
+	b := make(common.RawBytes, 2*addr.IABytes+sp.DstHost.Size()+sp.SrcHost.Size()+
+		scmp.HdrLen+sp.Pld.Len())
+	off := 0
+	sp.DstIA.Write(b[off:])
+	off += addr.IABytes
+	sp.SrcIA.Write(b[off:])
+	off += addr.IABytes
+	off += copy(b[off:], sp.DstHost.Pack())
+	off += copy(b[off:], sp.SrcHost.Pack())
+	off += copy(b[off:], rawHdr)
+	if _, err := sp.Pld.WritePld(b[off:]); err != nil {
+		return nil, common.NewBasicError("Unable to write SCMP payload", err)
+	}
+	return b, nil
+}
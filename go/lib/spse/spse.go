@@ -117,31 +117,61 @@ func (s *BaseExtn) Type() common.ExtnType {
 }
 
 func NewExtn(secMode SecMode) (*Extn, error) {
+	return NewExtnWithMetaLen(secMode, TimestampLength)
+}
+
+// NewExtnWithMetaLen creates an extension with metadata of length metaLen.
+// The metadata starts with the timestamp and may carry additional mode
+// specific data, its length must be 4 + 8i.
+func NewExtnWithMetaLen(secMode SecMode, metaLen int) (*Extn, error) {
+	authLen, err := authLength(secMode)
+	if err != nil {
+		return nil, err
+	}
+	if metaLen < TimestampLength || (metaLen-TimestampLength)%common.LineLen != 0 {
+		return nil, common.NewBasicError("Invalid metadata length", nil, "len", metaLen)
+	}
 	s := &Extn{BaseExtn: &BaseExtn{SecMode: secMode}}
+	s.Metadata = make(common.RawBytes, metaLen)
+	s.Authenticator = make(common.RawBytes, authLen)
+	return s, nil
+}
 
-	var metaLen, authLen int
+// NewExtnFromRaw parses the SCIONPacketSecurity extension from raw bytes. The
+// metadata length is inferred from the length of the extension.
+func NewExtnFromRaw(b common.RawBytes) (*Extn, error) {
+	if len(b) < SecModeLength {
+		return nil, common.NewBasicError("Invalid SCIONPacketSecurity extension length", nil,
+			"len", len(b))
+	}
+	secMode := SecMode(b[0])
+	authLen, err := authLength(secMode)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewExtnWithMetaLen(secMode, len(b)-SecModeLength-authLen)
+	if err != nil {
+		return nil, err
+	}
+	authOffset := SecModeLength + len(s.Metadata)
+	copy(s.Metadata, b[SecModeLength:authOffset])
+	copy(s.Authenticator, b[authOffset:])
+	return s, nil
+}
 
+func authLength(secMode SecMode) (int, error) {
 	switch secMode {
 	case AesCMac:
-		metaLen = AesCMacMetaLength
-		authLen = AesCMacAuthLength
+		return AesCMacAuthLength, nil
 	case HmacSha256:
-		metaLen = HmacSha256MetaLength
-		authLen = HmacSha256MetaLength
+		return HmacSha256AuthLength, nil
 	case Ed25519:
-		metaLen = ED25519MetaLength
-		authLen = ED25519AuthLength
+		return ED25519AuthLength, nil
 	case GcmAes128:
-		metaLen = GcmAes128MetaLength
-		authLen = GcmAes128AuthLength
+		return GcmAes128AuthLength, nil
 	default:
-		return nil, common.NewBasicError("Invalid SecMode code", nil, "SecMode", secMode)
+		return 0, common.NewBasicError("Invalid SecMode code", nil, "SecMode", secMode)
 	}
-
-	s.Metadata = make(common.RawBytes, metaLen)
-	s.Authenticator = make(common.RawBytes, authLen)
-
-	return s, nil
 }
 
 // Set the Metadata.
@@ -188,7 +218,7 @@ func (s *Extn) Pack() (common.RawBytes, error) {
 }
 
 func (s *Extn) Copy() common.Extension {
-	c, _ := NewExtn(s.SecMode)
+	c, _ := NewExtnWithMetaLen(s.SecMode, len(s.Metadata))
 	copy(c.Metadata, s.Metadata)
 	copy(c.Authenticator, s.Authenticator)
 	return c
@@ -59,6 +59,11 @@ type RawTopo struct {
 
 type RawSrvInfo struct {
 	Addrs RawAddrMap
+	// Weight is the share of the anycast traffic sent to the instance,
+	// relative to the other instances of the service. (default 1)
+	Weight uint `json:",omitempty"`
+	// Unhealthy manually marks instances that must not receive anycast traffic.
+	Unhealthy bool `json:",omitempty"`
 }
 
 func (ras RawSrvInfo) String() string {
@@ -110,10 +110,35 @@ type Topo struct {
 	DSNames  ServiceNames
 	SIG      IDAddrMap
 	SIGNames ServiceNames
+	// SvcInsts contains the anycast selection parameters of the service
+	// instances, by instance name. Instances without an entry have weight 1
+	// and are not marked unhealthy.
+	SvcInsts map[string]SvcInst
 
 	ZK map[int]*addr.AppAddr
 }
 
+// SvcInst contains the parameters used to select a service instance for an
+// anycast SVC address.
+type SvcInst struct {
+	// Weight is the share of the anycast traffic sent to the instance,
+	// relative to the other instances of the service. 0 is equivalent to 1.
+	Weight uint
+	// Unhealthy is set if the instance must not receive anycast traffic. It
+	// is set manually in the topology file; no health checks are performed.
+	Unhealthy bool
+}
+
+// SvcInst returns the anycast selection parameters of the service instance
+// with the given name.
+func (t *Topo) SvcInst(name string) SvcInst {
+	inst := t.SvcInsts[name]
+	if inst.Weight == 0 {
+		inst.Weight = 1
+	}
+	return inst
+}
+
 // Create new empty Topo object, including all possible service maps etc.
 func NewTopo() *Topo {
 	return &Topo{
@@ -240,31 +265,31 @@ func (t *Topo) populateBR(raw *RawTopo) error {
 func (t *Topo) populateServices(raw *RawTopo) error {
 	// Populate BS, CS, PS, SB, RS, SIG and DS maps
 	var err error
-	t.BSNames, err = svcMapFromRaw(raw.BeaconService, common.BS, t.BS, t.Overlay)
+	t.BSNames, err = t.svcMapFromRaw(raw.BeaconService, common.BS, t.BS, t.Overlay)
 	if err != nil {
 		return err
 	}
-	t.CSNames, err = svcMapFromRaw(raw.CertificateService, common.CS, t.CS, t.Overlay)
+	t.CSNames, err = t.svcMapFromRaw(raw.CertificateService, common.CS, t.CS, t.Overlay)
 	if err != nil {
 		return err
 	}
-	t.PSNames, err = svcMapFromRaw(raw.PathService, common.PS, t.PS, t.Overlay)
+	t.PSNames, err = t.svcMapFromRaw(raw.PathService, common.PS, t.PS, t.Overlay)
 	if err != nil {
 		return err
 	}
-	t.SBNames, err = svcMapFromRaw(raw.SibraService, common.SB, t.SB, t.Overlay)
+	t.SBNames, err = t.svcMapFromRaw(raw.SibraService, common.SB, t.SB, t.Overlay)
 	if err != nil {
 		return err
 	}
-	t.RSNames, err = svcMapFromRaw(raw.RainsService, common.RS, t.RS, t.Overlay)
+	t.RSNames, err = t.svcMapFromRaw(raw.RainsService, common.RS, t.RS, t.Overlay)
 	if err != nil {
 		return err
 	}
-	t.SIGNames, err = svcMapFromRaw(raw.SIG, common.SIG, t.SIG, t.Overlay)
+	t.SIGNames, err = t.svcMapFromRaw(raw.SIG, common.SIG, t.SIG, t.Overlay)
 	if err != nil {
 		return err
 	}
-	t.DSNames, err = svcMapFromRaw(raw.DiscoveryService, common.DS, t.DS, t.Overlay)
+	t.DSNames, err = t.svcMapFromRaw(raw.DiscoveryService, common.DS, t.DS, t.Overlay)
 	if err != nil {
 		return err
 	}
@@ -365,8 +390,9 @@ func (svc *SVCInfo) GetAllTopoAddrs() []TopoAddr {
 }
 
 // Convert map of Name->RawSrvInfo into map of Name->TopoAddr and sorted slice of Names
+// Non-default anycast selection parameters are added to t.SvcInsts.
 // stype is only used for error reporting
-func svcMapFromRaw(ras map[string]*RawSrvInfo, stype string, smap IDAddrMap,
+func (t *Topo) svcMapFromRaw(ras map[string]*RawSrvInfo, stype string, smap IDAddrMap,
 	ot overlay.Type) ([]string, error) {
 
 	var snames []string
@@ -379,6 +405,12 @@ func svcMapFromRaw(ras map[string]*RawSrvInfo, stype string, smap IDAddrMap,
 		}
 		smap[name] = *svcTopoAddr
 		snames = append(snames, name)
+		if svc.Weight > 1 || svc.Unhealthy {
+			if t.SvcInsts == nil {
+				t.SvcInsts = make(map[string]SvcInst)
+			}
+			t.SvcInsts[name] = SvcInst{Weight: svc.Weight, Unhealthy: svc.Unhealthy}
+		}
 	}
 	sort.Strings(snames)
 	return snames, nil
@@ -203,6 +203,19 @@ func Test_Service_Count(t *testing.T) {
 
 }
 
+func Test_Service_Insts(t *testing.T) {
+	fn := "testdata/basic.json"
+	loadTopo(fn, t)
+	c := testTopo
+	Convey("Checking anycast selection parameters of service instances", t, func() {
+		SoMsg("Weighted", c.SvcInst("bs1-ff00:0:311-1"), ShouldResemble, SvcInst{Weight: 2})
+		SoMsg("Default", c.SvcInst("bs1-ff00:0:311-2"), ShouldResemble, SvcInst{Weight: 1})
+		SoMsg("Unhealthy", c.SvcInst("bs1-ff00:0:311-3"), ShouldResemble,
+			SvcInst{Weight: 1, Unhealthy: true})
+		SoMsg("Only non-default entries", len(c.SvcInsts), ShouldEqual, 2)
+	})
+}
+
 func Test_ZK(t *testing.T) {
 	zks := map[int]*addr.AppAddr{
 		1: {
@@ -0,0 +1,106 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drkey provides the second-level DRKey store of SCIOND.
+//
+// Second-level keys are requested from the local certificate server and
+// cached until the end of their epoch.
+package drkey
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/infra/messenger"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+// Store fetches second-level keys from the local certificate server and
+// caches them until their epoch expires.
+type Store struct {
+	msgr  infra.Messenger
+	ia    addr.IA
+	cache *cache.Cache
+}
+
+// NewStore creates a new store that requests keys from the certificate
+// server in the local AS ia.
+func NewStore(msgr infra.Messenger, ia addr.IA) *Store {
+	return &Store{
+		msgr:  msgr,
+		ia:    ia,
+		cache: cache.New(cache.NoExpiration, time.Minute),
+	}
+}
+
+// GetLvl2Key returns the second-level key described by meta that is valid at
+// valTime. The epoch in meta is ignored.
+func (s *Store) GetLvl2Key(ctx context.Context, meta drkey.Lvl2Meta,
+	valTime time.Time) (drkey.Lvl2Key, error) {
+
+	cacheKey := keyOf(meta)
+	if v, ok := s.cache.Get(cacheKey); ok {
+		if key := v.(drkey.Lvl2Key); key.Epoch.Contains(valTime) {
+			return key, nil
+		}
+	}
+	key, err := s.fetch(ctx, meta, valTime)
+	if err != nil {
+		return drkey.Lvl2Key{}, err
+	}
+	if !key.Epoch.Contains(valTime) {
+		return drkey.Lvl2Key{}, common.NewBasicError("Fetched key not valid at requested time",
+			nil, "valTime", util.TimeToString(valTime), "epoch", key.Epoch)
+	}
+	// Only cache keys of the current epoch, such that the cached entry
+	// expires together with the key.
+	if ttl := time.Until(key.Epoch.End); key.Epoch.Contains(time.Now()) && ttl > 0 {
+		s.cache.Set(cacheKey, key, ttl)
+	}
+	return key, nil
+}
+
+func (s *Store) fetch(ctx context.Context, meta drkey.Lvl2Meta,
+	valTime time.Time) (drkey.Lvl2Key, error) {
+
+	req := drkey_mgmt.NewLvl2ReqFromMeta(meta, valTime)
+	csAddr := &snet.Addr{IA: s.ia, Host: addr.NewSVCUDPAppAddr(addr.SvcCS)}
+	rep, err := s.msgr.RequestDRKeyLvl2(ctx, req, csAddr, messenger.NextId())
+	if err != nil {
+		return drkey.Lvl2Key{}, common.NewBasicError("Unable to request second-level key", err,
+			"addr", csAddr)
+	}
+	log.FromCtx(ctx).Trace("[DRKeyStore] Received reply", "addr", csAddr, "rep", rep)
+	if len(rep.DRKey) != drkey.KeyLength {
+		return drkey.Lvl2Key{}, common.NewBasicError("Invalid key length", nil,
+			"expected", drkey.KeyLength, "actual", len(rep.DRKey))
+	}
+	return rep.ToKey(meta), nil
+}
+
+// keyOf returns the cache key for meta. The epoch is not part of the key.
+func keyOf(meta drkey.Lvl2Meta) string {
+	return fmt.Sprintf("%s %q %s:%s->%s:%s", meta.KeyType, meta.Protocol, meta.SrcIA,
+		meta.SrcHost, meta.DstIA, meta.DstHost)
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drkey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
+	"github.com/scionproto/scion/go/lib/drkey"
+	"github.com/scionproto/scion/go/lib/infra/mock_infra"
+	"github.com/scionproto/scion/go/lib/util"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestStoreGetLvl2Key(t *testing.T) {
+	Convey("GetLvl2Key", t, func() {
+		mctrl := gomock.NewController(t)
+		defer mctrl.Finish()
+		msgr := mock_infra.NewMockMessenger(mctrl)
+		store := NewStore(msgr, xtest.MustParseIA("1-ff00:0:111"))
+		meta := drkey.Lvl2Meta{
+			KeyType:  drkey.AS2Host,
+			Protocol: "scmp",
+			SrcIA:    xtest.MustParseIA("1-ff00:0:111"),
+			DstIA:    xtest.MustParseIA("1-ff00:0:112"),
+			DstHost:  addr.HostFromIPStr("127.0.0.1"),
+		}
+		now := time.Now()
+		rep := &drkey_mgmt.Lvl2Rep{
+			Timestamp:  util.TimeToSecs(now),
+			DRKey:      xtest.MustParseHexString("e328a2b10f12b5e31e74392e70924444"),
+			EpochBegin: util.TimeToSecs(now.Add(-time.Hour)),
+			EpochEnd:   util.TimeToSecs(now.Add(time.Hour)),
+		}
+		Convey("Fetched key is cached", func() {
+			msgr.EXPECT().RequestDRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any(),
+				gomock.Any()).Return(rep, nil).Times(1)
+			key, err := store.GetLvl2Key(context.Background(), meta, now)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("key", key.Key, ShouldResemble, drkey.DRKey(rep.DRKey))
+			SoMsg("epoch", key.Epoch, ShouldResemble,
+				drkey.NewEpoch(rep.EpochBegin, rep.EpochEnd))
+			cached, err := store.GetLvl2Key(context.Background(), meta, now)
+			SoMsg("cached err", err, ShouldBeNil)
+			SoMsg("cached key", cached.Equal(key), ShouldBeTrue)
+		})
+		Convey("Key not valid at requested time is rejected", func() {
+			msgr.EXPECT().RequestDRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any(),
+				gomock.Any()).Return(rep, nil)
+			_, err := store.GetLvl2Key(context.Background(), meta, now.Add(2*time.Hour))
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("Key with invalid length is rejected", func() {
+			rep.DRKey = rep.DRKey[:drkey.KeyLength-1]
+			msgr.EXPECT().RequestDRKeyLvl2(gomock.Any(), gomock.Any(), gomock.Any(),
+				gomock.Any()).Return(rep, nil)
+			_, err := store.GetLvl2Key(context.Background(), meta, now)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+	})
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/drkey_mgmt"
 	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
 	"github.com/scionproto/scion/go/lib/hostinfo"
 	"github.com/scionproto/scion/go/lib/infra"
@@ -31,6 +32,7 @@ import (
 	"github.com/scionproto/scion/go/lib/sciond"
 	"github.com/scionproto/scion/go/lib/topology"
 	"github.com/scionproto/scion/go/proto"
+	"github.com/scionproto/scion/go/sciond/internal/drkey"
 	"github.com/scionproto/scion/go/sciond/internal/fetcher"
 )
 
@@ -328,6 +330,50 @@ func isUnknown(err error) bool {
 	return err != nil
 }
 
+// DRKeyLvl2RequestHandler represents the shared global state for the handling
+// of all DRKeyLvl2Req queries. The SCIOND API spawns a goroutine with method
+// Handle for each DRKeyLvl2Req it receives.
+type DRKeyLvl2RequestHandler struct {
+	Store *drkey.Store
+}
+
+func (h *DRKeyLvl2RequestHandler) Handle(ctx context.Context, conn net.PacketConn,
+	src net.Addr, pld *sciond.Pld) {
+
+	logger := log.FromCtx(ctx)
+	logger.Debug("[DRKeyLvl2RequestHandler] Received request", "req", pld.DRKeyLvl2Req)
+	workCtx, workCancelF := context.WithTimeout(ctx, DefaultWorkTimeout)
+	defer workCancelF()
+	drkeyReply := &sciond.DRKeyLvl2Reply{}
+	meta, err := pld.DRKeyLvl2Req.ToMeta()
+	if err != nil {
+		logger.Info("Received invalid DRKey request", "err", err)
+		drkeyReply.ErrorCode = sciond.DRKeyErrorBadRequest
+	} else {
+		key, err := h.Store.GetLvl2Key(workCtx, meta, pld.DRKeyLvl2Req.ValidityTime())
+		switch {
+		case err != nil && workCtx.Err() == context.DeadlineExceeded:
+			logger.Error("Timed out fetching DRKey", "err", err)
+			drkeyReply.ErrorCode = sciond.DRKeyErrorCSTimeout
+		case err != nil:
+			logger.Error("Unable to get DRKey", "err", err)
+			drkeyReply.ErrorCode = sciond.DRKeyErrorInternal
+		default:
+			drkeyReply.Rep = drkey_mgmt.NewLvl2RepFromKey(key, time.Now())
+		}
+	}
+	reply := &sciond.Pld{
+		Id:             pld.Id,
+		Which:          proto.SCIONDMsg_Which_drkeyLvl2Reply,
+		DRKeyLvl2Reply: drkeyReply,
+	}
+	if err := sendReply(reply, conn, src); err != nil {
+		logger.Warn("Unable to reply to client", "client", src, "err", err)
+	} else {
+		logger.Trace("Sent reply", "drkey", drkeyReply)
+	}
+}
+
 func sendReply(pld *sciond.Pld, conn net.PacketConn, src net.Addr) error {
 	b, err := proto.PackRoot(pld)
 	if err != nil {
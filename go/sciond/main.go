@@ -43,6 +43,7 @@ import (
 	"github.com/scionproto/scion/go/lib/topology"
 	"github.com/scionproto/scion/go/proto"
 	"github.com/scionproto/scion/go/sciond/internal/config"
+	"github.com/scionproto/scion/go/sciond/internal/drkey"
 	"github.com/scionproto/scion/go/sciond/internal/fetcher"
 	"github.com/scionproto/scion/go/sciond/internal/metrics"
 	"github.com/scionproto/scion/go/sciond/internal/servers"
@@ -148,6 +149,9 @@ func realMain() int {
 			RevCache:   revCache,
 			TrustStore: trustStore,
 		},
+		proto.SCIONDMsg_Which_drkeyLvl2Req: &servers.DRKeyLvl2RequestHandler{
+			Store: drkey.NewStore(msger, itopo.Get().ISD_AS),
+		},
 	}
 	cleaner := periodic.StartPeriodicTask(pathdb.NewCleaner(pathDB),
 		periodic.NewTicker(300*time.Second), 295*time.Second)
@@ -0,0 +1,76 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/scionproto/scion/go/sig/mgmt"
+)
+
+var (
+	announceMtx sync.Mutex
+	// *mgmt.Prefixes
+	announcement atomic.Value
+)
+
+func init() {
+	// Start from the current time, such that remote SIGs do not mistake the
+	// announcement of a restarted SIG for an old one.
+	announcement.Store(mgmt.NewPrefixes(uint64(time.Now().UnixNano()), nil))
+}
+
+// SetAnnounce sets the local networks that are announced to remote SIGs. The
+// version of the announcement is only incremented if the networks changed.
+func SetAnnounce(ipnets []*net.IPNet) {
+	announceMtx.Lock()
+	defer announceMtx.Unlock()
+	cur := Announcement()
+	if equalNets(cur, ipnets) {
+		return
+	}
+	announcement.Store(mgmt.NewPrefixes(cur.Version+1, ipnets))
+}
+
+// Announcement returns the local networks that are announced to remote SIGs.
+func Announcement() *mgmt.Prefixes {
+	return announcement.Load().(*mgmt.Prefixes)
+}
+
+func equalNets(p *mgmt.Prefixes, ipnets []*net.IPNet) bool {
+	if len(p.Nets) != len(ipnets) {
+		return false
+	}
+	a := make([]string, 0, len(p.Nets))
+	for _, n := range p.Nets {
+		a = append(a, n.String())
+	}
+	b := make([]string, 0, len(ipnets))
+	for _, ipnet := range ipnets {
+		b = append(b, mgmt.NewNet(ipnet).String())
+	}
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
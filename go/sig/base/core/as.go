@@ -16,6 +16,7 @@
 package core
 
 import (
+	"encoding/json"
 	"net"
 	"sync"
 	"time"
@@ -25,14 +26,21 @@ import (
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/pathpol"
+	"github.com/scionproto/scion/go/lib/pktcls"
 	"github.com/scionproto/scion/go/lib/ringbuf"
 	"github.com/scionproto/scion/go/sig/base"
 	"github.com/scionproto/scion/go/sig/config"
 	"github.com/scionproto/scion/go/sig/egress"
 	"github.com/scionproto/scion/go/sig/egress/dispatcher"
+	"github.com/scionproto/scion/go/sig/egress/qos"
 	"github.com/scionproto/scion/go/sig/egress/router"
 	"github.com/scionproto/scion/go/sig/egress/session"
 	"github.com/scionproto/scion/go/sig/egress/worker"
+	"github.com/scionproto/scion/go/sig/ingress"
+	"github.com/scionproto/scion/go/sig/mgmt"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
+	"github.com/scionproto/scion/go/sig/siginfo"
 )
 
 const (
@@ -50,7 +58,23 @@ type ASEntry struct {
 	version           uint64 // used to track certain changes made to ASEntry
 	log.Logger
 
-	Session *session.Session
+	// Sessions contains the sessions to the remote AS, keyed by their ID. The
+	// default session always exists.
+	Sessions map[mgmt.SessionType]*session.Session
+	// sessPolicies contains the JSON encoding of the path policy and the
+	// multipath configuration of each session, and is used to detect changes.
+	sessPolicies map[mgmt.SessionType]string
+	selector     *base.PolicySelector
+
+	// allowCfg contains the networks the remote AS may announce.
+	allowCfg *config.ASEntry
+	// announced is the most recent announcement received from the remote AS.
+	announced *mgmt.Prefixes
+	// learned contains the allowed networks of the announcement.
+	learned map[string]*net.IPNet
+	// unhealthySince is the time the default session became unhealthy, or the
+	// zero value if it is healthy.
+	unhealthySince time.Time
 }
 
 func newASEntry(ia addr.IA) (*ASEntry, error) {
@@ -60,43 +84,213 @@ func newASEntry(ia addr.IA) (*ASEntry, error) {
 		IAString:          ia.String(),
 		Nets:              make(map[string]*net.IPNet),
 		healthMonitorStop: make(chan struct{}),
+		Sessions:          make(map[mgmt.SessionType]*session.Session),
+		sessPolicies:      make(map[mgmt.SessionType]string),
+		learned:           make(map[string]*net.IPNet),
 	}
-	var err error
-	pool, err := session.NewPathPool(ia)
-	if err != nil {
-		return nil, err
-	}
-	ae.Session, err = session.NewSession(ia, 0, ae.Logger, pool, worker.DefaultFactory)
+	sess, err := ae.newSession(config.DefaultSession, nil, nil, false, nil, nil)
 	if err != nil {
 		return nil, err
 	}
+	ae.Sessions[config.DefaultSession] = sess
+	ae.selector = base.NewPolicySelector(sess, nil)
 	return ae, nil
 }
 
-func (ae *ASEntry) ReloadConfig(cfg *config.ASEntry) bool {
+// ReloadConfig updates the sessions, packet policies, networks and allowed
+// networks of the remote AS to the ones in aeCfg. The classes and path
+// policies are taken from cfg. The routing table is not modified, the caller
+// is responsible for installing the routes returned by Routes and
+// LearnedRoutes.
+func (ae *ASEntry) ReloadConfig(cfg *config.Cfg, aeCfg *config.ASEntry) bool {
 	ae.Lock()
 	defer ae.Unlock()
-	// Method calls first to prevent skips due to logical short-circuit
-	s := ae.addNewNets(cfg.Nets)
-	return ae.delOldNets(cfg.Nets) && s
+	s := ae.reloadSessions(cfg, aeCfg)
+	ingress.SetEncrypted(ae.IA, aeCfg.Encrypted)
+	ae.addNewNets(aeCfg.Nets)
+	ae.delOldNets(aeCfg.Nets)
+	ae.reloadAllowedNets(aeCfg)
+	return s
 }
 
-// addNewNets adds the networks in ipnets that are not currently configured.
-func (ae *ASEntry) addNewNets(ipnets []*config.IPNet) bool {
+// reloadSessions creates the configured sessions that do not exist yet, and
+// replaces the sessions whose path policy, multipath, encryption, remote SIG or
+// queue configuration changed. Sessions that are no longer configured are removed.
+// Finally, the packet policies are updated.
+func (ae *ASEntry) reloadSessions(cfg *config.Cfg, aeCfg *config.ASEntry) bool {
 	s := true
-	for _, ipnet := range ipnets {
-		err := ae.addNet(ipnet.IPNet())
+	sessCfgs := map[mgmt.SessionType]string{config.DefaultSession: ""}
+	for sessId, policyName := range aeCfg.Sessions {
+		sessCfgs[sessId] = policyName
+	}
+	var stale []*session.Session
+	for sessId, policyName := range sessCfgs {
+		policy, err := cfg.Policy(policyName)
 		if err != nil {
-			ae.Error("Unable to add network", "net", ipnet, "err", err)
+			ae.Error("Unable to load session policy", "sessId", sessId, "err", err)
+			s = false
+			continue
+		}
+		mp, encrypt := aeCfg.Multipath[sessId], aeCfg.IsEncrypted(sessId)
+		queues, err := queueSpecs(cfg, aeCfg.QoS[sessId])
+		if err != nil {
+			ae.Error("Unable to load session queues", "sessId", sessId, "err", err)
+			s = false
+			continue
+		}
+		rawPolicy, err := json.Marshal(struct {
+			Policy    *pathpol.Policy
+			Multipath *config.Multipath
+			Encrypted bool
+			SIGs      []*config.RemoteSIG
+			Queues    []*qos.Spec
+		}{policy, mp, encrypt, aeCfg.SIGs, queues})
+		if err != nil {
+			ae.Error("Unable to encode session policy", "sessId", sessId, "err", err)
+			s = false
+			continue
+		}
+		oldSess, ok := ae.Sessions[sessId]
+		if ok && ae.sessPolicies[sessId] == string(rawPolicy) {
+			continue
+		}
+		sess, err := ae.newSession(sessId, policy, mp, encrypt, aeCfg.SIGs, queues)
+		if err != nil {
+			ae.Error("Unable to create session", "sessId", sessId, "err", err)
+			s = false
+			continue
+		}
+		if ae.egressRing != nil {
+			// The network setup is done, i.e., the other sessions are running.
+			sess.Start()
+		}
+		ae.Sessions[sessId] = sess
+		ae.sessPolicies[sessId] = string(rawPolicy)
+		if ok {
+			stale = append(stale, oldSess)
+		}
+		ae.Info("Configured session", "sessId", sessId, "policy", policyName)
+	}
+	for sessId, sess := range ae.Sessions {
+		if _, ok := sessCfgs[sessId]; !ok {
+			delete(ae.Sessions, sessId)
+			delete(ae.sessPolicies, sessId)
+			stale = append(stale, sess)
+			ae.Info("Removed session", "sessId", sessId)
+		}
+	}
+	policies := make([]base.PktPolicy, 0, len(aeCfg.PktPolicies))
+	for _, pp := range aeCfg.PktPolicies {
+		class, ok := cfg.Classes[pp.ClassName]
+		if !ok {
+			ae.Error("Ignoring packet policy, unknown class", "class", pp.ClassName)
 			s = false
+			continue
+		}
+		sess, ok := ae.Sessions[pp.SessId]
+		if !ok {
+			ae.Error("Ignoring packet policy, unknown session", "class", pp.ClassName,
+				"sessId", pp.SessId)
+			s = false
+			continue
+		}
+		policies = append(policies, base.PktPolicy{Class: class, Session: sess})
+	}
+	ae.selector.Update(ae.Sessions[config.DefaultSession], policies)
+	// The selector no longer returns the stale sessions, they can be cleaned up.
+	for _, sess := range stale {
+		if err := sess.Cleanup(); err != nil {
+			sess.Error("Error cleaning up session", "err", err)
 		}
 	}
 	return s
 }
 
-// delOldNets deletes currently configured networks that are not in ipnets.
-func (ae *ASEntry) delOldNets(ipnets []*config.IPNet) bool {
-	s := true
+// newSession creates a session with ID sessId, which uses the paths to the
+// remote AS that adhere to policy. If mp is not nil, the session uses multiple
+// paths at once. If encrypt is true, the session's frames are encrypted. If
+// sigs is not empty, the flows are spread across the remote SIG instances in
+// sigs. The packets are queued in the queues described by queues.
+func (ae *ASEntry) newSession(sessId mgmt.SessionType, policy *pathpol.Policy,
+	mp *config.Multipath, encrypt bool, sigs []*config.RemoteSIG,
+	queues []*qos.Spec) (*session.Session, error) {
+
+	if encrypt && !sigcrypto.Enabled() {
+		return nil, common.NewBasicError("Encrypted sessions not supported, "+
+			"keys and certificates not configured", nil)
+	}
+	pool, err := session.NewPathPoolWithPolicy(ae.IA, policy)
+	if err != nil {
+		return nil, err
+	}
+	pathMode, numPaths := egress.SinglePath, 1
+	if mp != nil {
+		pathMode, numPaths = mp.Mode, mp.Paths
+	}
+	sess, err := session.NewMultipathSession(ae.IA, sessId, ae.Logger, pool,
+		worker.DefaultFactory, pathMode, numPaths, encrypt, ae.sigInstances(sigs), queues)
+	if err != nil {
+		pool.Destroy()
+		return nil, err
+	}
+	return sess, nil
+}
+
+// queueSpecs returns the descriptions of the queues configured in qosCfg,
+// with the classes taken from cfg. If qosCfg is nil, no queues are returned.
+func queueSpecs(cfg *config.Cfg, qosCfg *config.QoS) ([]*qos.Spec, error) {
+	if qosCfg == nil {
+		return nil, nil
+	}
+	specs := make([]*qos.Spec, 0, len(qosCfg.Queues))
+	for _, q := range qosCfg.Queues {
+		var class *pktcls.Class
+		if q.ClassName != "" {
+			var ok bool
+			if class, ok = cfg.Classes[q.ClassName]; !ok {
+				return nil, common.NewBasicError("Unknown class", nil,
+					"queue", q.Name, "class", q.ClassName)
+			}
+		}
+		specs = append(specs, q.Spec(class))
+	}
+	return specs, nil
+}
+
+// sigInstances converts the configured remote SIG instances.
+func (ae *ASEntry) sigInstances(sigs []*config.RemoteSIG) []*siginfo.Instance {
+	insts := make([]*siginfo.Instance, 0, len(sigs))
+	for _, rs := range sigs {
+		insts = append(insts, &siginfo.Instance{
+			Sig: &siginfo.Sig{
+				IA:          ae.IA,
+				Host:        addr.HostFromIP(rs.Addr),
+				CtrlL4Port:  rs.CtrlPort,
+				EncapL4Port: rs.EncapPort,
+			},
+			Priority: rs.Priority,
+			Weight:   rs.Weight,
+		})
+	}
+	return insts
+}
+
+// addNewNets tracks the networks in ipnets that are not currently configured.
+func (ae *ASEntry) addNewNets(ipnets []*config.IPNet) {
+	for _, ipnet := range ipnets {
+		if _, ok := ae.Nets[ipnet.IPNet().String()]; ok {
+			continue
+		}
+		if ae.egressRing == nil {
+			// Ensure that the network setup is done
+			ae.setupNet()
+		}
+		ae.trackNet(ipnet.IPNet())
+	}
+}
+
+// delOldNets untracks currently configured networks that are not in ipnets.
+func (ae *ASEntry) delOldNets(ipnets []*config.IPNet) {
 Top:
 	for k, v := range ae.Nets {
 		for _, ipnet := range ipnets {
@@ -104,13 +298,29 @@ Top:
 				continue Top
 			}
 		}
-		err := ae.delNet(v)
-		if err != nil {
-			ae.Error("Unable to delete network", "net", k, "err", err)
-			s = false
-		}
+		ae.untrackNet(v)
 	}
-	return s
+}
+
+// Routes returns the routes for the networks of the remote AS.
+func (ae *ASEntry) Routes() []router.Route {
+	ae.RLock()
+	defer ae.RUnlock()
+	routes := make([]router.Route, 0, len(ae.Nets))
+	for _, ipnet := range ae.Nets {
+		routes = append(routes, router.Route{Net: ipnet, IA: ae.IA, Ring: ae.egressRing})
+	}
+	return routes
+}
+
+// ClearNets untracks all configured and learned networks of the remote AS,
+// without removing them from the routing table.
+func (ae *ASEntry) ClearNets() {
+	ae.Lock()
+	defer ae.Unlock()
+	ae.delOldNets(nil)
+	ae.announced = nil
+	ae.setLearnedNets(nil)
 }
 
 // AddNet idempotently adds a network for the remote IA.
@@ -132,7 +342,14 @@ func (ae *ASEntry) addNet(ipnet *net.IPNet) error {
 	if err := router.NetMap.Add(ipnet, ae.IA, ae.egressRing); err != nil {
 		return err
 	}
-	ae.Nets[key] = ipnet
+	ae.trackNet(ipnet)
+	return nil
+}
+
+// trackNet adds ipnet to the networks of the remote AS, without adding it to
+// the routing table.
+func (ae *ASEntry) trackNet(ipnet *net.IPNet) {
+	ae.Nets[ipnet.String()] = ipnet
 	ae.version++
 	// Generate NetworkChanged event
 	params := base.NetworkChangedParams{
@@ -143,7 +360,6 @@ func (ae *ASEntry) addNet(ipnet *net.IPNet) error {
 	}
 	base.NetworkChanged(params)
 	ae.Info("Added network", "net", ipnet)
-	return nil
 }
 
 // DelNet removes a network for the remote IA.
@@ -161,7 +377,14 @@ func (ae *ASEntry) delNet(ipnet *net.IPNet) error {
 	if err := router.NetMap.Delete(ipnet); err != nil {
 		return err
 	}
-	delete(ae.Nets, key)
+	ae.untrackNet(ipnet)
+	return nil
+}
+
+// untrackNet removes ipnet from the networks of the remote AS, without
+// removing it from the routing table.
+func (ae *ASEntry) untrackNet(ipnet *net.IPNet) {
+	delete(ae.Nets, ipnet.String())
 	ae.version++
 	// Generate NetworkChanged event
 	params := base.NetworkChangedParams{
@@ -172,7 +395,6 @@ func (ae *ASEntry) delNet(ipnet *net.IPNet) error {
 	}
 	base.NetworkChanged(params)
 	ae.Info("Removed network", "net", ipnet)
-	return nil
 }
 
 func (ae *ASEntry) monitorHealth() {
@@ -187,6 +409,11 @@ Top:
 		case <-ae.healthMonitorStop:
 			break Top
 		case <-ticker.C:
+			if ae.updateLearnedNets() {
+				if err := Map.replaceRoutes(); err != nil {
+					ae.Error("Installing learned networks failed", "err", err)
+				}
+			}
 			ae.performHealthCheck(&prevHealth, &prevVersion)
 		}
 	}
@@ -202,10 +429,15 @@ func (ae *ASEntry) performHealthCheck(prevHealth *bool, prevVersion *uint64) {
 		// Generate slice of networks.
 		// XXX: This could become a bottleneck, namely in case of a large number
 		// of remote prefixes and flappy health.
-		nets := make([]*net.IPNet, 0, len(ae.Nets))
+		nets := make([]*net.IPNet, 0, len(ae.Nets)+len(ae.learned))
 		for _, n := range ae.Nets {
 			nets = append(nets, n)
 		}
+		for k, n := range ae.learned {
+			if _, ok := ae.Nets[k]; !ok {
+				nets = append(nets, n)
+			}
+		}
 		// Overall health has changed. Generate event.
 		params := base.RemoteHealthChangedParams{
 			RemoteIA: ae.IA,
@@ -218,30 +450,39 @@ func (ae *ASEntry) performHealthCheck(prevHealth *bool, prevVersion *uint64) {
 	*prevVersion = ae.version
 }
 
+// checkHealth returns the health of the default session.
 func (ae *ASEntry) checkHealth() bool {
-	return ae.Session.Healthy()
+	return ae.Sessions[config.DefaultSession].Healthy()
 }
 
+// Cleanup stops the health monitor and the sessions of the remote AS. The
+// routes of the remote AS are owned by the ASMap, which removes them from the
+// routing table.
 func (ae *ASEntry) Cleanup() error {
+	ae.RLock()
+	monitored := ae.egressRing != nil
+	ae.RUnlock()
+	if monitored {
+		// Clean up health monitor. This must happen without holding the lock,
+		// as the monitor might be waiting for it to install learned networks.
+		ae.healthMonitorStop <- struct{}{}
+	}
 	ae.Lock()
 	defer ae.Unlock()
-	// Clean up health monitor
-	ae.healthMonitorStop <- struct{}{}
-	// Clean up NetMap entries
-	for _, v := range ae.Nets {
-		if err := ae.delNet(v); err != nil {
-			ae.Error("Error removing networks during cleanup", "err", err)
-		}
+	if ae.egressRing != nil {
+		ae.egressRing.Close()
 	}
-	ae.egressRing.Close()
 	// Clean up sessions, and associated workers.
 	ae.cleanSessions()
+	ingress.SetEncrypted(ae.IA, nil)
 	return nil
 }
 
 func (ae *ASEntry) cleanSessions() {
-	if err := ae.Session.Cleanup(); err != nil {
-		ae.Session.Error("Error cleaning up session", "err", err)
+	for _, sess := range ae.Sessions {
+		if err := sess.Cleanup(); err != nil {
+			sess.Error("Error cleaning up session", "err", err)
+		}
 	}
 }
 
@@ -250,13 +491,14 @@ func (ae *ASEntry) setupNet() {
 		prometheus.Labels{"ringId": ae.IAString, "sessId": ""})
 	go func() {
 		defer log.LogPanicAndExit()
-		dispatcher.NewDispatcher(ae.IA, ae.egressRing,
-			&base.SingleSession{Session: ae.Session}).Run()
+		dispatcher.NewDispatcher(ae.IA, ae.egressRing, ae.selector).Run()
 	}()
 	go func() {
 		defer log.LogPanicAndExit()
 		ae.monitorHealth()
 	}()
-	ae.Session.Start()
+	for _, sess := range ae.Sessions {
+		sess.Start()
+	}
 	ae.Info("Network setup done")
 }
@@ -0,0 +1,152 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"net"
+	"time"
+
+	"github.com/scionproto/scion/go/sig/base"
+	"github.com/scionproto/scion/go/sig/config"
+	"github.com/scionproto/scion/go/sig/egress/router"
+)
+
+const (
+	// learnedNetsTimeout is the time the default session to a remote AS must
+	// be unhealthy, before the networks learned from it are withdrawn.
+	learnedNetsTimeout = 10 * time.Second
+)
+
+// LearnedRoutes returns the routes for the networks learned from the remote
+// AS. Networks that are also configured statically are omitted.
+func (ae *ASEntry) LearnedRoutes() []router.Route {
+	ae.RLock()
+	defer ae.RUnlock()
+	routes := make([]router.Route, 0, len(ae.learned))
+	for key, ipnet := range ae.learned {
+		if _, ok := ae.Nets[key]; ok {
+			continue
+		}
+		routes = append(routes, router.Route{Net: ipnet, IA: ae.IA, Ring: ae.egressRing})
+	}
+	return routes
+}
+
+// reloadAllowedNets updates the networks the remote AS may announce, and
+// re-applies the most recent announcement.
+func (ae *ASEntry) reloadAllowedNets(aeCfg *config.ASEntry) {
+	ae.allowCfg = aeCfg
+	if len(aeCfg.AllowedNets) > 0 && ae.egressRing == nil {
+		// The sessions must run to receive announcements.
+		ae.setupNet()
+	}
+	ae.applyAnnouncement()
+}
+
+// updateLearnedNets applies the most recent announcement received on the
+// default session. If the session has been unhealthy for longer than
+// learnedNetsTimeout, all learned networks are withdrawn instead. It returns
+// whether the learned networks changed.
+func (ae *ASEntry) updateLearnedNets() bool {
+	ae.Lock()
+	defer ae.Unlock()
+	sess := ae.Sessions[config.DefaultSession]
+	if !sess.Healthy() {
+		if ae.unhealthySince.IsZero() {
+			ae.unhealthySince = time.Now()
+		}
+		if ae.announced == nil || time.Since(ae.unhealthySince) < learnedNetsTimeout {
+			return false
+		}
+		ae.Info("Withdrawing learned networks, session lost",
+			"duration", time.Since(ae.unhealthySince))
+		ae.announced = nil
+		return ae.setLearnedNets(nil)
+	}
+	ae.unhealthySince = time.Time{}
+	announced := sess.RemotePrefixes()
+	if announced == nil {
+		return false
+	}
+	if ae.announced != nil && ae.announced.Version == announced.Version {
+		return false
+	}
+	ae.Info("Received announcement", "announcement", announced)
+	ae.announced = announced
+	return ae.applyAnnouncement()
+}
+
+// applyAnnouncement sets the learned networks to the networks of the most
+// recent announcement that are allowed by the configuration. Invalid
+// announcements are ignored. It returns whether the learned networks changed.
+func (ae *ASEntry) applyAnnouncement() bool {
+	if ae.announced == nil {
+		return ae.setLearnedNets(nil)
+	}
+	ipnets, err := ae.announced.IPNets()
+	if err != nil {
+		ae.Error("Ignoring invalid announcement", "err", err)
+		return false
+	}
+	allowed := make([]*net.IPNet, 0, len(ipnets))
+	for _, ipnet := range ipnets {
+		if ae.allowCfg == nil || !ae.allowCfg.Allows(ipnet) {
+			ae.Info("Ignoring announced network, not allowed", "net", ipnet)
+			continue
+		}
+		allowed = append(allowed, ipnet)
+	}
+	return ae.setLearnedNets(allowed)
+}
+
+// setLearnedNets replaces the learned networks with ipnets. The routing table
+// is not modified. It returns whether the learned networks changed.
+func (ae *ASEntry) setLearnedNets(ipnets []*net.IPNet) bool {
+	learned := make(map[string]*net.IPNet, len(ipnets))
+	for _, ipnet := range ipnets {
+		learned[ipnet.String()] = ipnet
+	}
+	changed := false
+	for key, ipnet := range ae.learned {
+		if _, ok := learned[key]; !ok {
+			changed = true
+			ae.learnedNetChanged(ipnet, false)
+			ae.Info("Withdrew learned network", "net", ipnet)
+		}
+	}
+	for key, ipnet := range learned {
+		if _, ok := ae.learned[key]; !ok {
+			changed = true
+			ae.learnedNetChanged(ipnet, true)
+			ae.Info("Learned network", "net", ipnet)
+		}
+	}
+	ae.learned = learned
+	if changed {
+		ae.version++
+	}
+	return changed
+}
+
+func (ae *ASEntry) learnedNetChanged(ipnet *net.IPNet, added bool) {
+	// Generate NetworkChanged event
+	params := base.NetworkChangedParams{
+		RemoteIA: ae.IA,
+		IpNet:    *ipnet,
+		Healthy:  ae.checkHealth(),
+		Added:    added,
+	}
+	base.NetworkChanged(params)
+}
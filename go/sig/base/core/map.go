@@ -17,16 +17,22 @@
 package core
 
 import (
+	"net"
 	"sync"
 
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/sig/base"
 	"github.com/scionproto/scion/go/sig/config"
+	"github.com/scionproto/scion/go/sig/egress/router"
 )
 
 var Map = &ASMap{}
 
+// routesMtx serializes the updates of the routing table.
+var routesMtx sync.Mutex
+
 // ASMap is not concurrency safe against multiple writers.
 type ASMap sync.Map
 
@@ -60,13 +66,28 @@ func (am *ASMap) Range(f func(key addr.IAInt, value *ASEntry) bool) {
 	})
 }
 
+// ReloadConfig updates the remote ASes and their networks to the ones in cfg,
+// and the networks announced to remote SIGs. The routes of all networks are
+// replaced atomically in the routing table, such that networks can move
+// between ASes without interruption.
 func (am *ASMap) ReloadConfig(cfg *config.Cfg) bool {
+	announce := make([]*net.IPNet, 0, len(cfg.Announce))
+	for _, ipnet := range cfg.Announce {
+		announce = append(announce, ipnet.IPNet())
+	}
+	base.SetAnnounce(announce)
 	// Method calls first to prevent skips due to logical short-circuit
 	s := am.addNewIAs(cfg)
-	return am.delOldIAs(cfg) && s
+	oldIAs := am.removeOldIAs(cfg)
+	if err := am.replaceRoutes(); err != nil {
+		log.Error("ReloadConfig: Replacing routes failed", "err", err)
+		s = false
+	}
+	return am.cleanupOldIAs(oldIAs) && s
 }
 
-// addNewIAs adds the ASes in cfg that are not currently configured.
+// addNewIAs adds the ASes in cfg that are not currently configured, and
+// updates the networks of all ASes in cfg.
 func (am *ASMap) addNewIAs(cfg *config.Cfg) bool {
 	s := true
 	for ia, cfgEntry := range cfg.ASes {
@@ -77,33 +98,77 @@ func (am *ASMap) addNewIAs(cfg *config.Cfg) bool {
 			s = false
 			continue
 		}
-		s = ae.ReloadConfig(cfgEntry) && s
+		s = ae.ReloadConfig(cfg, cfgEntry) && s
 		log.Info("ReloadConfig: Added AS", "ia", ia)
 	}
 	return s
 }
 
-func (am *ASMap) delOldIAs(cfg *config.Cfg) bool {
-	s := true
-	// Delete all ASes that currently exist but are not in cfg
-	am.Range(func(iaInt addr.IAInt, as *ASEntry) bool {
-		ia := iaInt.IA()
-		if _, ok := cfg.ASes[ia]; !ok {
-			log.Info("ReloadConfig: Deleting AS...", "ia", ia)
-			// Deletion also handles session/tun device cleanup
-			err := am.DelIA(ia)
-			if err != nil {
-				log.Error("ReloadConfig: Deleting AS failed", "err", err)
-				s = false
-				return true
-			}
-			log.Info("ReloadConfig: Deleted AS", "ia", ia)
+// removeOldIAs removes all ASes that currently exist but are not in cfg from
+// the map, and returns them. Their networks are untracked, but not removed
+// from the routing table.
+func (am *ASMap) removeOldIAs(cfg *config.Cfg) []*ASEntry {
+	var oldIAs []*ASEntry
+	am.Range(func(iaInt addr.IAInt, ae *ASEntry) bool {
+		if _, ok := cfg.ASes[iaInt.IA()]; !ok {
+			ae.ClearNets()
+			am.Delete(iaInt)
+			oldIAs = append(oldIAs, ae)
 		}
 		return true
 	})
+	return oldIAs
+}
+
+// cleanupOldIAs cleans up the removed ASes.
+func (am *ASMap) cleanupOldIAs(oldIAs []*ASEntry) bool {
+	s := true
+	for _, ae := range oldIAs {
+		log.Info("ReloadConfig: Deleting AS...", "ia", ae.IA)
+		// Cleanup also handles session/tun device cleanup
+		if err := ae.Cleanup(); err != nil {
+			log.Error("ReloadConfig: Deleting AS failed", "err", err)
+			s = false
+			continue
+		}
+		log.Info("ReloadConfig: Deleted AS", "ia", ae.IA)
+	}
 	return s
 }
 
+// replaceRoutes replaces the routing table with the routes of all remote
+// ASes.
+func (am *ASMap) replaceRoutes() error {
+	routesMtx.Lock()
+	defer routesMtx.Unlock()
+	return router.NetMap.Replace(am.routes())
+}
+
+// routes returns the routes of all remote ASes. Configured networks take
+// precedence over learned ones, and a network learned from multiple ASes is
+// only routed to one of them.
+func (am *ASMap) routes() []router.Route {
+	var routes, learned []router.Route
+	am.Range(func(_ addr.IAInt, ae *ASEntry) bool {
+		routes = append(routes, ae.Routes()...)
+		learned = append(learned, ae.LearnedRoutes()...)
+		return true
+	})
+	known := make(map[string]bool, len(routes)+len(learned))
+	for _, r := range routes {
+		known[r.Net.String()] = true
+	}
+	for _, r := range learned {
+		if known[r.Net.String()] {
+			log.Info("Ignoring learned network, already routed", "net", r.Net, "ia", r.IA)
+			continue
+		}
+		known[r.Net.String()] = true
+		routes = append(routes, r)
+	}
+	return routes
+}
+
 // AddIA idempotently adds an entry for a remote IA.
 func (am *ASMap) AddIA(ia addr.IA) (*ASEntry, error) {
 	if ia.IsWildcard() {
@@ -122,17 +187,6 @@ func (am *ASMap) AddIA(ia addr.IA) (*ASEntry, error) {
 	return ae, nil
 }
 
-// DelIA removes an entry for a remote IA.
-func (am *ASMap) DelIA(ia addr.IA) error {
-	key := ia.IAInt()
-	ae, ok := am.Load(key)
-	if !ok {
-		return common.NewBasicError("DelIA: No entry found", nil, "ia", ia)
-	}
-	am.Delete(key)
-	return ae.Cleanup()
-}
-
 // ASEntry returns the entry for the specified remote IA, or nil if not present.
 func (am *ASMap) ASEntry(ia addr.IA) *ASEntry {
 	if as, ok := am.Load(ia.IAInt()); ok {
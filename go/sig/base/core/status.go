@@ -0,0 +1,159 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/sig/egress"
+	"github.com/scionproto/scion/go/sig/egress/session"
+	"github.com/scionproto/scion/go/sig/mgmt"
+)
+
+// ASStatus describes the state of a remote AS.
+type ASStatus struct {
+	IA      addr.IA
+	Healthy bool
+	// Nets contains the configured networks of the remote AS.
+	Nets []string
+	// Learned contains the networks learned from announcements of the remote
+	// AS.
+	Learned  []string
+	Sessions []*SessionStatus
+}
+
+// SessionStatus describes the state of a session to a remote AS.
+type SessionStatus struct {
+	Id        mgmt.SessionType
+	Healthy   bool
+	PathMode  egress.PathMode `json:",omitempty"`
+	Encrypted bool
+	// RemoteSIG is the remote SIG the session's traffic is sent to.
+	RemoteSIG string `json:",omitempty"`
+	// RemoteSIGs contains the remote SIG instances the session's
+	// flows are spread across, if the remote AS has multiple SIGs.
+	RemoteSIGs []string `json:",omitempty"`
+	// Paths contains the paths the session's traffic is sent on.
+	Paths []*PathStatus
+}
+
+// PathStatus describes a path used by a session.
+type PathStatus struct {
+	Path string
+	RTT  time.Duration
+	// Weight is the share of the traffic sent on the path by load balancing
+	// sessions.
+	Weight int `json:",omitempty"`
+}
+
+// Status returns the state of all remote ASes, sorted by IA.
+func (am *ASMap) Status() []*ASStatus {
+	var status []*ASStatus
+	am.Range(func(_ addr.IAInt, ae *ASEntry) bool {
+		status = append(status, ae.Status())
+		return true
+	})
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].IA.IAInt() < status[j].IA.IAInt()
+	})
+	return status
+}
+
+// SwitchPath requests session sessId of the remote AS ia to switch paths.
+func (am *ASMap) SwitchPath(ia addr.IA, sessId mgmt.SessionType) error {
+	ae, ok := am.Load(ia.IAInt())
+	if !ok {
+		return common.NewBasicError("Unknown AS", nil, "ia", ia)
+	}
+	ae.RLock()
+	defer ae.RUnlock()
+	sess, ok := ae.Sessions[sessId]
+	if !ok {
+		return common.NewBasicError("Unknown session", nil, "ia", ia, "sessId", sessId)
+	}
+	sess.SwitchPath()
+	return nil
+}
+
+// Status returns the state of the remote AS and its sessions.
+func (ae *ASEntry) Status() *ASStatus {
+	ae.RLock()
+	defer ae.RUnlock()
+	status := &ASStatus{
+		IA:       ae.IA,
+		Healthy:  ae.checkHealth(),
+		Nets:     make([]string, 0, len(ae.Nets)),
+		Learned:  make([]string, 0, len(ae.learned)),
+		Sessions: make([]*SessionStatus, 0, len(ae.Sessions)),
+	}
+	for k := range ae.Nets {
+		status.Nets = append(status.Nets, k)
+	}
+	for k := range ae.learned {
+		status.Learned = append(status.Learned, k)
+	}
+	sort.Strings(status.Nets)
+	sort.Strings(status.Learned)
+	for _, sess := range ae.Sessions {
+		status.Sessions = append(status.Sessions, sessionStatus(sess))
+	}
+	sort.Slice(status.Sessions, func(i, j int) bool {
+		return status.Sessions[i].Id < status.Sessions[j].Id
+	})
+	return status
+}
+
+func sessionStatus(sess *session.Session) *SessionStatus {
+	status := &SessionStatus{
+		Id:        sess.SessId,
+		Healthy:   sess.Healthy(),
+		PathMode:  sess.PathMode(),
+		Encrypted: sess.Encrypted(),
+	}
+	remote := sess.Remote()
+	if remote == nil {
+		return status
+	}
+	if remote.Sig != nil {
+		status.RemoteSIG = remote.Sig.String()
+	}
+	for _, inst := range remote.Sigs {
+		status.RemoteSIGs = append(status.RemoteSIGs, inst.String())
+	}
+	switch {
+	case len(remote.MultiPaths) > 0:
+		for _, wp := range remote.MultiPaths {
+			weight := 0
+			if sess.PathMode() == egress.LoadBalance {
+				weight = wp.Weight
+			}
+			status.Paths = append(status.Paths, pathStatus(wp.SessPath, weight))
+		}
+	case remote.SessPath != nil:
+		status.Paths = append(status.Paths, pathStatus(remote.SessPath, 0))
+	}
+	return status
+}
+
+func pathStatus(path *egress.SessPath, weight int) *PathStatus {
+	return &PathStatus{
+		Path:   path.PathEntry().Path.String(),
+		RTT:    path.RTT(),
+		Weight: weight,
+	}
+}
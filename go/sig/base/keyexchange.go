@@ -0,0 +1,86 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/sig/disp"
+	"github.com/scionproto/scion/go/sig/ingress"
+	"github.com/scionproto/scion/go/sig/mgmt"
+	"github.com/scionproto/scion/go/sig/sigcmn"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
+)
+
+// kxReplyTimeout is the time a key exchange reply is kept, to answer repeated
+// requests of the same key exchange. Initiators repeat a key exchange for at
+// most a second.
+const kxReplyTimeout = 10 * time.Second
+
+// kxReplies contains the recent key exchange replies, keyed by the remote IA,
+// session and key ID. It is only accessed by the PollReq handler.
+var kxReplies = make(map[string]*kxReply)
+
+type kxReply struct {
+	kx      *mgmt.KeyExchange
+	created time.Time
+}
+
+// keyExchangeRep answers the key exchange of a PollReq, and adds the derived
+// key to the ingress keys. Repeated requests of the same key exchange are
+// answered with the same reply. It returns nil if the key exchange cannot be
+// authenticated.
+func keyExchangeRep(rpld *disp.RegPld, req *mgmt.PollReq) *mgmt.KeyExchange {
+	kx := req.KeyExchange
+	if err := sigcrypto.Verify(rpld.Signed, rpld.Addr.IA, kx); err != nil {
+		log.Error("PollReqHdlr: Unable to authenticate key exchange", "src", rpld.Addr,
+			"err", err)
+		return nil
+	}
+	now := time.Now()
+	for k, r := range kxReplies {
+		if now.Sub(r.created) > kxReplyTimeout {
+			delete(kxReplies, k)
+		}
+	}
+	replyKey := fmt.Sprintf("%s-%s-%d", rpld.Addr.IA, req.Session, kx.KeyId)
+	if r, ok := kxReplies[replyKey]; ok {
+		if !bytes.Equal(r.kx.PeerPubKey, kx.PubKey) {
+			log.Error("PollReqHdlr: Key exchange reuses key ID", "src", rpld.Addr,
+				"keyId", kx.KeyId)
+			return nil
+		}
+		return r.kx
+	}
+	eph, err := sigcrypto.NewEphemeralKey()
+	if err != nil {
+		log.Error("PollReqHdlr: Unable to answer key exchange", "err", err)
+		return nil
+	}
+	rep := mgmt.NewKeyExchange(kx.KeyId, eph.Pub[:], kx.PubKey, sigcrypto.LocalChain())
+	key, err := sigcrypto.DeriveKey(eph, rep, rpld.Addr.IA, sigcmn.IA, req.Session)
+	if err != nil {
+		log.Error("PollReqHdlr: Unable to derive session key", "src", rpld.Addr, "err", err)
+		return nil
+	}
+	ingress.AddKey(rpld.Addr.IA, req.Session, key)
+	kxReplies[replyKey] = &kxReply{kx: rep, created: now}
+	log.Info("PollReqHdlr: Established session key", "src", rpld.Addr,
+		"session", req.Session, "keyId", key.Id)
+	return rep
+}
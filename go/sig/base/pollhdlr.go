@@ -25,6 +25,7 @@ import (
 	"github.com/scionproto/scion/go/sig/disp"
 	"github.com/scionproto/scion/go/sig/mgmt"
 	"github.com/scionproto/scion/go/sig/sigcmn"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
 )
 
 func PollReqHdlr() {
@@ -38,7 +39,12 @@ func PollReqHdlr() {
 		}
 		//log.Debug("PollReqHdlr: got PollReq", "src", rpld.Addr, "pld", req,
 		//	"replyAddr", sigcmn.MgmtAddr, "replySession", req.Session)
-		spld, err := mgmt.NewPld(rpld.Id, mgmt.NewPollRep(sigcmn.MgmtAddr, req.Session))
+		rep := mgmt.NewPollRep(sigcmn.MgmtAddr, req.Session)
+		rep.Prefixes = Announcement()
+		if req.KeyExchange != nil {
+			rep.KeyExchange = keyExchangeRep(rpld, req)
+		}
+		spld, err := mgmt.NewPld(rpld.Id, rep)
 		if err != nil {
 			log.Error("PollReqHdlr: Error creating SIGCtrl payload", "err", err)
 			break
@@ -48,7 +54,12 @@ func PollReqHdlr() {
 			log.Error("PollReqHdlr: Error creating Ctrl payload", "err", err)
 			break
 		}
-		scpld, err := cpld.SignedPld(infra.NullSigner)
+		var scpld *ctrl.SignedPld
+		if rep.KeyExchange != nil {
+			scpld, err = sigcrypto.Sign(cpld)
+		} else {
+			scpld, err = cpld.SignedPld(infra.NullSigner)
+		}
 		if err != nil {
 			log.Error("PollReqHdlr: Error creating signed Ctrl payload", "err", err)
 			break
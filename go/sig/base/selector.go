@@ -15,7 +15,10 @@
 package base
 
 import (
+	"sync/atomic"
+
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/pktcls"
 	"github.com/scionproto/scion/go/sig/egress"
 )
 
@@ -30,3 +33,52 @@ type SingleSession struct {
 func (ss *SingleSession) ChooseSess(b common.RawBytes) egress.Session {
 	return ss.Session
 }
+
+// PktPolicy maps the traffic class Class to the session Session.
+type PktPolicy struct {
+	Class   *pktcls.Class
+	Session egress.Session
+}
+
+var _ egress.SessionSelector = (*PolicySelector)(nil)
+
+// PolicySelector implements egress.SessionSelector, classifying packets with
+// its packet policies. ChooseSess returns the session of the first policy
+// whose class matches the packet, or the default session if no class matches.
+// The policies can be updated concurrently with ChooseSess.
+type PolicySelector struct {
+	// policies contains a *policySet
+	policies atomic.Value
+}
+
+type policySet struct {
+	def      egress.Session
+	policies []PktPolicy
+}
+
+// NewPolicySelector creates a selector with the default session def and the
+// packet policies policies.
+func NewPolicySelector(def egress.Session, policies []PktPolicy) *PolicySelector {
+	ps := &PolicySelector{}
+	ps.Update(def, policies)
+	return ps
+}
+
+// Update atomically replaces the default session and the packet policies.
+func (ps *PolicySelector) Update(def egress.Session, policies []PktPolicy) {
+	ps.policies.Store(&policySet{def: def, policies: policies})
+}
+
+func (ps *PolicySelector) ChooseSess(b common.RawBytes) egress.Session {
+	set := ps.policies.Load().(*policySet)
+	if len(set.policies) == 0 {
+		return set.def
+	}
+	pkt := pktcls.NewPacket(b)
+	for _, p := range set.policies {
+		if p.Class.Eval(pkt) {
+			return p.Session
+		}
+	}
+	return set.def
+}
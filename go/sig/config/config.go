@@ -18,16 +18,39 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net"
+	"os"
 
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/pathpol"
+	"github.com/scionproto/scion/go/lib/pktcls"
+	"github.com/scionproto/scion/go/sig/egress"
+	"github.com/scionproto/scion/go/sig/egress/qos"
+	"github.com/scionproto/scion/go/sig/mgmt"
 )
 
+// DefaultSession is the ID of the session that carries the traffic that
+// does not match any packet policy.
+const DefaultSession mgmt.SessionType = 0
+
+// MaxAnnounceNets is the maximum number of networks a SIG announces to
+// remote SIGs. The announcement must fit into a single poll reply.
+const MaxAnnounceNets = 64
+
+const maxPort = (1 << 16) - 1
+
 // Cfg is a direct Go representation of the JSON file format.
 type Cfg struct {
-	ASes          map[addr.IA]*ASEntry
+	ASes map[addr.IA]*ASEntry
+	// Announce contains the local networks that are announced to remote SIGs.
+	Announce []*IPNet `json:",omitempty"`
+	// Classes contains the traffic classes, keyed by their name.
+	Classes pktcls.ClassMap `json:",omitempty"`
+	// Policies contains the path policies, keyed by their name.
+	Policies      pathpol.PolicyMap `json:",omitempty"`
 	ConfigVersion uint64
 }
 
@@ -41,11 +64,322 @@ func LoadFromFile(path string) (*Cfg, error) {
 	if err := json.Unmarshal(b, cfg); err != nil {
 		return nil, common.NewBasicError("Unable to parse SIG config", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, common.NewBasicError("Invalid SIG config", err)
+	}
 	return cfg, nil
 }
 
+// SaveToFile writes the config to path. The file is replaced atomically, such
+// that a concurrent load never reads a partially written config.
+func (cfg *Cfg) SaveToFile(path string) error {
+	b, err := json.MarshalIndent(cfg, "", "    ")
+	if err != nil {
+		return common.NewBasicError("Unable to encode SIG config", err)
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return common.NewBasicError("Unable to write SIG config", err, "path", tmp)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return common.NewBasicError("Unable to replace SIG config", err, "path", path)
+	}
+	return nil
+}
+
+// Copy returns a deep copy of the config.
+func (cfg *Cfg) Copy() (*Cfg, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to encode SIG config", err)
+	}
+	c := &Cfg{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, common.NewBasicError("Unable to parse SIG config", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, common.NewBasicError("Invalid SIG config", err)
+	}
+	return c, nil
+}
+
+// Validate checks that the classes, policies and sessions referenced by the
+// AS entries exist. It also sets the names of the path policies, which are
+// not part of the JSON encoding of the policies themselves.
+func (cfg *Cfg) Validate() error {
+	for name, extPolicy := range cfg.Policies {
+		if extPolicy.Policy == nil {
+			extPolicy.Policy = &pathpol.Policy{}
+		}
+		extPolicy.Name = name
+	}
+	if len(cfg.Announce) > MaxAnnounceNets {
+		return common.NewBasicError("Too many announced networks", nil,
+			"max", MaxAnnounceNets, "actual", len(cfg.Announce))
+	}
+	for ia, ae := range cfg.ASes {
+		for sessId, policyName := range ae.Sessions {
+			if _, err := cfg.Policy(policyName); err != nil {
+				return common.NewBasicError("Invalid session policy", err,
+					"ia", ia, "sessId", sessId)
+			}
+		}
+		for _, pp := range ae.PktPolicies {
+			if _, ok := cfg.Classes[pp.ClassName]; !ok {
+				return common.NewBasicError("Unknown class", nil,
+					"ia", ia, "class", pp.ClassName)
+			}
+			if _, ok := ae.Sessions[pp.SessId]; !ok && pp.SessId != DefaultSession {
+				return common.NewBasicError("Unknown session", nil,
+					"ia", ia, "class", pp.ClassName, "sessId", pp.SessId)
+			}
+		}
+		for sessId, mp := range ae.Multipath {
+			if _, ok := ae.Sessions[sessId]; !ok && sessId != DefaultSession {
+				return common.NewBasicError("Unknown multipath session", nil,
+					"ia", ia, "sessId", sessId)
+			}
+			if err := mp.Validate(); err != nil {
+				return common.NewBasicError("Invalid multipath config", err,
+					"ia", ia, "sessId", sessId)
+			}
+		}
+		for _, sessId := range ae.Encrypted {
+			if _, ok := ae.Sessions[sessId]; !ok && sessId != DefaultSession {
+				return common.NewBasicError("Unknown encrypted session", nil,
+					"ia", ia, "sessId", sessId)
+			}
+		}
+		for sessId, q := range ae.QoS {
+			if _, ok := ae.Sessions[sessId]; !ok && sessId != DefaultSession {
+				return common.NewBasicError("Unknown QoS session", nil,
+					"ia", ia, "sessId", sessId)
+			}
+			if err := q.Validate(cfg.Classes); err != nil {
+				return common.NewBasicError("Invalid QoS config", err,
+					"ia", ia, "sessId", sessId)
+			}
+		}
+		sigs := make(map[string]bool, len(ae.SIGs))
+		for _, rs := range ae.SIGs {
+			if err := rs.Validate(); err != nil {
+				return common.NewBasicError("Invalid remote SIG", err, "ia", ia)
+			}
+			key := fmt.Sprintf("[%s]:%d", rs.Addr, rs.CtrlPort)
+			if sigs[key] {
+				return common.NewBasicError("Duplicate remote SIG", nil,
+					"ia", ia, "sig", key)
+			}
+			sigs[key] = true
+		}
+	}
+	return nil
+}
+
+// Policy returns the path policy with the specified name, with all extended
+// policies applied. The empty name refers to no policy, for which nil is
+// returned.
+func (cfg *Cfg) Policy(name string) (*pathpol.Policy, error) {
+	if name == "" {
+		return nil, nil
+	}
+	extPolicy, ok := cfg.Policies[name]
+	if !ok {
+		return nil, common.NewBasicError("Unknown policy", nil, "name", name)
+	}
+	extended := make([]*pathpol.ExtPolicy, 0, len(cfg.Policies))
+	for _, p := range cfg.Policies {
+		extended = append(extended, p)
+	}
+	return pathpol.PolicyFromExtPolicy(extPolicy, extended)
+}
+
 type ASEntry struct {
 	Nets []*IPNet
+	// Sessions maps the IDs of the sessions to the remote AS to the names of
+	// their path policies. An empty name means that the session uses all
+	// paths. The default session always exists, even if it is not listed.
+	Sessions map[mgmt.SessionType]string `json:",omitempty"`
+	// PktPolicies map traffic classes to sessions. A packet is sent on the
+	// session of the first policy whose class matches the packet. Packets that
+	// match no class are sent on the default session.
+	PktPolicies []*PktPolicy `json:",omitempty"`
+	// AllowedNets contains the networks the remote AS may announce. Announced
+	// networks that are not contained in any of them are ignored. If empty,
+	// no networks are learned from the remote AS.
+	//
+	// Announcements are not authenticated: any host that can send poll
+	// replies to the SIG in the name of the remote AS can announce networks.
+	// The allow-list only bounds the networks that can be hijacked this way,
+	// so it should be as narrow as possible.
+	AllowedNets []*IPNet `json:",omitempty"`
+	// Multipath configures sessions to send frames on multiple paths at once.
+	// Sessions that are not listed use a single path.
+	Multipath map[mgmt.SessionType]*Multipath `json:",omitempty"`
+	// Encrypted lists the sessions to the remote AS whose frames are
+	// encrypted. The remote SIG must list the same sessions, as cleartext
+	// frames of encrypted sessions are dropped.
+	Encrypted []mgmt.SessionType `json:",omitempty"`
+	// SIGs are the SIG instances of the remote AS. If empty, the remote SIG
+	// is discovered through the SIG anycast address.
+	SIGs []*RemoteSIG `json:",omitempty"`
+	// QoS configures the egress queues of sessions. Sessions that are not
+	// listed use a single queue.
+	QoS map[mgmt.SessionType]*QoS `json:",omitempty"`
+}
+
+// Allows returns whether ipnet is contained in one of the allowed networks.
+func (ae *ASEntry) Allows(ipnet *net.IPNet) bool {
+	ones, bits := ipnet.Mask.Size()
+	for _, allowed := range ae.AllowedNets {
+		aOnes, aBits := allowed.Mask.Size()
+		if aBits == bits && aOnes <= ones && allowed.IPNet().Contains(ipnet.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEncrypted returns whether the frames of session sessId are encrypted.
+func (ae *ASEntry) IsEncrypted(sessId mgmt.SessionType) bool {
+	for _, encSessId := range ae.Encrypted {
+		if encSessId == sessId {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteSIG is a SIG instance of a remote AS. Flows are spread across the
+// healthy instances with the lowest Priority value, in proportion to their
+// Weight. Instances with a higher Priority value are only used if none with a
+// lower value are healthy.
+type RemoteSIG struct {
+	Addr      net.IP
+	CtrlPort  int
+	EncapPort int
+	Priority  int `json:",omitempty"`
+	// Weight defaults to 1.
+	Weight int `json:",omitempty"`
+}
+
+// Validate checks the address and ports of the instance, and sets the default
+// weight.
+func (rs *RemoteSIG) Validate() error {
+	if rs.Addr == nil {
+		return common.NewBasicError("Missing address", nil)
+	}
+	for _, port := range []int{rs.CtrlPort, rs.EncapPort} {
+		if port < 1 || port > maxPort {
+			return common.NewBasicError("Invalid port", nil,
+				"min", 1, "max", maxPort, "actual", port)
+		}
+	}
+	if rs.Weight < 0 {
+		return common.NewBasicError("Invalid weight", nil, "weight", rs.Weight)
+	}
+	if rs.Weight == 0 {
+		rs.Weight = 1
+	}
+	return nil
+}
+
+// Multipath configures how a session spreads its frames across paths.
+type Multipath struct {
+	// Mode is either "loadbalance" or "redundant".
+	Mode egress.PathMode
+	// Paths is the number of disjoint paths the frames are spread across in
+	// load balancing mode. Redundant mode always uses two paths.
+	Paths int `json:",omitempty"`
+}
+
+// Validate checks that the mode is a multipath mode, and that the number of
+// paths is valid for it.
+func (mp *Multipath) Validate() error {
+	switch mp.Mode {
+	case egress.LoadBalance:
+		if mp.Paths < 2 || mp.Paths > egress.MaxPaths {
+			return common.NewBasicError("Invalid number of paths", nil,
+				"min", 2, "max", egress.MaxPaths, "actual", mp.Paths)
+		}
+	case egress.Redundant:
+	default:
+		return common.NewBasicError("Unknown multipath mode", nil, "mode", mp.Mode)
+	}
+	return nil
+}
+
+// QoS configures the egress queues of a session. A packet is put into the
+// first queue whose class matches the packet. Packets that match no class are
+// put into the last queue.
+type QoS struct {
+	Queues []*Queue
+}
+
+// Validate checks the queues, and that their classes exist in classes.
+func (q *QoS) Validate(classes pktcls.ClassMap) error {
+	if len(q.Queues) == 0 {
+		return common.NewBasicError("No queues", nil)
+	}
+	names := make(map[string]bool, len(q.Queues))
+	for _, queue := range q.Queues {
+		if queue.ClassName != "" {
+			if _, ok := classes[queue.ClassName]; !ok {
+				return common.NewBasicError("Unknown class", nil,
+					"queue", queue.Name, "class", queue.ClassName)
+			}
+		}
+		// Validate the spec to check the queue and set its defaults.
+		spec := queue.Spec(nil)
+		if err := spec.Validate(); err != nil {
+			return err
+		}
+		queue.Weight, queue.Size, queue.Drop = spec.Weight, spec.Size, spec.Drop
+		if names[queue.Name] {
+			return common.NewBasicError("Duplicate queue name", nil, "queue", queue.Name)
+		}
+		names[queue.Name] = true
+	}
+	return nil
+}
+
+// Queue is an egress queue of a session. Queues with a lower Priority value
+// are drained strictly before queues with a higher one. Queues with the same
+// Priority are drained in proportion to their Weight.
+type Queue struct {
+	Name string
+	// ClassName is the traffic class of the packets put into the queue. If
+	// empty, all packets are put into the queue.
+	ClassName string `json:",omitempty"`
+	Priority  int    `json:",omitempty"`
+	// Weight defaults to 1.
+	Weight int `json:",omitempty"`
+	// Size is the capacity of the queue in packets, defaults to 64.
+	Size int `json:",omitempty"`
+	// Drop is either "tail" (the default) or "red". RED must be set for the
+	// latter.
+	Drop qos.DropPolicy `json:",omitempty"`
+	RED  *qos.RED       `json:",omitempty"`
+}
+
+// Spec returns the description of the queue, with class being the traffic
+// class named by ClassName.
+func (q *Queue) Spec(class *pktcls.Class) *qos.Spec {
+	return &qos.Spec{
+		Name:     q.Name,
+		Class:    class,
+		Priority: q.Priority,
+		Weight:   q.Weight,
+		Size:     q.Size,
+		Drop:     q.Drop,
+		RED:      q.RED,
+	}
+}
+
+// PktPolicy maps the traffic class ClassName to the session SessId.
+type PktPolicy struct {
+	ClassName string
+	SessId    mgmt.SessionType
 }
 
 // IPNet is custom type of net.IPNet, to allow custom unmarshalling.
@@ -23,7 +23,12 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/pathpol"
+	"github.com/scionproto/scion/go/lib/pktcls"
 	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/sig/egress"
+	"github.com/scionproto/scion/go/sig/egress/qos"
+	"github.com/scionproto/scion/go/sig/mgmt"
 )
 
 var (
@@ -71,6 +76,79 @@ func TestLoadFromFile(t *testing.T) {
 				ConfigVersion: 9001,
 			},
 		},
+		{
+			Name:     "classes",
+			FileName: "02-classes",
+			Config: Cfg{
+				ASes: map[addr.IA]*ASEntry{
+					xtest.MustParseIA("1-ff00:0:1"): {
+						Nets: []*IPNet{
+							{
+								IP:   net.IP{192, 0, 2, 0},
+								Mask: net.CIDRMask(24, 8*net.IPv4len),
+							},
+						},
+						Sessions: map[mgmt.SessionType]string{
+							0: "",
+							1: "avoid-isd-2",
+						},
+						PktPolicies: []*PktPolicy{
+							{ClassName: "voip", SessId: 1},
+						},
+					},
+				},
+				Classes: pktcls.ClassMap{
+					"voip": pktcls.NewClass("voip",
+						pktcls.NewCondIPv4(&pktcls.IPv4MatchDSCP{DSCP: 0x2e})),
+				},
+				Policies: pathpol.PolicyMap{
+					"avoid-isd-2": &pathpol.ExtPolicy{
+						Policy: &pathpol.Policy{
+							Name: "avoid-isd-2",
+							ACL: &pathpol.ACL{
+								Entries: []*pathpol.ACLEntry{
+									{Action: pathpol.Deny, Rule: mustHopPredicate(t, "2-0#0")},
+									{Action: pathpol.Allow},
+								},
+							},
+						},
+					},
+				},
+				ConfigVersion: 9002,
+			},
+		},
+		{
+			Name:     "announce",
+			FileName: "03-announce",
+			Config: Cfg{
+				ASes: map[addr.IA]*ASEntry{
+					xtest.MustParseIA("1-ff00:0:1"): {
+						Nets: []*IPNet{},
+						AllowedNets: []*IPNet{
+							{
+								IP:   net.IP{10, 1, 0, 0},
+								Mask: net.CIDRMask(16, 8*net.IPv4len),
+							},
+							{
+								IP:   net.ParseIP("2001:DB8:1::"),
+								Mask: net.CIDRMask(48, 8*net.IPv6len),
+							},
+						},
+					},
+				},
+				Announce: []*IPNet{
+					{
+						IP:   net.IP{10, 2, 0, 0},
+						Mask: net.CIDRMask(24, 8*net.IPv4len),
+					},
+					{
+						IP:   net.IP{10, 2, 1, 0},
+						Mask: net.CIDRMask(24, 8*net.IPv4len),
+					},
+				},
+				ConfigVersion: 9003,
+			},
+		},
 	}
 
 	Convey("Test SIG config marshal/unmarshal", t, func() {
@@ -88,6 +166,204 @@ func TestLoadFromFile(t *testing.T) {
 	})
 }
 
+func TestSaveToFile(t *testing.T) {
+	Convey("Saved configs can be loaded", t, func() {
+		dir, cleanF := xtest.MustTempDir("", "sig-config")
+		defer cleanF()
+		for _, name := range []string{"01-loadfromfile", "02-classes", "03-announce"} {
+			cfg, err := LoadFromFile(filepath.Join("testdata", name+".json"))
+			xtest.FailOnErr(t, err)
+			path := filepath.Join(dir, name+".json")
+			SoMsg("save err", cfg.SaveToFile(path), ShouldBeNil)
+			loaded, err := LoadFromFile(path)
+			SoMsg("load err", err, ShouldBeNil)
+			SoMsg("cfg", loaded, ShouldResemble, cfg)
+			copied, err := cfg.Copy()
+			SoMsg("copy err", err, ShouldBeNil)
+			SoMsg("copy", copied, ShouldResemble, cfg)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	Convey("Validate", t, func() {
+		cfg := &Cfg{
+			ASes: map[addr.IA]*ASEntry{
+				xtest.MustParseIA("1-ff00:0:1"): {
+					Sessions:    map[mgmt.SessionType]string{1: "policy"},
+					PktPolicies: []*PktPolicy{{ClassName: "class", SessId: 1}},
+				},
+			},
+			Classes: pktcls.ClassMap{
+				"class": pktcls.NewClass("class", pktcls.CondBool(true)),
+			},
+			Policies: pathpol.PolicyMap{"policy": &pathpol.ExtPolicy{}},
+		}
+		Convey("Valid config is accepted", func() {
+			SoMsg("err", cfg.Validate(), ShouldBeNil)
+			policy, err := cfg.Policy("policy")
+			SoMsg("err policy", err, ShouldBeNil)
+			SoMsg("name", policy.Name, ShouldEqual, "policy")
+		})
+		Convey("Unknown class is rejected", func() {
+			cfg.Classes = nil
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Unknown policy is rejected", func() {
+			cfg.Policies = nil
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Unknown session is rejected", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].PktPolicies[0].SessId = 2
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Default session is always known", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].PktPolicies[0].SessId = DefaultSession
+			SoMsg("err", cfg.Validate(), ShouldBeNil)
+		})
+		Convey("Multipath sessions are accepted", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].Multipath = map[mgmt.SessionType]*Multipath{
+				DefaultSession: {Mode: egress.Redundant},
+				1:              {Mode: egress.LoadBalance, Paths: 3},
+			}
+			SoMsg("err", cfg.Validate(), ShouldBeNil)
+		})
+		Convey("Multipath for unknown session is rejected", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].Multipath = map[mgmt.SessionType]*Multipath{
+				2: {Mode: egress.Redundant},
+			}
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Invalid multipath config is rejected", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].Multipath = map[mgmt.SessionType]*Multipath{
+				1: {Mode: egress.LoadBalance, Paths: 1},
+			}
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Encrypted sessions are accepted", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].Encrypted = []mgmt.SessionType{DefaultSession, 1}
+			SoMsg("err", cfg.Validate(), ShouldBeNil)
+		})
+		Convey("Encrypted unknown session is rejected", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].Encrypted = []mgmt.SessionType{2}
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Remote SIGs are accepted, with default weight", func() {
+			ae := cfg.ASes[xtest.MustParseIA("1-ff00:0:1")]
+			ae.SIGs = []*RemoteSIG{
+				{Addr: net.IP{10, 0, 0, 1}, CtrlPort: 30256, EncapPort: 30056},
+				{Addr: net.IP{10, 0, 0, 2}, CtrlPort: 30256, EncapPort: 30056,
+					Priority: 1, Weight: 3},
+			}
+			SoMsg("err", cfg.Validate(), ShouldBeNil)
+			SoMsg("weight default", ae.SIGs[0].Weight, ShouldEqual, 1)
+			SoMsg("weight", ae.SIGs[1].Weight, ShouldEqual, 3)
+		})
+		Convey("Duplicate remote SIGs are rejected", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].SIGs = []*RemoteSIG{
+				{Addr: net.IP{10, 0, 0, 1}, CtrlPort: 30256, EncapPort: 30056},
+				{Addr: net.IP{10, 0, 0, 1}, CtrlPort: 30256, EncapPort: 30057},
+			}
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Remote SIG without port is rejected", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].SIGs = []*RemoteSIG{
+				{Addr: net.IP{10, 0, 0, 1}, CtrlPort: 30256},
+			}
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("QoS queues are accepted, with defaults", func() {
+			ae := cfg.ASes[xtest.MustParseIA("1-ff00:0:1")]
+			ae.QoS = map[mgmt.SessionType]*QoS{
+				1: {Queues: []*Queue{
+					{Name: "voip", ClassName: "class"},
+					{Name: "rest", Priority: 1, Size: 128, Drop: qos.REDDrop,
+						RED: &qos.RED{MinThresh: 32, MaxThresh: 96, MaxProb: 0.1}},
+				}},
+			}
+			SoMsg("err", cfg.Validate(), ShouldBeNil)
+			q := ae.QoS[1].Queues[0]
+			SoMsg("weight", q.Weight, ShouldEqual, 1)
+			SoMsg("size", q.Size, ShouldEqual, qos.DefaultSize)
+			SoMsg("drop", q.Drop, ShouldEqual, qos.TailDrop)
+		})
+		Convey("QoS for unknown session is rejected", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].QoS = map[mgmt.SessionType]*QoS{
+				2: {Queues: []*Queue{{Name: "rest"}}},
+			}
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("QoS queue with unknown class is rejected", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].QoS = map[mgmt.SessionType]*QoS{
+				1: {Queues: []*Queue{{Name: "voip", ClassName: "unknown"}}},
+			}
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Duplicate QoS queues are rejected", func() {
+			cfg.ASes[xtest.MustParseIA("1-ff00:0:1")].QoS = map[mgmt.SessionType]*QoS{
+				1: {Queues: []*Queue{{Name: "rest"}, {Name: "rest"}}},
+			}
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Too many announced networks are rejected", func() {
+			for i := 0; i <= MaxAnnounceNets; i++ {
+				cfg.Announce = append(cfg.Announce, &IPNet{
+					IP:   net.IP{10, 0, byte(i), 0},
+					Mask: net.CIDRMask(24, 8*net.IPv4len),
+				})
+			}
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestASEntryAllows(t *testing.T) {
+	Convey("ASEntry.Allows", t, func() {
+		ae := &ASEntry{
+			AllowedNets: []*IPNet{
+				(*IPNet)(mustParseCIDR(t, "10.1.0.0/16")),
+				(*IPNet)(mustParseCIDR(t, "2001:db8:1::/48")),
+			},
+		}
+		testCases := []struct {
+			Net     string
+			Allowed bool
+		}{
+			{"10.1.0.0/16", true},
+			{"10.1.2.0/24", true},
+			{"10.1.2.3/32", true},
+			{"10.0.0.0/8", false},
+			{"10.2.0.0/24", false},
+			{"2001:db8:1:2::/64", true},
+			{"2001:db8::/32", false},
+			{"::ffff:10.1.2.0/120", false},
+		}
+		for _, tc := range testCases {
+			SoMsg(tc.Net, ae.Allows(mustParseCIDR(t, tc.Net)), ShouldEqual, tc.Allowed)
+		}
+	})
+}
+
+func mustParseCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+
+	_, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ipnet
+}
+
+func mustHopPredicate(t *testing.T, s string) *pathpol.HopPredicate {
+	t.Helper()
+
+	hp, err := pathpol.HopPredicateFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hp
+}
+
 func TestIPNetUnmarshalJSON(t *testing.T) {
 	testCases := []struct {
 		Name  string
@@ -53,6 +53,9 @@ type RegPld struct {
 	Id   mgmt.MsgIdType
 	P    interface{}
 	Addr *snet.Addr
+	// Signed is the signed ctrl payload the message was received in. It is
+	// used to authenticate key exchanges.
+	Signed *ctrl.SignedPld
 }
 
 type RegPldChan chan *RegPld
@@ -98,7 +101,7 @@ func (dm *dispRegistry) Unregister(regType RegType, key RegPollKey) error {
 	return nil
 }
 
-func (dm *dispRegistry) sigCtrl(pld *mgmt.Pld, addr *snet.Addr) {
+func (dm *dispRegistry) sigCtrl(pld *mgmt.Pld, addr *snet.Addr, signed *ctrl.SignedPld) {
 	dm.Lock()
 	defer dm.Unlock()
 	u, err := pld.Union()
@@ -109,9 +112,9 @@ func (dm *dispRegistry) sigCtrl(pld *mgmt.Pld, addr *snet.Addr) {
 	msgId := pld.Id
 	switch pld := u.(type) {
 	case *mgmt.PollReq:
-		dm.PollReqC <- &RegPld{Id: msgId, P: pld, Addr: addr}
+		dm.PollReqC <- &RegPld{Id: msgId, P: pld, Addr: addr, Signed: signed}
 	case *mgmt.PollRep:
-		regPld := &RegPld{Id: msgId, P: pld, Addr: addr}
+		regPld := &RegPld{Id: msgId, P: pld, Addr: addr, Signed: signed}
 		if pld.Addr == nil || pld.Addr.Ctrl == nil {
 			log.Error("Incomplete SIG PollRep received", "src", addr, "pld", pld)
 			return
@@ -146,7 +149,7 @@ func dispFunc(dp *pktdisp.DispPkt) {
 	}
 	switch pld := u.(type) {
 	case *mgmt.Pld:
-		Dispatcher.sigCtrl(pld, src)
+		Dispatcher.sigCtrl(pld, src, scpld)
 	default:
 		log.Error("Unsupported ctrl payload type", "type", common.TypeOf(pld))
 	}
@@ -23,6 +23,7 @@ import (
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/ringbuf"
 	"github.com/scionproto/scion/go/sig/egress"
+	"github.com/scionproto/scion/go/sig/egress/icmp"
 	"github.com/scionproto/scion/go/sig/metrics"
 	"github.com/scionproto/scion/go/sig/mgmt"
 )
@@ -64,7 +65,20 @@ func (ed *egressDispatcher) Run() {
 				ed.Debug("EgressDispatcher: unable to find session")
 				continue
 			}
-			sess.Ring().Write(ringbuf.EntryList{buf}, true)
+			if mtu := sess.MTU(); mtu > 0 && icmp.TooBig(buf, mtu) {
+				// The sender was told to send smaller packets. Release buffer
+				// back to free buffer pool.
+				egress.EgressFreePkts.Write(ringbuf.EntryList{buf}, true)
+				metrics.PktsTooBig.WithLabelValues(sess.IA().String(), sess.ID().String()).Inc()
+				continue
+			}
+			if !sess.Queues().Enqueue(buf) {
+				// The packet was dropped by its queue, or the session has
+				// been closed, e.g. because it was replaced during a config
+				// reload. Release buffer back to free buffer pool.
+				egress.EgressFreePkts.Write(ringbuf.EntryList{buf}, true)
+				continue
+			}
 			ed.updateMetrics(sess.IA().IAInt(), sess.ID(), len(buf))
 		}
 	}
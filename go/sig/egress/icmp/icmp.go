@@ -0,0 +1,209 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package icmp signals the senders of packets that are too big to be forwarded
+// by the SIG without fragmentation. For IPv4 packets with the DF flag set, an
+// ICMP "fragmentation needed" message is written to the TUN device. For IPv6
+// packets, an ICMPv6 "packet too big" message is written.
+//
+// The SIG has no address in the networks behind the TUN device. The messages
+// are therefore sent from the destination address of the offending packet.
+package icmp
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+const (
+	ip4Ver     = 0x4
+	ip6Ver     = 0x6
+	ip4HdrLen  = 20
+	ip6HdrLen  = 40
+	icmpHdrLen = 8
+	protoICMP  = 1
+	protoICMP6 = 58
+	ttl        = 64
+	// ip4MinMTU is the minimum MTU of IPv4 links.
+	ip4MinMTU = 68
+	// ip6MinMTU is the minimum MTU of IPv6 links. Hosts cannot be asked to
+	// send smaller packets.
+	ip6MinMTU = 1280
+	// ip4FlagDF is the don't fragment flag in the flags and fragment offset
+	// field of the IPv4 header.
+	ip4FlagDF = 0x4000
+	// icmp4Unreach and icmp4FragNeeded are the type and code of ICMP
+	// "fragmentation needed" messages.
+	icmp4Unreach    = 3
+	icmp4FragNeeded = 4
+	// icmp6TooBig is the type of ICMPv6 "packet too big" messages.
+	icmp6TooBig = 2
+	// maxMsgsPerSec limits the rate at which messages are generated.
+	maxMsgsPerSec = 100
+)
+
+var (
+	mtx   sync.Mutex
+	tunIO io.Writer
+	// windowStart and windowMsgs implement the rate limit.
+	windowStart time.Time
+	windowMsgs  int
+)
+
+// Init sets the TUN device the messages are written to.
+func Init(w io.Writer) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	tunIO = w
+}
+
+// TooBig checks whether pkt is larger than mtu and must not be fragmented. In
+// that case, a message that contains the mtu is sent to the source of the
+// packet, and true is returned. The caller must then drop the packet. IPv4
+// packets without the DF flag are never too big, as the SIG carries packets
+// of any size. Neither are IPv6 packets if mtu is smaller than the minimum
+// IPv6 MTU.
+func TooBig(pkt common.RawBytes, mtu int) bool {
+	if len(pkt) <= mtu || len(pkt) == 0 {
+		return false
+	}
+	var msg common.RawBytes
+	switch pkt[0] >> 4 {
+	case ip4Ver:
+		if len(pkt) < ip4HdrLen || common.Order.Uint16(pkt[6:8])&ip4FlagDF == 0 {
+			return false
+		}
+		if mtu < ip4MinMTU {
+			mtu = ip4MinMTU
+		}
+		if !isICMPError(pkt) {
+			msg = fragNeeded(pkt, mtu)
+		}
+	case ip6Ver:
+		if len(pkt) < ip6HdrLen || mtu < ip6MinMTU {
+			return false
+		}
+		if !isICMPError(pkt) {
+			msg = packetTooBig(pkt, mtu)
+		}
+	default:
+		return false
+	}
+	// No messages are sent in response to ICMP error messages, but the packet
+	// is dropped nevertheless.
+	if msg != nil {
+		send(msg)
+	}
+	return true
+}
+
+func send(msg common.RawBytes) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if tunIO == nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(windowStart) >= time.Second {
+		windowStart, windowMsgs = now, 0
+	}
+	if windowMsgs >= maxMsgsPerSec {
+		return
+	}
+	windowMsgs++
+	if _, err := tunIO.Write(msg); err != nil {
+		log.Error("Unable to write ICMP message to TUN device", "err", err)
+	}
+}
+
+// isICMPError returns true if pkt is an ICMP or ICMPv6 error message.
+func isICMPError(pkt common.RawBytes) bool {
+	switch pkt[0] >> 4 {
+	case ip4Ver:
+		hdrLen := int(pkt[0]&0xF) * 4
+		if pkt[9] != protoICMP || len(pkt) <= hdrLen {
+			return false
+		}
+		switch pkt[hdrLen] {
+		case 3, 4, 5, 11, 12:
+			return true
+		}
+	case ip6Ver:
+		// ICMPv6 error messages have types below 128.
+		return pkt[6] == protoICMP6 && len(pkt) > ip6HdrLen && pkt[ip6HdrLen] < 128
+	}
+	return false
+}
+
+// fragNeeded returns an ICMP "fragmentation needed" message for the IPv4
+// packet pkt. It contains the header and the first 8 bytes of the payload of
+// pkt.
+func fragNeeded(pkt common.RawBytes, mtu int) common.RawBytes {
+	quoteLen := int(pkt[0]&0xF)*4 + 8
+	if quoteLen > len(pkt) {
+		quoteLen = len(pkt)
+	}
+	msg := make(common.RawBytes, ip4HdrLen+icmpHdrLen+quoteLen)
+	// IPv4 header.
+	msg[0] = ip4Ver<<4 | ip4HdrLen/4
+	common.Order.PutUint16(msg[2:4], uint16(len(msg)))
+	msg[8] = ttl
+	msg[9] = protoICMP
+	// The addresses of pkt are swapped.
+	copy(msg[12:16], pkt[16:20])
+	copy(msg[16:20], pkt[12:16])
+	common.Order.PutUint16(msg[10:12], util.Checksum(msg[:ip4HdrLen]))
+	// ICMP message.
+	icmp := msg[ip4HdrLen:]
+	icmp[0] = icmp4Unreach
+	icmp[1] = icmp4FragNeeded
+	common.Order.PutUint16(icmp[6:8], uint16(mtu))
+	copy(icmp[icmpHdrLen:], pkt[:quoteLen])
+	common.Order.PutUint16(icmp[2:4], util.Checksum(icmp))
+	return msg
+}
+
+// packetTooBig returns an ICMPv6 "packet too big" message for the IPv6 packet
+// pkt. It contains as much of pkt as fits into the minimum IPv6 MTU.
+func packetTooBig(pkt common.RawBytes, mtu int) common.RawBytes {
+	quoteLen := ip6MinMTU - ip6HdrLen - icmpHdrLen
+	if quoteLen > len(pkt) {
+		quoteLen = len(pkt)
+	}
+	msg := make(common.RawBytes, ip6HdrLen+icmpHdrLen+quoteLen)
+	// IPv6 header.
+	msg[0] = ip6Ver << 4
+	common.Order.PutUint16(msg[4:6], uint16(icmpHdrLen+quoteLen))
+	msg[6] = protoICMP6
+	msg[7] = ttl
+	// The addresses of pkt are swapped.
+	copy(msg[8:24], pkt[24:40])
+	copy(msg[24:40], pkt[8:24])
+	// ICMPv6 message.
+	icmp := msg[ip6HdrLen:]
+	icmp[0] = icmp6TooBig
+	common.Order.PutUint32(icmp[4:8], uint32(mtu))
+	copy(icmp[icmpHdrLen:], pkt[:quoteLen])
+	// The checksum covers the IPv6 pseudo header.
+	pseudo := make(common.RawBytes, 8)
+	common.Order.PutUint32(pseudo[0:4], uint32(len(icmp)))
+	pseudo[7] = protoICMP6
+	common.Order.PutUint16(icmp[2:4], util.Checksum(msg[8:40], pseudo, icmp))
+	return msg
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icmp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/util"
+)
+
+var (
+	src4 = net.IP{192, 0, 2, 1}
+	dst4 = net.IP{198, 51, 100, 1}
+	src6 = net.ParseIP("2001:db8::1")
+	dst6 = net.ParseIP("2001:db8:1::1")
+)
+
+func newPkt4(size int, df bool) common.RawBytes {
+	pkt := make(common.RawBytes, size)
+	pkt[0] = ip4Ver<<4 | ip4HdrLen/4
+	common.Order.PutUint16(pkt[2:4], uint16(size))
+	if df {
+		common.Order.PutUint16(pkt[6:8], ip4FlagDF)
+	}
+	pkt[8] = ttl
+	pkt[9] = 17
+	copy(pkt[12:16], src4)
+	copy(pkt[16:20], dst4)
+	return pkt
+}
+
+func newPkt6(size int) common.RawBytes {
+	pkt := make(common.RawBytes, size)
+	pkt[0] = ip6Ver << 4
+	common.Order.PutUint16(pkt[4:6], uint16(size-ip6HdrLen))
+	pkt[6] = 17
+	pkt[7] = ttl
+	copy(pkt[8:24], src6)
+	copy(pkt[24:40], dst6)
+	return pkt
+}
+
+func TestTooBig(t *testing.T) {
+	Convey("TooBig", t, func() {
+		buf := &bytes.Buffer{}
+		Init(buf)
+		windowMsgs = 0
+		Convey("Small packets are not too big", func() {
+			SoMsg("v4", TooBig(newPkt4(1000, true), 1400), ShouldBeFalse)
+			SoMsg("v6", TooBig(newPkt6(1400), 1400), ShouldBeFalse)
+			SoMsg("msg", buf.Len(), ShouldEqual, 0)
+		})
+		Convey("IPv4 packets without DF are not too big", func() {
+			SoMsg("v4", TooBig(newPkt4(1500, false), 1400), ShouldBeFalse)
+			SoMsg("msg", buf.Len(), ShouldEqual, 0)
+		})
+		Convey("IPv4 packets with DF trigger fragmentation needed", func() {
+			pkt := newPkt4(1500, true)
+			SoMsg("v4", TooBig(pkt, 1400), ShouldBeTrue)
+			msg := buf.Bytes()
+			SoMsg("len", len(msg), ShouldEqual, ip4HdrLen+icmpHdrLen+ip4HdrLen+8)
+			SoMsg("ip csum", util.Checksum(msg[:ip4HdrLen]), ShouldEqual, 0)
+			SoMsg("src", net.IP(msg[12:16]).Equal(dst4), ShouldBeTrue)
+			SoMsg("dst", net.IP(msg[16:20]).Equal(src4), ShouldBeTrue)
+			icmp := msg[ip4HdrLen:]
+			SoMsg("type", icmp[0], ShouldEqual, icmp4Unreach)
+			SoMsg("code", icmp[1], ShouldEqual, icmp4FragNeeded)
+			SoMsg("mtu", common.Order.Uint16(icmp[6:8]), ShouldEqual, 1400)
+			SoMsg("icmp csum", util.Checksum(icmp), ShouldEqual, 0)
+			SoMsg("quote", []byte(icmp[icmpHdrLen:]), ShouldResemble, []byte(pkt[:ip4HdrLen+8]))
+		})
+		Convey("IPv6 packets trigger packet too big", func() {
+			pkt := newPkt6(1500)
+			SoMsg("v6", TooBig(pkt, 1400), ShouldBeTrue)
+			msg := buf.Bytes()
+			SoMsg("len", len(msg), ShouldEqual, ip6MinMTU)
+			SoMsg("src", net.IP(msg[8:24]).Equal(dst6), ShouldBeTrue)
+			SoMsg("dst", net.IP(msg[24:40]).Equal(src6), ShouldBeTrue)
+			icmp := msg[ip6HdrLen:]
+			SoMsg("type", icmp[0], ShouldEqual, icmp6TooBig)
+			SoMsg("mtu", common.Order.Uint32(icmp[4:8]), ShouldEqual, 1400)
+			pseudo := make(common.RawBytes, 8)
+			common.Order.PutUint32(pseudo[0:4], uint32(len(icmp)))
+			pseudo[7] = protoICMP6
+			SoMsg("csum", util.Checksum(msg[8:40], pseudo, icmp), ShouldEqual, 0)
+		})
+		Convey("IPv6 packets are not too big below the minimum IPv6 MTU", func() {
+			SoMsg("v6", TooBig(newPkt6(1500), 1200), ShouldBeFalse)
+			SoMsg("msg", buf.Len(), ShouldEqual, 0)
+		})
+		Convey("ICMP errors are dropped without reply", func() {
+			pkt := newPkt6(1500)
+			pkt[6] = protoICMP6
+			pkt[ip6HdrLen] = icmp6TooBig
+			SoMsg("v6", TooBig(pkt, 1400), ShouldBeTrue)
+			SoMsg("msg", buf.Len(), ShouldEqual, 0)
+		})
+	})
+}
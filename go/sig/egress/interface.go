@@ -17,6 +17,7 @@ package egress
 import (
 	"fmt"
 	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -28,7 +29,9 @@ import (
 	"github.com/scionproto/scion/go/lib/sciond"
 	"github.com/scionproto/scion/go/lib/snet"
 	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+	"github.com/scionproto/scion/go/sig/egress/qos"
 	"github.com/scionproto/scion/go/sig/mgmt"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
 	"github.com/scionproto/scion/go/sig/siginfo"
 )
 
@@ -48,6 +51,26 @@ const (
 
 var EgressFreePkts *ringbuf.Ring
 
+// PathMode defines how a session spreads its frames across paths.
+type PathMode string
+
+const (
+	// SinglePath sends all frames on the current path of the session.
+	SinglePath PathMode = ""
+	// LoadBalance spreads the frames across multiple disjoint paths, weighted
+	// by their RTT.
+	LoadBalance PathMode = "loadbalance"
+	// Redundant sends every frame on two disjoint paths. The remote SIG
+	// discards the duplicates.
+	Redundant PathMode = "redundant"
+)
+
+// MaxPaths is the maximum number of paths a session uses at once.
+const MaxPaths = 8
+
+// maxWeight is the weight of the path with the lowest RTT.
+const maxWeight = 100
+
 // Session defines a stateful context for sending traffic to a remote AS.
 type Session interface {
 	// Logger defines common logging primitives
@@ -58,8 +81,8 @@ type Session interface {
 	ID() mgmt.SessionType
 	// Conn returns the session's outbound snet Conn
 	Conn() snet.Conn
-	// Ring returns the session's ring buffer.
-	Ring() *ringbuf.Ring
+	// Queues returns the session's egress queues.
+	Queues() *qos.Queues
 	// Remote returns the session's currently chosen SIG and path.
 	Remote() *RemoteInfo
 	// Cleanup shuts down the session and cleans resources.
@@ -71,6 +94,19 @@ type Session interface {
 	PathPool() PathPool
 	// AnnounceWorkerStopped is used to inform the session that its worker needed to shut down.
 	AnnounceWorkerStopped()
+	// PathMode returns how the session spreads its frames across paths.
+	PathMode() PathMode
+	// Encrypted returns true if the session's frames must be encrypted.
+	Encrypted() bool
+	// Key returns the current key of an encrypted session with the remote SIG
+	// sig, or nil if no key has been established yet.
+	Key(sig *siginfo.Sig) *sigcrypto.Key
+	// MTU returns the size of the largest packet the session forwards without
+	// signalling the sender that the packet is too big, or 0 if unknown.
+	MTU() int
+	// PathMTU returns the MTU of the session's paths reported by SCMP
+	// oversize packet errors, or 0 if none was reported recently.
+	PathMTU() int
 }
 
 // Runner is implemented by objects that operate as goroutines.
@@ -84,12 +120,57 @@ type WorkerFactory func(Session, log.Logger) Runner
 type RemoteInfo struct {
 	Sig      *siginfo.Sig
 	SessPath *SessPath
+	// MultiPaths contains the paths the frames are spread across, if the
+	// session uses multiple paths. Otherwise, frames are sent on SessPath.
+	MultiPaths []*WeightedPath
+	// Sigs contains the remote SIG instances the flows are spread across, if
+	// the remote AS has multiple SIGs. Otherwise, all frames are sent to Sig.
+	Sigs []*siginfo.Instance
 }
 
 func (r *RemoteInfo) String() string {
+	if len(r.Sigs) > 0 {
+		return fmt.Sprintf("Sig: %s Path: %s MultiPaths: %d Sigs: %d", r.Sig, r.SessPath,
+			len(r.MultiPaths), len(r.Sigs))
+	}
+	if len(r.MultiPaths) > 0 {
+		return fmt.Sprintf("Sig: %s Path: %s MultiPaths: %d", r.Sig, r.SessPath,
+			len(r.MultiPaths))
+	}
 	return fmt.Sprintf("Sig: %s Path: %s", r.Sig, r.SessPath)
 }
 
+// WeightedPath is a path used by a multipath session. Weight is the relative
+// share of the frames that are sent on the path.
+type WeightedPath struct {
+	SessPath *SessPath
+	Weight   int
+}
+
+// NewWeightedPaths weights paths by the inverse of their RTT. The path with
+// the lowest RTT gets maxWeight, paths without RTT measurement get the lowest
+// weight.
+func NewWeightedPaths(paths []*SessPath) []*WeightedPath {
+	var minRTT time.Duration
+	for _, path := range paths {
+		if rtt := path.RTT(); rtt > 0 && (minRTT == 0 || rtt < minRTT) {
+			minRTT = rtt
+		}
+	}
+	wps := make([]*WeightedPath, 0, len(paths))
+	for _, path := range paths {
+		weight := 1
+		if rtt := path.RTT(); rtt > 0 {
+			weight = int(maxWeight * minRTT / rtt)
+			if weight < 1 {
+				weight = 1
+			}
+		}
+		wps = append(wps, &WeightedPath{SessPath: path, Weight: weight})
+	}
+	return wps
+}
+
 // PathPool is implemented by objects that maintain sets of paths. PathPools
 // must be safe for concurrent use by multiple goroutines.
 type PathPool interface {
@@ -137,6 +218,36 @@ func (spp SessPathPool) Get(exclude spathmeta.PathKey) *SessPath {
 	return spp[exclude]
 }
 
+// GetDisjoint returns the path that shares the fewest interfaces with the
+// paths in used, preferring paths with fewer failures that are not close to
+// expiry. Paths in used are never returned. If no other path is available, nil
+// is returned.
+func (spp SessPathPool) GetDisjoint(used []*SessPath) *SessPath {
+	var best *SessPath
+	var bestOverlap, bestFail int
+	var bestExpiring bool
+Top:
+	for k, v := range spp {
+		for _, u := range used {
+			if k == u.Key() {
+				continue Top
+			}
+		}
+		overlap := 0
+		for _, u := range used {
+			overlap += v.Overlap(u)
+		}
+		expiring := v.IsCloseToExpiry()
+		better := best == nil || overlap < bestOverlap ||
+			(overlap == bestOverlap && bestExpiring && !expiring) ||
+			(overlap == bestOverlap && bestExpiring == expiring && int(v.failCount) < bestFail)
+		if better {
+			best, bestOverlap, bestFail, bestExpiring = v, overlap, int(v.failCount), expiring
+		}
+	}
+	return best
+}
+
 func (spp SessPathPool) Update(aps spathmeta.AppPathSet) {
 	// Remove any old entries that aren't present in the update.
 	for key := range spp {
@@ -162,6 +273,10 @@ type SessPath struct {
 	pathEntry *sciond.PathReplyEntry
 	lastFail  time.Time
 	failCount uint16
+	// rtt is the smoothed round trip time of the path in nanoseconds, 0 if
+	// not measured. It is accessed atomically, as it is reported by the
+	// management API.
+	rtt int64
 }
 
 func NewSessPath(key spathmeta.PathKey, pathEntry *sciond.PathReplyEntry) *SessPath {
@@ -187,6 +302,40 @@ func (sp *SessPath) Fail() {
 	}
 }
 
+// UpdateRTT adds an RTT sample to the smoothed RTT of the path.
+func (sp *SessPath) UpdateRTT(sample time.Duration) {
+	rtt := sp.RTT()
+	if rtt != 0 {
+		// Exponentially weighted moving average, as used by TCP (RFC 6298).
+		sample = rtt - rtt/8 + sample/8
+	}
+	atomic.StoreInt64(&sp.rtt, int64(sample))
+}
+
+// RTT returns the smoothed round trip time of the path, or 0 if it was not
+// measured yet.
+func (sp *SessPath) RTT() time.Duration {
+	return time.Duration(atomic.LoadInt64(&sp.rtt))
+}
+
+// Overlap returns the number of interfaces the path shares with other.
+func (sp *SessPath) Overlap(other *SessPath) int {
+	if sp.pathEntry == nil || other.pathEntry == nil {
+		return 0
+	}
+	ifaces := make(map[sciond.PathInterface]struct{})
+	for _, iface := range other.pathEntry.Path.Interfaces {
+		ifaces[iface] = struct{}{}
+	}
+	overlap := 0
+	for _, iface := range sp.pathEntry.Path.Interfaces {
+		if _, ok := ifaces[iface]; ok {
+			overlap++
+		}
+	}
+	return overlap
+}
+
 func (sp *SessPath) ExpireFails() {
 	if time.Since(sp.lastFail) > pathFailExpiration {
 		sp.failCount /= 2
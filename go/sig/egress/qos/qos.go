@@ -0,0 +1,374 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package qos implements the egress queues of SIG sessions.
+//
+// A session has one or more queues. Each packet is put into the first queue
+// whose traffic class matches the packet; packets that match none of the
+// classes are put into the last queue. Every queue has a priority, a weight,
+// a size and a drop policy. The session worker drains queues with a lower
+// priority value strictly before queues with a higher one, and queues with the
+// same priority in proportion to their weights.
+//
+// Packets are dropped if their queue is full (tail drop). Queues with the RED
+// drop policy additionally drop packets early, with a probability that grows
+// with the average length of the queue.
+package qos
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/pktcls"
+	"github.com/scionproto/scion/go/lib/ringbuf"
+	"github.com/scionproto/scion/go/sig/metrics"
+	"github.com/scionproto/scion/go/sig/mgmt"
+)
+
+const (
+	// DefaultQueue is the name of the queue of sessions without configured
+	// queues.
+	DefaultQueue = "default"
+	// DefaultSize is the default capacity of a queue, in packets.
+	DefaultSize = 64
+	// MaxSize is the maximum capacity of a queue, in packets.
+	MaxSize = 4096
+)
+
+// DropPolicy defines which packets a queue drops.
+type DropPolicy string
+
+const (
+	// TailDrop drops packets if the queue is full.
+	TailDrop DropPolicy = "tail"
+	// REDDrop drops packets early, based on the average queue length. See
+	// RED.
+	REDDrop DropPolicy = "red"
+)
+
+// Spec describes a queue.
+type Spec struct {
+	Name string
+	// Class is the traffic class of the packets put into the queue. If nil,
+	// all packets match.
+	Class    *pktcls.Class `json:",omitempty"`
+	Priority int
+	Weight   int
+	Size     int
+	Drop     DropPolicy
+	RED      *RED `json:",omitempty"`
+}
+
+// Validate checks the spec and sets the defaults of unset fields.
+func (s *Spec) Validate() error {
+	if s.Name == "" {
+		return common.NewBasicError("Missing queue name", nil)
+	}
+	if s.Weight < 0 {
+		return common.NewBasicError("Invalid weight", nil, "queue", s.Name, "weight", s.Weight)
+	}
+	if s.Weight == 0 {
+		s.Weight = 1
+	}
+	if s.Size == 0 {
+		s.Size = DefaultSize
+	}
+	if s.Size < 0 || s.Size > MaxSize {
+		return common.NewBasicError("Invalid queue size", nil,
+			"queue", s.Name, "min", 1, "max", MaxSize, "actual", s.Size)
+	}
+	switch s.Drop {
+	case "":
+		s.Drop = TailDrop
+		fallthrough
+	case TailDrop:
+		if s.RED != nil {
+			return common.NewBasicError("RED parameters for tail drop queue", nil,
+				"queue", s.Name)
+		}
+	case REDDrop:
+		if s.RED == nil {
+			return common.NewBasicError("Missing RED parameters", nil, "queue", s.Name)
+		}
+		if err := s.RED.Validate(s.Size); err != nil {
+			return common.NewBasicError("Invalid RED parameters", err, "queue", s.Name)
+		}
+	default:
+		return common.NewBasicError("Unknown drop policy", nil,
+			"queue", s.Name, "drop", s.Drop)
+	}
+	return nil
+}
+
+// Queues contains the egress queues of a session.
+type Queues struct {
+	queues []*Queue
+	// avail is signalled after a packet was enqueued.
+	avail     chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates the queues described by specs, for session sessId to the
+// remote AS ia. If specs is empty, a single tail drop queue with the default
+// size is created.
+func New(ia addr.IA, sessId mgmt.SessionType, specs []*Spec) (*Queues, error) {
+	if len(specs) == 0 {
+		specs = []*Spec{{Name: DefaultQueue}}
+	}
+	qs := &Queues{
+		avail:  make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+	names := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		if err := spec.Validate(); err != nil {
+			return nil, err
+		}
+		if names[spec.Name] {
+			return nil, common.NewBasicError("Duplicate queue name", nil, "queue", spec.Name)
+		}
+		names[spec.Name] = true
+		qs.queues = append(qs.queues, newQueue(ia, sessId, spec))
+	}
+	return qs, nil
+}
+
+// List returns the queues.
+func (qs *Queues) List() []*Queue {
+	return qs.queues
+}
+
+// Enqueue puts pkt into the queue of its traffic class. It returns false if
+// the packet was dropped, or the queues are closed. In that case, the caller
+// remains the owner of pkt.
+func (qs *Queues) Enqueue(pkt common.RawBytes) bool {
+	q := qs.classify(pkt)
+	if !q.enqueue(pkt) {
+		return false
+	}
+	select {
+	case qs.avail <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (qs *Queues) classify(raw common.RawBytes) *Queue {
+	last := qs.queues[len(qs.queues)-1]
+	if len(qs.queues) == 1 {
+		return last
+	}
+	pkt := pktcls.NewPacket(raw)
+	for _, q := range qs.queues {
+		if q.class == nil || q.class.Eval(pkt) {
+			return q
+		}
+	}
+	return last
+}
+
+// Wait blocks until a packet is enqueued, or the queues are closed. It
+// returns false if the queues are closed. Packets that were enqueued before
+// can still be read after the queues are closed.
+func (qs *Queues) Wait() bool {
+	select {
+	case <-qs.avail:
+		return true
+	case <-qs.closed:
+		return false
+	}
+}
+
+// Close closes the queues. Subsequent calls to Enqueue fail.
+func (qs *Queues) Close() {
+	qs.closeOnce.Do(func() {
+		for _, q := range qs.queues {
+			q.ring.Close()
+		}
+		close(qs.closed)
+	})
+}
+
+// Queue is a single egress queue.
+type Queue struct {
+	name     string
+	class    *pktcls.Class
+	priority int
+	weight   int
+	ring     *ringbuf.Ring
+	// mtx protects red.
+	mtx sync.Mutex
+	red *red
+
+	pkts     prometheus.Counter
+	bytes    prometheus.Counter
+	dropFull prometheus.Counter
+	dropRED  prometheus.Counter
+}
+
+func newQueue(ia addr.IA, sessId mgmt.SessionType, spec *Spec) *Queue {
+	labels := []string{ia.String(), sessId.String(), spec.Name}
+	q := &Queue{
+		name:     spec.Name,
+		class:    spec.Class,
+		priority: spec.Priority,
+		weight:   spec.Weight,
+		ring: ringbuf.New(spec.Size, nil, "egress_"+spec.Name,
+			prometheus.Labels{"ringId": ia.String(), "sessId": sessId.String()}),
+		pkts:     metrics.QueuePkts.WithLabelValues(labels...),
+		bytes:    metrics.QueueBytes.WithLabelValues(labels...),
+		dropFull: metrics.QueueDrops.WithLabelValues(append(labels, metrics.DropQueueFull)...),
+		dropRED:  metrics.QueueDrops.WithLabelValues(append(labels, metrics.DropRED)...),
+	}
+	if spec.Drop == REDDrop {
+		q.red = newRED(spec.RED)
+	}
+	return q
+}
+
+// Name returns the name of the queue.
+func (q *Queue) Name() string {
+	return q.name
+}
+
+// Priority returns the priority of the queue. Queues with lower values are
+// drained first.
+func (q *Queue) Priority() int {
+	return q.priority
+}
+
+// Weight returns the share of the queue among the queues with the same
+// priority.
+func (q *Queue) Weight() int {
+	return q.weight
+}
+
+// Len returns the number of packets in the queue.
+func (q *Queue) Len() int {
+	return q.ring.Len()
+}
+
+// Read reads packets from the queue into pkts, without blocking. It returns
+// the number of packets read.
+func (q *Queue) Read(pkts ringbuf.EntryList) int {
+	n, _ := q.ring.Read(pkts, false)
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func (q *Queue) enqueue(pkt common.RawBytes) bool {
+	if q.red != nil {
+		q.mtx.Lock()
+		drop := q.red.drop(q.ring.Len())
+		q.mtx.Unlock()
+		if drop {
+			q.dropRED.Inc()
+			return false
+		}
+	}
+	n, _ := q.ring.Write(ringbuf.EntryList{pkt}, false)
+	if n == 0 {
+		q.dropFull.Inc()
+	}
+	if n != 1 {
+		return false
+	}
+	q.pkts.Inc()
+	q.bytes.Add(float64(len(pkt)))
+	return true
+}
+
+// redWeight is the weight of the current queue length in the average queue
+// length.
+const redWeight = 0.002
+
+// RED configures random early detection. If the average queue length is
+// below MinThresh, no packets are dropped. Between MinThresh and MaxThresh,
+// the drop probability grows linearly up to MaxProb. Above MaxThresh, all
+// packets are dropped.
+type RED struct {
+	// MinThresh is the minimum average queue length in packets at which
+	// packets are dropped.
+	MinThresh int
+	// MaxThresh is the average queue length in packets above which all
+	// packets are dropped.
+	MaxThresh int
+	// MaxProb is the drop probability at MaxThresh.
+	MaxProb float64
+}
+
+// Validate checks that the thresholds are ordered and do not exceed the queue
+// size, and that the maximum drop probability is in (0, 1].
+func (r *RED) Validate(size int) error {
+	if r.MinThresh < 0 || r.MinThresh >= r.MaxThresh || r.MaxThresh > size {
+		return common.NewBasicError("Invalid thresholds", nil,
+			"min", r.MinThresh, "max", r.MaxThresh, "size", size)
+	}
+	if r.MaxProb <= 0 || r.MaxProb > 1 {
+		return common.NewBasicError("Invalid maximum drop probability", nil,
+			"maxProb", r.MaxProb)
+	}
+	return nil
+}
+
+// red is the state of the RED drop policy of a queue.
+type red struct {
+	*RED
+	avg float64
+	// count is the number of packets since the last drop.
+	count int
+	rand  *rand.Rand
+}
+
+func newRED(cfg *RED) *red {
+	return &red{
+		RED:   cfg,
+		count: -1,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// drop updates the average queue length with the current length qlen, and
+// returns whether the next packet should be dropped.
+func (r *red) drop(qlen int) bool {
+	r.avg += redWeight * (float64(qlen) - r.avg)
+	switch {
+	case r.avg < float64(r.MinThresh):
+		r.count = -1
+		return false
+	case r.avg >= float64(r.MaxThresh):
+		r.count = 0
+		return true
+	}
+	r.count++
+	pb := r.MaxProb * (r.avg - float64(r.MinThresh)) / float64(r.MaxThresh-r.MinThresh)
+	// Spread the drops evenly, instead of dropping in bursts.
+	pa := 1.0
+	if float64(r.count)*pb < 1 {
+		pa = pb / (1 - float64(r.count)*pb)
+	}
+	if r.rand.Float64() < pa {
+		r.count = 0
+		return true
+	}
+	return false
+}
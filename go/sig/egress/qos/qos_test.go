@@ -0,0 +1,165 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qos
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/pktcls"
+	"github.com/scionproto/scion/go/lib/ringbuf"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/sig/metrics"
+)
+
+func TestMain(m *testing.M) {
+	metrics.Init("test")
+	os.Exit(m.Run())
+}
+
+func TestEnqueue(t *testing.T) {
+	Convey("Packets are put into the queue of their class", t, func() {
+		qs := mustNew(t,
+			&Spec{Name: "voice", Class: dscpClass(0x2e)},
+			&Spec{Name: "video", Class: dscpClass(0x22)},
+			&Spec{Name: "rest"},
+		)
+		for _, dscp := range []uint8{0x2e, 0x22, 0x22, 0x00} {
+			SoMsg("enqueued", qs.Enqueue(testPkt(dscp, 100)), ShouldBeTrue)
+		}
+		queues := qs.List()
+		SoMsg("voice", queues[0].Len(), ShouldEqual, 1)
+		SoMsg("video", queues[1].Len(), ShouldEqual, 2)
+		SoMsg("rest", queues[2].Len(), ShouldEqual, 1)
+		SoMsg("avail", qs.Wait(), ShouldBeTrue)
+	})
+	Convey("Packets that match no class are put into the last queue", t, func() {
+		qs := mustNew(t,
+			&Spec{Name: "voice", Class: dscpClass(0x2e)},
+			&Spec{Name: "bulk", Class: dscpClass(0x08)},
+		)
+		SoMsg("enqueued", qs.Enqueue(testPkt(0x00, 100)), ShouldBeTrue)
+		SoMsg("voice", qs.List()[0].Len(), ShouldEqual, 0)
+		SoMsg("bulk", qs.List()[1].Len(), ShouldEqual, 1)
+	})
+	Convey("Full queues drop packets", t, func() {
+		qs := mustNew(t, &Spec{Name: "small", Size: 2})
+		SoMsg("1st", qs.Enqueue(testPkt(0, 100)), ShouldBeTrue)
+		SoMsg("2nd", qs.Enqueue(testPkt(0, 100)), ShouldBeTrue)
+		SoMsg("3rd", qs.Enqueue(testPkt(0, 100)), ShouldBeFalse)
+		pkts := make(ringbuf.EntryList, 4)
+		SoMsg("read", qs.List()[0].Read(pkts), ShouldEqual, 2)
+		SoMsg("4th", qs.Enqueue(testPkt(0, 100)), ShouldBeTrue)
+	})
+	Convey("Closed queues", t, func() {
+		qs := mustNew(t)
+		SoMsg("enqueued", qs.Enqueue(testPkt(0, 100)), ShouldBeTrue)
+		qs.Close()
+		SoMsg("enqueue", qs.Enqueue(testPkt(0, 100)), ShouldBeFalse)
+		pkts := make(ringbuf.EntryList, 4)
+		SoMsg("read", qs.List()[0].Read(pkts), ShouldEqual, 1)
+		SoMsg("read empty", qs.List()[0].Read(pkts), ShouldEqual, 0)
+		// Consume the notification of the first packet.
+		qs.Wait()
+		SoMsg("wait", qs.Wait(), ShouldBeFalse)
+	})
+}
+
+func TestSpecValidate(t *testing.T) {
+	Convey("Validate sets defaults", t, func() {
+		spec := &Spec{Name: "q"}
+		SoMsg("err", spec.Validate(), ShouldBeNil)
+		SoMsg("weight", spec.Weight, ShouldEqual, 1)
+		SoMsg("size", spec.Size, ShouldEqual, DefaultSize)
+		SoMsg("drop", spec.Drop, ShouldEqual, TailDrop)
+	})
+	red := &RED{MinThresh: 10, MaxThresh: 50, MaxProb: 0.1}
+	testCases := []struct {
+		Name string
+		Spec *Spec
+	}{
+		{"no name", &Spec{}},
+		{"negative weight", &Spec{Name: "q", Weight: -1}},
+		{"size too large", &Spec{Name: "q", Size: MaxSize + 1}},
+		{"unknown drop policy", &Spec{Name: "q", Drop: "head"}},
+		{"RED without parameters", &Spec{Name: "q", Drop: REDDrop}},
+		{"tail drop with RED parameters", &Spec{Name: "q", RED: red}},
+		{"RED thresholds exceed size", &Spec{Name: "q", Size: 32, Drop: REDDrop, RED: red}},
+		{"RED thresholds unordered", &Spec{Name: "q", Drop: REDDrop,
+			RED: &RED{MinThresh: 50, MaxThresh: 10, MaxProb: 0.1}}},
+		{"RED probability too large", &Spec{Name: "q", Drop: REDDrop,
+			RED: &RED{MinThresh: 10, MaxThresh: 50, MaxProb: 1.5}}},
+	}
+	Convey("Invalid specs", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				SoMsg("err", tc.Spec.Validate(), ShouldNotBeNil)
+			})
+		}
+	})
+}
+
+func TestRED(t *testing.T) {
+	Convey("RED drops based on the average queue length", t, func() {
+		r := newRED(&RED{MinThresh: 10, MaxThresh: 20, MaxProb: 0.5})
+		Convey("Below the minimum threshold no packets are dropped", func() {
+			for i := 0; i < 1000; i++ {
+				SoMsg("drop", r.drop(5), ShouldBeFalse)
+			}
+		})
+		Convey("Above the maximum threshold all packets are dropped", func() {
+			r.avg = 25
+			SoMsg("drop", r.drop(25), ShouldBeTrue)
+		})
+		Convey("Between the thresholds some packets are dropped", func() {
+			r.avg = 15
+			drops := 0
+			for i := 0; i < 1000; i++ {
+				if r.drop(15) {
+					drops++
+				}
+			}
+			SoMsg("some", drops, ShouldBeGreaterThan, 0)
+			SoMsg("not all", drops, ShouldBeLessThan, 1000)
+		})
+	})
+}
+
+func mustNew(t *testing.T, specs ...*Spec) *Queues {
+	qs, err := New(xtest.MustParseIA("1-ff00:0:110"), 0, specs)
+	xtest.FailOnErr(t, err)
+	return qs
+}
+
+func dscpClass(dscp uint8) *pktcls.Class {
+	return pktcls.NewClass("dscp", pktcls.NewCondIPv4(&pktcls.IPv4MatchDSCP{DSCP: dscp}))
+}
+
+// testPkt returns an IPv4 packet with the DSCP field set to dscp and total
+// length size.
+func testPkt(dscp uint8, size int) common.RawBytes {
+	pkt := make(common.RawBytes, size)
+	pkt[0] = 0x45
+	pkt[1] = dscp << 2
+	common.Order.PutUint16(pkt[2:], uint16(size))
+	pkt[8] = 64
+	pkt[9] = 17
+	copy(pkt[12:16], []byte{10, 0, 0, 1})
+	copy(pkt[16:20], []byte{10, 0, 0, 2})
+	return pkt
+}
@@ -36,6 +36,8 @@ const (
 	ip6Ver    = 0x6
 	ip4DstOff = 16
 	ip6DstOff = 24
+	ip4HdrLen = 20
+	ip6HdrLen = 40
 )
 
 var _ egress.Runner = (*Reader)(nil)
@@ -106,11 +108,22 @@ BatchLoop:
 }
 
 func (r *Reader) getDestIP(b common.RawBytes) (net.IP, error) {
+	if len(b) == 0 {
+		return nil, common.NewBasicError("Empty egress packet", nil)
+	}
 	ver := (b[0] >> 4)
 	switch ver {
 	case ip4Ver:
+		if len(b) < ip4HdrLen {
+			return nil, common.NewBasicError("Truncated IPv4 egress packet", nil,
+				"len", len(b))
+		}
 		return net.IP(b[ip4DstOff : ip4DstOff+net.IPv4len]), nil
 	case ip6Ver:
+		if len(b) < ip6HdrLen {
+			return nil, common.NewBasicError("Truncated IPv6 egress packet", nil,
+				"len", len(b))
+		}
 		return net.IP(b[ip6DstOff : ip6DstOff+net.IPv6len]), nil
 	default:
 		return nil, common.NewBasicError("Unsupported IP protocol version in egress packet", nil,
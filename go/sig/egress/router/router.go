@@ -17,6 +17,7 @@
 package router
 
 import (
+	"fmt"
 	"net"
 	"sync"
 
@@ -31,34 +32,54 @@ type NetMapI interface {
 	Add(*net.IPNet, addr.IA, *ringbuf.Ring) error
 	Delete(*net.IPNet) error
 	Lookup(net.IP) (addr.IA, *ringbuf.Ring)
+	// Replace atomically replaces all networks with routes.
+	Replace(routes []Route) error
 }
 
-// Networks is an unordered mapping of non-overlapping IP allocations to ASes. It is
-// concurrency safe. It is intended to be a stand-in until we have a a proper
-// mapping type, as the lookup is O(n) for the number of networks it contains.
+// Route maps a network to the ring buffer of a remote AS.
+type Route struct {
+	Net  *net.IPNet
+	IA   addr.IA
+	Ring *ringbuf.Ring
+}
+
+// Networks is a longest-prefix-match mapping of IPv4 and IPv6 allocations to
+// ASes. Networks may overlap, a lookup returns the AS of the most specific
+// network that contains the address. The lookup cost depends on the length
+// of the address, but not on the number of networks. It is concurrency safe.
 type Networks struct {
 	m    sync.RWMutex
-	nets []*network
+	nets map[string]*network
+	v4   *trieNode
+	v6   *trieNode
 }
 
 func (ns *Networks) Add(ipnet *net.IPNet, ia addr.IA, ring *ringbuf.Ring) error {
-	if ia.IsWildcard() {
-		return common.NewBasicError("Networks.Add(): Illegal wildcard remote AS", nil, "ia", ia)
+	newNet, err := newNetwork(ipnet, ia, ring)
+	if err != nil {
+		return err
 	}
-	if ring == nil {
-		return common.NewBasicError("Networks.Add(): ringBuf.Ring must not be nil", nil, "ia", ia)
-	}
-	cnet := newCanonNet(ipnet)
 	ns.m.Lock()
 	defer ns.m.Unlock()
-	newNet := &network{cnet, ia, ring}
-	for _, exnet := range ns.nets {
-		if exnet.net.Contains(cnet.IP) || cnet.Contains(exnet.net.IP) {
-			return common.NewBasicError("Networks.Add(): Networks overlap", nil,
-				"new", newNet, "existing", exnet)
-		}
+	if ns.nets == nil {
+		ns.nets, ns.v4, ns.v6 = make(map[string]*network), &trieNode{}, &trieNode{}
 	}
-	ns.nets = append(ns.nets, newNet)
+	return ns.addL(newNet)
+}
+
+func (ns *Networks) addL(newNet *network) error {
+	key := newNet.net.String()
+	if exnet, ok := ns.nets[key]; ok {
+		return common.NewBasicError("Networks.Add(): Network already present", nil,
+			"new", newNet, "existing", exnet)
+	}
+	ip, ones, v4 := newNet.net.prefix()
+	if v4 {
+		ns.v4.insert(ip, ones, newNet)
+	} else {
+		ns.v6.insert(ip, ones, newNet)
+	}
+	ns.nets[key] = newNet
 	return nil
 }
 
@@ -66,37 +87,57 @@ func (ns *Networks) Delete(ipnet *net.IPNet) error {
 	cnet := newCanonNet(ipnet)
 	ns.m.Lock()
 	defer ns.m.Unlock()
-	idx := ns.getIdxL(cnet)
-	if idx < 0 {
+	key := cnet.String()
+	if _, ok := ns.nets[key]; !ok {
 		return common.NewBasicError("Networks.Delete(): IPNet entry not present", nil, "net", ipnet)
 	}
-	// Fast delete, as it doesn't preserve order.
-	// https://github.com/golang/go/wiki/SliceTricks#delete-without-preserving-order
-	l := len(ns.nets)
-	ns.nets[idx] = ns.nets[l-1] // Copy last element to index i
-	ns.nets[l-1] = nil          // Zero last element, to allow it to be garbage collected.
-	ns.nets = ns.nets[:l-1]
+	ip, ones, v4 := cnet.prefix()
+	if v4 {
+		ns.v4.remove(ip, 0, ones)
+	} else {
+		ns.v6.remove(ip, 0, ones)
+	}
+	delete(ns.nets, key)
 	return nil
 }
 
 func (ns *Networks) Lookup(ip net.IP) (addr.IA, *ringbuf.Ring) {
 	ns.m.RLock()
 	defer ns.m.RUnlock()
-	for _, n := range ns.nets {
-		if n.net.Contains(ip) {
-			return n.ia, n.ring
-		}
+	var n *network
+	if ip4 := ip.To4(); ip4 != nil {
+		n = ns.v4.lookup(ip4)
+	} else if len(ip) == net.IPv6len {
+		n = ns.v6.lookup(ip)
 	}
-	return addr.IA{}, nil
+	if n == nil {
+		return addr.IA{}, nil
+	}
+	return n.ia, n.ring
 }
 
-func (ns *Networks) getIdxL(cnet *canonNet) int {
-	for i, n := range ns.nets {
-		if n.net.Equal(cnet) {
-			return i
+// Replace atomically replaces all networks with routes. If any of the routes
+// is invalid, or routes contain the same network twice, an error is returned
+// and the existing networks are left unchanged.
+func (ns *Networks) Replace(routes []Route) error {
+	newNs := &Networks{
+		nets: make(map[string]*network, len(routes)),
+		v4:   &trieNode{},
+		v6:   &trieNode{},
+	}
+	for _, r := range routes {
+		newNet, err := newNetwork(r.Net, r.IA, r.Ring)
+		if err != nil {
+			return err
+		}
+		if err := newNs.addL(newNet); err != nil {
+			return err
 		}
 	}
-	return -1
+	ns.m.Lock()
+	defer ns.m.Unlock()
+	ns.nets, ns.v4, ns.v6 = newNs.nets, newNs.v4, newNs.v6
+	return nil
 }
 
 type network struct {
@@ -105,6 +146,29 @@ type network struct {
 	ring *ringbuf.Ring
 }
 
+func newNetwork(ipnet *net.IPNet, ia addr.IA, ring *ringbuf.Ring) (*network, error) {
+	if ipnet == nil {
+		return nil, common.NewBasicError("Networks.Add(): IPNet must not be nil", nil, "ia", ia)
+	}
+	if ia.IsWildcard() {
+		return nil, common.NewBasicError("Networks.Add(): Illegal wildcard remote AS", nil,
+			"ia", ia)
+	}
+	if ring == nil {
+		return nil, common.NewBasicError("Networks.Add(): ringBuf.Ring must not be nil", nil,
+			"ia", ia)
+	}
+	if _, bits := ipnet.Mask.Size(); bits != 8*net.IPv4len && bits != 8*net.IPv6len {
+		return nil, common.NewBasicError("Networks.Add(): Invalid network mask", nil,
+			"net", ipnet)
+	}
+	return &network{newCanonNet(ipnet), ia, ring}, nil
+}
+
+func (n *network) String() string {
+	return fmt.Sprintf("%s -> %s", n.net, n.ia)
+}
+
 // canonNet contains a canonicalized version of net.IPNet, which allows it to
 // be tested for equality.
 type canonNet struct {
@@ -125,3 +189,64 @@ func (cn *canonNet) Equal(other *canonNet) bool {
 	}
 	return cn.Mask.String() == other.Mask.String() && cn.IP.Equal(other.IP)
 }
+
+// prefix returns the network address, the prefix length and whether the
+// network is an IPv4 network.
+func (cn *canonNet) prefix() (net.IP, int, bool) {
+	ones, bits := cn.Mask.Size()
+	if bits == 8*net.IPv4len {
+		return cn.IP.To4(), ones, true
+	}
+	return cn.IP.To16(), ones, false
+}
+
+// trieNode is a node of a binary trie, in which the networks are stored at
+// the depth of their prefix length.
+type trieNode struct {
+	children [2]*trieNode
+	net      *network
+}
+
+func (t *trieNode) insert(ip net.IP, ones int, n *network) {
+	for i := 0; i < ones; i++ {
+		b := bit(ip, i)
+		if t.children[b] == nil {
+			t.children[b] = &trieNode{}
+		}
+		t = t.children[b]
+	}
+	t.net = n
+}
+
+// remove removes the network with prefix length ones below t, which is at
+// the given depth, and prunes the nodes that are no longer needed. It returns
+// true if t is empty afterwards.
+func (t *trieNode) remove(ip net.IP, depth, ones int) bool {
+	if depth == ones {
+		t.net = nil
+	} else if b := bit(ip, depth); t.children[b] != nil {
+		if t.children[b].remove(ip, depth+1, ones) {
+			t.children[b] = nil
+		}
+	}
+	return t.net == nil && t.children[0] == nil && t.children[1] == nil
+}
+
+// lookup returns the network with the longest prefix that contains ip.
+func (t *trieNode) lookup(ip net.IP) *network {
+	var match *network
+	for i := 0; t != nil; i++ {
+		if t.net != nil {
+			match = t.net
+		}
+		if i == 8*len(ip) {
+			break
+		}
+		t = t.children[bit(ip, i)]
+	}
+	return match
+}
+
+func bit(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
@@ -74,11 +74,13 @@ func Test_Networks_Add(t *testing.T) {
 		{[]string{"192.0.2.0/24", "192.0.2.1/24"}, 1, false},
 		{[]string{"2001:db8::/48", "2001:db8::1/48"}, 1, false},
 		// Test adding supernet
-		{[]string{"192.0.2.0/25", "192.0.2.0/24"}, 1, false},
-		{[]string{"2001:db8::/49", "2001:db8::/48"}, 1, false},
+		{[]string{"192.0.2.0/25", "192.0.2.0/24"}, 2, true},
+		{[]string{"2001:db8::/49", "2001:db8::/48"}, 2, true},
 		// Test adding subnet
-		{[]string{"192.0.2.0/24", "192.0.2.0/25"}, 1, false},
-		{[]string{"2001:db8::/48", "2001:db8::/49"}, 1, false},
+		{[]string{"192.0.2.0/24", "192.0.2.0/25"}, 2, true},
+		{[]string{"2001:db8::/48", "2001:db8::/49"}, 2, true},
+		// Test default routes
+		{[]string{"0.0.0.0/0", "::/0"}, 2, true},
 	}
 	Convey("Networks.Add()", t, func() {
 		nets := &Networks{}
@@ -189,6 +191,140 @@ func Test_Networks_Lookup(t *testing.T) {
 	})
 }
 
+func Test_Networks_Lookup_Overlapping(t *testing.T) {
+	iaC := addr.IA{I: 1, A: 0xff0000000002}
+	routes := map[string]addr.IA{
+		"0.0.0.0/0":         iaC,
+		"192.0.2.0/24":      iaA,
+		"192.0.2.128/25":    iaB,
+		"192.0.2.200/32":    iaC,
+		"2001:db8::/32":     iaA,
+		"2001:db8:1::/48":   iaB,
+		"2001:db8:1::1/128": iaC,
+	}
+	var testCases = []struct {
+		ip string
+		ia addr.IA
+	}{
+		{"10.0.0.1", iaC},
+		{"192.0.2.1", iaA},
+		{"192.0.2.127", iaA},
+		{"192.0.2.128", iaB},
+		{"192.0.2.199", iaB},
+		{"192.0.2.200", iaC},
+		{"192.0.2.201", iaB},
+		{"2001:db8::1", iaA},
+		{"2001:db8:1::", iaB},
+		{"2001:db8:1::1", iaC},
+		{"2001:db8:1::2", iaB},
+		{"2001:db9::", addr.IA{}},
+	}
+	Convey("Networks.Lookup() with overlapping networks", t, func() {
+		nets := &Networks{}
+		for n, ia := range routes {
+			if err := nets.Add(parseNet(t, n), ia, &ringbuf.Ring{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, tc := range testCases {
+			Convey(tc.ip, func() {
+				ia, ring := nets.Lookup(net.ParseIP(tc.ip))
+				if tc.ia.IsZero() {
+					SoMsg("Lookup should fail", ring, ShouldBeNil)
+				} else {
+					SoMsg("Lookup should succeed", ring, ShouldNotBeNil)
+					SoMsg("IA should match", ia, ShouldResemble, tc.ia)
+				}
+			})
+		}
+		Convey("Deleting a more specific network falls back to the less specific one", func() {
+			SoMsg("err", nets.Delete(parseNet(t, "192.0.2.128/25")), ShouldBeNil)
+			ia, _ := nets.Lookup(net.ParseIP("192.0.2.199"))
+			SoMsg("ia", ia, ShouldResemble, iaA)
+			ia, _ = nets.Lookup(net.ParseIP("192.0.2.200"))
+			SoMsg("ia /32", ia, ShouldResemble, iaC)
+		})
+		Convey("Deleting a less specific network keeps the more specific ones", func() {
+			SoMsg("err", nets.Delete(parseNet(t, "2001:db8::/32")), ShouldBeNil)
+			_, ring := nets.Lookup(net.ParseIP("2001:db8::1"))
+			SoMsg("ring", ring, ShouldBeNil)
+			ia, _ := nets.Lookup(net.ParseIP("2001:db8:1::2"))
+			SoMsg("ia", ia, ShouldResemble, iaB)
+		})
+	})
+}
+
+func Test_Networks_Replace(t *testing.T) {
+	Convey("Networks.Replace()", t, func() {
+		nets := defNetworks(t)
+		Convey("Valid routes replace all networks", func() {
+			err := nets.Replace([]Route{
+				{Net: parseNet(t, "192.0.2.0/24"), IA: iaB, Ring: &ringbuf.Ring{}},
+				{Net: parseNet(t, "2001:db8:3::/48"), IA: iaA, Ring: &ringbuf.Ring{}},
+			})
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("nets", len(nets.nets), ShouldEqual, 2)
+			ia, _ := nets.Lookup(net.ParseIP("192.0.2.1"))
+			SoMsg("ia IPv4", ia, ShouldResemble, iaB)
+			ia, _ = nets.Lookup(net.ParseIP("2001:db8:3::1"))
+			SoMsg("ia IPv6", ia, ShouldResemble, iaA)
+			_, ring := nets.Lookup(net.ParseIP("2001:db8::1"))
+			SoMsg("old network removed", ring, ShouldBeNil)
+		})
+		Convey("Invalid routes leave the networks unchanged", func() {
+			err := nets.Replace([]Route{
+				{Net: parseNet(t, "192.0.2.0/24"), IA: iaB, Ring: &ringbuf.Ring{}},
+				{Net: parseNet(t, "192.0.2.1/24"), IA: iaA, Ring: &ringbuf.Ring{}},
+			})
+			SoMsg("err", err, ShouldNotBeNil)
+			ia, _ := nets.Lookup(net.ParseIP("192.0.2.4"))
+			SoMsg("ia", ia, ShouldResemble, iaB)
+		})
+	})
+}
+
+func Benchmark_Networks_Lookup(b *testing.B) {
+	for _, size := range []int{16, 1024, 65536} {
+		nets := &Networks{}
+		routes := make([]Route, 0, 2*size)
+		for i := 0; i < size; i++ {
+			routes = append(routes,
+				Route{
+					Net: &net.IPNet{
+						IP:   net.IPv4(10, byte(i>>8), byte(i), 0).To4(),
+						Mask: net.CIDRMask(24, 32),
+					},
+					IA:   iaA,
+					Ring: &ringbuf.Ring{},
+				},
+				Route{
+					Net: &net.IPNet{
+						IP:   net.IP{0x20, 0x01, 0x0d, 0xb8, byte(i >> 8), byte(i), 15: 0},
+						Mask: net.CIDRMask(48, 128),
+					},
+					IA:   iaB,
+					Ring: &ringbuf.Ring{},
+				},
+			)
+		}
+		if err := nets.Replace(routes); err != nil {
+			b.Fatal(err)
+		}
+		ip4 := net.IPv4(10, byte(size>>9), byte(size>>1), 1)
+		ip6 := net.IP{0x20, 0x01, 0x0d, 0xb8, byte(size >> 9), byte(size >> 1), 15: 1}
+		b.Run(fmt.Sprintf("IPv4/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				nets.Lookup(ip4)
+			}
+		})
+		b.Run(fmt.Sprintf("IPv6/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				nets.Lookup(ip6)
+			}
+		})
+	}
+}
+
 func Test_ipNet_Equal(t *testing.T) {
 	var testCases = []struct {
 		netA string
@@ -0,0 +1,104 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"bytes"
+	"math/rand"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/sig/disp"
+	"github.com/scionproto/scion/go/sig/mgmt"
+	"github.com/scionproto/scion/go/sig/sigcmn"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
+	"github.com/scionproto/scion/go/sig/siginfo"
+)
+
+// keyExchange is a key exchange initiated by the session monitor of an
+// encrypted session.
+type keyExchange struct {
+	id   uint32
+	eph  *sigcrypto.EphemeralKey
+	sent time.Time
+	// sig is the remote SIG the key is established with.
+	sig *siginfo.Sig
+}
+
+// keyExchange returns the key exchange to attach to the next PollReq to the
+// remote SIG sig, or nil if the session is not encrypted or its key with sig is
+// not due for renewal. A key exchange is repeated until the remote SIG replies,
+// or a new one is started after tout. Keys are only established with remote
+// SIGs whose address is known, as every remote SIG instance has its own key.
+func (sm *sessMonitor) keyExchange(sig *siginfo.Sig) *mgmt.KeyExchange {
+	if !sm.sess.encrypt || sig == nil || sig.Host.Equal(addr.SvcSIG) {
+		return nil
+	}
+	key := sm.sess.Key(sig)
+	if key != nil && time.Since(key.Created) < sigcrypto.RekeyInterval {
+		return nil
+	}
+	// Abandon the key exchanges the remote SIGs did not reply to in time.
+	for k, kx := range sm.kxs {
+		if time.Since(kx.sent) > tout {
+			delete(sm.kxs, k)
+		}
+	}
+	kx, ok := sm.kxs[sig.String()]
+	if !ok {
+		eph, err := sigcrypto.NewEphemeralKey()
+		if err != nil {
+			sm.Error("sessMonitor: Unable to start key exchange", "err", err)
+			return nil
+		}
+		kx = &keyExchange{id: rand.Uint32(), eph: eph, sent: time.Now(), sig: sig}
+		sm.kxs[sig.String()] = kx
+	}
+	return mgmt.NewKeyExchange(kx.id, kx.eph.Pub[:], nil, sigcrypto.LocalChain())
+}
+
+// handleKeyExchange completes the outstanding key exchange with the reply kx
+// of a remote SIG. If the reply is authentic, the derived key becomes the
+// session's key with that remote SIG.
+func (sm *sessMonitor) handleKeyExchange(rpld *disp.RegPld, kx *mgmt.KeyExchange) {
+	var state *keyExchange
+	for _, s := range sm.kxs {
+		if s.id == kx.KeyId {
+			state = s
+			break
+		}
+	}
+	if state == nil {
+		// Reply to a completed or abandoned key exchange.
+		return
+	}
+	if !bytes.Equal(kx.PeerPubKey, state.eph.Pub[:]) {
+		sm.Error("sessMonitor: Key exchange reply for wrong public key", "src", rpld.Addr)
+		return
+	}
+	if err := sigcrypto.Verify(rpld.Signed, sm.sess.IA(), kx); err != nil {
+		sm.Error("sessMonitor: Unable to authenticate key exchange", "src", rpld.Addr,
+			"err", err)
+		return
+	}
+	key, err := sigcrypto.DeriveKey(state.eph, kx, sigcmn.IA, sm.sess.IA(), sm.sess.SessId)
+	if err != nil {
+		sm.Error("sessMonitor: Unable to derive session key", "src", rpld.Addr, "err", err)
+		return
+	}
+	sm.sess.setKey(state.sig, key)
+	delete(sm.kxs, state.sig.String())
+	sm.Info("sessMonitor: Established session key", "keyId", key.Id, "remote", state.sig)
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"time"
+
+	"github.com/scionproto/scion/go/sig/egress"
+	"github.com/scionproto/scion/go/sig/mgmt"
+)
+
+// probe is a PollReq sent on one of the paths of a multipath session, to
+// check the path and measure its RTT.
+type probe struct {
+	path *egress.SessPath
+	sent time.Time
+}
+
+// updateMultiPaths replaces the paths of a multipath session that were
+// invalidated or did not answer probes for longer than tout, with the most
+// disjoint paths available. It then probes all paths and updates the
+// session's remote snapshot, such that the worker uses the current paths and
+// weights.
+func (sm *sessMonitor) updateMultiPaths() {
+	now := time.Now()
+	active := sm.mpaths[:0]
+	for _, path := range sm.mpaths {
+		if _, ok := sm.sessPathPool[path.Key()]; !ok {
+			sm.Info("sessMonitor: Multipath path was invalidated", "path", path)
+			delete(sm.mpLastReply, path.Key())
+			continue
+		}
+		if since := now.Sub(sm.mpLastReply[path.Key()]); since > tout {
+			sm.Info("sessMonitor: Multipath path timeout", "path", path, "duration", since)
+			path.Fail()
+			delete(sm.mpLastReply, path.Key())
+			continue
+		}
+		active = append(active, path)
+	}
+	sm.mpaths = active
+	for len(sm.mpaths) < sm.sess.numPaths {
+		path := sm.sessPathPool.GetDisjoint(sm.mpaths)
+		if path == nil {
+			break
+		}
+		sm.mpaths = append(sm.mpaths, path)
+		// Give the path time to answer its first probe.
+		sm.mpLastReply[path.Key()] = now
+		sm.Info("sessMonitor: New multipath path", "path", path)
+	}
+	for id, p := range sm.probes {
+		if now.Sub(p.sent) > tout {
+			delete(sm.probes, id)
+		}
+	}
+	if sm.smRemote.Sig == nil {
+		return
+	}
+	base := mgmt.MsgIdType(now.UnixNano())
+	for i, path := range sm.mpaths {
+		// The IDs must differ from the ID of the regular PollReq.
+		id := base + mgmt.MsgIdType(i+1)
+		sm.probes[id] = &probe{path: path, sent: now}
+		sm.sendPoll(id, sm.smRemote.Sig, path, nil)
+	}
+	sm.updateSessSnap()
+}
+
+// handleProbeRep handles the reply to the probe with the given ID.
+func (sm *sessMonitor) handleProbeRep(id mgmt.MsgIdType, p *probe) {
+	delete(sm.probes, id)
+	now := time.Now()
+	p.path.UpdateRTT(now.Sub(p.sent))
+	if _, ok := sm.mpLastReply[p.path.Key()]; ok {
+		sm.mpLastReply[p.path.Key()] = now
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"bytes"
+	"sync/atomic"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/fatal"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/sock/reliable"
+	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/sig/egress"
+	"github.com/scionproto/scion/go/sig/egress/worker"
+)
+
+const (
+	// pmtuTimeout is how long an MTU reported by an SCMP oversize packet
+	// error applies to the session's paths.
+	pmtuTimeout = 10 * time.Minute
+	// pmtuMaxDropDiv bounds the effect of an SCMP oversize packet error: it
+	// lowers the MTU of a path by at most 1/pmtuMaxDropDiv of the MTU
+	// announced for the path. The errors are not authenticated, so this
+	// limits the damage a forged error can do.
+	pmtuMaxDropDiv = 4
+)
+
+// pathMTU is an MTU reported by an SCMP oversize packet error.
+type pathMTU struct {
+	mtu     int
+	expires time.Time
+}
+
+// MTU returns the size of the largest packet that fits into a single frame on
+// the session's current paths, or 0 if the session has no path yet.
+func (s *Session) MTU() int {
+	return int(atomic.LoadInt64(&s.mtu))
+}
+
+// PathMTU returns the MTU of the session's paths reported by the most recent
+// SCMP oversize packet error, or 0 if no error was received in pmtuTimeout.
+func (s *Session) PathMTU() int {
+	pmtu := s.pathMTU.Load().(*pathMTU)
+	if pmtu == nil || time.Now().After(pmtu.expires) {
+		return 0
+	}
+	return pmtu.mtu
+}
+
+// readConn reads from the session's outbound connection until the session is
+// cleaned up. Oversize packet errors lower the path MTU of the session, other
+// packets are unexpected and only logged.
+func (s *Session) readConn() {
+	fatal.Check()
+	buf := make(common.RawBytes, common.MaxMTU)
+	for {
+		select {
+		case <-s.pktDispStop:
+			return
+		default:
+		}
+		n, src, err := s.conn.ReadFromSCION(buf)
+		if err != nil {
+			if reliable.IsDispatcherError(err) {
+				fatal.Fatal(err)
+				return
+			}
+			if opErr := oversizeError(err); opErr != nil {
+				s.handleOversize(opErr)
+				continue
+			}
+			log.Error("Session: Error reading from connection", "err", err)
+			continue
+		}
+		log.Debug("Session: Unexpected packet", "src", src, "raw", buf[:n])
+	}
+}
+
+// handleOversize lowers the path MTU of the session, if the SCMP oversize
+// packet error opErr was received on the reverse of one of the session's
+// current paths. Errors for other paths are ignored.
+func (s *Session) handleOversize(opErr *snet.OpError) {
+	info, ok := opErr.Info().(*scmp.InfoPktSize)
+	if !ok {
+		return
+	}
+	pathEntry := s.activePath(opErr.Path())
+	if pathEntry == nil {
+		s.Debug("Session: Ignoring SCMP oversize packet error for inactive path",
+			"mtu", info.MTU)
+		return
+	}
+	s.lowerPathMTU(int(info.MTU), int(pathEntry.Path.Mtu))
+}
+
+// activePath returns the current path of the session whose reverse is
+// revPath, or nil if there is none.
+func (s *Session) activePath(revPath *spath.Path) *sciond.PathReplyEntry {
+	if revPath == nil {
+		return nil
+	}
+	fwdPath := revPath.Copy()
+	if err := fwdPath.Reverse(); err != nil {
+		return nil
+	}
+	remote := s.Remote()
+	if remote == nil {
+		return nil
+	}
+	paths := []*egress.SessPath{remote.SessPath}
+	for _, wp := range remote.MultiPaths {
+		paths = append(paths, wp.SessPath)
+	}
+	for _, path := range paths {
+		if path == nil {
+			continue
+		}
+		pathEntry := path.PathEntry()
+		if pathEntry != nil && pathEntry.Path != nil &&
+			bytes.Equal(pathEntry.Path.FwdPath, fwdPath.Raw) {
+			return pathEntry
+		}
+	}
+	return nil
+}
+
+// lowerPathMTU sets the path MTU of the session to mtu, if it is lower than
+// the current one. announced is the MTU announced for the path the error
+// refers to. The MTU is lowered by at most 1/pmtuMaxDropDiv of announced, and
+// never below the minimum SCION MTU.
+func (s *Session) lowerPathMTU(mtu, announced int) {
+	if mtu >= announced {
+		return
+	}
+	if floor := announced - announced/pmtuMaxDropDiv; mtu < floor {
+		mtu = floor
+	}
+	if mtu < common.MinMTU {
+		mtu = common.MinMTU
+	}
+	now := time.Now()
+	if curr := s.PathMTU(); curr != 0 && curr < mtu {
+		return
+	}
+	s.pathMTU.Store(&pathMTU{mtu: mtu, expires: now.Add(pmtuTimeout)})
+	s.Info("Session: Path MTU lowered by SCMP oversize packet error", "mtu", mtu)
+}
+
+// oversizeError returns the SCMP oversize packet error contained in err, or
+// nil if err does not contain one.
+func oversizeError(err error) *snet.OpError {
+	for ; err != nil; err = common.GetNestedError(err) {
+		if opErr, ok := err.(*snet.OpError); ok {
+			if _, ok := opErr.Info().(*scmp.InfoPktSize); ok {
+				return opErr
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// updateMTU updates the MTU of the session, and exports it as a metric.
+func (sm *sessMonitor) updateMTU() {
+	mtu := worker.InnerMTU(sm.sess.Remote(), sm.sess.encrypt, sm.sess.PathMTU())
+	atomic.StoreInt64(&sm.sess.mtu, int64(mtu))
+	sm.sess.mtuGauge.Set(float64(mtu))
+}
@@ -28,13 +28,16 @@ import (
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/pathmgr"
-	"github.com/scionproto/scion/go/lib/pktdisp"
-	"github.com/scionproto/scion/go/lib/ringbuf"
+	"github.com/scionproto/scion/go/lib/pathpol"
 	"github.com/scionproto/scion/go/lib/snet"
 	"github.com/scionproto/scion/go/lib/spath/spathmeta"
 	"github.com/scionproto/scion/go/sig/egress"
+	"github.com/scionproto/scion/go/sig/egress/qos"
+	"github.com/scionproto/scion/go/sig/metrics"
 	"github.com/scionproto/scion/go/sig/mgmt"
 	"github.com/scionproto/scion/go/sig/sigcmn"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
+	"github.com/scionproto/scion/go/sig/siginfo"
 )
 
 var _ egress.Session = (*Session)(nil)
@@ -51,50 +54,112 @@ type Session struct {
 	// *egress.RemoteInfo
 	currRemote atomic.Value
 	// bool
-	healthy        atomic.Value
-	ring           *ringbuf.Ring
+	healthy atomic.Value
+	// *mgmt.Prefixes
+	remotePrefixes atomic.Value
+	queues         *qos.Queues
 	conn           snet.Conn
 	sessMonStop    chan struct{}
 	sessMonStopped chan struct{}
+	// switchPathReq requests the session monitor to switch paths.
+	switchPathReq  chan struct{}
 	pktDispStop    chan struct{}
 	pktDispStopped chan struct{}
 	workerStopped  chan struct{}
 	factory        egress.WorkerFactory
+	started        bool
+	// pathMode defines how frames are spread across paths.
+	pathMode egress.PathMode
+	// numPaths is the number of paths used at once in multipath modes.
+	numPaths int
+	// encrypt is true if the session's frames are encrypted.
+	encrypt bool
+	// map[string]*sigcrypto.Key, the keys established with the remote SIGs,
+	// keyed by the string representation of the remote SIG.
+	keys atomic.Value
+	// sigs contains the configured SIG instances of the remote AS. If empty,
+	// the remote SIG is discovered through the SIG anycast address.
+	sigs []*siginfo.Instance
+	// mtu is the MTU of the session, accessed atomically.
+	mtu int64
+	// *pathMTU
+	pathMTU  atomic.Value
+	mtuGauge prometheus.Gauge
 }
 
 func NewSession(dstIA addr.IA, sessId mgmt.SessionType, logger log.Logger,
 	pool egress.PathPool, factory egress.WorkerFactory) (*Session, error) {
 
+	return NewMultipathSession(dstIA, sessId, logger, pool, factory, egress.SinglePath, 1,
+		false, nil, nil)
+}
+
+// NewMultipathSession creates a session that spreads its frames across
+// numPaths paths as defined by pathMode. Redundant sessions always use two
+// paths. If encrypt is true, the session's frames are encrypted with keys
+// established with the remote SIG. If sigs is not empty, the flows are spread
+// across the healthy instances in sigs, instead of being sent to the remote
+// SIG discovered through the SIG anycast address. The packets are queued in
+// the queues described by queues, or a single default queue if it is empty.
+func NewMultipathSession(dstIA addr.IA, sessId mgmt.SessionType, logger log.Logger,
+	pool egress.PathPool, factory egress.WorkerFactory, pathMode egress.PathMode,
+	numPaths int, encrypt bool, sigs []*siginfo.Instance,
+	queues []*qos.Spec) (*Session, error) {
+
+	switch pathMode {
+	case egress.SinglePath:
+		numPaths = 1
+	case egress.Redundant:
+		numPaths = 2
+	case egress.LoadBalance:
+		if numPaths < 2 || numPaths > egress.MaxPaths {
+			return nil, common.NewBasicError("Invalid number of paths", nil,
+				"min", 2, "max", egress.MaxPaths, "actual", numPaths)
+		}
+	default:
+		return nil, common.NewBasicError("Unknown path mode", nil, "mode", pathMode)
+	}
 	var err error
 	s := &Session{
-		Logger:  logger.New("sessId", sessId),
-		ia:      dstIA,
-		SessId:  sessId,
-		pool:    pool,
-		factory: factory,
+		Logger:   logger.New("sessId", sessId),
+		ia:       dstIA,
+		SessId:   sessId,
+		pool:     pool,
+		factory:  factory,
+		pathMode: pathMode,
+		numPaths: numPaths,
+		encrypt:  encrypt,
+		sigs:     sigs,
 	}
 	s.currRemote.Store((*egress.RemoteInfo)(nil))
 	s.healthy.Store(false)
-	s.ring = ringbuf.New(64, nil, "egress",
-		prometheus.Labels{"ringId": dstIA.String(), "sessId": sessId.String()})
+	s.remotePrefixes.Store((*mgmt.Prefixes)(nil))
+	s.keys.Store(map[string]*sigcrypto.Key{})
+	s.pathMTU.Store((*pathMTU)(nil))
+	s.mtuGauge = metrics.SessionMTU.WithLabelValues(dstIA.String(), sessId.String())
+	if s.queues, err = qos.New(dstIA, sessId, queues); err != nil {
+		return nil, err
+	}
 	// Not using a fixed local port, as this is for outgoing data only.
-	s.conn, err = snet.ListenSCION("udp4",
+	s.conn, err = snet.ListenSCION(sigcmn.Network(),
 		&snet.Addr{IA: sigcmn.IA, Host: &addr.AppAddr{L3: sigcmn.Host}})
 	s.sessMonStop = make(chan struct{})
 	s.sessMonStopped = make(chan struct{})
+	s.switchPathReq = make(chan struct{}, 1)
 	s.pktDispStop = make(chan struct{})
 	s.pktDispStopped = make(chan struct{})
 	s.workerStopped = make(chan struct{})
-	// spawn a PktDispatcher to log any unexpected messages received on a write-only connection.
+	// Read from the write-only connection, to receive SCMP errors.
 	go func() {
 		defer log.LogPanicAndExit()
 		defer close(s.pktDispStopped)
-		pktdisp.PktDispatcher(s.conn, pktdisp.DispLogger, s.pktDispStop)
+		s.readConn()
 	}()
 	return s, err
 }
 
 func (s *Session) Start() {
+	s.started = true
 	go func() {
 		defer log.LogPanicAndExit()
 		newSessMonitor(s).run()
@@ -106,12 +171,14 @@ func (s *Session) Start() {
 }
 
 func (s *Session) Cleanup() error {
-	s.ring.Close()
+	s.queues.Close()
 	close(s.sessMonStop)
-	s.Debug("egress.Session Cleanup: wait for worker")
-	<-s.workerStopped
-	s.Debug("egress.Session Cleanup: wait for session monitor")
-	<-s.sessMonStopped
+	if s.started {
+		s.Debug("egress.Session Cleanup: wait for worker")
+		<-s.workerStopped
+		s.Debug("egress.Session Cleanup: wait for session monitor")
+		<-s.sessMonStopped
+	}
 	close(s.pktDispStop)
 	s.Debug("egress.Session Cleanup: wait for pktDisp")
 	s.conn.SetReadDeadline(time.Now())
@@ -130,8 +197,8 @@ func (s *Session) Remote() *egress.RemoteInfo {
 	return s.currRemote.Load().(*egress.RemoteInfo)
 }
 
-func (s *Session) Ring() *ringbuf.Ring {
-	return s.ring
+func (s *Session) Queues() *qos.Queues {
+	return s.queues
 }
 
 func (s *Session) Conn() snet.Conn {
@@ -151,10 +218,62 @@ func (s *Session) Healthy() bool {
 	return s.healthy.Load().(bool)
 }
 
+// RemotePrefixes returns the networks most recently announced by the remote
+// SIG, or nil if none were announced yet.
+func (s *Session) RemotePrefixes() *mgmt.Prefixes {
+	return s.remotePrefixes.Load().(*mgmt.Prefixes)
+}
+
 func (s *Session) PathPool() egress.PathPool {
 	return s.pool
 }
 
+func (s *Session) PathMode() egress.PathMode {
+	return s.pathMode
+}
+
+func (s *Session) Encrypted() bool {
+	return s.encrypt
+}
+
+func (s *Session) Key(sig *siginfo.Sig) *sigcrypto.Key {
+	if sig == nil {
+		return nil
+	}
+	return s.keys.Load().(map[string]*sigcrypto.Key)[sig.String()]
+}
+
+// setKey sets the key established with the remote SIG sig. Expired keys of
+// other remote SIGs are removed. It must only be called by the session
+// monitor.
+func (s *Session) setKey(sig *siginfo.Sig, key *sigcrypto.Key) {
+	old := s.keys.Load().(map[string]*sigcrypto.Key)
+	keys := make(map[string]*sigcrypto.Key, len(old)+1)
+	for k, v := range old {
+		if time.Since(v.Created) < sigcrypto.KeyLifetime {
+			keys[k] = v
+		}
+	}
+	keys[sig.String()] = key
+	s.keys.Store(keys)
+}
+
+// Sigs returns the configured SIG instances of the remote AS.
+func (s *Session) Sigs() []*siginfo.Instance {
+	return s.sigs
+}
+
+// SwitchPath requests the session to move its traffic to different paths. The
+// paths currently in use are counted as failed, such that they are not chosen
+// again right away.
+func (s *Session) SwitchPath() {
+	select {
+	case s.switchPathReq <- struct{}{}:
+	default:
+		// A switch is already pending.
+	}
+}
+
 func (s *Session) AnnounceWorkerStopped() {
 	close(s.workerStopped)
 }
@@ -167,7 +286,13 @@ type PathPool struct {
 var _ egress.PathPool = (*PathPool)(nil)
 
 func NewPathPool(dst addr.IA) (*PathPool, error) {
-	pool, err := sigcmn.PathMgr.Watch(context.TODO(), sigcmn.IA, dst)
+	return NewPathPoolWithPolicy(dst, nil)
+}
+
+// NewPathPoolWithPolicy creates a pool of the paths to dst that adhere to
+// policy. A nil policy does not filter any paths.
+func NewPathPoolWithPolicy(dst addr.IA, policy *pathpol.Policy) (*PathPool, error) {
+	pool, err := sigcmn.PathMgr.WatchFilter(context.TODO(), sigcmn.IA, dst, policy)
 	if err != nil {
 		return nil, common.NewBasicError("Unable to register watch", err)
 	}
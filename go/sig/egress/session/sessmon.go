@@ -24,10 +24,12 @@ import (
 	"github.com/scionproto/scion/go/lib/infra"
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
 	"github.com/scionproto/scion/go/sig/disp"
 	"github.com/scionproto/scion/go/sig/egress"
 	"github.com/scionproto/scion/go/sig/mgmt"
 	"github.com/scionproto/scion/go/sig/sigcmn"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
 	"github.com/scionproto/scion/go/sig/siginfo"
 )
 
@@ -57,13 +59,33 @@ type sessMonitor struct {
 	// last PollReq sent, so that sessMonitor can correlate replies to the
 	// remoteInfo used for the request.
 	updateMsgId mgmt.MsgIdType
+	// the time the last PollReq was sent, used to measure the RTT of the path.
+	updateSent time.Time
 	// the last time a PollRep was received.
 	lastReply time.Time
+	// the paths used by a multipath session.
+	mpaths []*egress.SessPath
+	// the last time a probe reply was received on each of the paths in mpaths.
+	mpLastReply map[spathmeta.PathKey]time.Time
+	// the outstanding probes on the paths in mpaths, keyed by message ID.
+	probes map[mgmt.MsgIdType]*probe
+	// the outstanding key exchanges of an encrypted session, keyed by the
+	// remote SIG.
+	kxs map[string]*keyExchange
+	// the health state of the configured remote SIG instances, sorted by
+	// priority. Empty if the remote SIG is discovered via anycast.
+	sigs []*sigState
+	// the remote SIG instances the flows are currently spread across.
+	selected []*siginfo.Instance
 }
 
 func newSessMonitor(sess *Session) *sessMonitor {
 	return &sessMonitor{
 		Logger: sess.Logger, sess: sess, pool: sess.pool, sessPathPool: make(egress.SessPathPool),
+		mpLastReply: make(map[spathmeta.PathKey]time.Time),
+		probes:      make(map[mgmt.MsgIdType]*probe),
+		kxs:         make(map[string]*keyExchange),
+		sigs:        newSigStates(sess.sigs),
 	}
 }
 
@@ -86,6 +108,10 @@ func (sm *sessMonitor) run() {
 		},
 		SessPath: sm.sessPathPool.Get(""),
 	}
+	if len(sm.sigs) > 0 {
+		// The remote SIG instances are known, no need to discover them.
+		sm.selectSigs()
+	}
 Top:
 	for {
 		select {
@@ -95,9 +121,18 @@ Top:
 			// Update paths and sigs
 			sm.sessPathPool.Update(sm.pool.Paths())
 			sm.updateRemote()
+			if len(sm.sigs) > 0 {
+				sm.selectSigs()
+			}
 			sm.sendReq()
+			if sm.sess.pathMode != egress.SinglePath {
+				sm.updateMultiPaths()
+			}
+			sm.updateMTU()
 		case rpld := <-regc:
 			sm.handleRep(rpld)
+		case <-sm.sess.switchPathReq:
+			sm.switchPath()
 		case <-pathExpiryTick.C:
 			for _, path := range sm.sessPathPool {
 				path.ExpireFails()
@@ -128,8 +163,11 @@ func (sm *sessMonitor) updateRemote() {
 			// checking for the path.
 			sm.smRemote.SessPath.Fail()
 		}
-		// Start monitoring new path and discover a new SIG.
-		sm.smRemote.Sig.Host = addr.SvcSIG
+		// Start monitoring new path and discover a new SIG, unless the remote
+		// SIG instances are configured.
+		if len(sm.sigs) == 0 {
+			sm.smRemote.Sig = &siginfo.Sig{IA: sm.smRemote.Sig.IA, Host: addr.SvcSIG}
+		}
 		sm.smRemote.SessPath = sm.getNewPath(sm.smRemote.SessPath)
 		// XXX(roosd): The session's remote SIG will remain the same until the
 		// monitor discovers a remote SIG.
@@ -180,11 +218,32 @@ func (sm *sessMonitor) updateRemote() {
 	}
 }
 
+// switchPath moves the session to different paths. The current paths are
+// counted as failed, such that they are not chosen again right away. Paths of
+// multipath sessions are replaced on the next tick.
+func (sm *sessMonitor) switchPath() {
+	if sm.smRemote.SessPath != nil {
+		sm.smRemote.SessPath.Fail()
+	}
+	sm.smRemote.SessPath = sm.getNewPath(sm.smRemote.SessPath)
+	for _, path := range sm.mpaths {
+		path.Fail()
+		delete(sm.mpLastReply, path.Key())
+	}
+	sm.mpaths = nil
+	sm.updateSessSnap()
+	sm.Info("sessMonitor: Switched path on request", "remote", sm.smRemote)
+}
+
 // updateSessSnap updates the remote snapshot in the session. If the new remote
 // SIG host is an SVC address, the previous host of the session is kept.
 func (sm *sessMonitor) updateSessSnap() {
 	// Copy the remote to avoid capturing the object in the session.
 	remote := *sm.smRemote
+	if len(sm.mpaths) > 0 {
+		remote.MultiPaths = egress.NewWeightedPaths(sm.mpaths)
+	}
+	remote.Sigs = sm.selected
 	// XXX(roosd): Data traffic should never be sent to a SVC address if avoidable.
 	if remote.Sig.Host.Equal(addr.SvcSIG) {
 		old := sm.sess.Remote()
@@ -205,11 +264,28 @@ func (sm *sessMonitor) getNewPath(old *egress.SessPath) *egress.SessPath {
 }
 
 func (sm *sessMonitor) sendReq() {
+	if len(sm.sigs) > 0 {
+		sm.sendSigReqs()
+		return
+	}
 	if sm.smRemote == nil || sm.smRemote.SessPath == nil {
 		return
 	}
 	sm.updateMsgId = mgmt.MsgIdType(time.Now().UnixNano())
-	spld, err := mgmt.NewPld(sm.updateMsgId, mgmt.NewPollReq(sigcmn.MgmtAddr, sm.sess.SessId))
+	sm.updateSent = time.Now()
+	sm.sendPoll(sm.updateMsgId, sm.smRemote.Sig, sm.smRemote.SessPath,
+		sm.keyExchange(sm.smRemote.Sig))
+}
+
+// sendPoll sends a PollReq with the given ID to the remote SIG sig on path. If
+// kx is not nil, it is attached to the request, which is then signed with the
+// key of the local AS.
+func (sm *sessMonitor) sendPoll(id mgmt.MsgIdType, sig *siginfo.Sig, path *egress.SessPath,
+	kx *mgmt.KeyExchange) {
+
+	req := mgmt.NewPollReq(sigcmn.MgmtAddr, sm.sess.SessId)
+	req.KeyExchange = kx
+	spld, err := mgmt.NewPld(id, req)
 	if err != nil {
 		sm.Error("sessMonitor: Error creating SIGCtrl payload", "err", err)
 		return
@@ -219,7 +295,12 @@ func (sm *sessMonitor) sendReq() {
 		sm.Error("sessMonitor: Error creating Ctrl payload", "err", err)
 		return
 	}
-	scpld, err := cpld.SignedPld(infra.NullSigner)
+	var scpld *ctrl.SignedPld
+	if kx != nil {
+		scpld, err = sigcrypto.Sign(cpld)
+	} else {
+		scpld, err = cpld.SignedPld(infra.NullSigner)
+	}
 	if err != nil {
 		sm.Error("sessMonitor: Error creating signed Ctrl payload", "err", err)
 		return
@@ -229,12 +310,12 @@ func (sm *sessMonitor) sendReq() {
 		sm.Error("sessMonitor: Error packing signed Ctrl payload", "err", err)
 		return
 	}
-	raddr := sm.smRemote.Sig.CtrlSnetAddr()
-	raddr.Path = spath.New(sm.smRemote.SessPath.PathEntry().Path.FwdPath)
+	raddr := sig.CtrlSnetAddr()
+	raddr.Path = spath.New(path.PathEntry().Path.FwdPath)
 	if err := raddr.Path.InitOffsets(); err != nil {
 		sm.Error("sessMonitor: Error initializing path offsets", "err", err)
 	}
-	nh, err := sm.smRemote.SessPath.PathEntry().HostInfo.Overlay()
+	nh, err := path.PathEntry().HostInfo.Overlay()
 	if err != nil {
 		sm.Error("sessMonitor: Unsupported NextHop", "err", err)
 	}
@@ -260,10 +341,23 @@ func (sm *sessMonitor) handleRep(rpld *disp.RegPld) {
 			"expected", sm.sess.IA(), "actual", rpld.Addr.IA)
 		return
 	}
+	if pollRep.KeyExchange != nil {
+		sm.handleKeyExchange(rpld, pollRep.KeyExchange)
+	}
+	if p, ok := sm.probes[rpld.Id]; ok {
+		sm.handleProbeRep(rpld.Id, p)
+		return
+	}
+	if sm.handleSigRep(rpld.Id, pollRep) {
+		return
+	}
 	// Only update the session's RemoteInfo if we get a response matching
 	// the last poll we sent.
 	if sm.updateMsgId == rpld.Id {
 		sm.lastReply = time.Now()
+		if sm.smRemote.SessPath != nil {
+			sm.smRemote.SessPath.UpdateRTT(sm.lastReply.Sub(sm.updateSent))
+		}
 		// Update sessmon's remote.
 		sm.smRemote.Sig = &siginfo.Sig{
 			IA:          sm.smRemote.Sig.IA,
@@ -277,6 +371,9 @@ func (sm *sessMonitor) handleRep(rpld *disp.RegPld) {
 			sm.updateSessSnap()
 			sm.Info("sessMonitor: updating remote Info", "msgId", rpld.Id, "remote", sm.smRemote)
 		}
+		if pollRep.Prefixes != nil {
+			sm.sess.remotePrefixes.Store(pollRep.Prefixes)
+		}
 		sm.sess.healthy.Store(true)
 	} else {
 		// This is going to happen if latency of the path is greater than the poll ticker period.
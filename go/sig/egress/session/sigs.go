@@ -0,0 +1,138 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"sort"
+	"time"
+
+	"github.com/scionproto/scion/go/sig/mgmt"
+	"github.com/scionproto/scion/go/sig/siginfo"
+)
+
+// sigState is the health state of a configured remote SIG instance. An
+// instance is healthy if it replied to a PollReq within tout.
+type sigState struct {
+	inst *siginfo.Instance
+	// pollId is the ID of the last PollReq sent to the instance.
+	pollId mgmt.MsgIdType
+	// pollSent is the time the last PollReq was sent to the instance.
+	pollSent time.Time
+	// lastReply is the last time a PollRep was received from the instance.
+	lastReply time.Time
+}
+
+func newSigStates(insts []*siginfo.Instance) []*sigState {
+	states := make([]*sigState, 0, len(insts))
+	for _, inst := range insts {
+		states = append(states, &sigState{inst: inst})
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].inst.Priority < states[j].inst.Priority
+	})
+	return states
+}
+
+func (st *sigState) healthy(now time.Time) bool {
+	return !st.lastReply.IsZero() && now.Sub(st.lastReply) <= tout
+}
+
+// selectSigs selects the healthy instances with the lowest priority value as
+// the instances the flows are spread across. If no instance is healthy, the
+// instances with the lowest priority value are selected, such that traffic
+// resumes as soon as they recover. The session's remote is updated if the
+// selection changed.
+func (sm *sessMonitor) selectSigs() {
+	now := time.Now()
+	anyHealthy := false
+	for _, st := range sm.sigs {
+		if st.healthy(now) {
+			anyHealthy = true
+			break
+		}
+	}
+	var selected []*siginfo.Instance
+	for _, st := range sm.sigs {
+		if anyHealthy && !st.healthy(now) {
+			continue
+		}
+		if len(selected) > 0 && st.inst.Priority != selected[0].Priority {
+			// The states are sorted by priority.
+			break
+		}
+		selected = append(selected, st.inst)
+	}
+	if sameInstances(selected, sm.selected) {
+		return
+	}
+	sm.selected = selected
+	sm.smRemote.Sig = selected[0].Sig
+	sm.updateSessSnap()
+	sm.Info("sessMonitor: Selected remote SIGs", "sigs", selected, "healthy", anyHealthy)
+}
+
+// sendSigReqs polls all configured remote SIG instances on the current path.
+func (sm *sessMonitor) sendSigReqs() {
+	if sm.smRemote.SessPath == nil {
+		return
+	}
+	now := time.Now()
+	base := mgmt.MsgIdType(now.UnixNano())
+	for i, st := range sm.sigs {
+		// The IDs must differ from the IDs of the multipath probes, which
+		// are always larger.
+		st.pollId = base - mgmt.MsgIdType(i)
+		st.pollSent = now
+		sm.sendPoll(st.pollId, st.inst.Sig, sm.smRemote.SessPath,
+			sm.keyExchange(st.inst.Sig))
+	}
+}
+
+// handleSigRep handles the reply to the PollReq with the given ID, if it was
+// the last PollReq sent to one of the configured remote SIG instances. It
+// returns false otherwise.
+func (sm *sessMonitor) handleSigRep(id mgmt.MsgIdType, pollRep *mgmt.PollRep) bool {
+	for _, st := range sm.sigs {
+		if st.pollId != id {
+			continue
+		}
+		now := time.Now()
+		st.lastReply = now
+		// A reply from any of the instances shows that the path works.
+		sm.lastReply = now
+		if sm.smRemote.SessPath != nil {
+			sm.smRemote.SessPath.UpdateRTT(now.Sub(st.pollSent))
+		}
+		if pollRep.Prefixes != nil {
+			sm.sess.remotePrefixes.Store(pollRep.Prefixes)
+		}
+		sm.sess.healthy.Store(true)
+		sm.selectSigs()
+		return true
+	}
+	return false
+}
+
+func sameInstances(a, b []*siginfo.Instance) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
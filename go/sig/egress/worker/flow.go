@@ -0,0 +1,79 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"hash/fnv"
+
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+const (
+	ip4Ver     = 0x4
+	ip6Ver     = 0x6
+	ip4SrcOff  = 12
+	ip4AddrLen = 8
+	ip4HdrLen  = 20
+	ip6SrcOff  = 8
+	ip6AddrLen = 32
+	ip6HdrLen  = 40
+	protoTCP   = 6
+	protoUDP   = 17
+	portsLen   = 4
+)
+
+// flowHash returns the hash of the 5-tuple of an IP packet. The ports are
+// only included for TCP and UDP packets that are not fragments and, for IPv6,
+// that have no extension headers. Packets that cannot be parsed all hash to
+// the same value.
+func flowHash(pkt common.RawBytes) uint64 {
+	h := fnv.New64a()
+	if len(pkt) == 0 {
+		return h.Sum64()
+	}
+	var addrs, l4 common.RawBytes
+	var proto uint8
+	switch pkt[0] >> 4 {
+	case ip4Ver:
+		if len(pkt) < ip4HdrLen {
+			return h.Sum64()
+		}
+		addrs = pkt[ip4SrcOff : ip4SrcOff+ip4AddrLen]
+		proto = pkt[9]
+		hdrLen := int(pkt[0]&0xF) * 4
+		// Only the first fragment carries the ports.
+		fragmented := common.Order.Uint16(pkt[6:8])&0x3FFF != 0
+		if !fragmented && hdrLen >= ip4HdrLen && len(pkt) >= hdrLen+portsLen {
+			l4 = pkt[hdrLen : hdrLen+portsLen]
+		}
+	case ip6Ver:
+		if len(pkt) < ip6HdrLen {
+			return h.Sum64()
+		}
+		addrs = pkt[ip6SrcOff : ip6SrcOff+ip6AddrLen]
+		proto = pkt[6]
+		if len(pkt) >= ip6HdrLen+portsLen {
+			l4 = pkt[ip6HdrLen : ip6HdrLen+portsLen]
+		}
+	default:
+		return h.Sum64()
+	}
+	h.Write(addrs)
+	h.Write([]byte{proto})
+	if proto == protoTCP || proto == protoUDP {
+		h.Write(l4)
+	}
+	return h.Sum64()
+}
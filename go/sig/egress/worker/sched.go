@@ -0,0 +1,118 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"sort"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ringbuf"
+	"github.com/scionproto/scion/go/sig/egress/qos"
+)
+
+// quantum is the number of bytes a queue with weight 1 may send per round.
+const quantum = 1500
+
+// scheduler reads packets from the queues of a session. Queues with a lower
+// priority value are drained strictly before queues with a higher one. Queues
+// with the same priority are served by deficit round robin, i.e., each
+// non-empty queue may send weight*quantum bytes per round. Queues may
+// overdraw their credit by one packet, the overdraft is deducted from the
+// credit of the next round.
+type scheduler struct {
+	levels []*schedLevel
+}
+
+// schedLevel contains the queues with the same priority.
+type schedLevel struct {
+	queues []*schedQueue
+	// next is the index of the queue that is served next.
+	next int
+	// visiting is true if the queue at index next already received its
+	// credit for the current round.
+	visiting bool
+}
+
+type schedQueue struct {
+	q       *qos.Queue
+	deficit int
+}
+
+func newScheduler(queues []*qos.Queue) *scheduler {
+	s := &scheduler{}
+	sorted := append([]*qos.Queue(nil), queues...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority() < sorted[j].Priority()
+	})
+	var l *schedLevel
+	for i, q := range sorted {
+		if i == 0 || q.Priority() != sorted[i-1].Priority() {
+			l = &schedLevel{}
+			s.levels = append(s.levels, l)
+		}
+		l.queues = append(l.queues, &schedQueue{q: q})
+	}
+	return s
+}
+
+// read reads packets into pkts without blocking, and returns the number of
+// packets read.
+func (s *scheduler) read(pkts ringbuf.EntryList) int {
+	n := 0
+	for _, l := range s.levels {
+		n += l.read(pkts[n:])
+		if n == len(pkts) {
+			break
+		}
+	}
+	return n
+}
+
+func (l *schedLevel) read(pkts ringbuf.EntryList) int {
+	n := 0
+	for n < len(pkts) && l.pending() {
+		sq := l.queues[l.next]
+		if !l.visiting {
+			sq.deficit += sq.q.Weight() * quantum
+			l.visiting = true
+		}
+		for sq.deficit > 0 && n < len(pkts) {
+			if sq.q.Read(pkts[n:n+1]) != 1 {
+				// Empty queues do not accumulate credit.
+				sq.deficit = 0
+				break
+			}
+			sq.deficit -= len(pkts[n].(common.RawBytes))
+			n++
+		}
+		if n == len(pkts) && sq.deficit > 0 {
+			// Continue with the same queue on the next read.
+			break
+		}
+		l.next = (l.next + 1) % len(l.queues)
+		l.visiting = false
+	}
+	return n
+}
+
+// pending returns true if any of the queues contains packets.
+func (l *schedLevel) pending() bool {
+	for _, sq := range l.queues {
+		if sq.q.Len() > 0 {
+			return true
+		}
+	}
+	return false
+}
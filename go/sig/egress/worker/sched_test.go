@@ -0,0 +1,110 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/pktcls"
+	"github.com/scionproto/scion/go/lib/ringbuf"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/sig/egress/qos"
+	"github.com/scionproto/scion/go/sig/metrics"
+)
+
+func TestMain(m *testing.M) {
+	metrics.Init("test")
+	os.Exit(m.Run())
+}
+
+func TestScheduler(t *testing.T) {
+	Convey("Queues with lower priority values are drained first", t, func() {
+		qs := newTestQueues(t,
+			&qos.Spec{Name: "a", Class: dscpClass(1), Priority: 1},
+			&qos.Spec{Name: "b", Priority: 0},
+		)
+		enqueue(t, qs, 1, 3, 1000)
+		enqueue(t, qs, 2, 3, 100)
+		s := newScheduler(qs.List())
+		SoMsg("order", readAll(s, 2), ShouldEqual, "bbbaaa")
+	})
+	Convey("Queues with the same priority are drained in proportion to their weights",
+		t, func() {
+			qs := newTestQueues(t,
+				&qos.Spec{Name: "a", Class: dscpClass(1), Weight: 2},
+				&qos.Spec{Name: "b", Weight: 1},
+			)
+			enqueue(t, qs, 1, 8, 750)
+			enqueue(t, qs, 2, 8, 750)
+			s := newScheduler(qs.List())
+			SoMsg("order", readAll(s, 5), ShouldEqual, "aaaabbaaaabbbbbb")
+		})
+	Convey("Overdrawn credit is deducted in the next round", t, func() {
+		qs := newTestQueues(t,
+			&qos.Spec{Name: "a", Class: dscpClass(1)},
+			&qos.Spec{Name: "b"},
+		)
+		enqueue(t, qs, 1, 2, 3000)
+		enqueue(t, qs, 2, 4, 1500)
+		s := newScheduler(qs.List())
+		SoMsg("order", readAll(s, 16), ShouldEqual, "abbabb")
+	})
+	Convey("Reading empty queues returns no packets", t, func() {
+		qs := newTestQueues(t, &qos.Spec{Name: "a"})
+		s := newScheduler(qs.List())
+		SoMsg("n", s.read(make(ringbuf.EntryList, 4)), ShouldEqual, 0)
+	})
+}
+
+func newTestQueues(t *testing.T, specs ...*qos.Spec) *qos.Queues {
+	qs, err := qos.New(xtest.MustParseIA("1-ff00:0:110"), 0, specs)
+	xtest.FailOnErr(t, err)
+	return qs
+}
+
+func dscpClass(dscp uint8) *pktcls.Class {
+	return pktcls.NewClass("dscp", pktcls.NewCondIPv4(&pktcls.IPv4MatchDSCP{DSCP: dscp}))
+}
+
+// enqueue puts n IPv4 packets of length size with DSCP dscp into qs.
+func enqueue(t *testing.T, qs *qos.Queues, dscp uint8, n, size int) {
+	for i := 0; i < n; i++ {
+		pkt := make(common.RawBytes, size)
+		pkt[0] = 0x45
+		pkt[1] = dscp << 2
+		common.Order.PutUint16(pkt[2:], uint16(size))
+		if !qs.Enqueue(pkt) {
+			t.Fatalf("Unable to enqueue packet")
+		}
+	}
+}
+
+// readAll reads all packets from s, batchSize packets at a time. It returns
+// the order in which the packets were read, with each packet represented by
+// 'a' plus its DSCP value minus one.
+func readAll(s *scheduler, batchSize int) string {
+	var order []byte
+	pkts := make(ringbuf.EntryList, batchSize)
+	for n := s.read(pkts); n > 0; n = s.read(pkts) {
+		for _, pkt := range pkts[:n] {
+			order = append(order, 'a'+pkt.(common.RawBytes)[1]>>2-1)
+		}
+	}
+	return string(order)
+}
@@ -13,7 +13,7 @@
 // limitations under the License.
 
 // Package worker implements the logic for reading packets from a session's
-// ring buffer, encapsulating them and writing them to the network as frames.
+// queues, encapsulating them and writing them to the network as frames.
 package worker
 
 import (
@@ -31,6 +31,7 @@ import (
 	"github.com/scionproto/scion/go/sig/metrics"
 	"github.com/scionproto/scion/go/sig/mgmt"
 	"github.com/scionproto/scion/go/sig/sigcmn"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
 	"github.com/scionproto/scion/go/sig/siginfo"
 )
 
@@ -41,6 +42,16 @@ import (
 //   sequence number wrapping. The epoch values are the lowest 16b of the unix
 //   timestamp at the reset point.
 //
+//   Sessions that spread frames across multiple paths use one lane per path.
+//   Each lane has its own epoch and sequence numbers, such that frames sent
+//   on different paths are reassembled independently. Packets never span
+//   frames of different lanes. Sessions that send frames redundantly on
+//   multiple paths use a single lane, the remote SIG discards duplicates
+//   based on the sequence number.
+//
+//   If the remote AS has multiple SIGs, every flow is pinned to one of them.
+//   The frames sent to each remote SIG have their own lanes.
+//
 //   0B       1        2        3        4        5        6        7
 //   +--------+--------+--------+--------+--------+--------+--------+--------+
 //   | Sess Id|      Epoch      |    Sequence number       |     Index       |
@@ -48,6 +59,9 @@ import (
 //
 //   Inside the frame, all encapsulated packets are preceded by a 2B length
 //   field, and then padded to an 8B boundary
+//
+//   Frames of encrypted sessions are encrypted as described in package
+//   sigcrypto. A new key always starts new epochs.
 
 const (
 	PktLenSize = 2
@@ -70,9 +84,44 @@ type worker struct {
 	currPathEntry *sciond.PathReplyEntry
 	frameSentCtrs metrics.CtrPair
 
+	// currPaths contains the paths of a multipath session.
+	currPaths []*egress.WeightedPath
+	// currSigs contains the remote SIG instances the flows are pinned to, if
+	// the remote AS has multiple SIGs.
+	currSigs []*siginfo.Instance
+	// targets contains the frame of each remote SIG that packets are sent to,
+	// keyed by the string representation of the remote SIG.
+	targets map[string]*target
+	pkts    ringbuf.EntryList
+	// sched reads the packets from the session's queues.
+	sched *scheduler
+}
+
+// target contains the frame and the sequence number state of the frames sent
+// to one remote SIG. The frames to different remote SIGs are independent of
+// each other.
+type target struct {
+	sig *siginfo.Sig
+	f   *frame
+	// lanes contains the sequence number state of each path. Single path
+	// and redundant sessions only use the first lane.
+	lanes []lane
+	// laneIdx is the index of the lane of the current frame.
+	laneIdx int
+	// contPkt is true if the current frame continues a packet of the
+	// previous frame, i.e., it must be sent in the same lane.
+	contPkt bool
+	// key is the key the frames of an encrypted session are encrypted with.
+	key *sigcrypto.Key
+}
+
+// lane contains the sequence number state of frames sent on one path.
+type lane struct {
 	epoch uint16
 	seq   uint32
-	pkts  ringbuf.EntryList
+	// current is the current weight of the lane for smooth weighted
+	// round-robin scheduling.
+	current int
 }
 
 func NewWorker(sess egress.Session, logger log.Logger) *worker {
@@ -84,37 +133,53 @@ func NewWorker(sess egress.Session, logger log.Logger) *worker {
 			Pkts:  metrics.FramesSent.WithLabelValues(sess.IA().String(), sess.ID().String()),
 			Bytes: metrics.FrameBytesSent.WithLabelValues(sess.IA().String(), sess.ID().String()),
 		},
-		pkts: make(ringbuf.EntryList, 0, egress.EgressBufPkts),
+		pkts:    make(ringbuf.EntryList, 0, egress.EgressBufPkts),
+		targets: make(map[string]*target),
+		sched:   newScheduler(sess.Queues().List()),
 	}
 }
 
 func (w *worker) Run() {
 	defer log.LogPanicAndExit()
 	w.Info("EgressWorker: starting")
-	f := newFrame()
 
 TopLoop:
 	for {
-		// If the frame is empty, block indefinitely for more packets.
-		fEmpty := f.offset == sigcmn.SIGHdrSize
+		// If all frames are empty, block indefinitely for more packets.
+		fEmpty := w.framesEmpty()
 		if !w.read(fEmpty) {
 			break TopLoop
 		}
 		if fEmpty {
 			// Cover the case where no packets have arrived in a while, and the
-			// current path is stale.
-			w.resetFrame(f)
+			// current path or remote SIG is stale.
+			w.updateRemote()
+			w.pruneTargets()
+			for _, t := range w.targets {
+				w.resetFrame(t)
+			}
 		} else if len(w.pkts) == 0 {
-			// Didn't read any new packets, send partial frame.
-			if err := w.write(f); err != nil {
-				w.Error("Error sending frame", "err", err)
+			// Didn't read any new packets, send partial frames.
+			for _, t := range w.targets {
+				if t.f.offset == sigcmn.SIGHdrSize {
+					continue
+				}
+				t.contPkt = false
+				if err := w.write(t); err != nil {
+					w.Error("Error sending frame", "err", err)
+				}
 			}
 			continue TopLoop
 		}
 		// Process buffered packets.
 		for i := range w.pkts {
 			pkt := w.pkts[i].(common.RawBytes)
-			if err := w.processPkt(f, pkt); err != nil {
+			t := w.target(pkt)
+			if t == nil {
+				// FIXME(kormat): add some metrics to track this.
+				continue
+			}
+			if err := w.processPkt(t, pkt); err != nil {
 				w.Error("Error sending frame", "err", err)
 			}
 		}
@@ -128,7 +193,8 @@ TopLoop:
 	w.sess.AnnounceWorkerStopped()
 }
 
-func (w *worker) processPkt(f *frame, pkt common.RawBytes) error {
+func (w *worker) processPkt(t *target, pkt common.RawBytes) error {
+	f := t.f
 	f.startPkt(uint16(len(pkt)))
 	pktOff := 0
 	// Write chunks of the packet to frames, sending off frames as they fill up.
@@ -136,7 +202,8 @@ func (w *worker) processPkt(f *frame, pkt common.RawBytes) error {
 		pktOff += f.readFrom(pkt[pktOff:])
 		if f.isFull() {
 			// There's no point in trying to fit another packet into this frame.
-			if err := w.write(f); err != nil {
+			t.contPkt = pktOff != len(pkt)
+			if err := w.write(t); err != nil {
 				// Skip the rest of this packet.
 				return err
 			}
@@ -149,50 +216,114 @@ func (w *worker) processPkt(f *frame, pkt common.RawBytes) error {
 	}
 }
 
-// Return false if the ringbuf is closed.
+// Return false if the queues are closed and empty.
 func (w *worker) read(block bool) bool {
 	w.pkts = w.pkts[:cap(w.pkts)]
-	n, _ := w.sess.Ring().Read(w.pkts, block)
-	if n < 0 {
-		return false
+	n := w.sched.read(w.pkts)
+	for n == 0 && block {
+		open := w.sess.Queues().Wait()
+		n = w.sched.read(w.pkts)
+		if !open && n == 0 {
+			return false
+		}
 	}
 	w.pkts = w.pkts[:n]
 	// FIXME(kormat): add worker read metrics here.
 	return true
 }
 
-func (w *worker) write(f *frame) error {
+// target returns the target of the remote SIG the packet is sent to. If the
+// remote AS has multiple SIGs, the flow of the packet is pinned to one of
+// them. Targets are created as needed.
+func (w *worker) target(pkt common.RawBytes) *target {
+	sig := w.currSig
+	if len(w.currSigs) > 0 {
+		if inst := siginfo.Pin(w.currSigs, flowHash(pkt)); inst != nil {
+			sig = inst.Sig
+		}
+	}
+	if sig == nil {
+		return nil
+	}
+	t, ok := w.targets[sig.String()]
+	if !ok {
+		t = &target{sig: sig, f: newFrame(), lanes: make([]lane, 1, egress.MaxPaths)}
+		w.targets[sig.String()] = t
+		w.resetFrame(t)
+	}
+	return t
+}
+
+// framesEmpty returns true if none of the targets has a partial frame.
+func (w *worker) framesEmpty() bool {
+	for _, t := range w.targets {
+		if t.f.offset != sigcmn.SIGHdrSize {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *worker) write(t *target) error {
 	// TODO(kormat): consider looking for an updated path here, and switching
 	// to it if the mtu isn't smaller than the current one.
-	defer w.resetFrame(f)
-	if w.currPathEntry == nil {
+	defer w.resetFrame(t)
+	pathEntries := w.framePaths(t)
+	if len(pathEntries) == 0 {
 		// FIXME(kormat): add some metrics to track this.
 		return nil
 	}
-	if w.currSig == nil {
-		// FIXME(kormat): add some metrics to track this.
+	if w.sess.Encrypted() && t.key == nil {
+		// No key has been established with the remote SIG yet.
 		return nil
 	}
-	snetAddr := w.currSig.EncapSnetAddr()
-	snetAddr.Path = spath.New(w.currPathEntry.Path.FwdPath)
+	f := t.f
+	l := &t.lanes[t.laneIdx]
+	if l.seq == 0 {
+		l.epoch = t.newEpoch()
+	}
+	f.writeHdr(w.sess.ID(), l.epoch, l.seq)
+	// Update sequence number for next packet
+	l.seq += 1
+	if l.seq > MaxSeq {
+		l.seq = 0
+	}
+	raw := f.raw()
+	if t.key != nil {
+		raw = t.key.Seal(raw)
+	}
+	// Redundant frames only fail if they could not be sent on any path.
+	var firstErr error
+	failed := 0
+	for _, pathEntry := range pathEntries {
+		if err := w.writeTo(t.sig, raw, pathEntry); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+		}
+	}
+	if failed == len(pathEntries) {
+		return firstErr
+	}
+	return nil
+}
+
+// writeTo sends the raw frame to the remote SIG sig on the given path.
+func (w *worker) writeTo(sig *siginfo.Sig, raw common.RawBytes,
+	pathEntry *sciond.PathReplyEntry) error {
+
+	snetAddr := sig.EncapSnetAddr()
+	snetAddr.Path = spath.New(pathEntry.Path.FwdPath)
 	if err := snetAddr.Path.InitOffsets(); err != nil {
 		return common.NewBasicError("Error initializing path offsets", err)
 	}
-	nh, err := w.currPathEntry.HostInfo.Overlay()
+	nh, err := pathEntry.HostInfo.Overlay()
 	if err != nil {
 		return common.NewBasicError("Egress unsupported NextHop", err)
 	}
 	snetAddr.NextHop = nh
-	if w.seq == 0 {
-		w.epoch = uint16(time.Now().Unix() & 0xFFFF)
-	}
-	f.writeHdr(w.sess.ID(), w.epoch, w.seq)
-	// Update sequence number for next packet
-	w.seq += 1
-	if w.seq > MaxSeq {
-		w.seq = 0
-	}
-	bytesWritten, err := w.sess.Conn().WriteToSCION(f.raw(), snetAddr)
+	bytesWritten, err := w.sess.Conn().WriteToSCION(raw, snetAddr)
 	if err != nil {
 		return common.NewBasicError("Egress write error", err)
 	}
@@ -201,26 +332,135 @@ func (w *worker) write(f *frame) error {
 	return nil
 }
 
-func (w *worker) resetFrame(f *frame) {
-	var mtu uint16 = common.MinMTU
-	var addrLen, pathLen uint16
+// framePaths returns the paths the current frame of t is sent on.
+func (w *worker) framePaths(t *target) []*sciond.PathReplyEntry {
+	switch {
+	case len(w.currPaths) == 0:
+		if w.currPathEntry == nil {
+			return nil
+		}
+		return []*sciond.PathReplyEntry{w.currPathEntry}
+	case w.sess.PathMode() == egress.Redundant:
+		pathEntries := make([]*sciond.PathReplyEntry, 0, len(w.currPaths))
+		for _, wp := range w.currPaths {
+			pathEntries = append(pathEntries, wp.SessPath.PathEntry())
+		}
+		return pathEntries
+	default:
+		return []*sciond.PathReplyEntry{w.currPaths[t.laneIdx].SessPath.PathEntry()}
+	}
+}
+
+// newEpoch returns the epoch for a lane whose sequence number is reset. The
+// epoch is based on the current time, but differs from the epochs of all other
+// lanes in use.
+func (t *target) newEpoch() uint16 {
+	epoch := uint16(time.Now().Unix() & 0xFFFF)
+	for i := 0; i < len(t.lanes); i++ {
+		if i != t.laneIdx && t.lanes[i].seq != 0 && t.lanes[i].epoch == epoch {
+			epoch++
+			// Restart the check with the new epoch.
+			i = -1
+		}
+	}
+	return epoch
+}
+
+// nextLane returns the lane of the next frame, using smooth weighted
+// round-robin scheduling across the paths of a multipath session.
+func (t *target) nextLane(paths []*egress.WeightedPath) int {
+	total, best := 0, 0
+	for i, wp := range paths {
+		t.lanes[i].current += wp.Weight
+		total += wp.Weight
+		if t.lanes[i].current > t.lanes[best].current {
+			best = i
+		}
+	}
+	t.lanes[best].current -= total
+	return best
+}
+
+// updateRemote loads the current remote SIGs and paths of the session.
+func (w *worker) updateRemote() {
 	remote := w.sess.Remote()
-	if remote != nil {
-		w.currSig = remote.Sig
-		if w.currSig != nil {
-			addrLen = uint16(spkt.AddrHdrLen(w.currSig.Host, sigcmn.Host))
+	if remote == nil {
+		return
+	}
+	w.currSig = remote.Sig
+	w.currSigs = remote.Sigs
+	w.currPathEntry = nil
+	if remote.SessPath != nil {
+		w.currPathEntry = remote.SessPath.PathEntry()
+	}
+	w.currPaths = nil
+	if w.sess.PathMode() != egress.SinglePath {
+		w.currPaths = remote.MultiPaths
+	}
+}
+
+// pruneTargets removes the targets of the remote SIGs that are no longer in
+// use. It must only be called if all frames are empty.
+func (w *worker) pruneTargets() {
+	for k, t := range w.targets {
+		if !w.inUse(t.sig) {
+			delete(w.targets, k)
+		}
+	}
+}
+
+// inUse returns true if sig is one of the current remote SIGs.
+func (w *worker) inUse(sig *siginfo.Sig) bool {
+	for _, inst := range w.currSigs {
+		if inst.Sig.Equal(sig) {
+			return true
+		}
+	}
+	return len(w.currSigs) == 0 && w.currSig.Equal(sig)
+}
+
+func (w *worker) resetFrame(t *target) {
+	var mtu uint16 = common.MinMTU
+	var pathLen uint16
+	w.updateRemote()
+	addrLen := uint16(spkt.AddrHdrLen(t.sig.Host, sigcmn.Host))
+	for len(t.lanes) < len(w.currPaths) {
+		t.lanes = append(t.lanes, lane{})
+	}
+	if key := w.sess.Key(t.sig); key != t.key && !t.contPkt {
+		// Switching to a new key starts new epochs on all lanes. Together with
+		// the epochs of different lanes being distinct, this ensures that
+		// nonces are never reused with the same key.
+		t.key = key
+		for i := range t.lanes {
+			t.lanes[i].seq = 0
 		}
-		w.currPathEntry = nil
-		if remote.SessPath != nil {
-			w.currPathEntry = remote.SessPath.PathEntry()
+	}
+	if len(w.currPaths) > 0 && w.sess.PathMode() == egress.LoadBalance {
+		// Packets must not span frames of different lanes. If the set of paths
+		// shrank, the continued packet is lost anyway.
+		if !t.contPkt || t.laneIdx >= len(w.currPaths) {
+			t.laneIdx = t.nextLane(w.currPaths)
 		}
-		if w.currPathEntry != nil {
-			mtu = w.currPathEntry.Path.Mtu
-			pathLen = uint16(len(w.currPathEntry.Path.FwdPath))
+	} else {
+		t.laneIdx = 0
+	}
+	// The frame must fit on all paths it is sent on.
+	pathMTU := uint16(w.sess.PathMTU())
+	for i, pathEntry := range w.framePaths(t) {
+		pMtu, pLen := pathEntry.Path.Mtu, uint16(len(pathEntry.Path.FwdPath))
+		if pathMTU > 0 && pathMTU < pMtu {
+			pMtu = pathMTU
+		}
+		if i == 0 || pMtu-pLen < mtu-pathLen {
+			mtu, pathLen = pMtu, pLen
 		}
 	}
+	if w.sess.Encrypted() {
+		mtu -= sigcrypto.Overhead
+	}
 	// FIXME(kormat): to do this properly, need to account for any ext headers.
-	f.reset(mtu - spkt.CmnHdrLen - addrLen - pathLen - l4.UDPLen)
+	t.f.reset(mtu - spkt.CmnHdrLen - addrLen - pathLen - l4.UDPLen)
 }
 
 type frame struct {
@@ -270,3 +510,44 @@ func (f *frame) writeHdr(sessId mgmt.SessionType, epoch uint16, seq uint32) {
 	common.Order.PutUintN(f.b[3:6], uint64(seq), 3)
 	common.Order.PutUint16(f.b[6:8], f.idx)
 }
+
+// InnerMTU returns the size of the largest packet that fits into a single
+// frame on all the paths of remote, or 0 if remote has no path or remote SIG.
+// The MTU of the paths is capped to pathMTU, unless it is 0.
+func InnerMTU(remote *egress.RemoteInfo, encrypted bool, pathMTU int) int {
+	if remote == nil || remote.Sig == nil {
+		return 0
+	}
+	var paths []*egress.SessPath
+	for _, wp := range remote.MultiPaths {
+		paths = append(paths, wp.SessPath)
+	}
+	if len(paths) == 0 && remote.SessPath != nil {
+		paths = append(paths, remote.SessPath)
+	}
+	addrLen := spkt.AddrHdrLen(remote.Sig.Host, sigcmn.Host)
+	for _, inst := range remote.Sigs {
+		if l := spkt.AddrHdrLen(inst.Host, sigcmn.Host); l > addrLen {
+			addrLen = l
+		}
+	}
+	overhead := spkt.CmnHdrLen + addrLen + l4.UDPLen + SigHdrLen + PktLenSize
+	if encrypted {
+		overhead += sigcrypto.Overhead
+	}
+	mtu := 0
+	for _, path := range paths {
+		pathEntry := path.PathEntry()
+		pMtu := int(pathEntry.Path.Mtu)
+		if pathMTU > 0 && pathMTU < pMtu {
+			pMtu = pathMTU
+		}
+		if m := pMtu - overhead - len(pathEntry.Path.FwdPath); mtu == 0 || m < mtu {
+			mtu = m
+		}
+	}
+	if mtu < 0 {
+		return 0
+	}
+	return mtu
+}
@@ -71,7 +71,7 @@ func NewDispatcher(tio io.ReadWriteCloser) *Dispatcher {
 
 func (d *Dispatcher) Run() error {
 	var err error
-	extConn, err = snet.ListenSCION("udp4", d.laddr)
+	extConn, err = snet.ListenSCION(sigcmn.Network(), d.laddr)
 	if err != nil {
 		return common.NewBasicError("Unable to initialize extConn", err)
 	}
@@ -0,0 +1,136 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingress
+
+import (
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/sig/mgmt"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
+)
+
+var keys = newKeyTable()
+
+// AddKey adds a key established with a remote SIG in ia for the session
+// sessId. Frames encrypted with the key are accepted for
+// sigcrypto.KeyLifetime.
+func AddKey(ia addr.IA, sessId mgmt.SessionType, key *sigcrypto.Key) {
+	keys.add(ia, sessId, key)
+}
+
+// SetEncrypted sets the sessions of the remote AS ia that must be encrypted.
+// Cleartext frames received on these sessions are dropped.
+func SetEncrypted(ia addr.IA, sessIds []mgmt.SessionType) {
+	keys.setEncrypted(ia, sessIds)
+}
+
+type keyRef struct {
+	ia     addr.IAInt
+	sessId mgmt.SessionType
+	id     uint32
+}
+
+type sessRef struct {
+	ia     addr.IAInt
+	sessId mgmt.SessionType
+}
+
+// keyTable contains the keys of the encrypted sessions of remote SIGs.
+type keyTable struct {
+	sync.Mutex
+	keys      map[keyRef]*sessKey
+	encrypted map[sessRef]struct{}
+}
+
+func newKeyTable() *keyTable {
+	return &keyTable{
+		keys:      make(map[keyRef]*sessKey),
+		encrypted: make(map[sessRef]struct{}),
+	}
+}
+
+func (kt *keyTable) add(ia addr.IA, sessId mgmt.SessionType, key *sigcrypto.Key) {
+	kt.Lock()
+	defer kt.Unlock()
+	now := time.Now()
+	for k, sk := range kt.keys {
+		if now.After(sk.expires) {
+			delete(kt.keys, k)
+		}
+	}
+	kt.keys[keyRef{ia: ia.IAInt(), sessId: sessId, id: key.Id}] = &sessKey{
+		Key:     key,
+		expires: now.Add(sigcrypto.KeyLifetime),
+		windows: make(map[int]*seqWindow),
+	}
+}
+
+// get returns the key with ID id of the session, or nil if there is no such
+// key or if it expired.
+func (kt *keyTable) get(ia addr.IA, sessId mgmt.SessionType, id uint32) *sessKey {
+	kt.Lock()
+	defer kt.Unlock()
+	sk, ok := kt.keys[keyRef{ia: ia.IAInt(), sessId: sessId, id: id}]
+	if !ok || time.Now().After(sk.expires) {
+		return nil
+	}
+	return sk
+}
+
+func (kt *keyTable) setEncrypted(ia addr.IA, sessIds []mgmt.SessionType) {
+	kt.Lock()
+	defer kt.Unlock()
+	for k := range kt.encrypted {
+		if k.ia == ia.IAInt() {
+			delete(kt.encrypted, k)
+		}
+	}
+	for _, sessId := range sessIds {
+		kt.encrypted[sessRef{ia: ia.IAInt(), sessId: sessId}] = struct{}{}
+	}
+}
+
+// isEncrypted returns whether the session must be encrypted.
+func (kt *keyTable) isEncrypted(ia addr.IA, sessId mgmt.SessionType) bool {
+	kt.Lock()
+	defer kt.Unlock()
+	_, ok := kt.encrypted[sessRef{ia: ia.IAInt(), sessId: sessId}]
+	return ok
+}
+
+// sessKey is a key of an encrypted session. It keeps track of the sequence
+// numbers of the frames decrypted with it, to detect replayed frames.
+type sessKey struct {
+	*sigcrypto.Key
+	expires time.Time
+	mtx     sync.Mutex
+	// windows contains the received sequence numbers of each epoch.
+	windows map[int]*seqWindow
+}
+
+// accept returns whether a frame with the given epoch and sequence number was
+// not received before with this key.
+func (sk *sessKey) accept(epoch, seqNr int) bool {
+	sk.mtx.Lock()
+	defer sk.mtx.Unlock()
+	w, ok := sk.windows[epoch]
+	if !ok {
+		w = &seqWindow{}
+		sk.windows[epoch] = w
+	}
+	return !w.Old(seqNr) && w.Insert(seqNr)
+}
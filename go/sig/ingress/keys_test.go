@@ -0,0 +1,63 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingress
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/sig/mgmt"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
+)
+
+func TestKeyTable(t *testing.T) {
+	Convey("keyTable", t, func() {
+		kt := newKeyTable()
+		ia := xtest.MustParseIA("1-ff00:0:110")
+		key, err := sigcrypto.NewKey(7, make([]byte, 16))
+		xtest.FailOnErr(t, err)
+		kt.add(ia, 1, key)
+		Convey("Keys are bound to the IA, session and ID", func() {
+			SoMsg("match", kt.get(ia, 1, 7), ShouldNotBeNil)
+			SoMsg("ia", kt.get(xtest.MustParseIA("1-ff00:0:111"), 1, 7), ShouldBeNil)
+			SoMsg("session", kt.get(ia, 2, 7), ShouldBeNil)
+			SoMsg("id", kt.get(ia, 1, 8), ShouldBeNil)
+		})
+		Convey("Expired keys are not returned", func() {
+			kt.get(ia, 1, 7).expires = time.Now().Add(-time.Second)
+			SoMsg("key", kt.get(ia, 1, 7), ShouldBeNil)
+		})
+		Convey("Replayed frames are rejected", func() {
+			sk := kt.get(ia, 1, 7)
+			SoMsg("first", sk.accept(100, 5), ShouldBeTrue)
+			SoMsg("replay", sk.accept(100, 5), ShouldBeFalse)
+			SoMsg("other epoch", sk.accept(101, 5), ShouldBeTrue)
+			SoMsg("new", sk.accept(100, 6), ShouldBeTrue)
+		})
+		Convey("Encrypted sessions are replaced", func() {
+			kt.setEncrypted(ia, []mgmt.SessionType{1, 2})
+			SoMsg("1", kt.isEncrypted(ia, 1), ShouldBeTrue)
+			SoMsg("2", kt.isEncrypted(ia, 2), ShouldBeTrue)
+			kt.setEncrypted(ia, []mgmt.SessionType{2})
+			SoMsg("1 removed", kt.isEncrypted(ia, 1), ShouldBeFalse)
+			SoMsg("2 kept", kt.isEncrypted(ia, 2), ShouldBeTrue)
+			kt.setEncrypted(ia, nil)
+			SoMsg("2 removed", kt.isEncrypted(ia, 2), ShouldBeFalse)
+		})
+	})
+}
@@ -36,6 +36,8 @@ type ReassemblyList struct {
 	markedForDeletion bool
 	entries           *list.List
 	buf               *bytes.Buffer
+	// seen tracks the received sequence numbers to discard duplicate frames.
+	seen seqWindow
 }
 
 // NewReassemblyList returns a ReassemblyList object for the given epoch and with
@@ -57,6 +59,17 @@ func NewReassemblyList(epoch int, capacity int, s sender) *ReassemblyList {
 // that involve the newly added frame. Completely processed frames get removed from the
 // list and released to the pool of frame buffers.
 func (l *ReassemblyList) Insert(frame *FrameBuf) {
+	// Discard frames that were already received, e.g., on another path.
+	if l.seen.Old(frame.seqNr) {
+		metrics.FramesTooOld.Inc()
+		frame.Release()
+		return
+	}
+	if !l.seen.Insert(frame.seqNr) {
+		metrics.FramesDuplicated.Inc()
+		frame.Release()
+		return
+	}
 	// If this is the first frame, write all complete packets to the wire and
 	// add the frame to the reassembly list if it contains a fragment at the end.
 	if l.entries.Len() == 0 {
@@ -0,0 +1,74 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingress
+
+// seqWindowSize is the number of sequence numbers up to the highest received
+// one, for which duplicate frames are detected.
+const seqWindowSize = 1024
+
+// seqWindow tracks the sequence numbers of the frames received in an epoch,
+// to detect duplicate frames, e.g., frames sent redundantly on multiple paths.
+type seqWindow struct {
+	initialized bool
+	// max is the highest sequence number received.
+	max int
+	// seen contains a bit for each sequence number in the window, indexed by
+	// the sequence number modulo seqWindowSize.
+	seen [seqWindowSize / 64]uint64
+}
+
+// Old returns whether seqNr is below the window, i.e., it cannot be
+// determined whether the frame is a duplicate.
+func (w *seqWindow) Old(seqNr int) bool {
+	return w.initialized && seqNr <= w.max-seqWindowSize
+}
+
+// Insert records seqNr, and returns false if it was already recorded before.
+// seqNr must not be below the window.
+func (w *seqWindow) Insert(seqNr int) bool {
+	switch {
+	case !w.initialized:
+		w.initialized = true
+		w.max = seqNr
+	case seqNr > w.max:
+		if seqNr-w.max >= seqWindowSize {
+			w.seen = [seqWindowSize / 64]uint64{}
+		} else {
+			for i := w.max + 1; i < seqNr; i++ {
+				w.clear(i)
+			}
+		}
+		w.max = seqNr
+	case w.isSet(seqNr):
+		return false
+	}
+	w.set(seqNr)
+	return true
+}
+
+func (w *seqWindow) isSet(seqNr int) bool {
+	i := seqNr % seqWindowSize
+	return w.seen[i/64]&(1<<uint(i%64)) != 0
+}
+
+func (w *seqWindow) set(seqNr int) {
+	i := seqNr % seqWindowSize
+	w.seen[i/64] |= 1 << uint(i%64)
+}
+
+func (w *seqWindow) clear(seqNr int) {
+	i := seqNr % seqWindowSize
+	w.seen[i/64] &^= 1 << uint(i%64)
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingress
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSeqWindow(t *testing.T) {
+	Convey("seqWindow", t, func() {
+		w := &seqWindow{}
+		Convey("First sequence number is new", func() {
+			SoMsg("old", w.Old(10), ShouldBeFalse)
+			SoMsg("insert", w.Insert(10), ShouldBeTrue)
+		})
+		Convey("Duplicates are detected", func() {
+			w.Insert(10)
+			w.Insert(11)
+			SoMsg("dup 10", w.Insert(10), ShouldBeFalse)
+			SoMsg("dup 11", w.Insert(11), ShouldBeFalse)
+		})
+		Convey("Reordered frames within the window are new", func() {
+			w.Insert(10)
+			w.Insert(13)
+			SoMsg("insert 12", w.Insert(12), ShouldBeTrue)
+			SoMsg("insert 11", w.Insert(11), ShouldBeTrue)
+			SoMsg("dup 12", w.Insert(12), ShouldBeFalse)
+		})
+		Convey("Bits of skipped sequence numbers are cleared", func() {
+			w.Insert(10)
+			w.Insert(10 + seqWindowSize - 1)
+			SoMsg("insert 10+size", w.Insert(10+seqWindowSize), ShouldBeTrue)
+			SoMsg("insert 11+size", w.Insert(11+seqWindowSize), ShouldBeTrue)
+		})
+		Convey("Large jumps reset the window", func() {
+			w.Insert(10)
+			w.Insert(10 + 3*seqWindowSize)
+			SoMsg("old 10", w.Old(10), ShouldBeTrue)
+			SoMsg("insert 11+2*size", w.Insert(11+2*seqWindowSize), ShouldBeTrue)
+		})
+		Convey("Sequence numbers below the window are old", func() {
+			w.Insert(seqWindowSize + 10)
+			SoMsg("old 10", w.Old(10), ShouldBeTrue)
+			SoMsg("old 11", w.Old(11), ShouldBeFalse)
+		})
+	})
+}
@@ -26,6 +26,8 @@ import (
 	"github.com/scionproto/scion/go/lib/snet"
 	"github.com/scionproto/scion/go/sig/metrics"
 	"github.com/scionproto/scion/go/sig/mgmt"
+	"github.com/scionproto/scion/go/sig/sigcmn"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
 )
 
 const (
@@ -106,6 +108,10 @@ func (w *Worker) Run() {
 // packets to the wire and then adding the frame to the corresponding reassembly
 // list if needed.
 func (w *Worker) processFrame(frame *FrameBuf) {
+	if !w.decrypt(frame) {
+		frame.Release()
+		return
+	}
 	epoch := int(common.Order.Uint16(frame.raw[1:3]))
 	seqNr := int(common.Order.UintN(frame.raw[3:6], 3))
 	index := int(common.Order.Uint16(frame.raw[6:8]))
@@ -125,6 +131,42 @@ func (w *Worker) processFrame(frame *FrameBuf) {
 	rlist.Insert(frame)
 }
 
+// decrypt decrypts the frame in place if it is encrypted. It returns false if
+// the frame must be dropped, i.e., if it cannot be authenticated, if it was
+// replayed, or if it is a cleartext frame of an encrypted session.
+func (w *Worker) decrypt(frame *FrameBuf) bool {
+	raw := frame.raw[:frame.frameLen]
+	if len(raw) < sigcmn.SIGHdrSize {
+		w.Error("Frame too short", "len", len(raw))
+		return false
+	}
+	if !sigcrypto.IsEncrypted(raw) {
+		if keys.isEncrypted(w.Remote.IA, w.SessId) {
+			w.Error("Dropping cleartext frame of encrypted session")
+			return false
+		}
+		return true
+	}
+	key := keys.get(w.Remote.IA, w.SessId, sigcrypto.FrameKeyId(raw))
+	if key == nil {
+		w.Error("Dropping frame with unknown key", "keyId", sigcrypto.FrameKeyId(raw))
+		return false
+	}
+	raw, err := key.Open(raw)
+	if err != nil {
+		w.Error("Dropping frame", "err", err)
+		return false
+	}
+	epoch := int(common.Order.Uint16(raw[1:3]))
+	seqNr := int(common.Order.UintN(raw[3:6], 3))
+	if !key.accept(epoch, seqNr) {
+		// Duplicates of frames sent on multiple paths are expected.
+		return false
+	}
+	frame.frameLen = len(raw)
+	return true
+}
+
 func (w *Worker) getRlist(epoch int) *ReassemblyList {
 	rlist, ok := w.rlists[epoch]
 	if !ok {
@@ -0,0 +1,202 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sigapi implements the runtime management API of the SIG.
+//
+// The API is served as JSON over HTTP, on its own address (sig.APIAddr). The
+// requests are not authenticated, so the address defaults to the loopback
+// interface:
+//
+//  GET    /api/v1/ases                                 List remote ASes and their state.
+//  GET    /api/v1/ases/<ia>                            Show a single remote AS.
+//  PUT    /api/v1/ases/<ia>                            Add or replace a remote AS (config.ASEntry).
+//  DELETE /api/v1/ases/<ia>                            Remove a remote AS.
+//  POST   /api/v1/ases/<ia>/nets                       Add a prefix (e.g. "10.0.1.0/24").
+//  DELETE /api/v1/ases/<ia>/nets                       Remove a prefix.
+//  POST   /api/v1/ases/<ia>/sessions/<id>/switchpath   Force a session to switch paths.
+//
+// Changes are applied to the SIG config through a Manager, so they take
+// effect in the same way as a config reload, and are persisted to the config
+// file. Mutating requests reply with the resulting config version.
+package sigapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/httpapi"
+	"github.com/scionproto/scion/go/sig/base/core"
+	"github.com/scionproto/scion/go/sig/config"
+	"github.com/scionproto/scion/go/sig/mgmt"
+)
+
+const prefix = "/api/v1/ases"
+
+const (
+	badRequest = http.StatusBadRequest
+	notFound   = http.StatusNotFound
+)
+
+// Init starts serving the API on address. The API is not registered on the
+// default HTTP mux, such that it is not exposed on the metrics address.
+func Init(m *Manager, address string) error {
+	return httpapi.Serve(address, newMux(m))
+}
+
+func newMux(m *Manager) *http.ServeMux {
+	h := httpapi.HandlerFunc((&handler{mgr: m}).route)
+	mux := http.NewServeMux()
+	mux.Handle(prefix, h)
+	mux.Handle(prefix+"/", h)
+	return mux
+}
+
+type handler struct {
+	mgr *Manager
+}
+
+func (h *handler) route(r *http.Request) (interface{}, error) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			return nil, httpapi.ErrMethod(r.Method)
+		}
+		return core.Map.Status(), nil
+	}
+	parts := strings.Split(path, "/")
+	ia, err := addr.IAFromString(parts[0])
+	if err != nil {
+		return nil, httpapi.NewError(badRequest, err)
+	}
+	switch {
+	case len(parts) == 1:
+		return h.as(r, ia)
+	case len(parts) == 2 && parts[1] == "nets":
+		return h.nets(r, ia)
+	case len(parts) == 4 && parts[1] == "sessions" && parts[3] == "switchpath":
+		return h.switchPath(r, ia, parts[2])
+	}
+	return nil, httpapi.ErrPath(r.URL.Path)
+}
+
+func (h *handler) as(r *http.Request, ia addr.IA) (interface{}, error) {
+	switch r.Method {
+	case http.MethodGet:
+		ae := core.Map.ASEntry(ia)
+		if ae == nil {
+			return nil, errUnknownAS(ia)
+		}
+		return ae.Status(), nil
+	case http.MethodPut:
+		aeCfg := &config.ASEntry{}
+		if err := decode(r, aeCfg); err != nil {
+			return nil, err
+		}
+		return h.update(func(cfg *config.Cfg) error {
+			if cfg.ASes == nil {
+				cfg.ASes = make(map[addr.IA]*config.ASEntry)
+			}
+			cfg.ASes[ia] = aeCfg
+			return nil
+		})
+	case http.MethodDelete:
+		return h.update(func(cfg *config.Cfg) error {
+			if _, ok := cfg.ASes[ia]; !ok {
+				return errUnknownAS(ia)
+			}
+			delete(cfg.ASes, ia)
+			return nil
+		})
+	}
+	return nil, httpapi.ErrMethod(r.Method)
+}
+
+func (h *handler) nets(r *http.Request, ia addr.IA) (interface{}, error) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		return nil, httpapi.ErrMethod(r.Method)
+	}
+	ipnet := &config.IPNet{}
+	if err := decode(r, ipnet); err != nil {
+		return nil, err
+	}
+	return h.update(func(cfg *config.Cfg) error {
+		aeCfg, ok := cfg.ASes[ia]
+		if !ok {
+			return errUnknownAS(ia)
+		}
+		idx := -1
+		for i, n := range aeCfg.Nets {
+			if n.String() == ipnet.String() {
+				idx = i
+				break
+			}
+		}
+		if r.Method == http.MethodPost {
+			if idx >= 0 {
+				return httpapi.NewError(badRequest, common.NewBasicError("Prefix already present",
+					nil, "ia", ia, "net", ipnet))
+			}
+			aeCfg.Nets = append(aeCfg.Nets, ipnet)
+			return nil
+		}
+		if idx < 0 {
+			return httpapi.NewError(notFound, common.NewBasicError("Unknown prefix", nil,
+				"ia", ia, "net", ipnet))
+		}
+		aeCfg.Nets = append(aeCfg.Nets[:idx], aeCfg.Nets[idx+1:]...)
+		return nil
+	})
+}
+
+func (h *handler) switchPath(r *http.Request, ia addr.IA, raw string) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, httpapi.ErrMethod(r.Method)
+	}
+	id, err := strconv.ParseUint(raw, 10, 8)
+	if err != nil {
+		return nil, httpapi.NewError(badRequest, common.NewBasicError("Invalid session id", err,
+			"id", raw))
+	}
+	if err := core.Map.SwitchPath(ia, mgmt.SessionType(id)); err != nil {
+		return nil, httpapi.NewError(notFound, err)
+	}
+	return struct{}{}, nil
+}
+
+func (h *handler) update(f func(cfg *config.Cfg) error) (interface{}, error) {
+	version, err := h.mgr.Update(f)
+	if err != nil {
+		return nil, err
+	}
+	return &updateReply{ConfigVersion: version}, nil
+}
+
+type updateReply struct {
+	ConfigVersion uint64
+}
+
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return httpapi.NewError(badRequest, common.NewBasicError("Unable to parse request", err))
+	}
+	return nil
+}
+
+func errUnknownAS(ia addr.IA) error {
+	return httpapi.NewError(notFound, common.NewBasicError("Unknown AS", nil, "ia", ia))
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/sig/config"
+	"github.com/scionproto/scion/go/sig/mgmt"
+)
+
+func TestManagerUpdate(t *testing.T) {
+	Convey("Manager.Update", t, func() {
+		m, path, cleanF := newTestManager(t)
+		defer cleanF()
+		ia := xtest.MustParseIA("1-ff00:0:112")
+
+		Convey("Applies and persists the config with a new version", func() {
+			version, err := m.Update(func(cfg *config.Cfg) error {
+				cfg.ASes[ia] = &config.ASEntry{}
+				return nil
+			})
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("version", version, ShouldEqual, 2)
+			SoMsg("applied", m.cfg.ASes, ShouldContainKey, ia)
+			saved, err := config.LoadFromFile(path)
+			xtest.FailOnErr(t, err)
+			SoMsg("saved version", saved.ConfigVersion, ShouldEqual, 2)
+			SoMsg("saved", saved.ASes, ShouldContainKey, ia)
+		})
+		Convey("Keeps the config if the change fails", func() {
+			_, err := m.Update(func(cfg *config.Cfg) error {
+				cfg.ASes[ia] = &config.ASEntry{}
+				return errUnknownAS(ia)
+			})
+			SoMsg("err", err, ShouldNotBeNil)
+			SoMsg("version", m.cfg.ConfigVersion, ShouldEqual, 1)
+			SoMsg("unchanged", m.cfg.ASes, ShouldNotContainKey, ia)
+		})
+		Convey("Keeps the config if the result is invalid", func() {
+			_, err := m.Update(func(cfg *config.Cfg) error {
+				cfg.ASes[ia] = &config.ASEntry{
+					Sessions: map[mgmt.SessionType]string{1: "unknown"},
+				}
+				return nil
+			})
+			SoMsg("err", err, ShouldNotBeNil)
+			SoMsg("version", m.cfg.ConfigVersion, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestHandlerUpdates(t *testing.T) {
+	Convey("Mutating API requests", t, func() {
+		m, _, cleanF := newTestManager(t)
+		defer cleanF()
+		mux := newMux(m)
+		tests := []struct {
+			Name    string
+			Method  string
+			Path    string
+			Body    string
+			Code    int
+			Version uint64
+		}{
+			{
+				Name:    "add AS",
+				Method:  http.MethodPut,
+				Path:    "/api/v1/ases/1-ff00:0:112",
+				Body:    `{"Nets": ["10.0.2.0/24"]}`,
+				Code:    http.StatusOK,
+				Version: 2,
+			},
+			{
+				Name:    "add prefix",
+				Method:  http.MethodPost,
+				Path:    "/api/v1/ases/1-ff00:0:111/nets",
+				Body:    `"10.0.3.0/24"`,
+				Code:    http.StatusOK,
+				Version: 2,
+			},
+			{
+				Name:    "remove prefix",
+				Method:  http.MethodDelete,
+				Path:    "/api/v1/ases/1-ff00:0:111/nets",
+				Body:    `"10.0.1.0/24"`,
+				Code:    http.StatusOK,
+				Version: 2,
+			},
+			{
+				Name:    "remove AS",
+				Method:  http.MethodDelete,
+				Path:    "/api/v1/ases/1-ff00:0:111",
+				Code:    http.StatusOK,
+				Version: 2,
+			},
+			{
+				Name:   "duplicate prefix",
+				Method: http.MethodPost,
+				Path:   "/api/v1/ases/1-ff00:0:111/nets",
+				Body:   `"10.0.1.0/24"`,
+				Code:   http.StatusBadRequest,
+			},
+			{
+				Name:   "unknown AS",
+				Method: http.MethodDelete,
+				Path:   "/api/v1/ases/1-ff00:0:112",
+				Code:   http.StatusNotFound,
+			},
+			{
+				Name:   "invalid body",
+				Method: http.MethodPut,
+				Path:   "/api/v1/ases/1-ff00:0:112",
+				Body:   `{`,
+				Code:   http.StatusBadRequest,
+			},
+			{
+				Name:   "invalid method",
+				Method: http.MethodPatch,
+				Path:   "/api/v1/ases/1-ff00:0:111",
+				Code:   http.StatusMethodNotAllowed,
+			},
+		}
+		for _, test := range tests {
+			Convey(test.Name, func() {
+				req := httptest.NewRequest(test.Method, test.Path, strings.NewReader(test.Body))
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+				SoMsg("code", rec.Code, ShouldEqual, test.Code)
+				if test.Code != http.StatusOK {
+					SoMsg("version", m.cfg.ConfigVersion, ShouldEqual, 1)
+					return
+				}
+				var reply updateReply
+				xtest.FailOnErr(t, json.Unmarshal(rec.Body.Bytes(), &reply))
+				SoMsg("reply version", reply.ConfigVersion, ShouldEqual, test.Version)
+				SoMsg("version", m.cfg.ConfigVersion, ShouldEqual, test.Version)
+			})
+		}
+	})
+}
+
+// newTestManager returns a manager with a loaded config file that contains
+// the remote AS 1-ff00:0:111. Configs are not applied to the SIG.
+func newTestManager(t *testing.T) (*Manager, string, func()) {
+	dir, cleanF := xtest.MustTempDir("", "sigapi")
+	path := filepath.Join(dir, "sig.json")
+	cfg := &config.Cfg{
+		ASes: map[addr.IA]*config.ASEntry{
+			xtest.MustParseIA("1-ff00:0:111"): {
+				Nets: []*config.IPNet{mustParseIPNet(t, "10.0.1.0/24")},
+			},
+		},
+		ConfigVersion: 1,
+	}
+	xtest.FailOnErr(t, cfg.SaveToFile(path))
+	m := &Manager{path: path, apply: func(*config.Cfg) bool { return true }}
+	if !m.Load() {
+		cleanF()
+		t.Fatal("Unable to load config")
+	}
+	return m, path, cleanF
+}
+
+func mustParseIPNet(t *testing.T, s string) *config.IPNet {
+	ipnet := &config.IPNet{}
+	xtest.FailOnErr(t, json.Unmarshal([]byte(`"`+s+`"`), ipnet))
+	return ipnet
+}
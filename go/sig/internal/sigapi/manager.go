@@ -0,0 +1,96 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigapi
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/httpapi"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/sig/base/core"
+	"github.com/scionproto/scion/go/sig/config"
+	"github.com/scionproto/scion/go/sig/metrics"
+)
+
+// Manager serializes the changes to the SIG config, whether they are loaded
+// from the config file or made through the management API. Changes made
+// through the API are persisted to the config file with an incremented config
+// version, such that they survive reloads and restarts.
+type Manager struct {
+	mtx  sync.Mutex
+	path string
+	cfg  *config.Cfg
+	// apply applies a config to the SIG, and reports whether it was fully
+	// applied.
+	apply func(cfg *config.Cfg) bool
+}
+
+func NewManager(path string) *Manager {
+	return &Manager{path: path, apply: core.Map.ReloadConfig}
+}
+
+// Load loads the config file, and applies it to the SIG.
+func (m *Manager) Load() bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	cfg, err := config.LoadFromFile(m.path)
+	if err != nil {
+		log.Error("loadConfig: Failed", "err", err)
+		return false
+	}
+	// The config is kept even if it is only partially applied, as it
+	// reflects the state of the config file.
+	m.cfg = cfg
+	if !m.apply(cfg) {
+		return false
+	}
+	atomic.StoreUint64(&metrics.ConfigVersion, cfg.ConfigVersion)
+	return true
+}
+
+// Update applies f to a copy of the current config. The modified config is
+// validated, persisted and applied to the SIG with an incremented config
+// version. The new config version is returned.
+func (m *Manager) Update(f func(cfg *config.Cfg) error) (uint64, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.cfg == nil {
+		return 0, common.NewBasicError("No config loaded", nil)
+	}
+	cfg, err := m.cfg.Copy()
+	if err != nil {
+		return 0, err
+	}
+	if err := f(cfg); err != nil {
+		return 0, err
+	}
+	cfg.ConfigVersion = m.cfg.ConfigVersion + 1
+	if err := cfg.Validate(); err != nil {
+		return 0, httpapi.NewError(badRequest, common.NewBasicError("Invalid SIG config", err))
+	}
+	if err := cfg.SaveToFile(m.path); err != nil {
+		return 0, err
+	}
+	m.cfg = cfg
+	if !m.apply(cfg) {
+		return 0, common.NewBasicError("Config saved, but not fully applied", nil,
+			"version", cfg.ConfigVersion)
+	}
+	atomic.StoreUint64(&metrics.ConfigVersion, cfg.ConfigVersion)
+	log.Info("Config updated through management API", "version", cfg.ConfigVersion)
+	return cfg.ConfigVersion, nil
+}
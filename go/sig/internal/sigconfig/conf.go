@@ -23,6 +23,7 @@ import (
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/config"
 	"github.com/scionproto/scion/go/lib/env"
+	"github.com/scionproto/scion/go/lib/truststorage"
 )
 
 const (
@@ -30,6 +31,7 @@ const (
 	DefaultEncapPort   = 30056
 	DefaultTunName     = "sig"
 	DefaultTunRTableId = 11
+	DefaultAPIAddr     = "127.0.0.1:30457"
 )
 
 var _ config.Config = (*Config)(nil)
@@ -39,6 +41,7 @@ type Config struct {
 	Metrics env.Metrics
 	Sciond  env.SciondClient `toml:"sd_client"`
 	Sig     SigConf
+	TrustDB truststorage.TrustDBConf
 }
 
 func (cfg *Config) InitDefaults() {
@@ -47,6 +50,7 @@ func (cfg *Config) InitDefaults() {
 		&cfg.Metrics,
 		&cfg.Sciond,
 		&cfg.Sig,
+		&cfg.TrustDB,
 	)
 }
 
@@ -56,6 +60,7 @@ func (cfg *Config) Validate() error {
 		&cfg.Metrics,
 		&cfg.Sciond,
 		&cfg.Sig,
+		&cfg.TrustDB,
 	)
 }
 
@@ -65,6 +70,7 @@ func (cfg *Config) Sample(dst io.Writer, path config.Path, _ config.CtxMap) {
 		&cfg.Metrics,
 		&cfg.Sciond,
 		&cfg.Sig,
+		&cfg.TrustDB,
 	)
 }
 
@@ -82,7 +88,7 @@ type SigConf struct {
 	SIGConfig string
 	// IA the local IA (required)
 	IA addr.IA
-	// IP the bind IP address (required)
+	// IP the bind IP address, IPv4 or IPv6 (required)
 	IP net.IP
 	// Control data port, e.g. keepalives. (default DefaultCtrlPort)
 	CtrlPort uint16
@@ -98,6 +104,18 @@ type SigConf struct {
 	SrcIP4 net.IP
 	// IPv6 source address hint to put into routing table.
 	SrcIP6 net.IP
+	// Directory containing the keys and certificates of the AS, used to
+	// authenticate the key exchanges of encrypted sessions. Encrypted sessions
+	// are not supported if unset. (default "")
+	ConfigDir string
+	// APIAddr is the address the management API is served on. The API is not
+	// authenticated, so it should only be reachable locally.
+	// (default DefaultAPIAddr)
+	APIAddr string
+	// VerifyRevocations indicates whether SCMP revocations are only acted
+	// upon once their DRKey authentication has been verified. This requires
+	// that the border routers authenticate their SCMP errors. (default false)
+	VerifyRevocations bool
 }
 
 // InitDefaults sets the default values to unset values.
@@ -114,6 +132,9 @@ func (cfg *SigConf) InitDefaults() {
 	if cfg.TunRTableId == 0 {
 		cfg.TunRTableId = DefaultTunRTableId
 	}
+	if cfg.APIAddr == "" {
+		cfg.APIAddr = DefaultAPIAddr
+	}
 }
 
 // Validate validate the config and returns an error if a value is not valid.
@@ -130,9 +151,18 @@ func (cfg *SigConf) Validate() error {
 	if cfg.IA.IsWildcard() {
 		return common.NewBasicError("Wildcard IA not allowed", nil)
 	}
-	if cfg.IP.IsUnspecified() {
+	if len(cfg.IP) == 0 || cfg.IP.IsUnspecified() {
 		return common.NewBasicError("IP must be set", nil)
 	}
+	if len(cfg.SrcIP4) > 0 && cfg.SrcIP4.To4() == nil {
+		return common.NewBasicError("SrcIP4 must be an IPv4 address", nil, "ip", cfg.SrcIP4)
+	}
+	if len(cfg.SrcIP6) > 0 && cfg.SrcIP6.To4() != nil {
+		return common.NewBasicError("SrcIP6 must be an IPv6 address", nil, "ip", cfg.SrcIP6)
+	}
+	if _, _, err := net.SplitHostPort(cfg.APIAddr); err != nil {
+		return common.NewBasicError("Invalid APIAddr", err, "addr", cfg.APIAddr)
+	}
 	return nil
 }
 
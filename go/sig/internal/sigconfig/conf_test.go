@@ -23,6 +23,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/scionproto/scion/go/lib/env/envtest"
+	"github.com/scionproto/scion/go/lib/truststorage/truststoragetest"
 	"github.com/scionproto/scion/go/lib/xtest"
 )
 
@@ -40,9 +41,51 @@ func TestConfigSample(t *testing.T) {
 	})
 }
 
+func TestSigConfValidate(t *testing.T) {
+	Convey("Validate", t, func() {
+		cfg := &SigConf{
+			ID:        "sig4",
+			SIGConfig: "/etc/scion/sig/sig.json",
+			IA:        xtest.MustParseIA("1-ff00:0:113"),
+			IP:        net.ParseIP("192.0.2.100"),
+			APIAddr:   DefaultAPIAddr,
+		}
+		Convey("IPv4 address is accepted", func() {
+			SoMsg("err", cfg.Validate(), ShouldBeNil)
+		})
+		Convey("IPv6 address is accepted", func() {
+			cfg.IP = net.ParseIP("2001:db8::100")
+			cfg.SrcIP4 = net.ParseIP("192.0.2.1")
+			cfg.SrcIP6 = net.ParseIP("2001:db8::1")
+			SoMsg("err", cfg.Validate(), ShouldBeNil)
+		})
+		Convey("Missing address is rejected", func() {
+			cfg.IP = nil
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("Unspecified address is rejected", func() {
+			cfg.IP = net.IPv6unspecified
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("IPv6 source hint for IPv4 is rejected", func() {
+			cfg.SrcIP4 = net.ParseIP("2001:db8::1")
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("IPv4 source hint for IPv6 is rejected", func() {
+			cfg.SrcIP6 = net.ParseIP("192.0.2.1")
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+		Convey("API address without port is rejected", func() {
+			cfg.APIAddr = "127.0.0.1"
+			SoMsg("err", cfg.Validate(), ShouldNotBeNil)
+		})
+	})
+}
+
 func InitTestConfig(cfg *Config) {
 	envtest.InitTest(nil, &cfg.Logging, &cfg.Metrics, &cfg.Sciond)
 	InitTestSigConf(&cfg.Sig)
+	truststoragetest.InitTestConfig(&cfg.TrustDB)
 }
 
 func InitTestSigConf(cfg *SigConf) {
@@ -52,6 +95,7 @@ func InitTestSigConf(cfg *SigConf) {
 func CheckTestConfig(cfg *Config, id string) {
 	envtest.CheckTest(nil, &cfg.Logging, &cfg.Metrics, &cfg.Sciond, id)
 	CheckTestSigConf(&cfg.Sig, id)
+	truststoragetest.CheckTestConfig(&cfg.TrustDB, id)
 }
 
 func CheckTestSigConf(cfg *SigConf, id string) {
@@ -64,4 +108,7 @@ func CheckTestSigConf(cfg *SigConf, id string) {
 	SoMsg("Dispatcher correct", cfg.Dispatcher, ShouldEqual, "")
 	SoMsg("Tun correct", cfg.Tun, ShouldEqual, DefaultTunName)
 	SoMsg("TunRTableId correct", cfg.TunRTableId, ShouldEqual, DefaultTunRTableId)
+	SoMsg("ConfigDir correct", cfg.ConfigDir, ShouldEqual, "/etc/scion")
+	SoMsg("APIAddr correct", cfg.APIAddr, ShouldEqual, DefaultAPIAddr)
+	SoMsg("VerifyRevocations correct", cfg.VerifyRevocations, ShouldBeFalse)
 }
@@ -26,7 +26,7 @@ SIGConfig = "/etc/scion/sig/sig.json"
 # The local IA. (required)
 IA = "1-ff00:0:113"
 
-# The bind IP address. (required)
+# The bind IP address, IPv4 or IPv6. (required)
 IP = "192.0.2.100"
 
 # Control data port, e.g. keepalives. (default 30256)
@@ -43,4 +43,18 @@ Tun = "sig"
 
 # Id of the routing table. (default 11)
 TunRTableId = 11
+
+# Directory containing the keys and certificates of the AS, used to
+# authenticate the key exchanges of encrypted sessions. Encrypted sessions are
+# not supported if unset. (default "")
+ConfigDir = "/etc/scion"
+
+# Address of the management API. The API is not authenticated, so it should
+# only be reachable locally. (default "127.0.0.1:30457")
+APIAddr = "127.0.0.1:30457"
+
+# Only act on SCMP revocations once their DRKey authentication has been
+# verified. This requires that the border routers authenticate their SCMP
+# errors. (default false)
+VerifyRevocations = false
 `
@@ -22,7 +22,6 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"os/user"
-	"sync/atomic"
 
 	"github.com/BurntSushi/toml"
 	"github.com/syndtr/gocapability/capability"
@@ -32,20 +31,22 @@ import (
 	"github.com/scionproto/scion/go/lib/fatal"
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/sig/base"
-	"github.com/scionproto/scion/go/sig/base/core"
-	"github.com/scionproto/scion/go/sig/config"
 	"github.com/scionproto/scion/go/sig/disp"
 	"github.com/scionproto/scion/go/sig/egress"
+	"github.com/scionproto/scion/go/sig/egress/icmp"
 	"github.com/scionproto/scion/go/sig/egress/reader"
 	"github.com/scionproto/scion/go/sig/ingress"
+	"github.com/scionproto/scion/go/sig/internal/sigapi"
 	"github.com/scionproto/scion/go/sig/internal/sigconfig"
 	"github.com/scionproto/scion/go/sig/metrics"
 	"github.com/scionproto/scion/go/sig/sigcmn"
+	"github.com/scionproto/scion/go/sig/sigcrypto"
 	"github.com/scionproto/scion/go/sig/xnet"
 )
 
 var (
-	cfg sigconfig.Config
+	cfg    sigconfig.Config
+	cfgMgr *sigapi.Manager
 )
 
 func init() {
@@ -80,6 +81,7 @@ func realMain() int {
 		log.Crit("Unable to create & configure TUN device", "err", err)
 		return 1
 	}
+	icmp.Init(tunIO)
 	if err := setup(); err != nil {
 		log.Crit("Setup failed", "err", err)
 		return 1
@@ -90,8 +92,8 @@ func realMain() int {
 	}()
 	environment := env.SetupEnv(
 		func() {
-			success := loadConfig(cfg.Sig.SIGConfig)
-			// Errors already logged in loadConfig
+			success := cfgMgr.Load()
+			// Errors already logged in Load
 			log.Info("reloadOnSIGHUP: reload done", "success", success)
 		},
 	)
@@ -192,27 +194,32 @@ func setup() error {
 	if err := sigcmn.Init(cfg.Sig, cfg.Sciond); err != nil {
 		return common.NewBasicError("Error during initialization", err)
 	}
+	if cfg.Sig.ConfigDir != "" {
+		if err := setupCrypto(); err != nil {
+			return common.NewBasicError("Unable to initialize crypto", err)
+		}
+	}
 	egress.Init()
 	disp.Init(sigcmn.CtrlConn)
 	// Parse sig config
-	if loadConfig(cfg.Sig.SIGConfig) != true {
+	cfgMgr = sigapi.NewManager(cfg.Sig.SIGConfig)
+	if cfgMgr.Load() != true {
 		return common.NewBasicError("Unable to load sig config on startup", nil)
 	}
+	if err := sigapi.Init(cfgMgr, cfg.Sig.APIAddr); err != nil {
+		return common.NewBasicError("Unable to start SIG API", err)
+	}
 	return nil
 }
 
-func loadConfig(path string) bool {
-	cfg, err := config.LoadFromFile(path)
+// setupCrypto loads the keys and certificates used to authenticate the key
+// exchanges of encrypted sessions.
+func setupCrypto() error {
+	trustDB, err := cfg.TrustDB.New()
 	if err != nil {
-		log.Error("loadConfig: Failed", "err", err)
-		return false
-	}
-	ok := core.Map.ReloadConfig(cfg)
-	if !ok {
-		return false
+		return common.NewBasicError("Unable to initialize trustDB", err)
 	}
-	atomic.StoreUint64(&metrics.ConfigVersion, cfg.ConfigVersion)
-	return true
+	return sigcrypto.Init(cfg.Sig.ConfigDir, trustDB, cfg.Sig.IA)
 }
 
 func spawnIngressDispatcher(tunIO io.ReadWriteCloser) {
@@ -46,6 +46,18 @@ var (
 	FramesDuplicated   prometheus.Counter
 
 	EgressRxQueueFull *prometheus.CounterVec
+	PktsTooBig        *prometheus.CounterVec
+	SessionMTU        *prometheus.GaugeVec
+
+	QueuePkts  *prometheus.CounterVec
+	QueueBytes *prometheus.CounterVec
+	QueueDrops *prometheus.CounterVec
+)
+
+// Reasons for dropping packets in egress queues.
+const (
+	DropQueueFull = "queue_full"
+	DropRED       = "red"
 )
 
 // Version number of loaded config, atomic
@@ -80,6 +92,16 @@ func Init(elem string) {
 
 	EgressRxQueueFull = newCVec("egress_recv_queue_full_total",
 		"Egress packets dropped due to full queues.", []string{"IA"})
+	PktsTooBig = newCVec("pkts_too_big_total",
+		"Egress packets dropped because they exceed the session MTU.", iaLabels)
+	SessionMTU = prom.NewGaugeVec(namespace, "", "session_mtu_bytes",
+		"Largest packet a session forwards without signalling that it is too big.", iaLabels)
+
+	queueLabels := []string{"IA", "sessId", "queue"}
+	QueuePkts = newCVec("queue_pkts_total", "Number of packets enqueued.", queueLabels)
+	QueueBytes = newCVec("queue_bytes_total", "Number of packet bytes enqueued.", queueLabels)
+	QueueDrops = newCVec("queue_drops_total", "Number of packets dropped by egress queues.",
+		append(queueLabels, "reason"))
 
 	// Initialize ringbuf metrics.
 	ringbuf.InitMetrics("sig", []string{"ringId", "sessId"})
@@ -0,0 +1,56 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mgmt
+
+import (
+	"fmt"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var _ proto.Cerealizable = (*KeyExchange)(nil)
+
+// KeyExchange establishes the key of an encrypted session. The initiator
+// attaches it to its poll requests, the remote SIG answers with its own
+// ephemeral public key in the poll reply. Both messages are signed with the
+// key of the sender's AS, whose certificate chain is included.
+type KeyExchange struct {
+	KeyId  uint32
+	PubKey []byte
+	// PeerPubKey is the ephemeral public key of the initiator. It is only set
+	// in replies.
+	PeerPubKey []byte
+	Chain      common.RawBytes
+}
+
+func NewKeyExchange(keyId uint32, pubKey, peerPubKey []byte,
+	chain common.RawBytes) *KeyExchange {
+
+	return &KeyExchange{KeyId: keyId, PubKey: pubKey, PeerPubKey: peerPubKey, Chain: chain}
+}
+
+func (kx *KeyExchange) ProtoId() proto.ProtoIdType {
+	return proto.SIGKeyExchange_TypeID
+}
+
+func (kx *KeyExchange) Write(b common.RawBytes) (int, error) {
+	return proto.WriteRoot(kx, b)
+}
+
+func (kx *KeyExchange) String() string {
+	return fmt.Sprintf("KeyId: %d PubKey: %x PeerPubKey: %x", kx.KeyId, kx.PubKey,
+		kx.PeerPubKey)
+}
@@ -26,6 +26,12 @@ var _ proto.Cerealizable = (*Poll)(nil)
 type Poll struct {
 	Addr    *Addr
 	Session SessionType
+	// Prefixes contains the networks announced by the sender. It is only set
+	// in replies.
+	Prefixes *Prefixes
+	// KeyExchange establishes the key of an encrypted session. It is only set
+	// for encrypted sessions.
+	KeyExchange *KeyExchange
 }
 
 func newPoll(a *Addr, s SessionType) *Poll {
@@ -41,7 +47,14 @@ func (p *Poll) Write(b common.RawBytes) (int, error) {
 }
 
 func (p *Poll) String() string {
-	return fmt.Sprintf("%s Session: %s", p.Addr, p.Session)
+	s := fmt.Sprintf("%s Session: %s", p.Addr, p.Session)
+	if p.Prefixes != nil {
+		s += fmt.Sprintf(" Prefixes: %s", p.Prefixes)
+	}
+	if p.KeyExchange != nil {
+		s += fmt.Sprintf(" KeyExchange: %s", p.KeyExchange)
+	}
+	return s
 }
 
 type PollReq struct {
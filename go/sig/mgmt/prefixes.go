@@ -0,0 +1,123 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mgmt
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var _ proto.Cerealizable = (*Prefixes)(nil)
+
+// Prefixes contains the networks a SIG serves locally, and announces to remote
+// SIGs in its poll replies. The version changes whenever the set of networks
+// changes, such that receivers can skip unchanged announcements.
+type Prefixes struct {
+	Version uint64
+	Nets    []*Net
+}
+
+func NewPrefixes(version uint64, ipnets []*net.IPNet) *Prefixes {
+	p := &Prefixes{Version: version, Nets: make([]*Net, 0, len(ipnets))}
+	for _, ipnet := range ipnets {
+		p.Nets = append(p.Nets, NewNet(ipnet))
+	}
+	return p
+}
+
+// IPNets returns the announced networks. An error is returned if any of the
+// networks is invalid.
+func (p *Prefixes) IPNets() ([]*net.IPNet, error) {
+	ipnets := make([]*net.IPNet, 0, len(p.Nets))
+	for _, n := range p.Nets {
+		ipnet, err := n.IPNet()
+		if err != nil {
+			return nil, err
+		}
+		ipnets = append(ipnets, ipnet)
+	}
+	return ipnets, nil
+}
+
+func (p *Prefixes) ProtoId() proto.ProtoIdType {
+	return proto.SIGPrefixes_TypeID
+}
+
+func (p *Prefixes) Write(b common.RawBytes) (int, error) {
+	return proto.WriteRoot(p, b)
+}
+
+func (p *Prefixes) String() string {
+	nets := make([]string, 0, len(p.Nets))
+	for _, n := range p.Nets {
+		nets = append(nets, n.String())
+	}
+	return fmt.Sprintf("Version: %d Nets: [%s]", p.Version, strings.Join(nets, ", "))
+}
+
+var _ proto.Cerealizable = (*Net)(nil)
+
+// Net is the wire representation of a network announced by a SIG.
+type Net struct {
+	Ip   []byte
+	Ones uint8
+}
+
+func NewNet(ipnet *net.IPNet) *Net {
+	ones, _ := ipnet.Mask.Size()
+	ip := ipnet.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return &Net{Ip: append([]byte(nil), ip...), Ones: uint8(ones)}
+}
+
+// IPNet returns the network. An error is returned if the address length or
+// the prefix length are invalid, or if the address has host bits set.
+func (n *Net) IPNet() (*net.IPNet, error) {
+	bits := len(n.Ip) * 8
+	if bits != net.IPv4len*8 && bits != net.IPv6len*8 {
+		return nil, common.NewBasicError("Invalid network address length", nil,
+			"len", len(n.Ip))
+	}
+	if int(n.Ones) > bits {
+		return nil, common.NewBasicError("Invalid network prefix length", nil,
+			"ones", n.Ones, "bits", bits)
+	}
+	ipnet := &net.IPNet{
+		IP:   append(net.IP(nil), n.Ip...),
+		Mask: net.CIDRMask(int(n.Ones), bits),
+	}
+	if !ipnet.IP.Equal(ipnet.IP.Mask(ipnet.Mask)) {
+		return nil, common.NewBasicError("Network is not canonical", nil, "net", ipnet)
+	}
+	return ipnet, nil
+}
+
+func (n *Net) ProtoId() proto.ProtoIdType {
+	return proto.SIGNet_TypeID
+}
+
+func (n *Net) Write(b common.RawBytes) (int, error) {
+	return proto.WriteRoot(n, b)
+}
+
+func (n *Net) String() string {
+	return fmt.Sprintf("%s/%d", net.IP(n.Ip), n.Ones)
+}
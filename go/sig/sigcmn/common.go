@@ -22,7 +22,10 @@ import (
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/env"
+	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/pathmgr"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/scmp"
 	"github.com/scionproto/scion/go/lib/snet"
 	"github.com/scionproto/scion/go/lib/sock/reliable"
 	"github.com/scionproto/scion/go/sig/internal/sigconfig"
@@ -62,7 +65,7 @@ func Init(cfg sigconfig.SigConf, sdCfg env.SciondClient) error {
 	}
 	PathMgr = snet.DefNetwork.PathResolver()
 	l4 := addr.NewL4UDPInfo(cfg.CtrlPort)
-	CtrlConn, err = snet.ListenSCIONWithBindSVC("udp4",
+	CtrlConn, err = snet.ListenSCIONWithBindSVC(Network(),
 		&snet.Addr{IA: IA, Host: &addr.AppAddr{L3: Host, L4: l4}}, nil, addr.SvcSIG)
 	if err != nil {
 		return common.NewBasicError("Error creating ctrl socket", err)
@@ -70,6 +73,15 @@ func Init(cfg sigconfig.SigConf, sdCfg env.SciondClient) error {
 	return nil
 }
 
+// Network returns the snet network of the local SIG address, i.e., "udp4" for
+// an IPv4 address and "udp6" for an IPv6 address.
+func Network() string {
+	if Host.Type() == addr.HostTypeIPv6 {
+		return "udp6"
+	}
+	return "udp4"
+}
+
 func EncapSnetAddr() *snet.Addr {
 	l4 := addr.NewL4UDPInfo(uint16(encapPort))
 	return &snet.Addr{IA: IA, Host: &addr.AppAddr{L3: Host, L4: l4}}
@@ -94,7 +106,7 @@ func initSNET(cfg sigconfig.SigConf, sdCfg env.SciondClient) error {
 	defer timer.Stop()
 Top:
 	for {
-		err = snet.Init(cfg.IA, sdCfg.Path, reliable.NewDispatcherService(cfg.Dispatcher))
+		err = initNetwork(cfg, sdCfg)
 		if err == nil {
 			break
 		}
@@ -106,3 +118,59 @@ Top:
 	}
 	return err
 }
+
+// initNetwork initializes the default snet network. Unlike the network
+// created by snet.Init, it passes SCMP oversize packet errors to the SIG, such
+// that sessions can adapt to the MTU of their paths. If configured, SCMP
+// revocations are verified before the path resolver acts on them.
+func initNetwork(cfg sigconfig.SigConf, sdCfg env.SciondClient) error {
+	sciondConn, err := sciond.NewService(sdCfg.Path, true).Connect()
+	if err != nil {
+		return common.NewBasicError("Unable to initialize SCIOND service", err)
+	}
+	pr := pathmgr.New(
+		sciondConn,
+		pathmgr.Timers{
+			NormalRefire: time.Minute,
+			ErrorRefire:  3 * time.Second,
+		},
+		log.Root(),
+	)
+	network := snet.NewCustomNetworkWithPR(cfg.IA,
+		&snet.DefaultPacketDispatcherService{
+			Dispatcher:  reliable.NewDispatcherService(cfg.Dispatcher),
+			SCMPHandler: newSCMPHandler(cfg, pr, sciondConn),
+		},
+		pr,
+	)
+	return snet.InitWithNetwork(network)
+}
+
+func newSCMPHandler(cfg sigconfig.SigConf, pr pathmgr.Resolver,
+	sciondConn sciond.Connector) snet.SCMPHandler {
+
+	if !cfg.VerifyRevocations {
+		return snet.NewSCMPOversizeHandler(pr)
+	}
+	// The SIG does not fetch the certificate chains of remote ASes, so
+	// revocations authenticated with hash tree signatures are not acted upon.
+	return &scmpHandler{
+		revocations: snet.NewSCMPAuthHandler(pr, sciondConn, nil),
+		oversize:    snet.NewSCMPOversizeHandler(nil),
+	}
+}
+
+// scmpHandler passes SCMP revocations to the authenticating handler, and all
+// other SCMP messages to the oversize handler.
+type scmpHandler struct {
+	revocations snet.SCMPHandler
+	oversize    snet.SCMPHandler
+}
+
+func (h *scmpHandler) Handle(pkt *snet.SCIONPacket) error {
+	hdr, ok := pkt.L4Header.(*scmp.Hdr)
+	if ok && hdr.Class == scmp.C_Path && hdr.Type == scmp.T_P_RevokedIF {
+		return h.revocations.Handle(pkt)
+	}
+	return h.oversize.Handle(pkt)
+}
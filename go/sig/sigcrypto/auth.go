@@ -0,0 +1,146 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigcrypto
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/infra/modules/trust"
+	"github.com/scionproto/scion/go/lib/infra/modules/trust/trustdb"
+	"github.com/scionproto/scion/go/lib/keyconf"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/scrypto"
+	"github.com/scionproto/scion/go/lib/scrypto/cert"
+	"github.com/scionproto/scion/go/lib/util"
+	"github.com/scionproto/scion/go/proto"
+	"github.com/scionproto/scion/go/sig/mgmt"
+)
+
+const dbTimeout = time.Second
+
+var (
+	signer     infra.Signer
+	trustDB    trustdb.TrustDB
+	localChain common.RawBytes
+)
+
+// Init loads the keys and certificates of the local AS from cfgDir, and
+// inserts the certificates into db. The TRCs of remote ISDs must be present in
+// db to authenticate key exchanges with SIGs in these ISDs. Encrypted sessions
+// are not supported unless Init succeeds.
+func Init(cfgDir string, db trustdb.TrustDB, ia addr.IA) error {
+	store := trust.NewStore(db, ia, nil, log.Root())
+	if err := store.LoadAuthoritativeCrypto(filepath.Join(cfgDir, "certs")); err != nil {
+		return common.NewBasicError("Unable to load local crypto", err)
+	}
+	keys, err := keyconf.Load(filepath.Join(cfgDir, "keys"), false, false, false, false)
+	if err != nil {
+		return common.NewBasicError("Unable to load key config", err)
+	}
+	ctx, cancelF := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancelF()
+	meta, err := trust.CreateSignMeta(ctx, ia, db)
+	if err != nil {
+		return common.NewBasicError("Unable to create sign meta", err)
+	}
+	s, err := trust.NewBasicSigner(keys.SignKey, meta)
+	if err != nil {
+		return common.NewBasicError("Unable to create signer", err)
+	}
+	chain, err := db.GetChainVersion(ctx, ia, meta.Src.ChainVer)
+	if err != nil || chain == nil {
+		return common.NewBasicError("Unable to find local certificate chain", err)
+	}
+	raw, err := chain.Compress()
+	if err != nil {
+		return common.NewBasicError("Unable to compress local certificate chain", err)
+	}
+	signer, trustDB, localChain = s, db, raw
+	return nil
+}
+
+// Enabled returns whether encrypted sessions are supported.
+func Enabled() bool {
+	return signer != nil
+}
+
+// LocalChain returns the compressed certificate chain of the local AS.
+func LocalChain() common.RawBytes {
+	return localChain
+}
+
+// Sign signs the ctrl payload of a key exchange with the key of the local AS.
+func Sign(cpld *ctrl.Pld) (*ctrl.SignedPld, error) {
+	if !Enabled() {
+		return nil, common.NewBasicError("Encrypted sessions not supported", nil)
+	}
+	return cpld.SignedPld(signer)
+}
+
+// Verify verifies that spld, which contains the key exchange kx, is signed by
+// ia. The signature is verified with the certificate chain contained in kx,
+// which in turn is verified with the TRC of the issuing ISD. Signatures older
+// than trust.SignatureValidity are rejected.
+func Verify(spld *ctrl.SignedPld, ia addr.IA, kx *mgmt.KeyExchange) error {
+	if !Enabled() {
+		return common.NewBasicError("Encrypted sessions not supported", nil)
+	}
+	sign := spld.Sign
+	if sign == nil || sign.Type == proto.SignType_none || len(sign.Signature) == 0 {
+		return common.NewBasicError("Key exchange not signed", nil)
+	}
+	src, err := ctrl.NewSignSrcDefFromRaw(sign.Src)
+	if err != nil {
+		return common.NewBasicError("Unable to parse sign source", err)
+	}
+	if !src.IA.Equal(ia) {
+		return common.NewBasicError("Key exchange signed by wrong IA", nil,
+			"expected", ia, "actual", src.IA)
+	}
+	now, ts := time.Now(), sign.Time()
+	if ts.After(now) || now.Sub(ts) > trust.SignatureValidity {
+		return common.NewBasicError("Invalid signature timestamp", nil,
+			"ts", util.TimeToString(ts), "now", util.TimeToString(now))
+	}
+	chain, err := cert.ChainFromRaw(kx.Chain, true)
+	if err != nil {
+		return common.NewBasicError("Unable to parse certificate chain", err)
+	}
+	if !chain.Leaf.Subject.Equal(ia) || chain.Leaf.Version != src.ChainVer {
+		return common.NewBasicError("Certificate chain does not match sign source", nil,
+			"chain", chain, "src", src)
+	}
+	ctx, cancelF := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancelF()
+	t, err := trustDB.GetTRCMaxVersion(ctx, chain.Issuer.Issuer.I)
+	if err != nil || t == nil {
+		return common.NewBasicError("Unable to find TRC", err, "isd", chain.Issuer.Issuer.I)
+	}
+	if err := chain.Verify(ia, t); err != nil {
+		return common.NewBasicError("Unable to verify certificate chain", err)
+	}
+	err = scrypto.Verify(sign.SigInput(spld.Blob, false), sign.Signature,
+		chain.Leaf.SubjectSignKey, chain.Leaf.SignAlgorithm)
+	if err != nil {
+		return common.NewBasicError("Unable to verify signature", err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sigcrypto implements the encryption of SIG frames. The key of an
+// encrypted session is established by an ephemeral X25519 key exchange that is
+// piggybacked on the poll messages of the session, and authenticated with the
+// keys of the two ASes.
+//
+// An encrypted frame carries the ID of its key after the authentication tag.
+// The frame header and the key ID are authenticated, but not encrypted:
+//
+//   +--------+--------+--------+--------+--------+--------+--------+--------+
+//   |                        SIG frame header (8B)                          |
+//   +--------+--------+--------+--------+--------+--------+--------+--------+
+//   |                         Encrypted payload ...                         |
+//   +--------+--------+--------+--------+--------+--------+--------+--------+
+//   |                       Authentication tag (16B)                        |
+//   +--------+--------+--------+--------+--------+--------+--------+--------+
+//   |              Key ID               |
+//   +--------+--------+--------+--------+
+//
+// The highest bit of the index field of the header marks encrypted frames.
+// The nonce consists of the session ID, epoch and sequence number, i.e., the
+// sender must not reuse sequence numbers of an epoch with the same key.
+package sigcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/sig/mgmt"
+	"github.com/scionproto/scion/go/sig/sigcmn"
+)
+
+const (
+	// PubKeyLen is the length of the ephemeral public keys.
+	PubKeyLen = 32
+	// FlagEncrypted is set in the index field of encrypted frames.
+	FlagEncrypted uint16 = 1 << 15
+	// Overhead is the number of bytes encryption adds to a frame.
+	Overhead = tagLen + keyIdLen
+	// RekeyInterval is the interval after which the initiator of an encrypted
+	// session establishes a new key.
+	RekeyInterval = 10 * time.Minute
+	// KeyLifetime is the time the receiver of an encrypted session accepts
+	// frames encrypted with a key.
+	KeyLifetime = 2 * RekeyInterval
+
+	keyLen   = 16
+	tagLen   = 16
+	keyIdLen = 4
+	nonceLen = 12
+	hdrLen   = sigcmn.SIGHdrSize
+)
+
+// keyInfo is the context string of the key derivation.
+var keyInfo = []byte("SIG frame key")
+
+// EphemeralKey is an X25519 key pair used for a single key exchange.
+type EphemeralKey struct {
+	Pub  [PubKeyLen]byte
+	priv [PubKeyLen]byte
+}
+
+func NewEphemeralKey() (*EphemeralKey, error) {
+	e := &EphemeralKey{}
+	if _, err := rand.Read(e.priv[:]); err != nil {
+		return nil, common.NewBasicError("Unable to generate ephemeral key", err)
+	}
+	curve25519.ScalarBaseMult(&e.Pub, &e.priv)
+	return e, nil
+}
+
+// DeriveKey derives the key of a session from the ephemeral key e and the
+// key exchange reply kx. The initiator and the remote SIG derive the same key,
+// e must be the key pair whose public key is contained in kx. The key is bound
+// to the ASes of both parties and the session.
+func DeriveKey(e *EphemeralKey, kx *mgmt.KeyExchange, initIA, respIA addr.IA,
+	sessId mgmt.SessionType) (*Key, error) {
+
+	if len(kx.PubKey) != PubKeyLen || len(kx.PeerPubKey) != PubKeyLen {
+		return nil, common.NewBasicError("Invalid public key length", nil,
+			"expected", PubKeyLen, "pubKey", len(kx.PubKey), "peerPubKey", len(kx.PeerPubKey))
+	}
+	var peer, secret [PubKeyLen]byte
+	switch {
+	case bytes.Equal(kx.PubKey, e.Pub[:]):
+		copy(peer[:], kx.PeerPubKey)
+	case bytes.Equal(kx.PeerPubKey, e.Pub[:]):
+		copy(peer[:], kx.PubKey)
+	default:
+		return nil, common.NewBasicError("Key exchange does not contain local public key", nil)
+	}
+	curve25519.ScalarMult(&secret, &e.priv, &peer)
+	if secret == [PubKeyLen]byte{} {
+		return nil, common.NewBasicError("Invalid public key", nil)
+	}
+	salt := make([]byte, 0, 2*PubKeyLen)
+	salt = append(append(salt, kx.PeerPubKey...), kx.PubKey...)
+	info := make(common.RawBytes, len(keyInfo)+2*addr.IABytes+1+keyIdLen)
+	off := copy(info, keyInfo)
+	initIA.Write(info[off:])
+	off += addr.IABytes
+	respIA.Write(info[off:])
+	off += addr.IABytes
+	info[off] = uint8(sessId)
+	common.Order.PutUint32(info[off+1:], kx.KeyId)
+	raw := make([]byte, keyLen)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret[:], salt, info), raw); err != nil {
+		return nil, common.NewBasicError("Unable to derive key", err)
+	}
+	return NewKey(kx.KeyId, raw)
+}
+
+// Key encrypts and decrypts the frames of a session.
+type Key struct {
+	Id uint32
+	// Created is the time the key was established.
+	Created time.Time
+	aead    cipher.AEAD
+}
+
+// NewKey creates an AES-GCM key with the given ID from raw.
+func NewKey(id uint32, raw []byte) (*Key, error) {
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to create cipher", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to create AEAD", err)
+	}
+	return &Key{Id: id, Created: time.Now(), aead: aead}, nil
+}
+
+// Seal encrypts the frame in place, and marks it as encrypted. The returned
+// frame is Overhead bytes longer, i.e., frame must have sufficient capacity.
+func (k *Key) Seal(frame common.RawBytes) common.RawBytes {
+	idx := common.Order.Uint16(frame[6:8])
+	common.Order.PutUint16(frame[6:8], idx|FlagEncrypted)
+	var nonce [nonceLen]byte
+	var aad [hdrLen + keyIdLen]byte
+	copy(nonce[:], frame[:6])
+	copy(aad[:], frame[:hdrLen])
+	common.Order.PutUint32(aad[hdrLen:], k.Id)
+	sealed := k.aead.Seal(frame[hdrLen:hdrLen], nonce[:], frame[hdrLen:], aad[:])
+	frame = frame[:hdrLen+len(sealed)+keyIdLen]
+	common.Order.PutUint32(frame[hdrLen+len(sealed):], k.Id)
+	return frame
+}
+
+// Open authenticates and decrypts the frame in place. The returned frame
+// is Overhead bytes shorter, and no longer marked as encrypted.
+func (k *Key) Open(frame common.RawBytes) (common.RawBytes, error) {
+	if len(frame) < hdrLen+Overhead {
+		return nil, common.NewBasicError("Encrypted frame too short", nil, "len", len(frame))
+	}
+	var nonce [nonceLen]byte
+	var aad [hdrLen + keyIdLen]byte
+	copy(nonce[:], frame[:6])
+	copy(aad[:], frame[:hdrLen])
+	copy(aad[hdrLen:], frame[len(frame)-keyIdLen:])
+	opened, err := k.aead.Open(frame[hdrLen:hdrLen], nonce[:],
+		frame[hdrLen:len(frame)-keyIdLen], aad[:])
+	if err != nil {
+		return nil, common.NewBasicError("Unable to decrypt frame", err)
+	}
+	idx := common.Order.Uint16(frame[6:8])
+	common.Order.PutUint16(frame[6:8], idx&^FlagEncrypted)
+	return frame[:hdrLen+len(opened)], nil
+}
+
+// IsEncrypted returns whether the frame is marked as encrypted.
+func IsEncrypted(frame common.RawBytes) bool {
+	return common.Order.Uint16(frame[6:8])&FlagEncrypted != 0
+}
+
+// FrameKeyId returns the ID of the key an encrypted frame was encrypted with.
+func FrameKeyId(frame common.RawBytes) uint32 {
+	if len(frame) < hdrLen+Overhead {
+		return 0
+	}
+	return common.Order.Uint32(frame[len(frame)-keyIdLen:])
+}
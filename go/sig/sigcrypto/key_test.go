@@ -0,0 +1,105 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigcrypto
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/sig/mgmt"
+)
+
+func TestDeriveKey(t *testing.T) {
+	Convey("DeriveKey", t, func() {
+		initIA, respIA := xtest.MustParseIA("1-ff00:0:110"), xtest.MustParseIA("1-ff00:0:111")
+		initKey, err := NewEphemeralKey()
+		xtest.FailOnErr(t, err)
+		respKey, err := NewEphemeralKey()
+		xtest.FailOnErr(t, err)
+		kx := mgmt.NewKeyExchange(42, respKey.Pub[:], initKey.Pub[:], nil)
+		Convey("Both parties derive the same key", func() {
+			k1, err := DeriveKey(initKey, kx, initIA, respIA, 1)
+			SoMsg("err1", err, ShouldBeNil)
+			k2, err := DeriveKey(respKey, kx, initIA, respIA, 1)
+			SoMsg("err2", err, ShouldBeNil)
+			SoMsg("id", k1.Id, ShouldEqual, 42)
+			frame := newTestFrame("payload")
+			frame, err = k2.Open(k1.Seal(frame))
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("frame", frame, ShouldResemble, newTestFrame("payload"))
+		})
+		Convey("Keys are bound to the session", func() {
+			k1, err := DeriveKey(initKey, kx, initIA, respIA, 1)
+			SoMsg("err1", err, ShouldBeNil)
+			k2, err := DeriveKey(respKey, kx, initIA, respIA, 2)
+			SoMsg("err2", err, ShouldBeNil)
+			_, err = k2.Open(k1.Seal(newTestFrame("payload")))
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("Unknown public keys are rejected", func() {
+			other, err := NewEphemeralKey()
+			xtest.FailOnErr(t, err)
+			_, err = DeriveKey(other, kx, initIA, respIA, 1)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("Invalid public keys are rejected", func() {
+			kx.PubKey = make([]byte, PubKeyLen)
+			_, err := DeriveKey(initKey, kx, initIA, respIA, 1)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestKeySealOpen(t *testing.T) {
+	Convey("Seal and Open", t, func() {
+		k, err := NewKey(7, make([]byte, keyLen))
+		xtest.FailOnErr(t, err)
+		frame := k.Seal(newTestFrame("payload"))
+		SoMsg("len", len(frame), ShouldEqual, len(newTestFrame("payload"))+Overhead)
+		SoMsg("encrypted", IsEncrypted(frame), ShouldBeTrue)
+		SoMsg("keyId", FrameKeyId(frame), ShouldEqual, 7)
+		Convey("Open restores the frame", func() {
+			opened, err := k.Open(frame)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("frame", opened, ShouldResemble, newTestFrame("payload"))
+			SoMsg("encrypted", IsEncrypted(opened), ShouldBeFalse)
+		})
+		Convey("Modified headers are rejected", func() {
+			frame[3] ^= 1
+			_, err := k.Open(frame)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("Modified payloads are rejected", func() {
+			frame[hdrLen] ^= 1
+			_, err := k.Open(frame)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("Short frames are rejected", func() {
+			_, err := k.Open(frame[:hdrLen+Overhead-1])
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+	})
+}
+
+// newTestFrame returns a frame with a fixed header and the given payload, with
+// enough capacity to be sealed.
+func newTestFrame(payload string) common.RawBytes {
+	frame := make(common.RawBytes, hdrLen, hdrLen+len(payload)+Overhead)
+	copy(frame, []byte{1, 0x12, 0x34, 0, 0, 5, 0, 1})
+	return append(frame, payload...)
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package siginfo
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// Instance is one of several SIG instances of a remote AS. Flows are spread
+// across the healthy instances with the lowest Priority value, in proportion
+// to their Weight.
+type Instance struct {
+	*Sig
+	Priority int
+	Weight   int
+}
+
+func (i *Instance) String() string {
+	return fmt.Sprintf("%s prio: %d weight: %d", i.Sig, i.Priority, i.Weight)
+}
+
+// Pin returns the instance the flow with hash flowHash is sent to. Flows are
+// pinned using weighted rendezvous hashing, i.e., a flow is always sent to
+// the same instance as long as that instance is part of insts. If an instance
+// is removed, only the flows pinned to it move to other instances.
+func Pin(insts []*Instance, flowHash uint64) *Instance {
+	var best *Instance
+	var bestScore float64
+	for _, inst := range insts {
+		if inst.Weight <= 0 {
+			continue
+		}
+		score := float64(inst.Weight) / -math.Log(unitFloat(mix(flowHash^inst.hash())))
+		if best == nil || score > bestScore {
+			best, bestScore = inst, score
+		}
+	}
+	return best
+}
+
+func (i *Instance) hash() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(i.Sig.String()))
+	return h.Sum64()
+}
+
+// mix is the finalizer of splitmix64, it spreads the bits of x evenly.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// unitFloat maps x to the open interval (0, 1).
+func unitFloat(x uint64) float64 {
+	return (float64(x>>11) + 0.5) / (1 << 53)
+}
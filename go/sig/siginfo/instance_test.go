@@ -0,0 +1,68 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package siginfo
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+)
+
+func newInst(ip string, weight int) *Instance {
+	return &Instance{
+		Sig: &Sig{
+			IA:          addr.IA{I: 1, A: 0xff0000000001},
+			Host:        addr.HostFromIP(net.ParseIP(ip)),
+			CtrlL4Port:  30256,
+			EncapL4Port: 30056,
+		},
+		Weight: weight,
+	}
+}
+
+func TestPin(t *testing.T) {
+	Convey("Pin", t, func() {
+		a, b, c := newInst("192.0.2.1", 1), newInst("192.0.2.2", 1), newInst("192.0.2.3", 2)
+		insts := []*Instance{a, b, c}
+		const flows = 4000
+		Convey("Flows are pinned to the same instance", func() {
+			for i := uint64(0); i < flows; i++ {
+				SoMsg("pin", Pin(insts, i), ShouldEqual, Pin([]*Instance{c, b, a}, i))
+			}
+		})
+		Convey("Flows are spread in proportion to the weights", func() {
+			counts := make(map[*Instance]int)
+			for i := uint64(0); i < flows; i++ {
+				counts[Pin(insts, i)]++
+			}
+			SoMsg("a", counts[a], ShouldAlmostEqual, flows/4, flows/20)
+			SoMsg("b", counts[b], ShouldAlmostEqual, flows/4, flows/20)
+			SoMsg("c", counts[c], ShouldAlmostEqual, flows/2, flows/20)
+		})
+		Convey("Removing an instance only moves its flows", func() {
+			for i := uint64(0); i < flows; i++ {
+				if pinned := Pin(insts, i); pinned != b {
+					SoMsg("pin", Pin([]*Instance{a, c}, i), ShouldEqual, pinned)
+				}
+			}
+		})
+		Convey("Instances without weight are not used", func() {
+			SoMsg("pin", Pin([]*Instance{newInst("192.0.2.4", 0)}, 1), ShouldBeNil)
+		})
+	})
+}
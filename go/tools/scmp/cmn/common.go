@@ -76,7 +76,8 @@ func init() {
 	flag.BoolVar(&Interactive, "i", false, "Interactive mode")
 	flag.DurationVar(&Interval, "interval", DefaultInterval, "time between packets (echo only)")
 	flag.DurationVar(&Timeout, "timeout", DefaultTimeout, "timeout per packet")
-	flag.UintVar(&Count, "c", 0, "Total number of packet to send (echo only). Maximum value 65535")
+	flag.UintVar(&Count, "c", 0,
+		"Total number of packet to send (echo and trace only). Maximum value 65535")
 	flag.Var((*snet.Addr)(&Local), "local", "(Mandatory) address to listen on")
 	flag.Var((*snet.Addr)(&Remote), "remote", "(Mandatory for clients) address to connect to")
 	flag.Var((*snet.Addr)(&Bind), "bind", "address to bind to, if running behind NAT")
@@ -93,6 +94,7 @@ command:
    echo
    tr | traceroute
    rp | recordpath
+   trace
 
 flags:
 `)
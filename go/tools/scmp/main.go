@@ -32,6 +32,7 @@ import (
 	"github.com/scionproto/scion/go/tools/scmp/cmn"
 	"github.com/scionproto/scion/go/tools/scmp/echo"
 	"github.com/scionproto/scion/go/tools/scmp/recordpath"
+	"github.com/scionproto/scion/go/tools/scmp/trace"
 	"github.com/scionproto/scion/go/tools/scmp/traceroute"
 )
 
@@ -80,6 +81,10 @@ func main() {
 	}
 	defer cmn.Conn.Close()
 
+	// Trace all paths, unless a path is chosen interactively.
+	if cmd == "trace" && !cmn.Remote.IA.Equal(cmn.Local.IA) && !cmn.Interactive {
+		os.Exit(tracePaths())
+	}
 	// If remote is not in local AS, we need a path!
 	var pathStr string
 	if !cmn.Remote.IA.Equal(cmn.Local.IA) {
@@ -102,6 +107,8 @@ func doCommand(cmd string) int {
 		traceroute.Run()
 	case "rp", "recordpath":
 		recordpath.Run()
+	case "trace":
+		trace.Run()
 	default:
 		fmt.Fprintf(os.Stderr, "ERROR: Invalid command %s\n", cmd)
 		flag.Usage()
@@ -114,7 +121,21 @@ func doCommand(cmd string) int {
 	return 0
 }
 
-func choosePath() sciond.PathReplyEntry {
+// tracePaths runs the trace command on all paths to the remote AS.
+func tracePaths() int {
+	paths := queryPaths()
+	for i := range paths {
+		fmt.Printf("Using path [%2d]:\n  %s\n", i, paths[i].Path.String())
+		cmn.Mtu = setPath(&paths[i])
+		trace.Run()
+	}
+	if cmn.Stats.Sent != cmn.Stats.Recv {
+		return 1
+	}
+	return 0
+}
+
+func queryPaths() []sciond.PathReplyEntry {
 	reply, err := sdConn.Paths(context.Background(), cmn.Remote.IA, cmn.Local.IA, 0,
 		sciond.PathReqFlags{Refresh: *refresh})
 	if err != nil {
@@ -123,11 +144,15 @@ func choosePath() sciond.PathReplyEntry {
 	if reply.ErrorCode != sciond.ErrorOk {
 		cmn.Fatal("SCIOND unable to retrieve paths: %s\n", reply.ErrorCode)
 	}
-	var pathIndex uint64
-	paths := reply.Entries
-	if len(paths) == 0 {
+	if len(reply.Entries) == 0 {
 		cmn.Fatal("No paths available to remote destination")
 	}
+	return reply.Entries
+}
+
+func choosePath() sciond.PathReplyEntry {
+	var pathIndex uint64
+	paths := queryPaths()
 	if cmn.Interactive {
 		fmt.Printf("Available paths to %v\n", cmn.Remote.IA)
 		for i := range paths {
@@ -151,7 +176,11 @@ func choosePath() sciond.PathReplyEntry {
 
 func setPathAndMtu() uint16 {
 	path := choosePath()
-	cmn.PathEntry = &path
+	return setPath(&path)
+}
+
+func setPath(path *sciond.PathReplyEntry) uint16 {
+	cmn.PathEntry = path
 	cmn.Remote.Path = spath.New(cmn.PathEntry.Path.FwdPath)
 	cmn.Remote.Path.InitOffsets()
 	cmn.Remote.NextHop, _ = cmn.PathEntry.HostInfo.Overlay()
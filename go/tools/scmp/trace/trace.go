@@ -0,0 +1,166 @@
+// Copyright 2019 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trace implements the trace command of the scmp tool. It sends SCMP
+// echo requests carrying the hop trace extension, and prints the entries the
+// border routers recorded on the way to the remote host and back.
+package trace
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/hpkt"
+	"github.com/scionproto/scion/go/lib/layers"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/spkt"
+	"github.com/scionproto/scion/go/tools/scmp/cmn"
+)
+
+// DefaultCount is the number of probes sent per path if no count is set.
+const DefaultCount = 3
+
+var (
+	id uint64
+)
+
+// Run sends the probes on the current path, and prints the recorded hops.
+func Run() {
+	cmn.SetupSignals(nil)
+	count := cmn.Count
+	if count == 0 {
+		count = DefaultCount
+	}
+	// Each border router on the path records at most one entry per traversed
+	// interface, on the way to the remote host and back.
+	slots := 1
+	if cmn.PathEntry != nil {
+		slots = 2 * len(cmn.PathEntry.Path.Interfaces)
+	}
+	if slots > layers.HopTraceMaxSlots {
+		slots = layers.HopTraceMaxSlots
+	}
+	id = cmn.Rand()
+	for seq := uint16(0); uint(seq) < count; seq++ {
+		if err := probe(seq, slots); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		}
+	}
+}
+
+func probe(seq uint16, slots int) error {
+	extn, err := layers.NewExtnHopTrace(uint32(id), slots)
+	if err != nil {
+		return err
+	}
+	info := &scmp.InfoEcho{Id: id, Seq: seq}
+	pkt := cmn.NewSCMPPkt(scmp.T_G_EchoRequest, info, extn)
+	b := make(common.RawBytes, cmn.Mtu)
+	ts := time.Now()
+	cmn.UpdatePktTS(pkt, ts)
+	pktLen, err := hpkt.WriteScnPkt(pkt, b)
+	if err != nil {
+		return common.NewBasicError("Unable to serialize SCION packet", err)
+	}
+	written, err := cmn.Conn.WriteTo(b[:pktLen], cmn.NextHopAddr())
+	if err != nil {
+		return common.NewBasicError("Unable to write", err)
+	} else if written != pktLen {
+		return common.NewBasicError("Wrote incomplete message", nil,
+			"written", written, "expected", pktLen)
+	}
+	cmn.Stats.Sent += 1
+	cmn.Conn.SetReadDeadline(ts.Add(cmn.Timeout))
+	for {
+		pktLen, err = cmn.Conn.Read(b)
+		if err != nil {
+			return common.NewBasicError("Unable to read", err, "scmp_seq", seq)
+		}
+		now := time.Now()
+		pktRecv := &spkt.ScnPkt{}
+		if err := hpkt.ParseScnPkt(pktRecv, b[:pktLen]); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: SCION packet parse error: %v\n", err)
+			continue
+		}
+		scmpHdr, trace, err := validate(pktRecv, seq)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			continue
+		}
+		cmn.Stats.Recv += 1
+		rtt := now.Sub(scmpHdr.Time()).Round(time.Microsecond)
+		prettyPrint(pktRecv, pktLen, seq, rtt, scmpHdr.Time(), trace)
+		return nil
+	}
+}
+
+func validate(pkt *spkt.ScnPkt, seq uint16) (*scmp.Hdr, *layers.ExtnHopTrace, error) {
+	scmpHdr, scmpPld, err := cmn.Validate(pkt)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, ok := scmpPld.Info.(*scmp.InfoEcho)
+	if !ok {
+		return nil, nil,
+			common.NewBasicError("Not an Info Echo", nil, "type", common.TypeOf(scmpPld.Info))
+	}
+	if info.Id != id {
+		return nil, nil,
+			common.NewBasicError("Wrong SCMP ID", nil, "expected", id, "actual", info.Id)
+	}
+	if info.Seq != seq {
+		return nil, nil,
+			common.NewBasicError("Wrong SCMP seq", nil, "expected", seq, "actual", info.Seq)
+	}
+	for _, e := range pkt.HBHExt {
+		if trace, ok := e.(*layers.ExtnHopTrace); ok {
+			return scmpHdr, trace, nil
+		}
+	}
+	return nil, nil, common.NewBasicError("No hop trace extension in reply", nil)
+}
+
+// prettyPrint prints the hops recorded in trace. For each hop, it prints the
+// time the router received the probe relative to the time the probe was sent,
+// the latency since the previous hop, and the time the probe spent in the
+// router. The times are only accurate if the clocks of the routers are
+// synchronized.
+func prettyPrint(pkt *spkt.ScnPkt, pktLen int, seq uint16, rtt time.Duration,
+	sent time.Time, trace *layers.ExtnHopTrace) {
+
+	fmt.Printf("%d bytes from %s,[%s] scmp_seq=%d time=%s Hops=%d\n",
+		pktLen, pkt.SrcIA, pkt.SrcHost, seq, rtt, len(trace.Entries))
+	if len(trace.Entries) == 0 {
+		return
+	}
+	fmt.Printf(" %3s %-18s %7s %7s %12s %12s %12s\n",
+		"Hop", "ISD-AS", "Ingress", "Egress", "Time", "Latency", "Queue")
+	prev := sent
+	replied := false
+	for i, e := range trace.Entries {
+		fmt.Printf(" %3d %-18s %7d %7d %12s %12s %12s\n", i, e.IA, e.Ingress, e.Egress,
+			e.Timestamp.Sub(sent).Round(time.Microsecond),
+			e.Timestamp.Sub(prev).Round(time.Microsecond),
+			e.QueueTime.Round(time.Microsecond))
+		prev = e.Timestamp
+		if !replied && e.IA.Equal(pkt.SrcIA) && e.Ingress != 0 && e.Egress == 0 {
+			// The probe was delivered to the remote host, the remaining
+			// entries were recorded on the way back.
+			fmt.Printf(" --- reply ---\n")
+			replied = true
+		}
+	}
+}
@@ -527,7 +527,8 @@ class CertServer(SCIONElement):
             err.append("TRC not present for %s(v: %s)" % (rep.isd_as[0], rep.p.trcVer))
         if err:
             raise SCIONVerificationError(", ".join(err))
-        raw = get_signing_input_rep(rep.isd_as, rep.p.timestamp, rep.p.expTime, rep.p.cipher)
+        raw = get_signing_input_rep(rep.isd_as, rep.p.timestamp, rep.p.expTime, rep.p.cipher,
+                                    rep.p.epochBegin)
         try:
             verify_sig_chain_trc(raw, rep.p.signature, rep.isd_as, chain, trc)
         except SCIONVerificationError as e:
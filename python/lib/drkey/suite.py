@@ -82,7 +82,7 @@ def derive_drkey_raw(sec_val, dst_ia):
         [struct.pack("!I", dst_ia._isd), struct.pack("!I", dst_ia._as), bytes(8)]))
 
 
-def get_signing_input_rep(isd_as, timestamp, exp_time, cipher):
+def get_signing_input_rep(isd_as, timestamp, exp_time, cipher, epoch_begin=0):
     """
     Pack the input such that it can be signed.
 
@@ -90,12 +90,16 @@ def get_signing_input_rep(isd_as, timestamp, exp_time, cipher):
     :param int timestamp: signature creation time (format: drkey_time()).
     :param int exp_time: DRKey expiration time (format: drkey_time()).
     :param bytes cipher: the encrypted first order DRKey.
+    :param int epoch_begin: DRKey validity begin, only signed if set (format: drkey_time()).
     :returns: the packed input to sign.
     :rtype: bytes
     """
     ts = struct.pack("!Q", timestamp)
     exp = struct.pack("!Q", exp_time)
-    return b"".join([isd_as.pack(), cipher, ts, exp])
+    parts = [isd_as.pack(), cipher, ts, exp]
+    if epoch_begin:
+        parts.append(struct.pack("!Q", epoch_begin))
+    return b"".join(parts)
 
 
 def _encrypt_drkey(drkey, private_key, public_key):
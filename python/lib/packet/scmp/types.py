@@ -219,6 +219,18 @@ class SCMPSibraClass(TypeBase):
     #: Request flag not set in setup packet
     # Payload: basic, sibra ext header
     SETUP_NO_REQ = 1
+    #: Invalid SIBRA opaque field
+    # Info: ext idx
+    # Payload: basic, exts
+    BAD_SOF = 2
+    #: SIBRA reservation expired
+    # Info: ext idx
+    # Payload: basic, exts
+    EXPIRED_RES = 3
+    #: SIBRA reservation bandwidth exceeded
+    # Info: ext idx
+    # Payload: basic, exts
+    BW_EXCEEDED = 4
 
 
 class SCMPIncParts(TypeBase):